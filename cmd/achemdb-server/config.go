@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/daniacca/achemdb/internal/achem"
 )
@@ -18,6 +19,16 @@ type ServerConfig struct {
 	SnapshotDir        string
 	SnapshotEveryTicks int
 	LogLevel           string
+	LogFormat          string
+	IsolateNotifiers   bool
+	StatsdAddr         string
+	StatsdInterval     time.Duration
+	NotifyMaxWorkers   int
+	MetricsEnabled     bool
+	AuthToken          string
+	RestoreOnStart     bool
+	EventLogSize       int
+	NotifierFileDir    string
 }
 
 // configResolver defines how to resolve a single configuration value
@@ -87,6 +98,118 @@ func loadServerConfig() ServerConfig {
 			description: "Log level: debug, info, warn, error",
 			setter:      func(c *ServerConfig, v string) { c.LogLevel = v },
 		},
+		{
+			flagName:    "log-format",
+			envVarName:  "ACHEMDB_LOG_FORMAT",
+			defaultVal:  LogFormatText,
+			description: "Log format: text, or json for one JSON object per line",
+			setter:      func(c *ServerConfig, v string) { c.LogFormat = v },
+		},
+		{
+			flagName:    "isolate-notifiers",
+			envVarName:  "ACHEMDB_ISOLATE_NOTIFIERS",
+			defaultVal:  "false",
+			description: "Give every environment its own notifiers (registered via /env/{envID}/notifiers) instead of the global ones",
+			setter: func(c *ServerConfig, v string) {
+				if val, err := strconv.ParseBool(v); err == nil {
+					c.IsolateNotifiers = val
+				} else {
+					log.Printf("Invalid value for isolate-notifiers: %s, using default false", v)
+					c.IsolateNotifiers = false
+				}
+			},
+		},
+		{
+			flagName:    "metrics",
+			envVarName:  "ACHEMDB_METRICS",
+			defaultVal:  "true",
+			description: "Collect engine metrics and expose them in Prometheus format on /metrics",
+			setter: func(c *ServerConfig, v string) {
+				if val, err := strconv.ParseBool(v); err == nil {
+					c.MetricsEnabled = val
+				} else {
+					log.Printf("Invalid value for metrics: %s, using default true", v)
+					c.MetricsEnabled = true
+				}
+			},
+		},
+		{
+			flagName:    "restore-on-start",
+			envVarName:  "ACHEMDB_RESTORE",
+			defaultVal:  "false",
+			description: "Recreate the environments found in the snapshot directory at startup, from their snapshots",
+			setter: func(c *ServerConfig, v string) {
+				if val, err := strconv.ParseBool(v); err == nil {
+					c.RestoreOnStart = val
+				} else {
+					log.Printf("Invalid value for restore-on-start: %s, using default false", v)
+					c.RestoreOnStart = false
+				}
+			},
+		},
+		{
+			flagName:    "auth-token",
+			envVarName:  "ACHEMDB_AUTH_TOKEN",
+			defaultVal:  "",
+			description: "optional bearer token required on every request except the health checks (/healthz, /livez, /readyz); empty disables authentication",
+			setter:      func(c *ServerConfig, v string) { c.AuthToken = v },
+		},
+		{
+			flagName:    "statsd-addr",
+			envVarName:  "ACHEMDB_STATSD_ADDR",
+			defaultVal:  "",
+			description: "optional StatsD address (host:port) to push metrics to over UDP; empty disables it",
+			setter:      func(c *ServerConfig, v string) { c.StatsdAddr = v },
+		},
+		{
+			flagName:    "statsd-interval",
+			envVarName:  "ACHEMDB_STATSD_INTERVAL",
+			defaultVal:  "10s",
+			description: "How often to push metrics to StatsD (e.g. 10s, 1m)",
+			setter: func(c *ServerConfig, v string) {
+				if val, err := time.ParseDuration(v); err == nil && val > 0 {
+					c.StatsdInterval = val
+				} else {
+					log.Printf("Invalid value for statsd-interval: %s, using default 10s", v)
+					c.StatsdInterval = 10 * time.Second
+				}
+			},
+		},
+		{
+			flagName:    "notify-max-workers",
+			envVarName:  "ACHEMDB_NOTIFY_MAX_WORKERS",
+			defaultVal:  "0",
+			description: "Scale notification workers up to this many while the queue is persistently full; 0 disables autoscaling",
+			setter: func(c *ServerConfig, v string) {
+				if val, err := strconv.Atoi(v); err == nil && val >= 0 {
+					c.NotifyMaxWorkers = val
+				} else {
+					log.Printf("Invalid value for notify-max-workers: %s, using default 0", v)
+					c.NotifyMaxWorkers = 0
+				}
+			},
+		},
+		{
+			flagName:    "event-log-size",
+			envVarName:  "ACHEMDB_EVENT_LOG_SIZE",
+			defaultVal:  strconv.Itoa(defaultEventLogSize),
+			description: "Number of recent notification events kept for GET /events; 0 disables the event log",
+			setter: func(c *ServerConfig, v string) {
+				if val, err := strconv.Atoi(v); err == nil && val >= 0 {
+					c.EventLogSize = val
+				} else {
+					log.Printf("Invalid value for event-log-size: %s, using default %d", v, defaultEventLogSize)
+					c.EventLogSize = defaultEventLogSize
+				}
+			},
+		},
+		{
+			flagName:    "notifier-file-dir",
+			envVarName:  "ACHEMDB_NOTIFIER_FILE_DIR",
+			defaultVal:  "",
+			description: "Directory file notifiers write to, with paths relative to it; empty disables file notifiers",
+			setter:      func(c *ServerConfig, v string) { c.NotifierFileDir = v },
+		},
 	}
 
 	// Register string flags first
@@ -161,7 +284,10 @@ func applyInitialSchemaToEnvironment(manager *achem.EnvironmentManager, globalNo
 	// Set the notification manager and snapshot config for the environment
 	env, exists := manager.GetEnvironment(envID)
 	if exists {
-		env.SetNotificationManager(globalNotifierMgr)
+		// A nil global manager means notifier isolation: the environment keeps its own
+		if globalNotifierMgr != nil {
+			env.SetNotificationManager(globalNotifierMgr)
+		}
 		if snapshotDir != "" {
 			env.SetSnapshotDir(snapshotDir)
 		}
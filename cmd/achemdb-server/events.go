@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+const (
+	// defaultEventLogSize is the number of notification events kept by default
+	defaultEventLogSize = 1000
+	// eventLogCallbackID is the ID of the callback feeding the event log
+	eventLogCallbackID = "__event_log__"
+)
+
+// eventLog keeps the most recent notification events of all environments in a
+// fixed-size ring buffer. It is safe for concurrent use.
+type eventLog struct {
+	mu     sync.Mutex
+	events []achem.NotificationEvent
+	next   int // index of the next write
+	full   bool
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{events: make([]achem.NotificationEvent, size)}
+}
+
+// add records an event, overwriting the oldest one when the buffer is full
+func (l *eventLog) add(event achem.NotificationEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events[l.next] = event
+	l.next++
+	if l.next == len(l.events) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// recent returns, oldest first, the last limit events of the environment envID and
+// the reaction reactionID. Empty filters match everything; limit <= 0 returns every
+// matching event.
+func (l *eventLog) recent(limit int, envID achem.EnvironmentID, reactionID string) []achem.NotificationEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.events)
+	}
+
+	// walk backwards from the newest event so the limit keeps the most recent ones
+	var matched []achem.NotificationEvent
+	for i := 0; i < count && (limit <= 0 || len(matched) < limit); i++ {
+		ev := l.events[(l.next-1-i+len(l.events))%len(l.events)]
+		if (envID == "" || ev.EnvironmentID == envID) && (reactionID == "" || ev.ReactionID == reactionID) {
+			matched = append(matched, ev)
+		}
+	}
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched
+}
+
+// SetEventLogSize sets how many notification events GET /events keeps, across all
+// environments. 0 disables the event log. Call before serving requests.
+func (s *Server) SetEventLogSize(size int) {
+	if size <= 0 {
+		s.events = nil
+		if s.globalNotifierMgr != nil {
+			s.globalNotifierMgr.UnregisterCallback(eventLogCallbackID)
+		}
+		return
+	}
+	s.events = newEventLog(size)
+	if s.globalNotifierMgr != nil {
+		s.recordEvents(s.globalNotifierMgr)
+	}
+}
+
+// recordEvents makes mgr append the events it dispatches to the event log, if enabled.
+// Environment-local managers don't inherit the callbacks of the global one, so each
+// manager is registered on its own.
+func (s *Server) recordEvents(mgr *achem.NotificationManager) {
+	if s.events != nil {
+		mgr.RegisterCallback(eventLogCallbackID, s.events.add)
+	}
+}
+
+// GET /events?limit=N&env=X&reaction=Y
+// Returns the most recent notification events, oldest first, optionally filtered by
+// environment and reaction
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if s.events == nil {
+		http.Error(w, "event log is disabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit: must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	events := s.events.recent(limit, achem.EnvironmentID(q.Get("env")), q.Get("reaction"))
+	if events == nil {
+		events = []achem.NotificationEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"events": events}); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+	}
+}
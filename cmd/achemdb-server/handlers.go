@@ -1,15 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/daniacca/achemdb/internal/achem"
 	achemnotifiers "github.com/daniacca/achemdb/internal/achem/notifiers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // extractEnvID extracts the environment ID from a path like "/env/{envID}/..."
@@ -34,14 +43,42 @@ func extractEnvID(path string) (achem.EnvironmentID, string) {
 	return envID, remainingPath
 }
 
+// GET /livez
+// GET /healthz
+// Liveness: reports that the process is up and serving requests
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
 
+// GET /readyz
+// Readiness: 503 until the environment set with SetReadyEnvironment exists, or, without
+// one, until at least one environment exists
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.readyEnvID != "" {
+		if _, exists := s.manager.GetEnvironment(s.readyEnvID); !exists {
+			http.Error(w, "not ready: environment "+string(s.readyEnvID)+" is not loaded", http.StatusServiceUnavailable)
+			return
+		}
+	} else if len(s.manager.ListEnvironments()) == 0 {
+		http.Error(w, "not ready: no environment loaded", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
 // POST /env/{envID}/schema
+// POST /env/{envID}/schema?seed=42
+// POST /env/{envID}/schema?max_molecules=10000&eviction_policy=lowest_energy
+// POST /env/{envID}/schema?validate=strict
 // Body: SchemaConfig JSON
-// Creates a new environment with the given ID and schema, or updates existing one
+// Creates a new environment with the given ID and schema, or updates existing one.
+// The optional seed makes the environment's random draws reproducible; max_molecules
+// caps the number of molecules, evicting the excess according to eviction_policy.
+// Updates that remove species with live molecules are reported, or rejected with
+// validate=strict.
 func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -51,6 +88,28 @@ func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	seed, hasSeed, err := parseSeedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	capacity, err := parseCapacityParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	strict := false
+	switch v := r.URL.Query().Get("validate"); v {
+	case "":
+	case "strict":
+		strict = true
+	default:
+		http.Error(w, "validate must be 'strict'", http.StatusBadRequest)
+		return
+	}
+
 	var cfg achem.SchemaConfig
 	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
 		http.Error(w, "invalid schema json: "+err.Error(), http.StatusBadRequest)
@@ -64,42 +123,174 @@ func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Try to create new environment, or update existing one
+	var orphaned achem.OrphanedMolecules
 	err = s.manager.CreateEnvironment(envID, schema)
 	if err != nil {
 		// Environment already exists, update its schema
-		if err := s.manager.UpdateEnvironmentSchema(envID, schema); err != nil {
-			s.logger.Errorf("Failed to update environment schema: env_id=%s error=%v", envID, err)
+		orphaned, err = s.manager.UpdateEnvironmentSchemaChecked(envID, schema, strict)
+		var orphanErr *achem.OrphanedMoleculesError
+		if errors.As(err, &orphanErr) {
+			http.Error(w, orphanErr.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			s.logger.Errorw("Failed to update environment schema", "env_id", envID, "error", err)
 			http.Error(w, "cannot update environment: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		s.logger.Infof("Environment schema updated: env_id=%s schema_name=%s", envID, cfg.Name)
+		if len(orphaned) > 0 {
+			s.logger.Warnw("Schema update orphaned molecules", "env_id", envID, "orphaned", orphaned.String())
+		}
+		s.logger.Infow("Environment schema updated", "env_id", envID, "schema_name", cfg.Name)
 	} else {
-		s.logger.Infof("Environment created: env_id=%s schema_name=%s", envID, cfg.Name)
+		s.logger.Infow("Environment created", "env_id", envID, "schema_name", cfg.Name)
 	}
 
 	// Set the notification manager and snapshot config for the environment
-	env, exists := s.manager.GetEnvironment(envID)
-	if exists {
-		env.SetNotificationManager(s.globalNotifierMgr)
-		// Set snapshot directory if configured
-		if s.snapshotDir != "" {
-			env.SetSnapshotDir(s.snapshotDir)
-		}
-		// Set snapshot frequency
-		if s.snapshotEveryTicks >= 0 {
-			env.SetSnapshotEveryNTicks(s.snapshotEveryTicks)
+	if env, exists := s.manager.GetEnvironment(envID); exists {
+		s.configureEnvironment(env)
+		if hasSeed {
+			env.SetRandomSeed(seed)
 		}
+		capacity.apply(env)
 	}
 
 	w.WriteHeader(http.StatusOK)
+	if len(orphaned) > 0 {
+		_, _ = w.Write([]byte("schema loaded; orphaned molecules: " + orphaned.String()))
+		return
+	}
 	_, _ = w.Write([]byte("schema loaded"))
 }
 
+// GET /env/{envID}/schema
+// Returns the schema configuration currently applied to the environment
+func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/schema", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	cfg, ok := env.SchemaConfig()
+	if !ok {
+		http.Error(w, "environment has no schema", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cfg); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseSeedParam parses the optional seed query parameter
+func parseSeedParam(r *http.Request) (int64, bool, error) {
+	raw := r.URL.Query().Get("seed")
+	if raw == "" {
+		return 0, false, nil
+	}
+	seed, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("seed must be an integer")
+	}
+	return seed, true, nil
+}
+
+// capacityParams holds the optional molecule cap requested when creating an environment
+type capacityParams struct {
+	maxMolecules    int
+	hasMaxMolecules bool
+	evictionPolicy  string
+}
+
+// parseCapacityParams parses the optional max_molecules and eviction_policy query parameters
+func parseCapacityParams(r *http.Request) (capacityParams, error) {
+	var p capacityParams
+	query := r.URL.Query()
+	if raw := query.Get("max_molecules"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("max_molecules must be a non-negative integer")
+		}
+		p.maxMolecules, p.hasMaxMolecules = n, true
+	}
+	switch policy := query.Get("eviction_policy"); policy {
+	case "", achem.EvictOldest, achem.EvictLowestEnergy:
+		p.evictionPolicy = policy
+	default:
+		return p, fmt.Errorf("eviction_policy must be '%s' or '%s'", achem.EvictOldest, achem.EvictLowestEnergy)
+	}
+	return p, nil
+}
+
+// apply sets the requested molecule cap and eviction policy on env
+func (p capacityParams) apply(env *achem.Environment) {
+	if p.hasMaxMolecules {
+		env.SetMaxMolecules(p.maxMolecules)
+	}
+	if p.evictionPolicy != "" {
+		_ = env.SetEvictionPolicy(p.evictionPolicy) // validated by parseCapacityParams
+	}
+}
+
+// configureEnvironment applies the server-wide notifier and snapshot settings to env
+func (s *Server) configureEnvironment(env *achem.Environment) {
+	// With notifier isolation the environment keeps its own notification manager, and
+	// so does an environment that registered local notifiers (see localNotificationManager)
+	if s.globalNotifierMgr != nil {
+		s.envNotifiersMu.Lock()
+		if env.GetNotificationManager().Fallback() != s.globalNotifierMgr {
+			env.SetNotificationManager(s.globalNotifierMgr)
+		}
+		s.envNotifiersMu.Unlock()
+	} else {
+		s.enableNotifyAutoscale(env.GetNotificationManager())
+		s.recordEvents(env.GetNotificationManager())
+	}
+	// Set snapshot directory if configured
+	if s.snapshotDir != "" {
+		env.SetSnapshotDir(s.snapshotDir)
+	}
+	// Set snapshot frequency
+	if s.snapshotEveryTicks >= 0 {
+		env.SetSnapshotEveryNTicks(s.snapshotEveryTicks)
+	}
+}
+
 // POST /env/{envID}/molecule
-// Body: { "species": "...", "payload": { ... } }
+// POST /env/{envID}/molecule?upsert=true
+// Body: { "species": "...", "payload": { ... }, "ttl": 10, "match": ["field", ...] }
+// New molecules get the default energy and stability of their species. With upsert, a
+// molecule of the same species with the same values for the match fields is updated
+// instead of inserting a new one.
 type insertMoleculeRequest struct {
-	Species string         `json:"species"`
-	Payload map[string]any `json:"payload"`
+	Species  string          `json:"species"`
+	Payload  map[string]any  `json:"payload"`
+	Match    []string        `json:"match,omitempty"`
+	TTL      int64           `json:"ttl,omitempty"`
+	Position *achem.Position `json:"position,omitempty"`
+}
+
+// molecule returns the molecule to insert for the request
+func (req insertMoleculeRequest) molecule() achem.Molecule {
+	opts := []achem.MoleculeOption{achem.WithTTL(req.TTL)}
+	if req.Position != nil {
+		opts = append(opts, achem.WithPosition(req.Position.X, req.Position.Y))
+	}
+	return achem.NewMolecule(achem.SpeciesName(req.Species), req.Payload, 0, opts...)
+}
+
+type upsertMoleculeResponse struct {
+	ID       achem.MoleculeID `json:"id"`
+	Inserted bool             `json:"inserted"`
 }
 
 func (s *Server) handleInsertMolecule(w http.ResponseWriter, r *http.Request) {
@@ -123,17 +314,225 @@ func (s *Server) handleInsertMolecule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	m := achem.NewMolecule(achem.SpeciesName(req.Species), req.Payload, 0)
-	env.Insert(m)
+	if req.TTL < 0 {
+		http.Error(w, "ttl must be non-negative", http.StatusBadRequest)
+		return
+	}
+
+	if !s.allowInsert(w, env, 1) {
+		return
+	}
+
+	m := req.molecule()
+
+	if r.URL.Query().Get("upsert") == "true" {
+		stored, inserted, err := env.Upsert(m, req.Match, achem.WithSpeciesDefaults())
+		if err != nil {
+			http.Error(w, "invalid upsert: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.logger.Debugw("Molecule upserted", "env_id", envID, "species", req.Species, "id", stored.ID, "inserted", inserted)
 
-	s.logger.Debugf("Molecule inserted: env_id=%s species=%s", envID, req.Species)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(upsertMoleculeResponse{ID: stored.ID, Inserted: inserted}); err != nil {
+			http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+
+	if err := env.Insert(m, achem.WithSpeciesDefaults()); err != nil {
+		http.Error(w, "invalid molecule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.logger.Debugw("Molecule inserted", "env_id", envID, "species", req.Species)
 
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
 
+// POST /env/{envID}/molecules/batch?ids={bool}
+// Body: [{ "species": "...", "payload": { ... }, "ttl": 10 }, ...]
+// Insert many molecules at once. The response reports the number of inserted molecules
+// and, with ids=true, their generated IDs in request order.
+type insertBatchResponse struct {
+	Inserted int                `json:"inserted"`
+	IDs      []achem.MoleculeID `json:"ids,omitempty"`
+}
+
+func (s *Server) handleInsertBatch(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/molecules/batch", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	var reqs []insertMoleculeRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mols := make([]achem.Molecule, 0, len(reqs))
+	for i, req := range reqs {
+		if req.Species == "" {
+			http.Error(w, fmt.Sprintf("molecule at index %d: species is required", i), http.StatusBadRequest)
+			return
+		}
+		if req.TTL < 0 {
+			http.Error(w, fmt.Sprintf("molecule at index %d: ttl must be non-negative", i), http.StatusBadRequest)
+			return
+		}
+		mols = append(mols, req.molecule())
+	}
+
+	if !s.allowInsert(w, env, len(mols)) {
+		return
+	}
+
+	ids, err := env.InsertBatch(mols, achem.WithSpeciesDefaults())
+	if err != nil {
+		http.Error(w, "invalid molecule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.logger.Debugw("Molecules inserted", "env_id", envID, "count", len(ids))
+
+	resp := insertBatchResponse{Inserted: len(ids)}
+	if r.URL.Query().Get("ids") == "true" {
+		resp.IDs = ids
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// allowInsert checks the environment's insert rate limit for n molecules.
+// If the limit is exceeded, it writes a 429 response with a Retry-After header and returns false.
+// More molecules than the burst size can never be accepted, so they get a 413 instead.
+func (s *Server) allowInsert(w http.ResponseWriter, env *achem.Environment, n int) bool {
+	ok, wait := env.AllowInsert(n)
+	if ok {
+		return true
+	}
+	if wait == 0 {
+		burst := env.GetInsertRateLimit().Burst
+		http.Error(w, fmt.Sprintf("batch of %d exceeds the burst of %d", n, burst), http.StatusRequestEntityTooLarge)
+		return false
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, "insert rate limit exceeded", http.StatusTooManyRequests)
+	return false
+}
+
+// GET /env/{envID}/ratelimit
+// PUT /env/{envID}/ratelimit
+// Body: { "per_second": 100, "burst": 200 } (per_second 0 disables the limit)
+// Reads or configures the per-environment insert rate limit
+func (s *Server) handleInsertRateLimit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/ratelimit", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req achem.InsertRateLimit
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.PerSecond < 0 || req.Burst < 0 {
+			http.Error(w, "per_second and burst must be non-negative", http.StatusBadRequest)
+			return
+		}
+		env.SetInsertRateLimit(req.PerSecond, req.Burst)
+		s.logger.Infow("Insert rate limit updated", "env_id", envID, "per_second", req.PerSecond, "burst", req.Burst)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env.GetInsertRateLimit()); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /env/{envID}/indexes
+// POST /env/{envID}/indexes
+// DELETE /env/{envID}/indexes
+// Body: { "species": "Order", "field": "status" }
+// Lists, sets or drops the secondary indexes on payload fields used by molecule queries
+func (s *Server) handleIndexes(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/indexes", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		var req achem.IndexField
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Species == "" || req.Field == "" {
+			http.Error(w, "species and field are required", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPost {
+			env.SetIndexField(req.Species, req.Field)
+			s.logger.Infow("Secondary index set", "env_id", envID, "species", req.Species, "field", req.Field)
+		} else {
+			env.DropIndexField(req.Species, req.Field)
+			s.logger.Infow("Secondary index dropped", "env_id", envID, "species", req.Species, "field", req.Field)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env.IndexFields()); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 // POST /env/{envID}/tick
-// Manually trigger a single step (useful for testing/debugging when auto-running is disabled)
+// POST /env/{envID}/tick?dry_run=true
+// Manually trigger a single step (useful for testing/debugging when auto-running is disabled).
+// With dry_run=true the step is computed but not applied, and a report of what it would
+// change is returned instead.
 func (s *Server) handleTick(w http.ResponseWriter, r *http.Request) {
 	envID, _ := extractEnvID(r.URL.Path)
 	if envID == "" {
@@ -147,14 +546,56 @@ func (s *Server) handleTick(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	dryRun := false
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		v, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			http.Error(w, "invalid dry_run: must be a boolean", http.StatusBadRequest)
+			return
+		}
+		dryRun = v
+	}
+
+	if dryRun {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(env.StepDryRun()); err != nil {
+			http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
 	env.Step()
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ticked"))
 }
 
+// POST /env/{envID}/reset
+// Remove all molecules and set the environment time back to 0, keeping the schema,
+// notifiers and settings. A running environment keeps running from tick 0.
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/reset", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	env.Reset()
+	s.logger.Infow("Environment reset", "env_id", envID)
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("environment reset"))
+}
+
 // POST /env/{envID}/start
 // Start the environment auto-running with the specified interval (in milliseconds)
 // Query param: interval (default: 1000ms)
+// Fails with 409 while a background run job steps the environment (see handleRunTicks).
 func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 	envID, _ := extractEnvID(r.URL.Path)
 	if envID == "" {
@@ -179,19 +620,536 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	env.Run(interval)
-	s.logger.Infof("Environment started: env_id=%s interval=%v", envID, interval)
+	// a run job steps the environment itself: auto-running too would interleave ticks
+	s.runJobsMu.Lock()
+	jobID, running := s.runJobInProgressLocked(envID)
+	if !running {
+		env.Run(interval)
+	}
+	s.runJobsMu.Unlock()
+	if running {
+		http.Error(w, "a run job is in progress for this environment: "+jobID, http.StatusConflict)
+		return
+	}
+	s.logger.Infow("Environment started", "env_id", envID, "interval", interval)
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("environment started"))
+}
+
+// POST /env/{envID}/stop
+// Stop the environment auto-running
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/stop", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	env.Stop()
+	s.logger.Infow("Environment stopped", "env_id", envID)
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("environment stopped"))
+}
+
+// POST /env/{envID}/pause
+// POST /env/{envID}/resume
+// Pause or resume the auto-running environment, keeping its interval
+func (s *Server) handlePauseResume(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}"+remainingPath, http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	if remainingPath == "/pause" {
+		env.Pause()
+		s.logger.Infow("Environment paused", "env_id", envID)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("environment paused"))
+		return
+	}
+
+	env.Resume()
+	s.logger.Infow("Environment resumed", "env_id", envID)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("environment resumed"))
+}
+
+// POST /env/{envID}/reactions/{reactionID}/disable
+// POST /env/{envID}/reactions/{reactionID}/enable
+// Stop a reaction from firing, or let it fire again, without changing the schema
+func (s *Server) handleReactionToggle(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}"+remainingPath, http.StatusBadRequest)
+		return
+	}
+
+	reactionID, action, ok := strings.Cut(strings.TrimPrefix(remainingPath, "/reactions/"), "/")
+	if !ok || reactionID == "" || (action != "disable" && action != "enable") {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	toggle := env.EnableReaction
+	if action == "disable" {
+		toggle = env.DisableReaction
+	}
+	if err := toggle(reactionID); err != nil {
+		http.Error(w, "reaction not found", http.StatusNotFound)
+		return
+	}
+
+	s.logger.Infow("Reaction "+action+"d", "env_id", envID, "reaction_id", reactionID)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("reaction " + action + "d"))
+}
+
+// GET /env/{envID}/molecules
+// GET /env/{envID}/molecules?species={species}&where.{field}={value}&limit={n}&offset={n}
+// GET /env/{envID}/molecules?older_than={ticks}&newer_than={ticks}
+// List molecules, optionally filtered by species, payload equality and age (ticks since
+// creation, relative to the environment time). Filtered results are sorted by
+// created_at then ID; X-Total-Count holds the number of matches before paging.
+func (s *Server) handleListMolecules(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/molecules", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if len(query) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(env.AllMolecules()); err != nil {
+			http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		return
+	}
+
+	where := make(achem.WhereConfig)
+	for key, values := range query {
+		field, ok := strings.CutPrefix(key, "where.")
+		if !ok {
+			continue
+		}
+		if field == "" || len(values) != 1 {
+			http.Error(w, "invalid where parameter: "+key+" must name a field and be given once", http.StatusBadRequest)
+			return
+		}
+		where[field] = achem.EqCondition{Eq: parseQueryValue(values[0])}
+	}
+
+	limit, offset := -1, 0
+	for name, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+name+": must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+
+	var opts []achem.QueryOption
+	for name, opt := range map[string]func(int64) achem.QueryOption{"older_than": achem.OlderThan, "newer_than": achem.NewerThan} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+name+": must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			opts = append(opts, opt(n))
+		}
+	}
+
+	mols := env.QueryMolecules(achem.SpeciesName(query.Get("species")), where, opts...)
+	total := len(mols)
+	mols = mols[min(offset, total):]
+	if limit >= 0 && limit < len(mols) {
+		mols = mols[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if err := json.NewEncoder(w).Encode(mols); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseQueryValue converts a where.* query value to the type it most likely has in a
+// payload: numbers and booleans are parsed, and a double-quoted value is always a string.
+func parseQueryValue(v string) any {
+	if unquoted, err := strconv.Unquote(v); err == nil && strings.HasPrefix(v, `"`) {
+		return unquoted
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	if v == "true" || v == "false" {
+		return v == "true"
+	}
+	return v
+}
+
+// patchMoleculeRequest is the body of a molecule PATCH: payload fields are merged over
+// the molecule's payload, the other fields replace its values when present
+type patchMoleculeRequest struct {
+	Payload   map[string]any `json:"payload,omitempty"`
+	Energy    *float64       `json:"energy,omitempty"`
+	Stability *float64       `json:"stability,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+}
+
+// apply returns m with the patch applied
+func (p patchMoleculeRequest) apply(m achem.Molecule) achem.Molecule {
+	if len(p.Payload) > 0 && m.Payload == nil {
+		m.Payload = make(map[string]any, len(p.Payload))
+	}
+	for k, v := range p.Payload {
+		m.Payload[k] = v
+	}
+	if p.Energy != nil {
+		m.Energy = *p.Energy
+	}
+	if p.Stability != nil {
+		m.Stability = *p.Stability
+	}
+	if p.Tags != nil {
+		m.Tags = p.Tags
+	}
+	return m
+}
+
+// moleculeETag returns the entity tag of a molecule: its Version, which changes on
+// every write
+func moleculeETag(m achem.Molecule) string {
+	return `"` + strconv.FormatUint(m.Version, 10) + `"`
+}
+
+// GET /env/{envID}/molecule/{id}
+// DELETE /env/{envID}/molecule/{id}
+// PATCH /env/{envID}/molecule/{id}
+// Header: If-Match: "<version>"
+// Fetch, remove or update a single molecule by ID. GET returns the molecule's version
+// in the ETag header; PATCH applies only if the molecule still has
+// the version given in If-Match, and fails with 412 otherwise (see UpdateMoleculeCAS).
+func (s *Server) handleMolecule(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/molecule/{id}", http.StatusBadRequest)
+		return
+	}
+
+	id := achem.MoleculeID(strings.TrimPrefix(remainingPath, "/molecule/"))
+	if id == "" {
+		http.Error(w, "molecule ID is required in path: /env/{envID}/molecule/{id}", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if !env.DeleteMolecule(id) {
+			http.Error(w, "molecule not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Debugw("Molecule deleted", "env_id", envID, "id", id)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("molecule deleted"))
+		return
+	}
+
+	if r.Method == http.MethodPatch {
+		s.handlePatchMolecule(w, r, env, id)
+		return
+	}
+
+	m, ok := env.GetMolecule(id)
+	if !ok {
+		http.Error(w, "molecule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", moleculeETag(m))
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /env/{envID}/molecule/{id}/trace
+// Explains whether the molecule can react in the next tick: for each reaction, whether
+// the input pattern matches, the effective rate, the partners found and the result of
+// each if-condition (see Environment.TraceMolecule)
+func (s *Server) handleTraceMolecule(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	id := achem.MoleculeID(strings.TrimSuffix(strings.TrimPrefix(remainingPath, "/molecule/"), "/trace"))
+	if id == "" {
+		http.Error(w, "molecule ID is required in path: /env/{envID}/molecule/{id}/trace", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	trace := env.TraceMolecule(id)
+	if !trace.Found {
+		http.Error(w, "molecule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(trace); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handlePatchMolecule applies a conditional molecule update, see handleMolecule
+func (s *Server) handlePatchMolecule(w http.ResponseWriter, r *http.Request, env *achem.Environment, id achem.MoleculeID) {
+	defer r.Body.Close()
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		http.Error(w, "If-Match header with the molecule's ETag is required", http.StatusPreconditionRequired)
+		return
+	}
+	expected, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+	if err != nil {
+		http.Error(w, "If-Match must be the molecule's ETag", http.StatusBadRequest)
+		return
+	}
+
+	var patch patchMoleculeRequest
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var updated achem.Molecule
+	ok, err := env.UpdateMoleculeCAS(id, expected, func(m achem.Molecule) achem.Molecule {
+		updated = patch.apply(m)
+		return updated
+	})
+	if errors.Is(err, achem.ErrMoleculeNotFound) {
+		http.Error(w, "molecule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "invalid molecule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, "molecule was modified since the given version", http.StatusPreconditionFailed)
+		return
+	}
+
+	// the stored molecule has a new version, read it back for the ETag
+	if m, found := env.GetMolecule(id); found {
+		updated = m
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", moleculeETag(updated))
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /env/{envID}/stats
+// Returns the environment time, total molecule count and molecule counts per species.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/stats", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env.Stats()); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /env/{envID}/stats/field?species={species}&field={field}&max_samples={n}
+// Returns min/max/mean and p50/p90/p95/p99 of a numeric payload field across a species.
+func (s *Server) handleFieldStats(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/stats/field", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	species := query.Get("species")
+	field := query.Get("field")
+	if species == "" || field == "" {
+		http.Error(w, "species and field query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	maxSamples := 0
+	if maxStr := query.Get("max_samples"); maxStr != "" {
+		v, err := strconv.Atoi(maxStr)
+		if err != nil || v <= 0 {
+			http.Error(w, "invalid max_samples: must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		maxSamples = v
+	}
+
+	stats := env.FieldStats(achem.SpeciesName(species), field, maxSamples)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// maxHistogramBuckets bounds the buckets query parameter of the histogram endpoint
+const maxHistogramBuckets = 1000
+
+// GET /env/{envID}/histogram?field={field}&buckets={n}&species={species}
+// Returns the distribution of energy, stability or a numeric payload field over
+// equal-width buckets, optionally restricted to one species.
+func (s *Server) handleHistogram(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/histogram", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	field := query.Get("field")
+	if field == "" {
+		http.Error(w, "field query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	buckets := achem.DefaultHistogramBuckets
+	if bucketsStr := query.Get("buckets"); bucketsStr != "" {
+		v, err := strconv.Atoi(bucketsStr)
+		if err != nil || v <= 0 || v > maxHistogramBuckets {
+			http.Error(w, fmt.Sprintf("invalid buckets: must be an integer between 1 and %d", maxHistogramBuckets), http.StatusBadRequest)
+			return
+		}
+		buckets = v
+	}
+
+	hist := env.Histogram(achem.SpeciesName(query.Get("species")), field, buckets)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(hist); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /env/{envID}/counters?reset={bool}
+// Returns the environment's activity counters. With reset=true the counters are
+// zeroed atomically with the read, for delta-based metrics collection.
+func (s *Server) handleCounters(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/counters", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	reset := false
+	if resetStr := r.URL.Query().Get("reset"); resetStr != "" {
+		v, err := strconv.ParseBool(resetStr)
+		if err != nil {
+			http.Error(w, "invalid reset: must be a boolean", http.StatusBadRequest)
+			return
+		}
+		reset = v
+	}
+
+	counters := env.Counters(reset)
 
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("environment started"))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(counters); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
-// POST /env/{envID}/stop
-// Stop the environment auto-running
-func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+// reactionStatsResponse is the body returned by GET /env/{envID}/reactions/stats
+type reactionStatsResponse struct {
+	Time      int64                 `json:"time"`
+	Reactions []achem.ReactionStats `json:"reactions"`
+}
+
+// GET /env/{envID}/reactions/stats
+// Returns, for every reaction, how many times it fired and the time it last fired,
+// since the environment was created or reset
+func (s *Server) handleReactionStats(w http.ResponseWriter, r *http.Request) {
 	envID, _ := extractEnvID(r.URL.Path)
 	if envID == "" {
-		http.Error(w, "environment ID is required in path: /env/{envID}/stop", http.StatusBadRequest)
+		http.Error(w, "environment ID is required in path: /env/{envID}/reactions/stats", http.StatusBadRequest)
 		return
 	}
 
@@ -201,18 +1159,36 @@ func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	env.Stop()
-	s.logger.Infof("Environment stopped: env_id=%s", envID)
+	resp := reactionStatsResponse{Time: env.Time(), Reactions: env.ReactionStats()}
 
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("environment stopped"))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
-// GET /env/{envID}/molecules
-func (s *Server) handleListMolecules(w http.ResponseWriter, r *http.Request) {
+// Long-poll bounds for GET /env/{envID}/watch
+const (
+	defaultWatchTimeout = 30 * time.Second
+	maxWatchTimeout     = 120 * time.Second
+)
+
+// watchResponse is the body returned by GET /env/{envID}/watch
+type watchResponse struct {
+	Time     int64            `json:"time"`
+	Complete bool             `json:"complete"`
+	Diffs    []achem.StepDiff `json:"diffs"`
+}
+
+// GET /env/{envID}/watch?since={tick}&timeout={ms}
+// Long-poll: blocks until the environment time advances past `since` (or the timeout
+// expires), then returns the diffs of the ticks after `since`. If `since` is omitted,
+// waits for the next tick. Returns immediately if the environment is already past `since`.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
 	envID, _ := extractEnvID(r.URL.Path)
 	if envID == "" {
-		http.Error(w, "environment ID is required in path: /env/{envID}/molecules", http.StatusBadRequest)
+		http.Error(w, "environment ID is required in path: /env/{envID}/watch", http.StatusBadRequest)
 		return
 	}
 
@@ -222,10 +1198,52 @@ func (s *Server) handleListMolecules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mols := env.AllMolecules()
+	since := env.Time()
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		v, err := strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || v < 0 {
+			http.Error(w, "invalid since: must be a non-negative integer (tick)", http.StatusBadRequest)
+			return
+		}
+		since = v
+	}
+
+	timeout := defaultWatchTimeout
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		ms, err := strconv.Atoi(timeoutStr)
+		if err != nil || ms <= 0 {
+			http.Error(w, "invalid timeout: must be a positive integer (milliseconds)", http.StatusBadRequest)
+			return
+		}
+		timeout = min(time.Duration(ms)*time.Millisecond, maxWatchTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	env.WaitForTick(ctx, since)
+
+	diffs, now, complete := env.DiffsSince(since)
+	if diffs == nil {
+		diffs = []achem.StepDiff{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(mols); err != nil {
+	if err := json.NewEncoder(w).Encode(watchResponse{Time: now, Complete: complete, Diffs: diffs}); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /schema/jsonschema
+// Returns a JSON Schema describing the schema format, for editor validation
+func (s *Server) handleSchemaJSONSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	if err := json.NewEncoder(w).Encode(achem.SchemaConfigJSONSchema()); err != nil {
 		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -249,6 +1267,118 @@ func (s *Server) handleListEnvironments(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// POST /envs/tick
+// POST /envs/tick?parallel=true
+// Advance every environment by one tick, in lockstep. Returns the summary of each
+// environment; one that fails to step doesn't prevent the others from stepping.
+func (s *Server) handleTickAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	parallel := false
+	if v := r.URL.Query().Get("parallel"); v != "" {
+		p, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid parallel: must be a boolean", http.StatusBadRequest)
+			return
+		}
+		parallel = p
+	}
+
+	results := s.manager.StepAll(parallel)
+	for _, res := range results {
+		if res.Error != "" {
+			s.logger.Warnw("Environment did not step", "env_id", res.EnvironmentID, "error", res.Error)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string][]achem.EnvironmentStepResult{"environments": results}); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /env/{envID}/export
+// Returns the environment as a portable bundle: schema, time, molecules and seed
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/export", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	bundle, err := env.Export()
+	if err != nil {
+		http.Error(w, "cannot export environment: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(bundle); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// POST /envs/import
+// POST /envs/import?env_id={envID}
+// Create an environment from a bundle returned by GET /env/{envID}/export. The
+// environment takes the bundle's ID unless env_id is given. The bundle is validated
+// before anything is created.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var bundle achem.Bundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		http.Error(w, "invalid bundle json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	envID := bundle.EnvironmentID
+	if v := r.URL.Query().Get("env_id"); v != "" {
+		envID = achem.EnvironmentID(v)
+	}
+	if envID == "" || strings.Contains(string(envID), "/") {
+		http.Error(w, "a valid environment ID is required in the bundle or the env_id parameter", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := achem.ValidateBundle(bundle); err != nil {
+		http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, exists := s.manager.GetEnvironment(envID); exists {
+		http.Error(w, "environment already exists", http.StatusConflict)
+		return
+	}
+	if err := s.manager.ImportEnvironment(envID, bundle); err != nil {
+		s.logger.Errorw("Failed to import environment", "env_id", envID, "error", err)
+		http.Error(w, "cannot import environment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	env, _ := s.manager.GetEnvironment(envID)
+	s.configureEnvironment(env)
+	s.logger.Infow("Environment imported", "env_id", envID, "schema_name", bundle.Schema.Name, "molecules", len(bundle.Molecules))
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("environment imported"))
+}
+
 // DELETE /env/{envID}
 // Delete an environment
 func (s *Server) handleDeleteEnvironment(w http.ResponseWriter, r *http.Request) {
@@ -258,13 +1388,26 @@ func (s *Server) handleDeleteEnvironment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	env, exists := s.manager.GetEnvironment(envID)
 	if err := s.manager.DeleteEnvironment(envID); err != nil {
-		s.logger.Warnf("Failed to delete environment: env_id=%s error=%v", envID, err)
+		s.logger.Warnw("Failed to delete environment", "env_id", envID, "error", err)
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	s.logger.Infof("Environment deleted: env_id=%s", envID)
+	// Close the environment's own notification manager (never the shared global one)
+	if exists {
+		if mgr := env.GetNotificationManager(); mgr != s.globalNotifierMgr {
+			if err := mgr.Close(); err != nil {
+				s.logger.Warnw("Failed to close environment notifiers", "env_id", envID, "error", err)
+			}
+		}
+	}
+	if s.metrics != nil {
+		s.metrics.forgetEnvironment(envID)
+	}
+
+	s.logger.Infow("Environment deleted", "env_id", envID)
 
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("environment deleted"))
@@ -282,20 +1425,68 @@ func (s *Server) handleEnvironmentRoutes(w http.ResponseWriter, r *http.Request)
 	switch {
 	case remainingPath == "/schema" && r.Method == http.MethodPost:
 		s.handleSchema(w, r)
+	case remainingPath == "/schema" && r.Method == http.MethodGet:
+		s.handleGetSchema(w, r)
 	case remainingPath == "/molecule" && r.Method == http.MethodPost:
 		s.handleInsertMolecule(w, r)
+	case strings.HasPrefix(remainingPath, "/molecule/") && strings.HasSuffix(remainingPath, "/trace") && r.Method == http.MethodGet:
+		s.handleTraceMolecule(w, r)
+	case strings.HasPrefix(remainingPath, "/molecule/") && (r.Method == http.MethodGet || r.Method == http.MethodDelete || r.Method == http.MethodPatch):
+		s.handleMolecule(w, r)
 	case remainingPath == "/tick" && r.Method == http.MethodPost:
 		s.handleTick(w, r)
+	case remainingPath == "/run" && r.Method == http.MethodPost:
+		s.handleRunTicks(w, r)
+	case strings.HasPrefix(remainingPath, "/run/") && r.Method == http.MethodGet:
+		s.handleRunJobStatus(w, r)
+	case strings.HasPrefix(remainingPath, "/run/") && r.Method == http.MethodDelete:
+		s.handleCancelRunJob(w, r)
+	case remainingPath == "/reset" && r.Method == http.MethodPost:
+		s.handleReset(w, r)
 	case remainingPath == "/start" && r.Method == http.MethodPost:
 		s.handleStart(w, r)
 	case remainingPath == "/stop" && r.Method == http.MethodPost:
 		s.handleStop(w, r)
+	case (remainingPath == "/pause" || remainingPath == "/resume") && r.Method == http.MethodPost:
+		s.handlePauseResume(w, r)
+	case remainingPath == "/reactions/stats" && r.Method == http.MethodGet:
+		s.handleReactionStats(w, r)
+	case strings.HasPrefix(remainingPath, "/reactions/") && r.Method == http.MethodPost:
+		s.handleReactionToggle(w, r)
 	case remainingPath == "/molecules" && r.Method == http.MethodGet:
 		s.handleListMolecules(w, r)
+	case remainingPath == "/molecules/batch" && r.Method == http.MethodPost:
+		s.handleInsertBatch(w, r)
+	case remainingPath == "/counters" && r.Method == http.MethodGet:
+		s.handleCounters(w, r)
+	case remainingPath == "/stats" && r.Method == http.MethodGet:
+		s.handleStats(w, r)
+	case remainingPath == "/stats/field" && r.Method == http.MethodGet:
+		s.handleFieldStats(w, r)
+	case remainingPath == "/histogram" && r.Method == http.MethodGet:
+		s.handleHistogram(w, r)
+	case remainingPath == "/watch" && r.Method == http.MethodGet:
+		s.handleWatch(w, r)
+	case remainingPath == "/ratelimit" && (r.Method == http.MethodGet || r.Method == http.MethodPut):
+		s.handleInsertRateLimit(w, r)
+	case remainingPath == "/indexes" && (r.Method == http.MethodGet || r.Method == http.MethodPost || r.Method == http.MethodDelete):
+		s.handleIndexes(w, r)
+	case remainingPath == "/notifiers" && (r.Method == http.MethodGet || r.Method == http.MethodPost):
+		s.handleEnvNotifiers(w, r)
+	case strings.HasPrefix(remainingPath, "/notifiers/") && isNotifierStreamPath(remainingPath) && r.Method == http.MethodGet:
+		s.handleEnvNotifiers(w, r)
+	case strings.HasPrefix(remainingPath, "/notifiers/") && r.Method == http.MethodDelete:
+		s.handleEnvNotifiers(w, r)
 	case remainingPath == "/snapshot" && r.Method == http.MethodPost:
 		s.handleSaveSnapshot(w, r)
 	case remainingPath == "/snapshot" && r.Method == http.MethodGet:
 		s.handleGetSnapshot(w, r)
+	case remainingPath == "/snapshot/diff" && r.Method == http.MethodGet:
+		s.handleSnapshotDiff(w, r)
+	case remainingPath == "/export" && r.Method == http.MethodGet:
+		s.handleExport(w, r)
+	case remainingPath == "" && r.Method == http.MethodPost:
+		s.handleCreateFromTemplate(w, r)
 	case remainingPath == "" && r.Method == http.MethodDelete:
 		s.handleDeleteEnvironment(w, r)
 	default:
@@ -305,11 +1496,18 @@ func (s *Server) handleEnvironmentRoutes(w http.ResponseWriter, r *http.Request)
 
 // handleNotifiersRoutes handles notifier management endpoints
 func (s *Server) handleNotifiersRoutes(w http.ResponseWriter, r *http.Request) {
+	if s.globalNotifierMgr == nil {
+		http.Error(w, "global notifiers are disabled: register notifiers via /env/{envID}/notifiers", http.StatusNotFound)
+		return
+	}
+
 	switch {
 	case r.URL.Path == "/notifiers" && r.Method == http.MethodGet:
 		s.handleListNotifiers(w, r)
 	case r.URL.Path == "/notifiers" && r.Method == http.MethodPost:
 		s.handleRegisterNotifier(w, r)
+	case strings.HasPrefix(r.URL.Path, "/notifiers/") && isNotifierStreamPath(r.URL.Path) && r.Method == http.MethodGet:
+		serveNotifierStream(w, r, s.globalNotifierMgr, strings.TrimPrefix(r.URL.Path, "/notifiers/"))
 	case strings.HasPrefix(r.URL.Path, "/notifiers/") && r.Method == http.MethodDelete:
 		s.handleUnregisterNotifier(w, r)
 	default:
@@ -320,17 +1518,33 @@ func (s *Server) handleNotifiersRoutes(w http.ResponseWriter, r *http.Request) {
 // GET /notifiers
 // List all registered notifiers
 func (s *Server) handleListNotifiers(w http.ResponseWriter, _ *http.Request) {
-	notifierIDs := s.globalNotifierMgr.ListNotifiers()
+	writeNotifierList(w, s.globalNotifierMgr)
+}
+
+// writeNotifierList writes the notifiers registered in mgr as JSON
+func writeNotifierList(w http.ResponseWriter, mgr *achem.NotificationManager) {
+	notifierIDs := mgr.ListNotifiers()
 
 	// Get notifier types
-	notifiers := make([]map[string]string, 0, len(notifierIDs))
+	notifiers := make([]map[string]any, 0, len(notifierIDs))
 	for _, id := range notifierIDs {
-		notifier, exists := s.globalNotifierMgr.GetNotifier(id)
+		notifier, exists := mgr.GetNotifier(id)
 		if exists {
-			notifiers = append(notifiers, map[string]string{
+			entry := map[string]any{
 				"id":   id,
 				"type": notifier.Type(),
-			})
+			}
+			if filter, ok := mgr.GetNotifierFilter(id); ok {
+				entry["filter"] = filter
+			}
+			if policy := mgr.GetRetryPolicy(id); policy != achem.DefaultRetryPolicy() {
+				entry["retry"] = map[string]int64{
+					"max_retries": int64(policy.MaxRetries),
+					"backoff_ms":  policy.Backoff.Milliseconds(),
+					"timeout_ms":  policy.Timeout.Milliseconds(),
+				}
+			}
+			notifiers = append(notifiers, entry)
 		}
 	}
 
@@ -344,13 +1558,68 @@ func (s *Server) handleListNotifiers(w http.ResponseWriter, _ *http.Request) {
 // POST /notifiers
 // Register a new notifier
 // Body: { "type": "webhook", "id": "my-webhook", "config": { "url": "http://..." } }
+// An optional "filter": { "reaction_ids": [...], "species": [...] } restricts the delivered events.
 type registerNotifierRequest struct {
-	Type   string         `json:"type"`
-	ID     string         `json:"id"`
-	Config map[string]any `json:"config"`
+	Type   string                `json:"type"`
+	ID     string                `json:"id"`
+	Config map[string]any        `json:"config"`
+	Filter *achem.NotifierFilter `json:"filter,omitempty"`
+}
+
+// retryPolicy returns the delivery retry policy of the request: DefaultRetryPolicy,
+// with the values given as "max_retries", "backoff_ms" and "timeout_ms" in the config
+func (req registerNotifierRequest) retryPolicy() (achem.RetryPolicy, error) {
+	policy := achem.DefaultRetryPolicy()
+
+	if v, ok, err := configInt(req.Config, "max_retries"); err != nil {
+		return policy, err
+	} else if ok {
+		policy.MaxRetries = v
+	}
+	if v, ok, err := configInt(req.Config, "backoff_ms"); err != nil {
+		return policy, err
+	} else if ok {
+		policy.Backoff = time.Duration(v) * time.Millisecond
+	}
+	if v, ok, err := configInt(req.Config, "timeout_ms"); err != nil {
+		return policy, err
+	} else if ok {
+		policy.Timeout = time.Duration(v) * time.Millisecond
+	}
+
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf("invalid retry config: %w", err)
+	}
+	return policy, nil
+}
+
+// configInt reads an integer from a notifier config. ok is false if key is absent.
+func configInt(config map[string]any, key string) (v int, ok bool, err error) {
+	raw, exists := config[key]
+	if !exists {
+		return 0, false, nil
+	}
+	f, isNumber := raw.(float64)
+	if !isNumber || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, false, fmt.Errorf("%s must be an integer", key)
+	}
+	return int(f), true, nil
+}
+
+// filter returns the event filter of the request (empty if none was given)
+func (req registerNotifierRequest) filter() achem.NotifierFilter {
+	if req.Filter == nil {
+		return achem.NotifierFilter{}
+	}
+	return *req.Filter
 }
 
 func (s *Server) handleRegisterNotifier(w http.ResponseWriter, r *http.Request) {
+	s.registerNotifier(w, r, s.globalNotifierMgr)
+}
+
+// registerNotifier decodes a registerNotifierRequest and registers the notifier in mgr
+func (s *Server) registerNotifier(w http.ResponseWriter, r *http.Request, mgr *achem.NotificationManager) {
 	defer r.Body.Close()
 
 	var req registerNotifierRequest
@@ -359,22 +1628,112 @@ func (s *Server) handleRegisterNotifier(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if req.ID == "" {
-		http.Error(w, "notifier ID is required", http.StatusBadRequest)
+	if err := s.addNotifier(mgr, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var notifier achem.Notifier
-	var err error
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("notifier registered"))
+}
 
-	switch req.Type {
+// addNotifier builds the notifier described by req and registers it in mgr, with its
+// filter and retry policy
+func (s *Server) addNotifier(mgr *achem.NotificationManager, req registerNotifierRequest) error {
+	policy, err := req.retryPolicy()
+	if err != nil {
+		return err
+	}
+
+	notifier, err := s.buildNotifier(req)
+	if err != nil {
+		return err
+	}
+
+	if err := mgr.RegisterNotifierWithFilter(notifier, req.filter()); err != nil {
+		_ = notifier.Close()
+		return fmt.Errorf("cannot register notifier: %w", err)
+	}
+	if err := mgr.SetRetryPolicy(notifier.ID(), policy); err != nil {
+		return fmt.Errorf("cannot register notifier: %w", err)
+	}
+	return nil
+}
+
+// validateNotifierConfig checks the type and config of a notifier registration without
+// creating the notifier: no connection is opened and no file is created.
+func (s *Server) validateNotifierConfig(notifierType string, config map[string]any) error {
+	switch notifierType {
 	case "webhook":
-		url, ok := req.Config["url"].(string)
-		if !ok || url == "" {
-			http.Error(w, "webhook URL is required", http.StatusBadRequest)
-			return
+		if url, ok := config["url"].(string); !ok || url == "" {
+			return fmt.Errorf("webhook URL is required")
+		}
+	case "websocket", "sse":
+	case "grpc":
+		if target, ok := config["target"].(string); !ok || target == "" {
+			return fmt.Errorf("gRPC target is required")
 		}
-		wh := achemnotifiers.NewWebhookNotifier(req.ID, url)
+	case "kafka":
+		if len(kafkaBrokers(config)) == 0 {
+			return fmt.Errorf("kafka brokers are required")
+		}
+		if topic, ok := config["topic"].(string); !ok || topic == "" {
+			return fmt.Errorf("kafka topic is required")
+		}
+	case "nats":
+		if url, ok := config["url"].(string); !ok || url == "" {
+			return fmt.Errorf("NATS URL is required")
+		}
+		if subject, ok := config["subject"].(string); !ok || subject == "" {
+			return fmt.Errorf("NATS subject is required")
+		}
+	case "rabbitmq":
+		if url, ok := config["url"].(string); !ok || url == "" {
+			return fmt.Errorf("RabbitMQ URL is required")
+		}
+		exchange, _ := config["exchange"].(string)
+		routingKey, _ := config["routing_key"].(string)
+		if exchange == "" && routingKey == "" {
+			return fmt.Errorf("RabbitMQ exchange or routing_key is required")
+		}
+	case "file":
+		path, ok := config["path"].(string)
+		if !ok || path == "" {
+			return fmt.Errorf("file path is required")
+		}
+		if _, err := notifierFilePath(s.notifierFileDir, path); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown notifier type: %s", notifierType)
+	}
+	return nil
+}
+
+// kafkaBrokers returns the non-empty broker addresses of a kafka notifier config
+func kafkaBrokers(config map[string]any) []string {
+	rawBrokers, _ := config["brokers"].([]any)
+	brokers := make([]string, 0, len(rawBrokers))
+	for _, b := range rawBrokers {
+		if broker, ok := b.(string); ok && broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers
+}
+
+// buildNotifier creates the notifier described by a registration request
+func (s *Server) buildNotifier(req registerNotifierRequest) (achem.Notifier, error) {
+	if req.ID == "" {
+		return nil, fmt.Errorf("notifier ID is required")
+	}
+	if err := s.validateNotifierConfig(req.Type, req.Config); err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case "webhook":
+		wh := achemnotifiers.NewWebhookNotifier(req.ID, req.Config["url"].(string))
 
 		// Set custom headers if provided
 		if headers, ok := req.Config["headers"].(map[string]any); ok {
@@ -385,19 +1744,99 @@ func (s *Server) handleRegisterNotifier(w http.ResponseWriter, r *http.Request)
 			}
 		}
 
-		notifier = wh
-	default:
-		http.Error(w, "unknown notifier type: "+req.Type, http.StatusBadRequest)
+		if secret, ok := req.Config["secret"].(string); ok {
+			wh.SetSigningSecret(secret)
+		}
+
+		return wh, nil
+	case "websocket":
+		// clients connect via GET /notifiers/{id}/ws (or /env/{envID}/notifiers/{id}/ws)
+		return achemnotifiers.NewWebSocketNotifier(req.ID), nil
+	case "sse":
+		// clients connect via GET /notifiers/{id}/events (or /env/{envID}/notifiers/{id}/events)
+		return achemnotifiers.NewSSENotifier(req.ID), nil
+	case "grpc":
+		creds := insecure.NewCredentials()
+		if useTLS, _ := req.Config["tls"].(bool); useTLS {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		return achemnotifiers.NewGRPCNotifier(req.ID, req.Config["target"].(string), grpc.WithTransportCredentials(creds))
+	case "kafka":
+		return achemnotifiers.NewKafkaNotifier(req.ID, kafkaBrokers(req.Config), req.Config["topic"].(string)), nil
+	case "nats":
+		nn, err := achemnotifiers.NewNATSNotifier(req.ID, req.Config["url"].(string), req.Config["subject"].(string))
+		if err != nil {
+			return nil, err
+		}
+		perEnvironment, _ := req.Config["per_environment"].(bool)
+		nn.SetPerEnvironmentSubject(perEnvironment)
+		return nn, nil
+	case "rabbitmq":
+		exchange, _ := req.Config["exchange"].(string)
+		routingKey, _ := req.Config["routing_key"].(string)
+		rn, err := achemnotifiers.NewRabbitMQNotifier(req.ID, req.Config["url"].(string), exchange, routingKey)
+		if err != nil {
+			return nil, err
+		}
+		return rn, nil
+	default: // "file", validated above
+		path, err := notifierFilePath(s.notifierFileDir, req.Config["path"].(string))
+		if err != nil {
+			return nil, err
+		}
+		fn, err := achemnotifiers.NewFileNotifier(req.ID, path)
+		if err != nil {
+			return nil, err
+		}
+		return fn, nil
+	}
+}
+
+// notifierFilePath resolves the path of a file notifier inside dir, the directory set by
+// the operator. The path comes from API clients, so absolute paths and paths leaving dir
+// are rejected, and file notifiers are disabled when no directory is set.
+func notifierFilePath(dir, path string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("file notifiers are disabled: no notifier file directory is configured")
+	}
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("file path must be relative to the notifier file directory")
+	}
+	full := filepath.Join(dir, filepath.Clean(path))
+	rel, err := filepath.Rel(dir, full)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file path must stay inside the notifier file directory")
+	}
+	return full, nil
+}
+
+// GET /notifiers/{id}/ws
+// GET /notifiers/{id}/events
+// GET /env/{envID}/notifiers/{id}/ws
+// GET /env/{envID}/notifiers/{id}/events
+// Connect to a streaming notifier: WebSocket upgrade (/ws) or Server-Sent Events (/events)
+func serveNotifierStream(w http.ResponseWriter, r *http.Request, mgr *achem.NotificationManager, notifierPath string) {
+	notifierID, endpoint, _ := strings.Cut(notifierPath, "/")
+	wantType := map[string]string{"ws": "websocket", "events": "sse"}[endpoint]
+
+	notifier, ok := mgr.GetNotifier(notifierID)
+	if !ok {
+		http.Error(w, "notifier not found", http.StatusNotFound)
 		return
 	}
 
-	if err = s.globalNotifierMgr.RegisterNotifier(notifier); err != nil {
-		http.Error(w, "cannot register notifier: "+err.Error(), http.StatusBadRequest)
+	handler, ok := notifier.(http.Handler)
+	if !ok || notifier.Type() != wantType {
+		http.Error(w, "notifier is not a "+wantType+" notifier", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("notifier registered"))
+	handler.ServeHTTP(w, r)
+}
+
+// isNotifierStreamPath reports whether path (relative to /notifiers/) is a streaming endpoint
+func isNotifierStreamPath(path string) bool {
+	return strings.HasSuffix(path, "/ws") || strings.HasSuffix(path, "/events")
 }
 
 // DELETE /notifiers/{id}
@@ -425,6 +1864,68 @@ func (s *Server) handleUnregisterNotifier(w http.ResponseWriter, r *http.Request
 	_, _ = w.Write([]byte("notifier unregistered"))
 }
 
+// GET    /env/{envID}/notifiers
+// POST   /env/{envID}/notifiers
+// DELETE /env/{envID}/notifiers/{id}
+// GET    /env/{envID}/notifiers/{id}/ws
+// GET    /env/{envID}/notifiers/{id}/events
+// Manage the notifiers registered for a single environment. Without notifier isolation,
+// the environment's reactions resolve notifier IDs against these first, then against
+// the global notifiers.
+func (s *Server) handleEnvNotifiers(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/notifiers", http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	// the local manager is only created when a notifier is registered; until then the
+	// environment has no local notifiers
+	mgr := s.localNotificationManager(env, r.Method == http.MethodPost)
+
+	switch r.Method {
+	case http.MethodGet:
+		if isNotifierStreamPath(remainingPath) {
+			if mgr == nil {
+				http.Error(w, "notifier not found", http.StatusNotFound)
+				return
+			}
+			serveNotifierStream(w, r, mgr, strings.TrimPrefix(remainingPath, "/notifiers/"))
+			return
+		}
+		if mgr == nil {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"notifiers":[]}` + "\n"))
+			return
+		}
+		writeNotifierList(w, mgr)
+	case http.MethodPost:
+		s.registerNotifier(w, r, mgr)
+	case http.MethodDelete:
+		notifierID := strings.TrimPrefix(remainingPath, "/notifiers/")
+		if notifierID == "" {
+			http.Error(w, "notifier ID is required", http.StatusBadRequest)
+			return
+		}
+		if mgr == nil {
+			http.Error(w, fmt.Sprintf("notifier with ID %s not found", notifierID), http.StatusNotFound)
+			return
+		}
+		if err := mgr.UnregisterNotifier(notifierID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("notifier unregistered"))
+	}
+}
+
 // POST /env/{envID}/snapshot
 // Triggers a synchronous snapshot save
 func (s *Server) handleSaveSnapshot(w http.ResponseWriter, r *http.Request) {
@@ -451,14 +1952,14 @@ func (s *Server) handleSaveSnapshot(w http.ResponseWriter, r *http.Request) {
 
 	// Save snapshot synchronously
 	if err := env.SaveSnapshot(); err != nil {
-		s.logger.Errorf("Failed to save snapshot: env_id=%s error=%v", envID, err)
+		s.logger.Errorw("Failed to save snapshot", "env_id", envID, "error", err)
 		http.Error(w, "failed to save snapshot: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Get snapshot path for response
-	path := env.SnapshotPath()
-	s.logger.Debugf("Snapshot saved: env_id=%s path=%s", envID, path)
+	path := env.LatestSnapshotPath()
+	s.logger.Debugw("Snapshot saved", "env_id", envID, "path", path)
 
 	response := map[string]string{
 		"status": "ok",
@@ -498,10 +1999,10 @@ func (s *Server) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
 	env.SetSnapshotDir(s.snapshotDir)
 
 	// Get snapshot path
-	path := env.SnapshotPath()
+	path := env.LatestSnapshotPath()
 
-	// Read snapshot file
-	data, err := os.ReadFile(path)
+	// Read snapshot file (compressed snapshots are served decompressed)
+	data, err := achem.ReadSnapshotFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			http.Error(w, "snapshot not found", http.StatusNotFound)
@@ -516,3 +2017,56 @@ func (s *Server) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(data)
 }
+
+// GET /env/{envID}/snapshot/diff?from=T1&to=T2
+// Compares the timestamped snapshots saved at times T1 and T2 (see snapshot retention)
+// and returns the added, removed and changed molecules
+func (s *Server) handleSnapshotDiff(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	if s.snapshotDir == "" {
+		http.Error(w, "snapshot directory not configured", http.StatusInternalServerError)
+		return
+	}
+	env.SetSnapshotDir(s.snapshotDir)
+
+	var times [2]int64
+	for i, name := range []string{"from", "to"} {
+		t, err := strconv.ParseInt(r.URL.Query().Get(name), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid "+name+": must be the time of a snapshot", http.StatusBadRequest)
+			return
+		}
+		times[i] = t
+	}
+
+	var snapshots [2]achem.Snapshot
+	for i, t := range times {
+		snapshot, err := env.ReadSnapshotAt(t)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				http.Error(w, fmt.Sprintf("snapshot at time %d not found", t), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "failed to read snapshot: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		snapshots[i] = snapshot
+	}
+
+	resp := struct {
+		From int64 `json:"from"`
+		To   int64 `json:"to"`
+		achem.SnapshotDiff
+	}{times[0], times[1], achem.DiffMolecules(snapshots[0].Molecules, snapshots[1].Molecules)}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+	}
+}
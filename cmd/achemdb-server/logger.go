@@ -1,9 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"strings"
+	"time"
 )
 
 // LogLevel represents the logging level
@@ -14,6 +18,7 @@ const (
 	LogLevelInfo
 	LogLevelWarn
 	LogLevelError
+	LogLevelFatal
 )
 
 // String returns the string representation of the log level
@@ -27,6 +32,8 @@ func (l LogLevel) String() string {
 		return "warn"
 	case LogLevelError:
 		return "error"
+	case LogLevelFatal:
+		return "fatal"
 	default:
 		return "unknown"
 	}
@@ -48,16 +55,34 @@ func parseLogLevel(level string) LogLevel {
 	}
 }
 
+// Log output formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 // Logger provides leveled logging functionality
 type Logger struct {
 	level LogLevel
+	json  *log.Logger // nil in text mode
 }
 
-// NewLogger creates a new logger with the specified log level
+// NewLogger creates a new logger with the specified log level, writing plain text
 func NewLogger(level string) *Logger {
-	return &Logger{
+	return NewLoggerWithFormat(level, LogFormatText)
+}
+
+// NewLoggerWithFormat creates a new logger with the specified log level and output
+// format: "text" (the default for unknown values) or "json", which writes one JSON
+// object per line with level, time, msg and the key/value pairs of structured calls.
+func NewLoggerWithFormat(level, format string) *Logger {
+	l := &Logger{
 		level: parseLogLevel(level),
 	}
+	if strings.EqualFold(format, LogFormatJSON) {
+		l.json = log.New(os.Stderr, "", 0)
+	}
+	return l
 }
 
 // shouldLog returns true if the given level should be logged
@@ -65,63 +90,160 @@ func (l *Logger) shouldLog(level LogLevel) bool {
 	return level >= l.level
 }
 
+// output writes a log entry in the configured format. kv holds alternating keys and
+// values; in text mode they are appended to msg as "msg: key=value ...".
+func (l *Logger) output(level LogLevel, msg string, kv ...any) {
+	if l.json != nil {
+		l.json.Print(jsonLogLine(level, msg, kv))
+		return
+	}
+
+	if len(kv) > 0 {
+		var b strings.Builder
+		b.WriteString(msg)
+		b.WriteByte(':')
+		for i := 0; i < len(kv); i += 2 {
+			fmt.Fprintf(&b, " %v=%v", kv[i], logValue(kv, i+1))
+		}
+		msg = b.String()
+	}
+	log.Print("[" + strings.ToUpper(level.String()) + "] " + msg)
+}
+
+// jsonLogLine encodes a log entry as a JSON object, keeping the key/value pairs in order
+func jsonLogLine(level LogLevel, msg string, kv []any) string {
+	var b bytes.Buffer
+	writeField := func(key string, value any) {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(value)
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(value))
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+
+	writeField("level", level.String())
+	writeField("time", time.Now().UTC().Format(time.RFC3339Nano))
+	writeField("msg", msg)
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		if key == "level" || key == "time" || key == "msg" {
+			key = "field_" + key
+		}
+		value := logValue(kv, i+1)
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case fmt.Stringer:
+			value = v.String()
+		}
+		writeField(key, value)
+	}
+	return "{" + b.String() + "}"
+}
+
+// logValue returns kv[i], or a marker when a key has no value
+func logValue(kv []any, i int) any {
+	if i < len(kv) {
+		return kv[i]
+	}
+	return "(missing)"
+}
+
 // Debugf logs a debug message
 func (l *Logger) Debugf(format string, v ...any) {
 	if l.shouldLog(LogLevelDebug) {
-		log.Printf("[DEBUG] "+format, v...)
+		l.output(LogLevelDebug, fmt.Sprintf(format, v...))
 	}
 }
 
 // Infof logs an info message
 func (l *Logger) Infof(format string, v ...any) {
 	if l.shouldLog(LogLevelInfo) {
-		log.Printf("[INFO] "+format, v...)
+		l.output(LogLevelInfo, fmt.Sprintf(format, v...))
 	}
 }
 
 // Warnf logs a warning message
 func (l *Logger) Warnf(format string, v ...any) {
 	if l.shouldLog(LogLevelWarn) {
-		log.Printf("[WARN] "+format, v...)
+		l.output(LogLevelWarn, fmt.Sprintf(format, v...))
 	}
 }
 
 // Errorf logs an error message
 func (l *Logger) Errorf(format string, v ...any) {
 	if l.shouldLog(LogLevelError) {
-		log.Printf("[ERROR] "+format, v...)
+		l.output(LogLevelError, fmt.Sprintf(format, v...))
 	}
 }
 
 // Fatalf logs an error message and exits
 func (l *Logger) Fatalf(format string, v ...any) {
+	if l.json != nil {
+		l.json.Print(jsonLogLine(LogLevelFatal, fmt.Sprintf(format, v...), nil))
+		os.Exit(1)
+	}
 	log.Fatalf("[FATAL] "+format, v...)
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(v ...any) {
 	if l.shouldLog(LogLevelDebug) {
-		log.Print("[DEBUG] ", fmt.Sprint(v...))
+		l.output(LogLevelDebug, fmt.Sprint(v...))
 	}
 }
 
 // Info logs an info message
 func (l *Logger) Info(v ...any) {
 	if l.shouldLog(LogLevelInfo) {
-		log.Print("[INFO] ", fmt.Sprint(v...))
+		l.output(LogLevelInfo, fmt.Sprint(v...))
 	}
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(v ...any) {
 	if l.shouldLog(LogLevelWarn) {
-		log.Print("[WARN] ", fmt.Sprint(v...))
+		l.output(LogLevelWarn, fmt.Sprint(v...))
 	}
 }
 
 // Error logs an error message
 func (l *Logger) Error(v ...any) {
 	if l.shouldLog(LogLevelError) {
-		log.Print("[ERROR] ", fmt.Sprint(v...))
+		l.output(LogLevelError, fmt.Sprint(v...))
+	}
+}
+
+// Debugw logs a debug message with key/value pairs
+func (l *Logger) Debugw(msg string, kv ...any) {
+	if l.shouldLog(LogLevelDebug) {
+		l.output(LogLevelDebug, msg, kv...)
+	}
+}
+
+// Infow logs an info message with key/value pairs
+func (l *Logger) Infow(msg string, kv ...any) {
+	if l.shouldLog(LogLevelInfo) {
+		l.output(LogLevelInfo, msg, kv...)
+	}
+}
+
+// Warnw logs a warning message with key/value pairs
+func (l *Logger) Warnw(msg string, kv ...any) {
+	if l.shouldLog(LogLevelWarn) {
+		l.output(LogLevelWarn, msg, kv...)
+	}
+}
+
+// Errorw logs an error message with key/value pairs
+func (l *Logger) Errorw(msg string, kv ...any) {
+	if l.shouldLog(LogLevelError) {
+		l.output(LogLevelError, msg, kv...)
 	}
 }
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/daniacca/achemdb/internal/achem"
 )
@@ -9,12 +15,26 @@ import (
 func main() {
 	cfg := loadServerConfig()
 
-	// Create logger with configured log level
-	logger := NewLogger(cfg.LogLevel)
+	// Create logger with configured log level and format
+	logger := NewLoggerWithFormat(cfg.LogLevel, cfg.LogFormat)
 
 	srv := NewServer(logger)
 	srv.SetSnapshotDir(cfg.SnapshotDir)
 	srv.SetSnapshotEveryTicks(cfg.SnapshotEveryTicks)
+	srv.SetIsolateNotifiers(cfg.IsolateNotifiers)
+	srv.SetNotifyMaxWorkers(cfg.NotifyMaxWorkers)
+	srv.SetEventLogSize(cfg.EventLogSize)
+	srv.SetNotifierFileDir(cfg.NotifierFileDir)
+	srv.SetMetricsEnabled(cfg.MetricsEnabled)
+
+	// Restore the environments saved before the last shutdown (or crash)
+	if cfg.RestoreOnStart {
+		restored, err := srv.restoreSnapshots()
+		if err != nil {
+			logger.Fatalf("Failed to restore snapshots from %s: %v", cfg.SnapshotDir, err)
+		}
+		logger.Infof("Restored %d environment(s) from %s", len(restored), cfg.SnapshotDir)
+	}
 
 	// Load initial schema if provided
 	if cfg.SchemaFile != "" {
@@ -22,18 +42,78 @@ func main() {
 		if err := applyInitialSchemaToEnvironment(srv.manager, srv.globalNotifierMgr, cfg.SchemaFile, achem.EnvironmentID(cfg.DefaultEnvID), cfg.SnapshotDir, cfg.SnapshotEveryTicks); err != nil {
 			logger.Fatalf("Failed to load initial schema: %v", err)
 		}
+		// like any environment created over HTTP, e.g. with --isolate-notifiers its own
+		// manager autoscales and feeds the event log
+		if env, ok := srv.manager.GetEnvironment(achem.EnvironmentID(cfg.DefaultEnvID)); ok {
+			srv.configureEnvironment(env)
+		}
 		logger.Infof("Initial schema loaded successfully")
+		srv.SetReadyEnvironment(achem.EnvironmentID(cfg.DefaultEnvID))
+	}
+
+	if cfg.StatsdAddr != "" {
+		emitter, err := newStatsdEmitter(srv, cfg.StatsdAddr, cfg.StatsdInterval)
+		if err != nil {
+			logger.Warnw("StatsD metrics disabled", "addr", cfg.StatsdAddr, "error", err)
+		} else {
+			emitter.Start()
+			defer emitter.Stop()
+			logger.Infof("Pushing StatsD metrics to %s every %s", cfg.StatsdAddr, cfg.StatsdInterval)
+		}
 	}
 
 	// Register HTTP handlers
-	http.HandleFunc("/healthz", srv.handleHealth)
+	http.HandleFunc("/healthz", srv.handleHealth) // alias of /livez
+	http.HandleFunc("/livez", srv.handleHealth)
+	http.HandleFunc("/readyz", srv.handleReady)
 	http.HandleFunc("/envs", srv.handleListEnvironments)
+	http.HandleFunc("/envs/tick", srv.handleTickAll)
+	http.HandleFunc("/envs/import", srv.handleImport)
+	http.HandleFunc("/metrics", srv.handleMetrics)
+	http.HandleFunc("/events", srv.handleEvents)
 	http.HandleFunc("/notifiers", srv.handleNotifiersRoutes)
 	http.HandleFunc("/notifiers/", srv.handleNotifiersRoutes)
-	http.HandleFunc("/env/", srv.handleEnvironmentRoutes)
+	http.HandleFunc("/templates", srv.handleTemplatesRoutes)
+	http.HandleFunc("/templates/", srv.handleTemplatesRoutes)
+	http.HandleFunc("/schema/jsonschema", srv.handleSchemaJSONSchema)
+	http.Handle("/env/", gzipRequestMiddleware(http.HandlerFunc(srv.handleEnvironmentRoutes)))
 
-	logger.Infof("achemdb-server listening on %s", cfg.Addr)
-	if err := http.ListenAndServe(cfg.Addr, nil); err != nil {
-		logger.Fatalf("Server stopped: %v", err)
+	var handler http.Handler = http.DefaultServeMux
+	if cfg.AuthToken != "" {
+		handler = tokenAuthMiddleware(cfg.AuthToken, handler)
+		logger.Infof("Bearer token authentication enabled")
+	}
+
+	// long-lived requests (event streams, watches) end when the server shuts down
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	httpServer := &http.Server{
+		Addr:        cfg.Addr,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	httpServer.RegisterOnShutdown(cancelRequests)
+	go func() {
+		logger.Infof("achemdb-server listening on %s", cfg.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatalf("Server stopped: %v", err)
+		}
+	}()
+
+	// Wait for SIGINT/SIGTERM, then stop the environments and save their state before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+	logger.Infof("Shutting down")
+
+	// stop serving requests first, so that nothing changes the environments after their
+	// final snapshot
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("HTTP shutdown failed: %v", err)
+	}
+	if err := srv.flushAll(shutdownCtx); err != nil {
+		logger.Errorf("Shutdown flush failed: %v", err)
 	}
+	logger.Infof("achemdb-server stopped")
 }
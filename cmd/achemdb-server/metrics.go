@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// promMetrics accumulates engine metrics and renders them in the Prometheus text
+// exposition format. It implements achem.Metrics.
+type promMetrics struct {
+	mu                sync.Mutex
+	reactionsFired    map[achem.EnvironmentID]map[string]int64
+	moleculesCreated  map[achem.EnvironmentID]int64
+	moleculesConsumed map[achem.EnvironmentID]int64
+	notificationsDrop map[achem.EnvironmentID]int64
+	notifications     map[string]*notificationResults // by notifier ID
+	snapshots         map[achem.EnvironmentID]*snapshotDurations
+}
+
+type notificationResults struct {
+	success int64
+	failure int64
+}
+
+type snapshotDurations struct {
+	count  int64
+	errors int64
+	sum    time.Duration
+}
+
+func newPromMetrics() *promMetrics {
+	return &promMetrics{
+		reactionsFired:    make(map[achem.EnvironmentID]map[string]int64),
+		moleculesCreated:  make(map[achem.EnvironmentID]int64),
+		moleculesConsumed: make(map[achem.EnvironmentID]int64),
+		notificationsDrop: make(map[achem.EnvironmentID]int64),
+		notifications:     make(map[string]*notificationResults),
+		snapshots:         make(map[achem.EnvironmentID]*snapshotDurations),
+	}
+}
+
+// ObserveStep implements achem.Metrics
+func (pm *promMetrics) ObserveStep(envID achem.EnvironmentID, tick achem.Counters) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if len(tick.ReactionsFired) > 0 {
+		fired := pm.reactionsFired[envID]
+		if fired == nil {
+			fired = make(map[string]int64)
+			pm.reactionsFired[envID] = fired
+		}
+		for id, n := range tick.ReactionsFired {
+			fired[id] += n
+		}
+	}
+	pm.moleculesCreated[envID] += tick.MoleculesCreated
+	pm.moleculesConsumed[envID] += tick.MoleculesConsumed
+	if tick.NotificationsDropped > 0 {
+		pm.notificationsDrop[envID] += tick.NotificationsDropped
+	}
+}
+
+// ObserveNotification implements achem.Metrics
+func (pm *promMetrics) ObserveNotification(notifierID string, err error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	res := pm.notifications[notifierID]
+	if res == nil {
+		res = &notificationResults{}
+		pm.notifications[notifierID] = res
+	}
+	if err != nil {
+		res.failure++
+	} else {
+		res.success++
+	}
+}
+
+// ObserveSnapshot implements achem.Metrics
+func (pm *promMetrics) ObserveSnapshot(envID achem.EnvironmentID, duration time.Duration, err error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	d := pm.snapshots[envID]
+	if d == nil {
+		d = &snapshotDurations{}
+		pm.snapshots[envID] = d
+	}
+	d.count++
+	d.sum += duration
+	if err != nil {
+		d.errors++
+	}
+}
+
+// forgetEnvironment drops the series of a deleted environment, so that /metrics doesn't
+// grow with every environment ever created. An environment created again with the same
+// ID starts its counters from zero.
+func (pm *promMetrics) forgetEnvironment(envID achem.EnvironmentID) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	delete(pm.reactionsFired, envID)
+	delete(pm.moleculesCreated, envID)
+	delete(pm.moleculesConsumed, envID)
+	delete(pm.notificationsDrop, envID)
+	delete(pm.snapshots, envID)
+}
+
+// write renders the accumulated metrics, plus the current molecule count of every
+// environment in manager. Series are sorted so that the output is stable.
+func (pm *promMetrics) write(b *strings.Builder, manager *achem.EnvironmentManager) {
+	envIDs := manager.ListEnvironments()
+	sort.Slice(envIDs, func(i, j int) bool { return envIDs[i] < envIDs[j] })
+
+	promHeader(b, "achemdb_molecules", "gauge", "Current number of molecules per environment.")
+	for _, envID := range envIDs {
+		if env, ok := manager.GetEnvironment(envID); ok {
+			fmt.Fprintf(b, "achemdb_molecules{env=%s} %d\n", promLabel(string(envID)), env.Stats().Molecules)
+		}
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	promHeader(b, "achemdb_reactions_fired_total", "counter", "Reactions fired with effects.")
+	for _, envID := range sortedEnvIDs(pm.reactionsFired) {
+		fired := pm.reactionsFired[envID]
+		reactionIDs := make([]string, 0, len(fired))
+		for id := range fired {
+			reactionIDs = append(reactionIDs, id)
+		}
+		sort.Strings(reactionIDs)
+		for _, id := range reactionIDs {
+			fmt.Fprintf(b, "achemdb_reactions_fired_total{env=%s,reaction=%s} %d\n", promLabel(string(envID)), promLabel(id), fired[id])
+		}
+	}
+
+	promHeader(b, "achemdb_molecules_created_total", "counter", "Molecules created by reactions.")
+	for _, envID := range sortedEnvIDs(pm.moleculesCreated) {
+		fmt.Fprintf(b, "achemdb_molecules_created_total{env=%s} %d\n", promLabel(string(envID)), pm.moleculesCreated[envID])
+	}
+
+	promHeader(b, "achemdb_molecules_consumed_total", "counter", "Molecules consumed by reactions.")
+	for _, envID := range sortedEnvIDs(pm.moleculesConsumed) {
+		fmt.Fprintf(b, "achemdb_molecules_consumed_total{env=%s} %d\n", promLabel(string(envID)), pm.moleculesConsumed[envID])
+	}
+
+	promHeader(b, "achemdb_notifications_total", "counter", "Notifier deliveries by result, after retries.")
+	notifierIDs := make([]string, 0, len(pm.notifications))
+	for id := range pm.notifications {
+		notifierIDs = append(notifierIDs, id)
+	}
+	sort.Strings(notifierIDs)
+	for _, id := range notifierIDs {
+		res := pm.notifications[id]
+		fmt.Fprintf(b, "achemdb_notifications_total{notifier=%s,result=\"success\"} %d\n", promLabel(id), res.success)
+		fmt.Fprintf(b, "achemdb_notifications_total{notifier=%s,result=\"failure\"} %d\n", promLabel(id), res.failure)
+	}
+
+	promHeader(b, "achemdb_notifications_dropped_total", "counter", "Notification events dropped because the queue was full.")
+	for _, envID := range sortedEnvIDs(pm.notificationsDrop) {
+		fmt.Fprintf(b, "achemdb_notifications_dropped_total{env=%s} %d\n", promLabel(string(envID)), pm.notificationsDrop[envID])
+	}
+
+	promHeader(b, "achemdb_snapshot_duration_seconds", "summary", "Time spent writing snapshots.")
+	for _, envID := range sortedEnvIDs(pm.snapshots) {
+		d := pm.snapshots[envID]
+		fmt.Fprintf(b, "achemdb_snapshot_duration_seconds_sum{env=%s} %g\n", promLabel(string(envID)), d.sum.Seconds())
+		fmt.Fprintf(b, "achemdb_snapshot_duration_seconds_count{env=%s} %d\n", promLabel(string(envID)), d.count)
+	}
+
+	promHeader(b, "achemdb_snapshot_errors_total", "counter", "Snapshot writes that failed.")
+	for _, envID := range sortedEnvIDs(pm.snapshots) {
+		fmt.Fprintf(b, "achemdb_snapshot_errors_total{env=%s} %d\n", promLabel(string(envID)), pm.snapshots[envID].errors)
+	}
+}
+
+func promHeader(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// promLabel quotes a label value, escaping the characters the text format requires.
+func promLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return `"` + v + `"`
+}
+
+func sortedEnvIDs[V any](m map[achem.EnvironmentID]V) []achem.EnvironmentID {
+	ids := make([]achem.EnvironmentID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// GET /metrics
+// Expose engine metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	if s.metrics == nil {
+		http.Error(w, "metrics disabled", http.StatusNotFound)
+		return
+	}
+
+	var b strings.Builder
+	s.metrics.write(&b, s.manager)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// errSnapshotWithoutSchema is reported for snapshots written before snapshots
+// recorded the schema, which can't be restored without one
+var errSnapshotWithoutSchema = errors.New("snapshot has no schema")
+
+// restoreSnapshots recreates every environment that has a snapshot in the snapshot
+// directory, rebuilding its schema from the snapshot. Environments whose snapshot can't
+// be loaded, or doesn't record a schema, are skipped with a warning. Returns the IDs of
+// the restored environments.
+func (s *Server) restoreSnapshots() ([]achem.EnvironmentID, error) {
+	if s.snapshotDir == "" {
+		return nil, nil
+	}
+	ids, err := achem.SnapshotEnvironmentIDs(s.snapshotDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var restored []achem.EnvironmentID
+	for _, id := range ids {
+		if _, exists := s.manager.GetEnvironment(id); exists {
+			continue
+		}
+		if err := s.manager.CreateEnvironment(id, nil); err != nil {
+			s.logger.Warnw("Failed to restore environment", "env_id", id, "error", err)
+			continue
+		}
+		env, _ := s.manager.GetEnvironment(id)
+		s.configureEnvironment(env)
+
+		err := env.LoadSnapshot()
+		if _, hasSchema := env.SchemaConfig(); err == nil && !hasSchema {
+			err = errSnapshotWithoutSchema
+		}
+		if err != nil {
+			s.logger.Warnw("Failed to restore environment", "env_id", id, "error", err)
+			_ = s.manager.DeleteEnvironment(id)
+			continue
+		}
+		s.logger.Infow("Environment restored from snapshot", "env_id", id, "time", env.Time(), "molecules", len(env.AllMolecules()))
+		restored = append(restored, id)
+	}
+	return restored, nil
+}
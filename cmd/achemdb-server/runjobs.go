@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+const (
+	// syncRunMaxTicks is the largest run executed within the request; longer runs
+	// become background jobs.
+	syncRunMaxTicks = 1000
+	// maxRunTicks bounds the number of ticks of a single run request.
+	maxRunTicks = 10_000_000
+	// runJobRetention is how long finished run jobs can still be polled.
+	runJobRetention = time.Hour
+)
+
+// runJob tracks a background run of a fixed number of ticks. Its fields are guarded
+// by Server.runJobsMu.
+type runJob struct {
+	id         string
+	envID      achem.EnvironmentID
+	ticks      int
+	completed  int
+	time       int64
+	done       bool
+	err        string
+	finishedAt time.Time
+
+	cancel   context.CancelFunc // stops the job before its next tick
+	finished chan struct{}      // closed when the job's goroutine returns
+}
+
+// runJobStatus is the JSON representation of a run job
+type runJobStatus struct {
+	JobID     string              `json:"job_id"`
+	EnvID     achem.EnvironmentID `json:"env_id"`
+	Ticks     int                 `json:"ticks"`
+	Completed int                 `json:"completed"`
+	Time      int64               `json:"time"`
+	Done      bool                `json:"done"`
+	Error     string              `json:"error,omitempty"`
+}
+
+func (j *runJob) status() runJobStatus {
+	return runJobStatus{
+		JobID:     j.id,
+		EnvID:     j.envID,
+		Ticks:     j.ticks,
+		Completed: j.completed,
+		Time:      j.time,
+		Done:      j.done,
+		Error:     j.err,
+	}
+}
+
+// POST /env/{envID}/run?ticks=N
+// Step the environment exactly N times. Runs of up to syncRunMaxTicks ticks complete
+// within the request and return { "ticks": N, "time": T }; longer runs are started in
+// the background and return 202 Accepted with the job status, to be polled at
+// GET /env/{envID}/run/{jobID} and cancelled with DELETE /env/{envID}/run/{jobID}. The
+// environment must not be auto-running, nor stepped by another run job.
+func (s *Server) handleRunTicks(w http.ResponseWriter, r *http.Request) {
+	envID, _ := extractEnvID(r.URL.Path)
+	if envID == "" {
+		http.Error(w, "environment ID is required in path: /env/{envID}/run", http.StatusBadRequest)
+		return
+	}
+
+	ticks, err := strconv.Atoi(r.URL.Query().Get("ticks"))
+	if err != nil || ticks < 1 || ticks > maxRunTicks {
+		http.Error(w, fmt.Sprintf("ticks must be an integer between 1 and %d", maxRunTicks), http.StatusBadRequest)
+		return
+	}
+
+	env, exists := s.manager.GetEnvironment(envID)
+	if !exists {
+		http.Error(w, "environment not found", http.StatusNotFound)
+		return
+	}
+
+	if env.IsRunning() {
+		http.Error(w, "environment is auto-running: stop it before running a fixed number of ticks", http.StatusConflict)
+		return
+	}
+
+	if ticks <= syncRunMaxTicks {
+		// a run job steps the environment itself: running now would interleave ticks.
+		// s.syncRuns (guarded by runJobsMu) keeps jobs from starting meanwhile.
+		s.runJobsMu.Lock()
+		jobID, running := s.runJobInProgressLocked(envID)
+		if !running {
+			s.syncRuns[envID]++
+		}
+		s.runJobsMu.Unlock()
+		if running {
+			http.Error(w, "a run job is already in progress for this environment: "+jobID, http.StatusConflict)
+			return
+		}
+
+		for range ticks {
+			env.Step()
+		}
+
+		s.runJobsMu.Lock()
+		if s.syncRuns[envID]--; s.syncRuns[envID] == 0 {
+			delete(s.syncRuns, envID)
+		}
+		s.runJobsMu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]int64{"ticks": int64(ticks), "time": env.Time()}); err != nil {
+			http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	job, err := s.startRunJob(envID, env, ticks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	s.logger.Infow("Run job started", "env_id", envID, "job_id", job.JobID, "ticks", ticks)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/env/"+string(envID)+"/run/"+job.JobID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// startRunJob registers a run job for env and starts it in the background. Only one
+// job can run per environment at a time, so that their ticks don't interleave, and not
+// while the environment is auto-running (see handleStart) or stepped by a synchronous run.
+func (s *Server) startRunJob(envID achem.EnvironmentID, env *achem.Environment, ticks int) (runJobStatus, error) {
+	s.runJobsMu.Lock()
+	defer s.runJobsMu.Unlock()
+
+	for id, job := range s.runJobs {
+		if job.done && time.Since(job.finishedAt) > runJobRetention {
+			delete(s.runJobs, id)
+		}
+	}
+	if id, running := s.runJobInProgressLocked(envID); running {
+		return runJobStatus{}, fmt.Errorf("a run job is already in progress for this environment: %s", id)
+	}
+	if env.IsRunning() {
+		return runJobStatus{}, fmt.Errorf("environment is auto-running: stop it before running a fixed number of ticks")
+	}
+	if s.syncRuns[envID] > 0 {
+		return runJobStatus{}, fmt.Errorf("a run is already in progress for this environment")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &runJob{id: achem.NewRandomID(), envID: envID, ticks: ticks, time: env.Time(), cancel: cancel, finished: make(chan struct{})}
+	s.runJobs[job.id] = job
+	go s.runTicks(ctx, job, env)
+	return job.status(), nil
+}
+
+// runJobInProgressLocked returns the ID of the run job stepping envID, if any. Must be
+// called with s.runJobsMu held.
+func (s *Server) runJobInProgressLocked(envID achem.EnvironmentID) (string, bool) {
+	for id, job := range s.runJobs {
+		if !job.done && job.envID == envID {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// runTicks steps env for the job, stopping early if ctx is cancelled or the environment
+// is deleted.
+func (s *Server) runTicks(ctx context.Context, job *runJob, env *achem.Environment) {
+	defer close(job.finished)
+	defer job.cancel()
+
+	var jobErr string
+	for i := range job.ticks {
+		if ctx.Err() != nil {
+			jobErr = "cancelled"
+			break
+		}
+		if current, exists := s.manager.GetEnvironment(job.envID); !exists || current != env {
+			jobErr = "environment deleted"
+			break
+		}
+		env.Step()
+
+		s.runJobsMu.Lock()
+		job.completed = i + 1
+		job.time = env.Time()
+		s.runJobsMu.Unlock()
+	}
+
+	s.runJobsMu.Lock()
+	job.done = true
+	job.err = jobErr
+	job.finishedAt = time.Now()
+	completed := job.completed
+	s.runJobsMu.Unlock()
+
+	s.logger.Infow("Run job finished", "env_id", job.envID, "job_id", job.id, "completed", completed)
+}
+
+// cancelRunJobs cancels every run job in progress and waits for them to stop, or for
+// ctx to expire.
+func (s *Server) cancelRunJobs(ctx context.Context) error {
+	s.runJobsMu.Lock()
+	var running []*runJob
+	for _, job := range s.runJobs {
+		if !job.done {
+			job.cancel()
+			running = append(running, job)
+		}
+	}
+	s.runJobsMu.Unlock()
+
+	for _, job := range running {
+		select {
+		case <-job.finished:
+		case <-ctx.Done():
+			return fmt.Errorf("cancelling run job %s: %w", job.id, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// GET /env/{envID}/run/{jobID}
+// Return the progress of a background run job
+func (s *Server) handleRunJobStatus(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	jobID := strings.TrimPrefix(remainingPath, "/run/")
+
+	s.runJobsMu.Lock()
+	job, exists := s.runJobs[jobID]
+	var status runJobStatus
+	if exists {
+		status = job.status()
+	}
+	s.runJobsMu.Unlock()
+
+	if !exists || status.EnvID != envID {
+		http.Error(w, "run job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// DELETE /env/{envID}/run/{jobID}
+// Cancel a background run job before its next tick, and return its final status. The
+// ticks already run are kept. Cancelling a finished job is a no-op.
+func (s *Server) handleCancelRunJob(w http.ResponseWriter, r *http.Request) {
+	envID, remainingPath := extractEnvID(r.URL.Path)
+	jobID := strings.TrimPrefix(remainingPath, "/run/")
+
+	s.runJobsMu.Lock()
+	job, exists := s.runJobs[jobID]
+	if exists && job.envID == envID {
+		job.cancel()
+	}
+	s.runJobsMu.Unlock()
+
+	if !exists || job.envID != envID {
+		http.Error(w, "run job not found", http.StatusNotFound)
+		return
+	}
+
+	select {
+	case <-job.finished:
+	case <-r.Context().Done():
+		return
+	}
+	s.logger.Infow("Run job cancelled", "env_id", envID, "job_id", jobID)
+
+	s.runJobsMu.Lock()
+	status := job.status()
+	s.runJobsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, "cannot encode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/daniacca/achemdb/internal/achem"
+import (
+	"sync"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
 
 // achemLoggerAdapter adapts the server's Logger to the achem.Logger interface
 type achemLoggerAdapter struct {
@@ -26,10 +30,21 @@ func (a *achemLoggerAdapter) Errorf(format string, v ...any) {
 // Server represents the HTTP server for AChemDB
 type Server struct {
 	manager            *achem.EnvironmentManager
-	globalNotifierMgr  *achem.NotificationManager
+	globalNotifierMgr  *achem.NotificationManager // nil when notifiers are isolated per environment
 	snapshotDir        string
 	snapshotEveryTicks int
+	notifyMaxWorkers   int          // 0 disables notification worker autoscaling
+	metrics            *promMetrics // nil when metrics are disabled
 	logger             *Logger
+	templatesMu        sync.RWMutex
+	templates          map[string]environmentTemplate
+	runJobsMu          sync.Mutex
+	runJobs            map[string]*runJob
+	syncRuns           map[achem.EnvironmentID]int
+	envNotifiersMu     sync.Mutex          // serializes the creation of environment-local notifier managers
+	readyEnvID         achem.EnvironmentID // environment required by /readyz, empty for any
+	events             *eventLog           // recent notification events, nil when disabled
+	notifierFileDir    string              // directory of file notifiers, empty when they are disabled
 }
 
 // NewServer creates a new server instance
@@ -37,10 +52,49 @@ func NewServer(logger *Logger) *Server {
 	// Convert server logger to achem.Logger interface
 	achemLogger := &achemLoggerAdapter{logger: logger}
 	globalMgr := achem.NewNotificationManagerWithLogger(achemLogger)
-	return &Server{
-		manager:           achem.NewEnvironmentManagerWithLogger(achemLogger),
+	manager := achem.NewEnvironmentManagerWithLogger(achemLogger)
+	metrics := newPromMetrics()
+	globalMgr.SetMetrics(metrics)
+	manager.SetMetrics(metrics)
+	s := &Server{
+		manager:           manager,
 		globalNotifierMgr: globalMgr,
+		metrics:           metrics,
 		logger:            logger,
+		templates:         make(map[string]environmentTemplate),
+		runJobs:           make(map[string]*runJob),
+		syncRuns:          make(map[achem.EnvironmentID]int),
+		events:            newEventLog(defaultEventLogSize),
+	}
+	s.recordEvents(globalMgr)
+	return s
+}
+
+// SetMetricsEnabled turns metric collection (and the /metrics endpoint) on or off.
+// When disabled, environments and notifiers record into a no-op implementation.
+func (s *Server) SetMetricsEnabled(enabled bool) {
+	var metrics achem.Metrics = achem.NewNoOpMetrics()
+	if enabled {
+		if s.metrics == nil {
+			s.metrics = newPromMetrics()
+		}
+		metrics = s.metrics
+	} else {
+		s.metrics = nil
+	}
+	s.manager.SetMetrics(metrics)
+	if s.globalNotifierMgr != nil {
+		s.globalNotifierMgr.SetMetrics(metrics)
+	}
+}
+
+// SetIsolateNotifiers enables per-environment notifier isolation. When enabled, the
+// global notifier manager is closed and disabled, and each environment only triggers
+// the notifiers registered through /env/{envID}/notifiers. Call before serving requests.
+func (s *Server) SetIsolateNotifiers(isolate bool) {
+	if isolate && s.globalNotifierMgr != nil {
+		_ = s.globalNotifierMgr.Close()
+		s.globalNotifierMgr = nil
 	}
 }
 
@@ -49,7 +103,70 @@ func (s *Server) SetSnapshotDir(dir string) {
 	s.snapshotDir = dir
 }
 
+// SetNotifierFileDir sets the directory file notifiers write to. Their paths are relative
+// to it and cannot leave it; an empty dir disables file notifiers.
+func (s *Server) SetNotifierFileDir(dir string) {
+	s.notifierFileDir = dir
+}
+
 // SetSnapshotEveryTicks sets the snapshot frequency for all environments
 func (s *Server) SetSnapshotEveryTicks(ticks int) {
 	s.snapshotEveryTicks = ticks
 }
+
+// SetReadyEnvironment makes /readyz wait for the environment id (e.g. the default
+// environment of the initial schema) instead of any environment
+func (s *Server) SetReadyEnvironment(id achem.EnvironmentID) {
+	s.readyEnvID = id
+}
+
+// SetNotifyMaxWorkers enables notification worker autoscaling, up to maxWorkers, on the
+// global notifier manager and on the managers of environments configured afterwards
+// (when notifiers are isolated). 0 disables autoscaling.
+func (s *Server) SetNotifyMaxWorkers(maxWorkers int) {
+	s.notifyMaxWorkers = maxWorkers
+	if s.globalNotifierMgr != nil {
+		s.enableNotifyAutoscale(s.globalNotifierMgr)
+	}
+}
+
+// localNotificationManager returns the notification manager holding the notifiers
+// registered for env through /env/{envID}/notifiers. With notifier isolation, that is
+// the environment's own manager. Otherwise environments start on the global manager,
+// and the first call with create set gives env a manager of its own, which falls back
+// to the global notifiers for the IDs it doesn't have. Without create, nil is returned
+// for environments that have no local notifiers yet.
+func (s *Server) localNotificationManager(env *achem.Environment, create bool) *achem.NotificationManager {
+	s.envNotifiersMu.Lock()
+	defer s.envNotifiersMu.Unlock()
+
+	mgr := env.GetNotificationManager()
+	if mgr != s.globalNotifierMgr {
+		return mgr
+	}
+	if !create {
+		return nil
+	}
+
+	local := achem.NewNotificationManagerWithLogger(&achemLoggerAdapter{logger: s.logger})
+	if s.metrics != nil {
+		local.SetMetrics(s.metrics)
+	}
+	local.SetFallback(s.globalNotifierMgr)
+	s.enableNotifyAutoscale(local)
+	s.recordEvents(local)
+	env.SetNotificationManager(local)
+	return local
+}
+
+// enableNotifyAutoscale applies the configured autoscaling to mgr, if any.
+func (s *Server) enableNotifyAutoscale(mgr *achem.NotificationManager) {
+	if s.notifyMaxWorkers <= 0 {
+		return
+	}
+	cfg := achem.DefaultNotificationAutoscale()
+	cfg.MaxWorkers = s.notifyMaxWorkers
+	if err := mgr.EnableAutoscale(cfg); err != nil {
+		s.logger.Warnw("Failed to enable notification autoscaling", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// shutdownTimeout bounds the graceful shutdown: stopping environments, flushing
+// snapshots and draining HTTP requests.
+const shutdownTimeout = 30 * time.Second
+
+// stopPollInterval is how often shutdown checks whether a run loop has exited
+const stopPollInterval = 10 * time.Millisecond
+
+// flushAll prepares the server for exit, once the HTTP server no longer serves requests:
+// it cancels the run jobs and stops every running environment, waiting for them to
+// exit, saves a final snapshot of each environment that has a snapshot directory, and
+// closes the notification managers, delivering queued notifications. It keeps going on
+// errors and returns them joined.
+func (s *Server) flushAll(ctx context.Context) error {
+	var errs []error
+	managers := make(map[*achem.NotificationManager]struct{})
+
+	if err := s.cancelRunJobs(ctx); err != nil {
+		return err
+	}
+
+	for _, id := range s.manager.ListEnvironments() {
+		env, ok := s.manager.GetEnvironment(id)
+		if !ok {
+			continue // deleted concurrently
+		}
+
+		env.Stop()
+		for env.IsRunning() {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("stopping environment %s: %w", id, ctx.Err())
+			case <-time.After(stopPollInterval):
+			}
+		}
+
+		if err := env.SaveSnapshot(); err != nil {
+			s.logger.Errorw("Failed to save final snapshot", "env_id", id, "error", err)
+			errs = append(errs, fmt.Errorf("saving snapshot of environment %s: %w", id, err))
+		}
+
+		// environments with isolated or local notifiers have their own manager
+		if mgr := env.GetNotificationManager(); mgr != nil && mgr != s.globalNotifierMgr {
+			managers[mgr] = struct{}{}
+		}
+	}
+
+	for mgr := range managers {
+		if err := mgr.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing notification manager: %w", err))
+		}
+	}
+	if s.globalNotifierMgr != nil {
+		if err := s.globalNotifierMgr.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing global notification manager: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// statsdMaxPacketSize keeps packets below the usual network MTU so that they are
+// never fragmented.
+const statsdMaxPacketSize = 1432
+
+// statsdEmitter periodically pushes environment metrics to a StatsD endpoint over UDP.
+// Counters are sent as deltas since the previous flush; molecule counts and queue
+// depths are sent as gauges.
+type statsdEmitter struct {
+	server   *Server
+	conn     net.Conn
+	prefix   string
+	interval time.Duration
+	last     map[achem.EnvironmentID]achem.Counters
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// newStatsdEmitter creates an emitter sending metrics for all environments of s to addr.
+func newStatsdEmitter(s *Server, addr string, interval time.Duration) (*statsdEmitter, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("statsd interval must be positive")
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdEmitter{
+		server:   s,
+		conn:     conn,
+		prefix:   "achemdb",
+		interval: interval,
+		last:     make(map[achem.EnvironmentID]achem.Counters),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}, nil
+}
+
+// Start runs the emitter in a background goroutine until Stop is called.
+func (e *statsdEmitter) Start() {
+	go func() {
+		defer close(e.doneCh)
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.flush()
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the emitter and closes its connection.
+func (e *statsdEmitter) Stop() {
+	close(e.stopCh)
+	<-e.doneCh
+	_ = e.conn.Close()
+}
+
+// flush collects the current metrics and sends them. Send failures are logged and
+// otherwise ignored: metrics are best-effort and must never affect the server.
+func (e *statsdEmitter) flush() {
+	for _, packet := range packStatsdLines(e.collect(), statsdMaxPacketSize) {
+		if _, err := e.conn.Write([]byte(packet)); err != nil {
+			e.server.logger.Debugw("Failed to send statsd metrics", "error", err)
+			return
+		}
+	}
+}
+
+// collect returns the StatsD lines for all environments and the global notification queue.
+func (e *statsdEmitter) collect() []string {
+	var lines []string
+
+	if mgr := e.server.globalNotifierMgr; mgr != nil {
+		lines = append(lines, fmt.Sprintf("%s.notification_queue_depth:%d|g", e.prefix, mgr.QueueDepth()))
+	}
+
+	envIDs := e.server.manager.ListEnvironments()
+	seen := make(map[achem.EnvironmentID]bool, len(envIDs))
+	for _, envID := range envIDs {
+		env, exists := e.server.manager.GetEnvironment(envID)
+		if !exists {
+			continue
+		}
+		seen[envID] = true
+		prefix := e.prefix + ".env." + statsdName(string(envID))
+
+		counts := env.SpeciesCounts()
+		species := make([]string, 0, len(counts))
+		for sp := range counts {
+			species = append(species, string(sp))
+		}
+		sort.Strings(species)
+		for _, sp := range species {
+			lines = append(lines, fmt.Sprintf("%s.molecules.%s:%d|g", prefix, statsdName(sp), counts[achem.SpeciesName(sp)]))
+		}
+
+		// environments with isolated notifiers have their own queue
+		if mgr := env.GetNotificationManager(); mgr != nil && mgr != e.server.globalNotifierMgr {
+			lines = append(lines, fmt.Sprintf("%s.notification_queue_depth:%d|g", prefix, mgr.QueueDepth()))
+		}
+
+		// Counters are read without resetting, so that /counters?reset=true keeps
+		// working for other collectors; deltas are computed against the last flush.
+		current := env.Counters(false)
+		delta := counterDelta(e.last[envID], current)
+		e.last[envID] = current
+
+		reactionIDs := make([]string, 0, len(delta.ReactionsFired))
+		for id := range delta.ReactionsFired {
+			reactionIDs = append(reactionIDs, id)
+		}
+		sort.Strings(reactionIDs)
+		for _, id := range reactionIDs {
+			lines = append(lines, fmt.Sprintf("%s.reactions_fired.%s:%d|c", prefix, statsdName(id), delta.ReactionsFired[id]))
+		}
+		lines = append(lines,
+			fmt.Sprintf("%s.molecules_created:%d|c", prefix, delta.MoleculesCreated),
+			fmt.Sprintf("%s.molecules_consumed:%d|c", prefix, delta.MoleculesConsumed),
+			fmt.Sprintf("%s.molecules_evicted:%d|c", prefix, delta.MoleculesEvicted),
+			fmt.Sprintf("%s.molecules_expired:%d|c", prefix, delta.MoleculesExpired),
+			fmt.Sprintf("%s.notifications:%d|c", prefix, delta.Notifications),
+			fmt.Sprintf("%s.notifications_dropped:%d|c", prefix, delta.NotificationsDropped),
+		)
+	}
+
+	for envID := range e.last {
+		if !seen[envID] {
+			delete(e.last, envID)
+		}
+	}
+
+	return lines
+}
+
+// counterDelta returns the increase from prev to current. A value lower than before
+// means the counters were reset in between, in which case the current value is the delta.
+func counterDelta(prev, current achem.Counters) achem.Counters {
+	diff := func(p, c int64) int64 {
+		if c < p {
+			return c
+		}
+		return c - p
+	}
+
+	delta := achem.Counters{ReactionsFired: make(map[string]int64)}
+	for id, n := range current.ReactionsFired {
+		if d := diff(prev.ReactionsFired[id], n); d > 0 {
+			delta.ReactionsFired[id] = d
+		}
+	}
+	delta.MoleculesCreated = diff(prev.MoleculesCreated, current.MoleculesCreated)
+	delta.MoleculesConsumed = diff(prev.MoleculesConsumed, current.MoleculesConsumed)
+	delta.MoleculesEvicted = diff(prev.MoleculesEvicted, current.MoleculesEvicted)
+	delta.MoleculesExpired = diff(prev.MoleculesExpired, current.MoleculesExpired)
+	delta.Notifications = diff(prev.Notifications, current.Notifications)
+	delta.NotificationsDropped = diff(prev.NotificationsDropped, current.NotificationsDropped)
+	return delta
+}
+
+// statsdName replaces the characters that have a meaning in the StatsD protocol
+// (and the metric path separator) so that IDs can be used as metric name segments.
+func statsdName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// packStatsdLines joins lines into newline-separated packets of at most maxSize bytes.
+// A single line longer than maxSize is sent in its own packet.
+func packStatsdLines(lines []string, maxSize int) []string {
+	var packets []string
+	var b strings.Builder
+	for _, line := range lines {
+		if b.Len() > 0 && b.Len()+1+len(line) > maxSize {
+			packets = append(packets, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(line)
+	}
+	if b.Len() > 0 {
+		packets = append(packets, b.String())
+	}
+	return packets
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 
 	"github.com/daniacca/achemdb/internal/achem"
 )
@@ -16,10 +19,15 @@ type seedMolecule struct {
 
 func main() {
 	var (
-		schemaFile = flag.String("schema-file", "", "path to schema JSON file (required)")
-		ticks      = flag.Int("ticks", 100, "number of ticks to run")
-		seedFile   = flag.String("seed", "", "path to seed molecules JSON file (optional)")
-		envID      = flag.String("env-id", "simulation", "environment ID")
+		schemaFile  = flag.String("schema-file", "", "path to schema JSON file (required)")
+		ticks       = flag.Int("ticks", 100, "number of ticks to run")
+		seedFile    = flag.String("seed", "", "path to seed molecules JSON file (optional)")
+		envID       = flag.String("env-id", "simulation", "environment ID")
+		rngTrace    = flag.String("rng-trace", "", "write every RNG draw as JSON lines to this file (optional, slow)")
+		rngSeed     = flag.Int64("rng-seed", 0, "seed the random generator for a reproducible run (optional, default: time-based)")
+		output      = flag.String("output", "", "record species counts at every tick, as \"csv\" or \"json\" (optional)")
+		outFile     = flag.String("out", "", "file to write the --output time series to (optional, default: stdout)")
+		sampleEvery = flag.Int("sample-every", 1, "with --output, record every Nth tick, plus the last one")
 	)
 	flag.Parse()
 
@@ -28,6 +36,26 @@ func main() {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if *output != "" && *output != outputCSV && *output != outputJSON {
+		fmt.Fprintf(os.Stderr, "error: --output must be %q or %q\n", outputCSV, outputJSON)
+		os.Exit(1)
+	}
+	if *outFile != "" && *output == "" {
+		fmt.Fprintf(os.Stderr, "error: --out requires --output\n")
+		os.Exit(1)
+	}
+	if *ticks < 0 {
+		fmt.Fprintf(os.Stderr, "error: --ticks must not be negative\n")
+		os.Exit(1)
+	}
+	if *sampleEvery < 1 {
+		fmt.Fprintf(os.Stderr, "error: --sample-every must be at least 1\n")
+		os.Exit(1)
+	}
+	if *sampleEvery != 1 && *output == "" {
+		fmt.Fprintf(os.Stderr, "error: --sample-every requires --output\n")
+		os.Exit(1)
+	}
 
 	// Load and validate schema
 	cfg, schema, err := loadSchemaFromFile(*schemaFile)
@@ -40,6 +68,24 @@ func main() {
 	env := achem.NewEnvironment(schema)
 	env.SetEnvironmentID(achem.EnvironmentID(*envID))
 
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "rng-seed" {
+			env.SetRandomSeed(*rngSeed)
+		}
+	})
+
+	if *rngTrace != "" {
+		f, err := os.Create(*rngTrace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating rng trace file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		traceWriter := bufio.NewWriter(f)
+		defer traceWriter.Flush()
+		env.SetRNGTrace(traceWriter)
+	}
+
 	// Load seed molecules if provided
 	if *seedFile != "" {
 		if err := loadSeedMolecules(env, *seedFile); err != nil {
@@ -49,12 +95,49 @@ func main() {
 	}
 
 	// Run simulation
-	for i := 0; i < *ticks; i++ {
-		env.Step()
+	if *output == "" {
+		printSummary(cfg.Name, env.StepN(*ticks), env)
+		return
+	}
+
+	// Stream the species counts to stdout or --out as the run progresses
+	out := os.Stdout
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		out = f
+	}
+	summary, err := writeRun(out, *output, cfg, env, *ticks, *sampleEvery)
+	if *outFile != "" {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing output: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Print summary
-	printSummary(cfg.Name, *ticks, env)
+	if *outFile != "" {
+		// with the series on stdout, keep it free of the summary
+		printSummary(cfg.Name, summary, env)
+	}
+}
+
+// writeRun runs the simulation, streaming its time series to w in the given format.
+func writeRun(w io.Writer, format string, cfg achem.SchemaConfig, env *achem.Environment, ticks, sampleEvery int) (achem.StepSummary, error) {
+	sw, err := newSeriesWriter(w, format, seriesColumns(cfg, env.Stats()))
+	if err != nil {
+		return achem.StepSummary{}, err
+	}
+	summary, err := recordSeries(env, ticks, sampleEvery, sw)
+	if err != nil {
+		return summary, err
+	}
+	return summary, sw.Close()
 }
 
 func loadSchemaFromFile(path string) (achem.SchemaConfig, *achem.Schema, error) {
@@ -94,45 +177,58 @@ func loadSeedMolecules(env *achem.Environment, path string) error {
 	}
 
 	// Insert each seed molecule with CreatedAt and LastTouchedAt = 0
-	for _, seed := range seeds {
+	for i, seed := range seeds {
 		m := achem.NewMolecule(achem.SpeciesName(seed.Species), seed.Payload, 0)
 		// Override timestamps to 0 as requested
 		m.CreatedAt = 0
 		m.LastTouchedAt = 0
-		env.Insert(m)
+		if err := env.Insert(m, achem.WithSpeciesDefaults()); err != nil {
+			return fmt.Errorf("seed %d: %w", i, err)
+		}
 	}
 
 	return nil
 }
 
-func printSummary(schemaName string, ticks int, env *achem.Environment) {
-	molecules := env.AllMolecules()
+func printSummary(schemaName string, summary achem.StepSummary, env *achem.Environment) {
+	stats := env.Stats()
 
-	// Count molecules by species
-	counts := make(map[achem.SpeciesName]int)
-	for _, m := range molecules {
-		counts[m.Species]++
-	}
-
-	fmt.Printf("Simulation finished (schema=%s, ticks=%d)\n", schemaName, ticks)
+	fmt.Printf("Simulation finished (schema=%s, ticks=%d, time=%d)\n", schemaName, summary.Ticks, summary.Time)
 	fmt.Println("Species counts:")
 
-	// Print in a consistent order (sorted by species name)
-	speciesList := make([]achem.SpeciesName, 0, len(counts))
-	for species := range counts {
-		speciesList = append(speciesList, species)
-	}
-
-	// Simple sort by string value
-	for i := 0; i < len(speciesList); i++ {
-		for j := i + 1; j < len(speciesList); j++ {
-			if speciesList[i] > speciesList[j] {
-				speciesList[i], speciesList[j] = speciesList[j], speciesList[i]
-			}
+	// Print in a consistent order (sorted by species name), including species that
+	// were active during the run but have no molecules left
+	seen := make(map[achem.SpeciesName]bool)
+	for _, counts := range []map[achem.SpeciesName]int64{summary.Created, summary.Consumed} {
+		for species := range counts {
+			seen[species] = true
 		}
 	}
+	for species := range stats.Species {
+		seen[species] = true
+	}
+	speciesList := make([]string, 0, len(seen))
+	for species := range seen {
+		speciesList = append(speciesList, string(species))
+	}
+	sort.Strings(speciesList)
 
 	for _, species := range speciesList {
-		fmt.Printf("  %s: %d\n", species, counts[species])
+		name := achem.SpeciesName(species)
+		fmt.Printf("  %s: %d (created %d, consumed %d)\n", species, stats.Species[name], summary.Created[name], summary.Consumed[name])
+	}
+
+	fmt.Println("Reactions fired:")
+	reactionIDs := make([]string, 0, len(summary.ReactionsFired))
+	for id := range summary.ReactionsFired {
+		reactionIDs = append(reactionIDs, id)
+	}
+	sort.Strings(reactionIDs)
+
+	if len(reactionIDs) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, id := range reactionIDs {
+		fmt.Printf("  %s: %d\n", id, summary.ReactionsFired[id])
 	}
 }
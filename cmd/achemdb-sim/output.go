@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// Output formats for the per-tick time series
+const (
+	outputCSV  = "csv"
+	outputJSON = "json"
+)
+
+// seriesWriter writes the time series one sample at a time, so that long runs
+// don't keep every sample in memory.
+type seriesWriter interface {
+	// Write appends one sample.
+	Write(stats achem.EnvStats) error
+	// Close terminates the output. It does not close the underlying writer.
+	Close() error
+}
+
+// newSeriesWriter returns a seriesWriter for the given format. For CSV, columns
+// lists the species to write, one column each.
+func newSeriesWriter(w io.Writer, format string, columns []string) (seriesWriter, error) {
+	switch format {
+	case outputCSV:
+		return newCSVSeriesWriter(w, columns)
+	case outputJSON:
+		return &jsonSeriesWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// seriesColumns returns the CSV columns for a run: every species declared by the
+// schema plus any other species present in initial (e.g. seeded ones), sorted by
+// name. Reactions can only create declared species, so no species appears later.
+func seriesColumns(cfg achem.SchemaConfig, initial achem.EnvStats) []string {
+	seen := make(map[string]bool)
+	for _, species := range cfg.Species {
+		seen[species.Name] = true
+	}
+	for species := range initial.Species {
+		seen[string(species)] = true
+	}
+	columns := make([]string, 0, len(seen))
+	for species := range seen {
+		columns = append(columns, species)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// csvSeriesWriter writes one row per sample, with a column per species.
+// Species absent at a tick have a count of 0.
+type csvSeriesWriter struct {
+	cw      *csv.Writer
+	columns []string
+	row     []string
+}
+
+func newCSVSeriesWriter(w io.Writer, columns []string) (*csvSeriesWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"tick"}, columns...)); err != nil {
+		return nil, err
+	}
+	return &csvSeriesWriter{cw: cw, columns: columns, row: make([]string, len(columns)+1)}, nil
+}
+
+func (c *csvSeriesWriter) Write(stats achem.EnvStats) error {
+	c.row[0] = strconv.FormatInt(stats.Time, 10)
+	for i, species := range c.columns {
+		c.row[i+1] = strconv.Itoa(stats.Species[achem.SpeciesName(species)])
+	}
+	return c.cw.Write(c.row)
+}
+
+func (c *csvSeriesWriter) Close() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// jsonSeriesWriter writes the samples as an indented JSON array, element by element.
+type jsonSeriesWriter struct {
+	w io.Writer
+	n int
+}
+
+func (j *jsonSeriesWriter) Write(stats achem.EnvStats) error {
+	data, err := json.MarshalIndent(stats, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if j.n == 0 {
+		sep = "[\n  "
+	}
+	j.n++
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonSeriesWriter) Close() error {
+	end := "\n]\n"
+	if j.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// recordSeries runs ticks ticks on env and writes the stats before the first tick,
+// every sampleEvery ticks and after the last one. Sampling only decides when stats
+// are read: the ticks themselves run exactly as without it.
+func recordSeries(env *achem.Environment, ticks, sampleEvery int, sw seriesWriter) (achem.StepSummary, error) {
+	summary := achem.StepSummary{
+		Time:           env.Time(),
+		ReactionsFired: make(map[string]int64),
+		Created:        make(map[achem.SpeciesName]int64),
+		Consumed:       make(map[achem.SpeciesName]int64),
+	}
+	if err := sw.Write(env.Stats()); err != nil {
+		return summary, err
+	}
+	for done := 0; done < ticks; {
+		n := min(sampleEvery, ticks-done)
+		mergeSummary(&summary, env.StepN(n))
+		done += n
+		if err := sw.Write(env.Stats()); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// mergeSummary adds the totals of src to dst.
+func mergeSummary(dst *achem.StepSummary, src achem.StepSummary) {
+	dst.Ticks += src.Ticks
+	dst.Time = src.Time
+	for id, n := range src.ReactionsFired {
+		dst.ReactionsFired[id] += n
+	}
+	for species, n := range src.Created {
+		dst.Created[species] += n
+	}
+	for species, n := range src.Consumed {
+		dst.Consumed[species] += n
+	}
+}
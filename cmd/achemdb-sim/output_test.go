@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+func testSimConfig() achem.SchemaConfig {
+	return achem.SchemaConfig{
+		Name:    "sim",
+		Species: []achem.SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []achem.ReactionConfig{{
+			ID:      "a_to_b",
+			Input:   achem.InputConfig{Species: "A"},
+			Rate:    0.3,
+			Effects: []achem.EffectConfig{{Transform: &achem.TransformEffectConfig{Species: "B"}}},
+		}},
+	}
+}
+
+func testSimEnv(t *testing.T) *achem.Environment {
+	t.Helper()
+	schema, err := achem.BuildSchemaFromConfig(testSimConfig())
+	if err != nil {
+		t.Fatalf("Failed to build schema: %v", err)
+	}
+	env := achem.NewEnvironment(schema)
+	env.SetRandomSeed(42)
+	for i := 0; i < 20; i++ {
+		env.Insert(achem.NewMolecule("A", nil, 0))
+	}
+	return env
+}
+
+// recordingWriter keeps the samples it is given, for the sampling tests
+type recordingWriter struct {
+	samples []achem.EnvStats
+}
+
+func (r *recordingWriter) Write(stats achem.EnvStats) error {
+	r.samples = append(r.samples, stats)
+	return nil
+}
+
+func (r *recordingWriter) Close() error { return nil }
+
+func TestSeriesColumns(t *testing.T) {
+	initial := achem.EnvStats{Species: map[achem.SpeciesName]int{"Seeded": 1, "A": 2}}
+	got := seriesColumns(testSimConfig(), initial)
+	want := []string{"A", "B", "Seeded"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected columns %v, got %v", want, got)
+	}
+}
+
+func TestCSVSeriesWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sw, err := newSeriesWriter(&buf, outputCSV, []string{"A", "B"})
+	if err != nil {
+		t.Fatalf("newSeriesWriter failed: %v", err)
+	}
+	samples := []achem.EnvStats{
+		{Time: 0, Species: map[achem.SpeciesName]int{"A": 3}},
+		{Time: 1, Species: map[achem.SpeciesName]int{"A": 1, "B": 2}},
+	}
+	for _, s := range samples {
+		if err := sw.Write(s); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := "tick,A,B\n0,3,0\n1,1,2\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestJSONSeriesWriter(t *testing.T) {
+	samples := []achem.EnvStats{
+		{Time: 0, Molecules: 3, Species: map[achem.SpeciesName]int{"A": 3}},
+		{Time: 1, Molecules: 3, Species: map[achem.SpeciesName]int{"A": 1, "B": 2}},
+	}
+
+	var buf bytes.Buffer
+	sw, err := newSeriesWriter(&buf, outputJSON, nil)
+	if err != nil {
+		t.Fatalf("newSeriesWriter failed: %v", err)
+	}
+	for _, s := range samples {
+		if err := sw.Write(s); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// the streamed array is laid out as if it was encoded in one go
+	var want bytes.Buffer
+	enc := json.NewEncoder(&want)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(samples); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if buf.String() != want.String() {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want.String(), buf.String())
+	}
+}
+
+func TestJSONSeriesWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	sw, _ := newSeriesWriter(&buf, outputJSON, nil)
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+}
+
+func TestNewSeriesWriter_UnknownFormat(t *testing.T) {
+	if _, err := newSeriesWriter(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestRecordSeries_Sampling(t *testing.T) {
+	tests := []struct {
+		ticks, sampleEvery int
+		want               []int64
+	}{
+		{ticks: 7, sampleEvery: 3, want: []int64{0, 3, 6, 7}},
+		{ticks: 6, sampleEvery: 3, want: []int64{0, 3, 6}},
+		{ticks: 2, sampleEvery: 5, want: []int64{0, 2}},
+		{ticks: 0, sampleEvery: 1, want: []int64{0}},
+	}
+	for _, tt := range tests {
+		rec := &recordingWriter{}
+		summary, err := recordSeries(testSimEnv(t), tt.ticks, tt.sampleEvery, rec)
+		if err != nil {
+			t.Fatalf("recordSeries failed: %v", err)
+		}
+		var got []int64
+		for _, s := range rec.samples {
+			got = append(got, s.Time)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ticks=%d sample-every=%d: expected ticks %v, got %v", tt.ticks, tt.sampleEvery, tt.want, got)
+		}
+		if summary.Ticks != tt.ticks {
+			t.Errorf("ticks=%d sample-every=%d: expected summary of %d ticks, got %d", tt.ticks, tt.sampleEvery, tt.ticks, summary.Ticks)
+		}
+	}
+}
+
+func TestRecordSeries_SamplingMatchesFullRun(t *testing.T) {
+	full := &recordingWriter{}
+	if _, err := recordSeries(testSimEnv(t), 10, 1, full); err != nil {
+		t.Fatalf("recordSeries failed: %v", err)
+	}
+	sampled := &recordingWriter{}
+	if _, err := recordSeries(testSimEnv(t), 10, 4, sampled); err != nil {
+		t.Fatalf("recordSeries failed: %v", err)
+	}
+
+	for _, s := range sampled.samples {
+		if !reflect.DeepEqual(s.Species, full.samples[s.Time].Species) {
+			t.Errorf("tick %d: sampled counts %v differ from full run %v", s.Time, s.Species, full.samples[s.Time].Species)
+		}
+	}
+}
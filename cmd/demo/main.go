@@ -53,10 +53,13 @@ func runSecurityAlertsDemo() {
 	env.SetEnvironmentID(achem.EnvironmentID("demo-env"))
 
 	for range randRange(5, 100) {
-		env.Insert(achem.NewMolecule("Event", map[string]any{
+		if err := env.Insert(achem.NewMolecule("Event", map[string]any{
 			"type": "login_failed",
 			"ip":   "1.2.3.4",
-		}, 0))
+		}, 0)); err != nil {
+			fmt.Printf("Failed to insert event: %v\n", err)
+			return
+		}
 	}
 
 	// Run 100 steps manually
@@ -147,9 +150,12 @@ func ExampleCallbackDemo() {
 	// Insert some input molecules
 	fmt.Println("Inserting random input molecules...")
 	for i := range randRange(5, 50) {
-		env.Insert(achem.NewMolecule("Input", map[string]any{
+		if err := env.Insert(achem.NewMolecule("Input", map[string]any{
 			"value": i,
-		}, 0))
+		}, 0)); err != nil {
+			fmt.Printf("Failed to insert input: %v\n", err)
+			return
+		}
 	}
 
 	fmt.Println("Running environment steps...")
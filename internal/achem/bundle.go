@@ -0,0 +1,110 @@
+package achem
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Bundle is a portable export of an environment: its schema configuration, time,
+// molecules and random seed, in a single document that can be shared and imported on
+// another server (see EnvironmentManager.ImportEnvironment).
+//
+// The seed is the one the environment was last seeded with, so an imported bundle
+// replays the draws from the point the seed was set, not from the time of the export.
+type Bundle struct {
+	EnvironmentID EnvironmentID `json:"environment_id"`
+	Schema        SchemaConfig  `json:"schema"`
+	Time          int64         `json:"time"`
+	Molecules     []Molecule    `json:"molecules"`
+	Seed          *int64        `json:"seed,omitempty"` // nil if the environment is not seeded
+}
+
+// errNoSchemaConfig is returned when exporting an environment whose schema was not
+// built from a SchemaConfig, and so can't be recreated elsewhere
+var errNoSchemaConfig = errors.New("environment schema was not built from a configuration")
+
+// Export returns a bundle of the environment's current state.
+// Returns an error if the environment's schema was not built from a SchemaConfig.
+func (e *Environment) Export() (Bundle, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	cfg, ok := e.schema.Config()
+	if !ok {
+		return Bundle{}, errNoSchemaConfig
+	}
+
+	b := Bundle{
+		EnvironmentID: e.envID,
+		Schema:        cfg,
+		Time:          e.time,
+		Molecules:     make([]Molecule, 0, len(e.mols)),
+	}
+	for _, m := range e.mols {
+		b.Molecules = append(b.Molecules, cloneMolecule(m))
+	}
+	sort.Slice(b.Molecules, func(i, j int) bool { return b.Molecules[i].ID < b.Molecules[j].ID })
+	if e.seeded {
+		seed := e.seed
+		b.Seed = &seed
+	}
+	return b, nil
+}
+
+// ValidateBundle checks that a bundle can be imported: its schema configuration must be
+// valid, its time non-negative, and its molecules must pass ValidateSnapshot and
+// Schema.ValidateMolecule against the schema. Returns the schema built from the bundle.
+func ValidateBundle(b Bundle) (*Schema, error) {
+	schema, err := BuildSchemaFromConfig(b.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if b.Time < 0 {
+		return nil, fmt.Errorf("time must be non-negative")
+	}
+	if err := ValidateSnapshot(Snapshot{Molecules: b.Molecules}, schema); err != nil {
+		return nil, err
+	}
+	for _, m := range b.Molecules {
+		if err := schema.ValidateMolecule(m); err != nil {
+			return nil, fmt.Errorf("molecule %s: %w", m.ID, err)
+		}
+	}
+	return schema, nil
+}
+
+// ImportEnvironment creates the environment id from a bundle, with the bundle's schema,
+// time, molecules and seed. The bundle is validated first (see ValidateBundle), so
+// nothing is created if it is invalid. Returns an error if the environment already
+// exists.
+func (em *EnvironmentManager) ImportEnvironment(id EnvironmentID, b Bundle) error {
+	schema, err := ValidateBundle(b)
+	if err != nil {
+		return err
+	}
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if _, exists := em.environments[id]; exists {
+		return fmt.Errorf("environment with id %s already exists", id)
+	}
+
+	env := NewEnvironmentWithLogger(schema, em.logger)
+	env.SetEnvironmentID(id)
+	env.SetMetrics(em.metrics)
+	env.manager = em
+	if b.Seed != nil {
+		env.SetRandomSeed(*b.Seed)
+	}
+
+	env.time = b.Time
+	for _, m := range b.Molecules {
+		env.mols[m.ID] = cloneMolecule(m)
+	}
+	env.syncVersionLocked()
+
+	em.environments[id] = env
+	return nil
+}
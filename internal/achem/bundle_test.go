@@ -0,0 +1,119 @@
+package achem
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnvironmentManager_ExportImport(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{ID: "alert", Input: InputConfig{Species: "Event"}, Rate: 0.5, Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	em := NewEnvironmentManager()
+	if err := em.CreateEnvironment("src", schema); err != nil {
+		t.Fatalf("CreateEnvironment failed: %v", err)
+	}
+	src, _ := em.GetEnvironment("src")
+	src.SetRandomSeed(42)
+	src.Insert(Molecule{ID: "e2", Species: "Event", Payload: map[string]any{"n": 2.0}, Position: &Position{X: 1, Y: 2}})
+	src.Insert(Molecule{ID: "e1", Species: "Event", Payload: map[string]any{"n": 1.0}})
+	src.StepN(3)
+
+	bundle, err := src.Export()
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if bundle.EnvironmentID != "src" || bundle.Time != 3 || bundle.Schema.Name != "alerts" || bundle.Seed == nil || *bundle.Seed != 42 {
+		t.Errorf("Unexpected bundle: %+v", bundle)
+	}
+	for i := 1; i < len(bundle.Molecules); i++ {
+		if bundle.Molecules[i-1].ID >= bundle.Molecules[i].ID {
+			t.Errorf("Expected molecules sorted by ID, got %+v", bundle.Molecules)
+		}
+	}
+
+	// the bundle survives a JSON round trip
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Bundle
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if err := em.ImportEnvironment("copy", decoded); err != nil {
+		t.Fatalf("ImportEnvironment failed: %v", err)
+	}
+	dst, _ := em.GetEnvironment("copy")
+	if dst.Time() != 3 || len(dst.AllMolecules()) != len(bundle.Molecules) {
+		t.Errorf("Expected time 3 and %d molecules, got time %d and %d molecules", len(bundle.Molecules), dst.Time(), len(dst.AllMolecules()))
+	}
+	if seed, ok := dst.RandomSeed(); !ok || seed != 42 {
+		t.Errorf("Expected the imported environment to be seeded with 42, got %d (%v)", seed, ok)
+	}
+	if err := em.ImportEnvironment("copy", decoded); err == nil {
+		t.Error("Expected importing over an existing environment to fail")
+	}
+
+	// two imports of the same bundle evolve identically
+	if err := em.ImportEnvironment("twin", decoded); err != nil {
+		t.Fatalf("ImportEnvironment failed: %v", err)
+	}
+	twin, _ := em.GetEnvironment("twin")
+	dst.StepN(5)
+	twin.StepN(5)
+	if a, b := len(dst.QueryMolecules("Alert", nil)), len(twin.QueryMolecules("Alert", nil)); a != b {
+		t.Errorf("Expected imports of the same bundle to evolve identically, got %d and %d alerts", a, b)
+	}
+
+	unseeded := NewEnvironment(schema)
+	if b, err := unseeded.Export(); err != nil || b.Seed != nil {
+		t.Errorf("Expected an unseeded environment to export no seed, got %+v (%v)", b.Seed, err)
+	}
+	if _, err := NewEnvironment(NewSchema("custom")).Export(); err == nil {
+		t.Error("Expected exporting a schema without configuration to fail")
+	}
+}
+
+func TestValidateBundle(t *testing.T) {
+	valid := Bundle{
+		Schema:    SchemaConfig{Name: "test", Species: []SpeciesConfig{{Name: "Event"}}},
+		Molecules: []Molecule{{ID: "e1", Species: "Event"}},
+	}
+	if _, err := ValidateBundle(valid); err != nil {
+		t.Fatalf("Expected a valid bundle, got %v", err)
+	}
+
+	tests := map[string]func(b *Bundle){
+		"invalid schema":    func(b *Bundle) { b.Schema.Name = "" },
+		"negative time":     func(b *Bundle) { b.Time = -1 },
+		"unknown species":   func(b *Bundle) { b.Molecules = []Molecule{{ID: "x", Species: "Ghost"}} },
+		"duplicate ID":      func(b *Bundle) { b.Molecules = []Molecule{{ID: "x", Species: "Event"}, {ID: "x", Species: "Event"}} },
+		"empty molecule ID": func(b *Bundle) { b.Molecules = []Molecule{{Species: "Event"}} },
+	}
+	for name, mutate := range tests {
+		b := valid
+		b.Molecules = append([]Molecule(nil), valid.Molecules...)
+		mutate(&b)
+		if _, err := ValidateBundle(b); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		em := NewEnvironmentManager()
+		if err := em.ImportEnvironment("env", b); err == nil {
+			t.Errorf("%s: expected the import to fail", name)
+		}
+		if len(em.ListEnvironments()) != 0 {
+			t.Errorf("%s: expected nothing to be created", name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package achem
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// Clone returns a stopped copy of the environment sharing the same schema, with a deep
+// copy of its molecules, their reaction cooldowns and the same time, so that the copy
+// can evolve independently (e.g. to explore an alternative scenario). The copy has no
+// environment ID, its own notification manager (with no notifiers), fresh counters and
+// no recorded diffs; the molecule cap, eviction policy, match cache, disabled
+// reactions, secondary indexes, ID generator and diff history settings are kept.
+// Snapshot, notifier and insert rate limit settings are not copied.
+func (e *Environment) Clone() *Environment {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	clone := NewEnvironmentWithLogger(e.schema, e.logger)
+	clone.time = e.time
+	for id, m := range e.mols {
+		clone.mols[id] = cloneMolecule(m)
+	}
+	clone.version = e.version
+	clone.metrics = e.metrics
+	clone.diffHistorySize = e.diffHistorySize
+	clone.maxMolecules = e.maxMolecules
+	clone.evictionPolicy = e.evictionPolicy
+	clone.matchCache = e.matchCache
+	clone.newID = e.newID
+	if len(e.disabledReactions) > 0 {
+		clone.disabledReactions = maps.Clone(e.disabledReactions)
+	}
+	for id, mols := range e.cooldowns {
+		if clone.cooldowns == nil {
+			clone.cooldowns = make(map[string]map[MoleculeID]int64, len(e.cooldowns))
+		}
+		clone.cooldowns[id] = maps.Clone(mols)
+	}
+	if len(e.indexes) > 0 {
+		clone.indexes = make(map[IndexField]fieldIndex, len(e.indexes))
+		for key := range e.indexes {
+			clone.indexes[key] = nil
+		}
+		clone.rebuildIndexesLocked()
+	}
+	if e.seeded {
+		// seeded runs stay ordered, but the clone doesn't replay the source's draws
+		clone.seed = time.Now().UnixNano()
+		clone.rand = rand.New(rand.NewSource(clone.seed))
+		clone.seeded = true
+	}
+	return clone
+}
+
+// cloneMolecule returns a copy of m that shares no mutable state with it.
+func cloneMolecule(m Molecule) Molecule {
+	if m.Payload != nil {
+		m.Payload = cloneValue(m.Payload).(map[string]any)
+	}
+	m.Tags = slices.Clone(m.Tags)
+	if m.Position != nil {
+		p := *m.Position
+		m.Position = &p
+	}
+	return m
+}
+
+// cloneValue deep-copies the maps and slices of a JSON-like value.
+func cloneValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = cloneValue(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = cloneValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
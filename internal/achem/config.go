@@ -1,20 +1,130 @@
 package achem
 
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+)
+
 // SpeciesConfig represents a species configuration used in JSON schemas.
 type SpeciesConfig struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description,omitempty"`
 	Meta        map[string]any `json:"meta,omitempty"`
+
+	// MaxCount caps the number of molecules of this species (0 = unlimited).
+	// Excess molecules are evicted at the end of each step in EvictOrder
+	// ("oldest" or "lowest_energy", default "oldest").
+	MaxCount       int      `json:"max_count,omitempty"`
+	EvictOrder     string   `json:"evict_order,omitempty"`
+	EvictNotifiers []string `json:"evict_notifiers,omitempty"`
+
+	// ExpireNotifiers receive an event when molecules of this species expire (TTL)
+	ExpireNotifiers []string `json:"expire_notifiers,omitempty"`
+
+	// DefaultEnergy and DefaultStability are given to new molecules of this species
+	// that don't set their own (inserted with the generic default of 1.0, or created
+	// by an effect without energy/stability).
+	DefaultEnergy    *float64 `json:"default_energy,omitempty"`
+	DefaultStability *float64 `json:"default_stability,omitempty"`
+
+	// Fields declares the type of payload fields, and which ones are required.
+	// Inserted and created molecules whose payload doesn't conform are rejected.
+	Fields map[string]FieldSpec `json:"fields,omitempty"`
 }
 
-// EqCondition represents an equality condition for filtering molecules.
-// Where is a map of field -> { eq: value }
+// EqCondition represents a condition on a payload field for filtering molecules.
+// Where is a map of field -> { eq: value }, { ne: value }, { in: [...] }, { nin: [...] },
+// { regex: "pattern" } or a range such as { gte: 1, lt: 10 }. When several operators are set, all of them
+// must hold. A condition with only Eq (or no operator at all) is an equality condition,
+// which can use the per-tick index.
 type EqCondition struct {
-	Eq any `json:"eq"`
+	Eq  any   `json:"eq,omitempty"`
+	Ne  any   `json:"ne,omitempty"`  // field value must differ
+	In  []any `json:"in,omitempty"`  // field value must equal one of the values
+	Nin []any `json:"nin,omitempty"` // field value must equal none of the values
+	Gt  any   `json:"gt,omitempty"`  // field value must be greater
+	Gte any   `json:"gte,omitempty"` // field value must be greater or equal
+	Lt  any   `json:"lt,omitempty"`  // field value must be lower
+	Lte any   `json:"lte,omitempty"` // field value must be lower or equal
+
+	// Regex is a regular expression (RE2 syntax) the field value, formatted as a
+	// string, must match. It is unanchored: use ^ and $ to match the whole value.
+	Regex string         `json:"regex,omitempty"`
+	regex *regexp.Regexp // compiled Regex, set by BuildSchemaFromConfig
+
+	// Clauses holds the nested where clauses of a "$or" or "$and" group key,
+	// encoded in JSON as a list instead of an operator object.
+	Clauses []WhereConfig `json:"-"`
+
+	unknownOps []string // operators found in JSON that aren't supported, see ValidateSchemaConfig
+}
+
+// Reserved where keys grouping nested where clauses: "$or" matches when any clause
+// matches, "$and" when all of them do. They can be nested and combined with fields.
+const (
+	WhereKeyOr  = "$or"
+	WhereKeyAnd = "$and"
+)
+
+// isWhereGroupKey reports whether key is a reserved group key rather than a field name.
+func isWhereGroupKey(key string) bool {
+	return key == WhereKeyOr || key == WhereKeyAnd
+}
+
+// whereOperators lists the operators accepted in EqCondition JSON
+var whereOperators = map[string]bool{
+	"eq": true, "ne": true, "in": true, "nin": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+	"regex": true,
+}
+
+// UnmarshalJSON decodes a condition, remembering unsupported operators so that
+// ValidateSchemaConfig can report them instead of silently ignoring them.
+func (c *EqCondition) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		*c = EqCondition{}
+		return json.Unmarshal(trimmed, &c.Clauses)
+	}
+
+	type plain EqCondition
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = EqCondition(p)
+	for op := range raw {
+		if !whereOperators[op] {
+			c.unknownOps = append(c.unknownOps, op)
+		}
+	}
+	sort.Strings(c.unknownOps)
+	return nil
+}
+
+// MarshalJSON encodes group clauses as a list and other conditions as an operator object.
+func (c EqCondition) MarshalJSON() ([]byte, error) {
+	if c.Clauses != nil {
+		return json.Marshal(c.Clauses)
+	}
+	type plain EqCondition
+	return json.Marshal(plain(c))
+}
+
+// isEq reports whether the condition is a plain equality condition.
+func (c EqCondition) isEq() bool {
+	return c.Clauses == nil && c.Regex == "" && c.Ne == nil && c.In == nil && c.Nin == nil &&
+		c.Gt == nil && c.Gte == nil && c.Lt == nil && c.Lte == nil
 }
 
 // WhereConfig defines filtering conditions for molecules.
-// Currently only equality conditions are supported.
+// Each field must satisfy its condition (see EqCondition), and each "$or"/"$and"
+// group its nested clauses (see WhereKeyOr and WhereKeyAnd).
 type WhereConfig map[string]EqCondition
 
 // ComparisonOp represents a comparison operator for field conditions.
@@ -36,11 +146,28 @@ type FieldCondition struct {
 	Value any    `json:"value"` // comparison value
 }
 
-// CountMoleculesConfig represents a count aggregation operation
+// Aggregations supported by CountMoleculesConfig.Agg
+const (
+	AggCount = "count" // number of matching molecules (default)
+	AggSum   = "sum"   // sum of Field over the matching molecules
+	AggAvg   = "avg"   // average of Field
+	AggMin   = "min"   // minimum of Field
+	AggMax   = "max"   // maximum of Field
+)
+
+// CountMoleculesConfig represents an aggregation over the molecules of a species. By
+// default it counts them; with another Agg it aggregates the numeric values of Field,
+// ignoring molecules where the field is missing or not numeric.
 type CountMoleculesConfig struct {
 	Species string         `json:"species"` // species to count
 	Where   WhereConfig    `json:"where,omitempty"`
-	Op      map[string]any `json:"op"` // operator and value, e.g., {"gte": 3}
+	Op      map[string]any `json:"op"`              // operator and value, e.g., {"gte": 3}
+	Agg     string         `json:"agg,omitempty"`   // AggCount (default), AggSum, AggAvg, AggMin or AggMax
+	Field   string         `json:"field,omitempty"` // field to aggregate (e.g. "energy" or a payload field); not used by AggCount
+
+	// WithinTicks restricts the aggregation to molecules created in the last WithinTicks
+	// ticks, i.e. with CreatedAt >= now - WithinTicks (0 = all molecules)
+	WithinTicks int64 `json:"within_ticks,omitempty"`
 }
 
 // IfConditionConfig represents a conditional check
@@ -58,21 +185,62 @@ type IfConditionConfig struct {
 type PartnerConfig struct {
 	Species string      `json:"species"` // species of the partner
 	Where   WhereConfig `json:"where,omitempty"`
-	Count   int         `json:"count"` // number of partners required (default: 1)
+	Count   int         `json:"count"`            // number of partners required (default: 1)
+	Select  string      `json:"select,omitempty"` // which partners to pick when more match, e.g. SelectOldest (default: the first found)
+
+	// WithinDistance only considers partners at most this far from the input molecule
+	// (0 = anywhere). See Molecule.Position.
+	WithinDistance      float64 `json:"within_distance,omitempty"`
+	IncludeUnpositioned bool    `json:"include_unpositioned,omitempty"` // molecules without a position pass the distance filter
 }
 
+// Catalyst modes: how a catalyst's RateBoost is applied to the reaction rate
+const (
+	CatalystModeAdd      = "add"      // rate += rate_boost when any catalyst matches
+	CatalystModeMultiply = "multiply" // rate *= 1 + rate_boost * matching catalysts
+)
+
 // CatalystConfig represents a catalyst molecule that increases reaction rate
 type CatalystConfig struct {
 	Species   string      `json:"species"`              // species of the catalyst
 	Where     WhereConfig `json:"where,omitempty"`      // conditions for catalyst matching
 	RateBoost float64     `json:"rate_boost,omitempty"` // amount to add to rate (default: 0.1)
 	MaxRate   *float64    `json:"max_rate,omitempty"`   // maximum effective rate (default: 1.0)
+	Mode      string      `json:"mode,omitempty"`       // CatalystModeAdd (default) or CatalystModeMultiply
+
+	// WithinDistance only considers catalysts at most this far from the input molecule
+	// (0 = anywhere). See Molecule.Position.
+	WithinDistance      float64 `json:"within_distance,omitempty"`
+	IncludeUnpositioned bool    `json:"include_unpositioned,omitempty"` // molecules without a position pass the distance filter
+}
+
+// InhibitorConfig represents a molecule that decreases reaction rate while present
+type InhibitorConfig struct {
+	Species       string      `json:"species"`                  // species of the inhibitor
+	Where         WhereConfig `json:"where,omitempty"`          // conditions for inhibitor matching
+	RateReduction float64     `json:"rate_reduction,omitempty"` // amount to subtract from rate (default: 0.1)
 }
 
 type InputConfig struct {
-	Species  string          `json:"species"`
-	Where    WhereConfig     `json:"where,omitempty"`
-	Partners []PartnerConfig `json:"partners,omitempty"` // partner molecules required for the reaction
+	Species string `json:"species"`
+	// AnySpecies, instead of Species, matches molecules of any of the listed species,
+	// for reactions that treat several species uniformly
+	AnySpecies []string        `json:"any_species,omitempty"`
+	Where      WhereConfig     `json:"where,omitempty"`
+	Partners   []PartnerConfig `json:"partners,omitempty"` // partner molecules required for the reaction
+
+	// Count makes the reaction take this many input molecules at once (default: 1), e.g.
+	// "3 A -> 1 B". The molecule being evaluated is grouped with other molecules matching
+	// the input; without enough of them the reaction doesn't fire.
+	Count int `json:"count,omitempty"`
+}
+
+// inputSpecies returns the species matched by the input: Species, or AnySpecies
+func (c InputConfig) inputSpecies() []string {
+	if c.Species != "" {
+		return []string{c.Species}
+	}
+	return c.AnySpecies
 }
 
 type CreateEffectConfig struct {
@@ -80,16 +248,69 @@ type CreateEffectConfig struct {
 	Payload   map[string]any `json:"payload,omitempty"`
 	Energy    *float64       `json:"energy,omitempty"`
 	Stability *float64       `json:"stability,omitempty"`
+	TTL       int64          `json:"ttl,omitempty"` // ticks until the created molecule expires (0 = never)
+
+	// Cross-environment routing: when set, the created molecule is inserted into
+	// the target environment(s) instead of the current one.
+	EmitTo     string   `json:"emit_to,omitempty"`      // single target environment ID
+	EmitToMany []string `json:"emit_to_many,omitempty"` // fan-out to several environment IDs
 }
 
+// UpdateEffectConfig modifies the input molecule in place. PayloadSet values may
+// reference the input molecule (e.g. "$m.ip"); PayloadIncrement adds to numeric fields,
+// treating a missing field as 0 and leaving non-numeric ones untouched.
 type UpdateEffectConfig struct {
-	EnergyAdd *float64 `json:"energy_add,omitempty"`
+	EnergyAdd        *float64           `json:"energy_add,omitempty"`
+	PayloadSet       map[string]any     `json:"payload_set,omitempty"`
+	PayloadIncrement map[string]float64 `json:"payload_increment,omitempty"`
+}
+
+// RequeueEffectConfig makes the input molecule re-enter the reaction loop within the
+// same tick, with the changes of the previous pass applied, for multi-pass
+// transformations. MaxIterations bounds the number of extra passes.
+type RequeueEffectConfig struct {
+	MaxIterations int `json:"max_iterations"` // 1..MaxRequeueIterations
+}
+
+// PromoteEffectConfig moves the input molecule to the next species of an ordered
+// ladder (e.g. Event -> Suspicion -> Alert -> Incident), keeping its ID and
+// timestamps and resetting its energy. A molecule at the top of the ladder is left as is.
+type PromoteEffectConfig struct {
+	Ladder []string `json:"ladder"`          // species, lowest first
+	Carry  []string `json:"carry,omitempty"` // payload fields carried forward (default: all)
+}
+
+// TransformEffectConfig renames the species of the input molecule in place, keeping its
+// ID, payload, energy and timestamps. Payload fields are merged over the existing ones
+// and may reference the input molecule (e.g. "$m.ip").
+type TransformEffectConfig struct {
+	Species string         `json:"species"`
+	Payload map[string]any `json:"payload,omitempty"`
+}
+
+// WeightedBranch is one of the alternatives of a choose effect
+type WeightedBranch struct {
+	Weight  float64        `json:"weight"`  // relative weight, must be positive
+	Effects []EffectConfig `json:"effects"` // effects applied when the branch is picked
+}
+
+// ChooseEffectConfig applies the effects of exactly one branch, picked at random with a
+// probability proportional to its weight (weights don't need to sum to 1).
+type ChooseEffectConfig struct {
+	Branches []WeightedBranch `json:"branches"`
 }
 
 type EffectConfig struct {
-	Consume bool                `json:"consume,omitempty"`
-	Create  *CreateEffectConfig `json:"create,omitempty"`
-	Update  *UpdateEffectConfig `json:"update,omitempty"`
+	Consume         bool                   `json:"consume,omitempty"`
+	ConsumePartners bool                   `json:"consume_partners,omitempty"` // also consume the matched partners
+	Create          *CreateEffectConfig    `json:"create,omitempty"`
+	Update          *UpdateEffectConfig    `json:"update,omitempty"`
+	Promote         *PromoteEffectConfig   `json:"promote,omitempty"`
+	Transform       *TransformEffectConfig `json:"transform,omitempty"`
+	Requeue         *RequeueEffectConfig   `json:"requeue,omitempty"`
+
+	// Probabilistic effects
+	Choose *ChooseEffectConfig `json:"choose,omitempty"`
 
 	// Conditional effects
 	If   *IfConditionConfig `json:"if,omitempty"`   // condition to check
@@ -98,17 +319,39 @@ type EffectConfig struct {
 }
 
 type ReactionConfig struct {
-	ID        string              `json:"id"`
-	Name      string              `json:"name"`
-	Input     InputConfig         `json:"input"`
-	Rate      float64             `json:"rate"`
-	Catalysts []CatalystConfig    `json:"catalysts,omitempty"` // catalysts that increase reaction rate
-	Effects   []EffectConfig      `json:"effects"`
-	Notify    *NotificationConfig `json:"notify,omitempty"` // notification configuration
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	Input      InputConfig         `json:"input"`
+	Rate       float64             `json:"rate"`
+	Catalysts  []CatalystConfig    `json:"catalysts,omitempty"`  // catalysts that increase reaction rate
+	Inhibitors []InhibitorConfig   `json:"inhibitors,omitempty"` // inhibitors that decrease reaction rate
+	Effects    []EffectConfig      `json:"effects"`
+	Notify     *NotificationConfig `json:"notify,omitempty"` // notification configuration
+
+	// MaxFiresPerTick caps how many times the reaction can fire in a single tick.
+	// Once reached, the reaction is skipped for the remaining molecules. 0 means unlimited.
+	MaxFiresPerTick int `json:"max_fires_per_tick,omitempty"`
+
+	// CooldownTicks makes a molecule ineligible for the reaction for this many ticks
+	// after it fired it as input, modeling a refractory period. 0 means no cooldown.
+	CooldownTicks int `json:"cooldown_ticks,omitempty"`
+
+	// Priority controls the order in which reactions see each molecule: higher
+	// priorities go first, so they win contested molecules. Ties keep declaration order.
+	Priority int `json:"priority,omitempty"`
 }
 
 type SchemaConfig struct {
 	Name      string           `json:"name"`
 	Species   []SpeciesConfig  `json:"species"`
 	Reactions []ReactionConfig `json:"reactions"`
+
+	// FloatTolerance is the absolute tolerance used when comparing non-integral
+	// numbers for equality in where/if conditions (default: DefaultFloatTolerance).
+	// Integral values are always compared exactly. Set to 0 for exact float equality.
+	FloatTolerance *float64 `json:"float_tolerance,omitempty"`
+
+	// DecayRate enables stability-based decay: each tick, a molecule is removed with
+	// probability decay_rate * (1 - stability). Must be between 0 and 1 (default: 0, disabled).
+	DecayRate float64 `json:"decay_rate,omitempty"`
 }
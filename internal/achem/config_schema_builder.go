@@ -2,12 +2,19 @@ package achem
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"math/rand"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 // ConfigReaction will be used to build a Reaction from a ReactionConfig
 type ConfigReaction struct {
-	cfg ReactionConfig
+	cfg       ReactionConfig
+	tolerance float64                 // numeric equality tolerance, see numericEqual
+	species   map[SpeciesName]Species // species of the schema, for their defaults
 }
 
 func (r *ConfigReaction) ID() string   { return r.cfg.ID }
@@ -19,28 +26,35 @@ func (r *ConfigReaction) Rate() float64 {
 	return r.cfg.Rate
 }
 
-// EffectiveRate calculates the effective rate considering catalysts
+// EffectiveRate calculates the effective rate considering catalysts and inhibitors
 func (r *ConfigReaction) EffectiveRate(m Molecule, env EnvView) float64 {
 	baseRate := r.Rate()
 
-	// If no catalysts, return base rate
-	if len(r.cfg.Catalysts) == 0 {
+	// If no catalysts or inhibitors, return base rate
+	if len(r.cfg.Catalysts) == 0 && len(r.cfg.Inhibitors) == 0 {
 		return baseRate
 	}
 
 	effectiveRate := baseRate
+	multiplier := 1.0
 	maxRate := 1.0
 
 	// Check each catalyst
 	for _, catalystCfg := range r.cfg.Catalysts {
-		catalysts := findCatalysts(catalystCfg, m, env)
+		catalysts := findCatalysts(catalystCfg, m, env, r.tolerance)
 		if len(catalysts) > 0 {
 			// Catalyst found, boost the rate
 			rateBoost := catalystCfg.RateBoost
 			if rateBoost <= 0 {
 				rateBoost = 0.1 // default boost
 			}
-			effectiveRate += rateBoost
+			if catalystCfg.Mode == CatalystModeMultiply {
+				// multiplicative boosts apply after all additive ones, so the
+				// result doesn't depend on the order of the catalysts
+				multiplier *= 1 + rateBoost*float64(len(catalysts))
+			} else {
+				effectiveRate += rateBoost
+			}
 
 			// Update max rate if this catalyst specifies one
 			if catalystCfg.MaxRate != nil && *catalystCfg.MaxRate < maxRate {
@@ -49,6 +63,19 @@ func (r *ConfigReaction) EffectiveRate(m Molecule, env EnvView) float64 {
 		}
 	}
 
+	effectiveRate *= multiplier
+
+	// Inhibitors apply after all catalyst boosts
+	for _, inhibitorCfg := range r.cfg.Inhibitors {
+		if len(filterBySpeciesAndWhere(env, SpeciesName(inhibitorCfg.Species), inhibitorCfg.Where, m, r.tolerance)) > 0 {
+			rateReduction := inhibitorCfg.RateReduction
+			if rateReduction <= 0 {
+				rateReduction = 0.1 // default reduction
+			}
+			effectiveRate -= rateReduction
+		}
+	}
+
 	// Ensure rate is between 0 and 1 and maxRate is respected
 	if effectiveRate > maxRate {
 		effectiveRate = maxRate
@@ -66,18 +93,22 @@ func (r *ConfigReaction) EffectiveRate(m Molecule, env EnvView) float64 {
 }
 
 // findCatalysts finds catalyst molecules matching the catalyst config
-func findCatalysts(catalystCfg CatalystConfig, m Molecule, env EnvView) []Molecule {
+func findCatalysts(catalystCfg CatalystConfig, m Molecule, env EnvView, tol float64) []Molecule {
 	// Get all molecules of the specified species that match where conditions
 	// Catalysts can be the same molecule or different molecules
 	// (unlike partners, catalysts don't exclude the molecule itself)
-	matches := filterBySpeciesAndWhere(env, SpeciesName(catalystCfg.Species), catalystCfg.Where, m)
-	return matches
+	matches := filterBySpeciesAndWhere(env, SpeciesName(catalystCfg.Species), catalystCfg.Where, m, tol)
+	return withinDistance(matches, m, catalystCfg.WithinDistance, catalystCfg.IncludeUnpositioned)
 }
 
 // match species + where.eq on payload
 // Note: Partner matching is done in Apply, not here, for performance reasons
 func (r *ConfigReaction) InputPattern(m Molecule) bool {
-	if string(m.Species) != r.cfg.Input.Species {
+	if r.cfg.Input.Species != "" {
+		if string(m.Species) != r.cfg.Input.Species {
+			return false
+		}
+	} else if !slices.Contains(r.cfg.Input.AnySpecies, string(m.Species)) {
 		return false
 	}
 
@@ -86,7 +117,7 @@ func (r *ConfigReaction) InputPattern(m Molecule) bool {
 	}
 
 	// Use matchWhere with m as both candidate and origin to support $m.* references
-	return matchWhere(r.cfg.Input.Where, m, m)
+	return matchWhere(r.cfg.Input.Where, m, m, r.tolerance)
 }
 
 // resolveValueFromMolecule is a wrapper around resolveValueRef for backward compatibility
@@ -127,8 +158,9 @@ func getFieldValue(field string, m Molecule) (any, bool) {
 	}
 }
 
-// compareValues compares two values using the specified operator
-func compareValues(left, right any, op string) bool {
+// compareValues compares two values using the specified operator.
+// Numeric equality (eq/ne) uses the given tolerance, see numericEqual.
+func compareValues(left, right any, op string, tol float64) bool {
 	// Handle nil cases
 	if left == nil && right == nil {
 		return op == "eq"
@@ -137,16 +169,22 @@ func compareValues(left, right any, op string) bool {
 		return op == "ne"
 	}
 
-	// Try numeric comparison first
+	// Try numeric comparison first. A numeric string compared with a number is
+	// coerced, so that e.g. "10" > 9 holds (a string comparison would say otherwise).
 	leftFloat, leftIsFloat := toFloat64(left)
 	rightFloat, rightIsFloat := toFloat64(right)
+	if leftIsFloat && !rightIsFloat {
+		rightFloat, rightIsFloat = parseNumericString(right)
+	} else if rightIsFloat && !leftIsFloat {
+		leftFloat, leftIsFloat = parseNumericString(left)
+	}
 
 	if leftIsFloat && rightIsFloat {
 		switch op {
 		case "eq":
-			return leftFloat == rightFloat
+			return numericEqual(leftFloat, rightFloat, tol)
 		case "ne":
-			return leftFloat != rightFloat
+			return !numericEqual(leftFloat, rightFloat, tol)
 		case "gt":
 			return leftFloat > rightFloat
 		case "gte":
@@ -204,15 +242,28 @@ func toFloat64(v any) (float64, bool) {
 	}
 }
 
+// parseNumericString parses v as a float64 if it is a string holding a finite number.
+func parseNumericString(v any) (float64, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
 // evaluateIfCondition evaluates an IfConditionConfig and returns true if condition is met
-func evaluateIfCondition(cond *IfConditionConfig, m Molecule, env EnvView) bool {
+func evaluateIfCondition(cond *IfConditionConfig, m Molecule, env EnvView, tol float64) bool {
 	if cond == nil {
 		return false
 	}
 
 	// Check if it's a count_molecules condition
 	if cond.CountMolecules != nil {
-		return evaluateCountMolecules(cond.CountMolecules, m, env)
+		return evaluateCountMolecules(cond.CountMolecules, m, env, tol)
 	}
 
 	// Otherwise, it's a field condition
@@ -225,63 +276,213 @@ func evaluateIfCondition(cond *IfConditionConfig, m Molecule, env EnvView) bool
 		return false
 	}
 
-	// Resolve the comparison value (might be a reference like "$m.ip")
-	compareValue := resolveValueRef(cond.Value, m)
+	// Resolve the comparison value (might be a reference to another field of the same
+	// molecule, like "$m.threshold"). A reference to a missing field never matches.
+	compareValue, ok := lookupValueRef(cond.Value, m)
+	if !ok {
+		return false
+	}
 
-	return compareValues(fieldValue, compareValue, cond.Op)
+	return compareValues(fieldValue, compareValue, cond.Op, tol)
 }
 
 // evaluateCountMolecules evaluates a count_molecules aggregation
-func evaluateCountMolecules(cfg *CountMoleculesConfig, m Molecule, env EnvView) bool {
+func evaluateCountMolecules(cfg *CountMoleculesConfig, m Molecule, env EnvView, tol float64) bool {
 	// Get all molecules of the specified species that match where conditions
-	candidates := filterBySpeciesAndWhere(env, SpeciesName(cfg.Species), cfg.Where, m)
+	candidates := filterBySpeciesAndWhere(env, SpeciesName(cfg.Species), cfg.Where, m, tol)
 
-	// Filter out the molecule itself
+	// Filter out the molecule itself, and molecules created before the time window
 	var matches []Molecule
 	for _, candidate := range candidates {
-		if candidate.ID != m.ID {
-			matches = append(matches, candidate)
+		if candidate.ID == m.ID {
+			continue
 		}
+		if cfg.WithinTicks > 0 && candidate.CreatedAt < env.EnvTime()-cfg.WithinTicks {
+			continue
+		}
+		matches = append(matches, candidate)
 	}
 
-	count := len(matches)
+	value, ok := aggregateMolecules(cfg.Agg, cfg.Field, matches)
+	if !ok {
+		// avg, min and max have no value without numeric fields to aggregate
+		return false
+	}
 
 	// Evaluate the operator
 	for op, opValue := range cfg.Op {
 		opValueFloat, ok := toFloat64(opValue)
 		if !ok {
-			opValueFloat = float64(count) // fallback
+			opValueFloat = value // fallback
 		}
-		return compareValues(float64(count), opValueFloat, op)
+		return compareValues(value, opValueFloat, op, tol)
 	}
 
 	return false
 }
 
-// findPartners finds partner molecules matching the partner config
-func findPartners(partnerCfg PartnerConfig, m Molecule, env EnvView) []Molecule {
+// aggregateMolecules applies the aggregation agg to field over molecules. Values are
+// coerced with toFloat64, and molecules without a numeric value are skipped. Returns
+// false if agg needs values and there are none.
+func aggregateMolecules(agg, field string, molecules []Molecule) (float64, bool) {
+	if agg == "" || agg == AggCount {
+		return float64(len(molecules)), true
+	}
+
+	var sum, lowest, highest float64
+	n := 0
+	for _, mol := range molecules {
+		raw, ok := getFieldValue(field, mol)
+		if !ok {
+			continue
+		}
+		v, ok := toFloat64(raw)
+		if !ok {
+			continue
+		}
+		if n == 0 || v < lowest {
+			lowest = v
+		}
+		if n == 0 || v > highest {
+			highest = v
+		}
+		sum += v
+		n++
+	}
+
+	switch agg {
+	case AggSum:
+		return sum, true
+	case AggAvg:
+		if n == 0 {
+			return 0, false
+		}
+		return sum / float64(n), true
+	case AggMin:
+		return lowest, n > 0
+	case AggMax:
+		return highest, n > 0
+	}
+	return 0, false
+}
+
+// findPartners finds partner molecules matching the partner config, skipping the
+// molecules in exclude
+func findPartners(partnerCfg PartnerConfig, m Molecule, env EnvView, tol float64, exclude map[MoleculeID]struct{}) []Molecule {
+	count := partnerCfg.Count
+	if count <= 0 {
+		count = 1 // default
+	}
+	if partnerCfg.Select != "" && count == 1 && len(partnerCfg.Where) == 0 && partnerCfg.WithinDistance <= 0 {
+		return bestPartner(SpeciesName(partnerCfg.Species), partnerCfg.Select, m, env, exclude)
+	}
+
 	// Get all molecules of the specified species that match where conditions
-	candidates := filterBySpeciesAndWhere(env, SpeciesName(partnerCfg.Species), partnerCfg.Where, m)
+	candidates := filterBySpeciesAndWhere(env, SpeciesName(partnerCfg.Species), partnerCfg.Where, m, tol)
+	candidates = withinDistance(candidates, m, partnerCfg.WithinDistance, partnerCfg.IncludeUnpositioned)
 
 	// Filter out the molecule itself
 	var matches []Molecule
 	for _, candidate := range candidates {
-		if candidate.ID != m.ID {
-			matches = append(matches, candidate)
+		if candidate.ID == m.ID {
+			continue
+		}
+		if _, excluded := exclude[candidate.ID]; excluded {
+			continue
 		}
+		matches = append(matches, candidate)
 	}
 
 	// Return up to the required count
-	count := partnerCfg.Count
-	if count <= 0 {
-		count = 1 // default
-	}
 	if len(matches) > count {
+		if partnerCfg.Select != "" {
+			return selectPartners(matches, partnerCfg.Select, m, count)
+		}
 		return matches[:count]
 	}
 	return matches
 }
 
+// matchInputGroup returns the other input molecules taken with m when the reaction has
+// an input count above 1, and false if there are not enough of them. They are the first
+// molecules matching the input pattern, skipping the molecules already consumed in this
+// tick, so that a molecule is never part of two groups that consume it.
+func (r *ConfigReaction) matchInputGroup(m Molecule, env EnvView, ctx ReactionContext) ([]Molecule, bool) {
+	need := r.cfg.Input.Count - 1
+	if need <= 0 {
+		return nil, true
+	}
+	group := make([]Molecule, 0, need)
+	for _, species := range r.cfg.Input.inputSpecies() {
+		for _, candidate := range env.MoleculesBySpecies(SpeciesName(species)) {
+			if candidate.ID == m.ID || !r.InputPattern(candidate) {
+				continue
+			}
+			if _, consumed := ctx.consumed[candidate.ID]; consumed {
+				continue
+			}
+			group = append(group, candidate)
+			if len(group) == need {
+				return group, true
+			}
+		}
+	}
+	return group, false
+}
+
+// matchPartners returns the partners of m for the reaction, and false if some partner
+// requirement is not met. When the reaction consumes its partners, molecules already
+// consumed in this tick are not eligible, so that a partner is never used up twice.
+// Molecules of the input group are never partners.
+func (r *ConfigReaction) matchPartners(m Molecule, group []Molecule, env EnvView, ctx ReactionContext) ([]Molecule, bool) {
+	var exclude map[MoleculeID]struct{}
+	if effectsConsumePartners(r.cfg.Effects) {
+		exclude = ctx.consumed
+	}
+	if len(group) > 0 {
+		exclude = maps.Clone(exclude)
+		if exclude == nil {
+			exclude = make(map[MoleculeID]struct{}, len(group))
+		}
+		for _, g := range group {
+			exclude[g.ID] = struct{}{}
+		}
+	}
+
+	partners := make([]Molecule, 0)
+	for _, partnerCfg := range r.cfg.Input.Partners {
+		requiredCount := partnerCfg.Count
+		if requiredCount <= 0 {
+			requiredCount = 1 // default to 1 if not specified
+		}
+
+		foundPartners := findPartners(partnerCfg, m, env, r.tolerance, exclude)
+		if len(foundPartners) < requiredCount {
+			return partners, false
+		}
+		partners = append(partners, foundPartners...)
+	}
+	return partners, true
+}
+
+// effectsConsumePartners reports whether any effect, including then/else branches,
+// consumes the partners
+func effectsConsumePartners(effects []EffectConfig) bool {
+	for _, eff := range effects {
+		if eff.ConsumePartners || effectsConsumePartners(eff.Then) || effectsConsumePartners(eff.Else) {
+			return true
+		}
+		if eff.Choose != nil {
+			for _, branch := range eff.Choose.Branches {
+				if effectsConsumePartners(branch.Effects) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // Apply will apply the effects of the reaction to the molecule
 func (r *ConfigReaction) Apply(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
 	effect := ReactionEffect{
@@ -290,51 +491,53 @@ func (r *ConfigReaction) Apply(m Molecule, env EnvView, ctx ReactionContext) Rea
 		NewMolecules: []Molecule{},
 	}
 
-	// Check for partners if required
-	partners := make([]Molecule, 0)
-	if len(r.cfg.Input.Partners) > 0 {
-		for _, partnerCfg := range r.cfg.Input.Partners {
-			requiredCount := partnerCfg.Count
-			if requiredCount <= 0 {
-				requiredCount = 1 // default to 1 if not specified
-			}
-
-			foundPartners := findPartners(partnerCfg, m, env)
-			if len(foundPartners) < requiredCount {
-				// Not enough partners found, return empty effect
-				return effect
-			}
-			partners = append(partners, foundPartners...)
-		}
+	// Group the input with the other input molecules, and find the partners; without
+	// enough of either the effect is empty
+	group, ok := r.matchInputGroup(m, env, ctx)
+	if !ok {
+		return effect
+	}
+	partners, ok := r.matchPartners(m, group, env, ctx)
+	if !ok {
+		return effect
 	}
 
 	// Apply effects
-	r.applyEffects(r.cfg.Effects, m, partners, env, ctx, &effect)
+	r.applyEffects(r.cfg.Effects, m, group, partners, env, ctx, &effect)
 
 	return effect
 }
 
-// applyEffects recursively applies effects, handling conditional logic
-func (r *ConfigReaction) applyEffects(effects []EffectConfig, m Molecule, partners []Molecule, env EnvView, ctx ReactionContext, effect *ReactionEffect) {
+// applyEffects recursively applies effects, handling conditional logic. Consume removes m
+// along with the rest of its input group; the other effects only apply to m.
+func (r *ConfigReaction) applyEffects(effects []EffectConfig, m Molecule, group []Molecule, partners []Molecule, env EnvView, ctx ReactionContext, effect *ReactionEffect) {
 	for _, eff := range effects {
 		// Handle conditional effects
 		if eff.If != nil {
-			conditionMet := evaluateIfCondition(eff.If, m, env)
+			conditionMet := evaluateIfCondition(eff.If, m, env, r.tolerance)
 			if conditionMet {
 				// Apply "then" effects
 				if len(eff.Then) > 0 {
-					r.applyEffects(eff.Then, m, partners, env, ctx, effect)
+					r.applyEffects(eff.Then, m, group, partners, env, ctx, effect)
 				}
 			} else {
 				// Apply "else" effects
 				if len(eff.Else) > 0 {
-					r.applyEffects(eff.Else, m, partners, env, ctx, effect)
+					r.applyEffects(eff.Else, m, group, partners, env, ctx, effect)
 				}
 			}
 			// Skip other effects in this config if it's conditional
 			continue
 		}
 
+		// Handle probabilistic effects: apply one weighted branch, and nothing else
+		if eff.Choose != nil {
+			if branch := chooseBranch(eff.Choose.Branches, ctx.Random); branch != nil {
+				r.applyEffects(branch.Effects, m, group, partners, env, ctx, effect)
+			}
+			continue
+		}
+
 		// Apply consume effect
 		if eff.Consume {
 			// Add the molecule ID to ConsumedIDs if not already present
@@ -342,33 +545,63 @@ func (r *ConfigReaction) applyEffects(effects []EffectConfig, m Molecule, partne
 			if !found {
 				effect.ConsumedIDs = append(effect.ConsumedIDs, m.ID)
 			}
-		}
-
-		// Apply update effect
-		if eff.Update != nil {
-			// Find existing change for this molecule, or create a new one
-			var change *MoleculeChange
-			for i := range effect.Changes {
-				if effect.Changes[i].ID == m.ID {
-					change = &effect.Changes[i]
-					break
+			for _, g := range group {
+				if !slices.Contains(effect.ConsumedIDs, g.ID) {
+					effect.ConsumedIDs = append(effect.ConsumedIDs, g.ID)
 				}
 			}
+		}
 
-			if change == nil {
-				// Create a copy of the molecule for the change
-				copy := m
-				effect.Changes = append(effect.Changes, MoleculeChange{
-					ID:      m.ID,
-					Updated: &copy,
-				})
-				change = &effect.Changes[len(effect.Changes)-1]
+		// Apply consume partners effect
+		if eff.ConsumePartners {
+			for _, p := range partners {
+				if !slices.Contains(effect.ConsumedIDs, p.ID) {
+					effect.ConsumedIDs = append(effect.ConsumedIDs, p.ID)
+				}
 			}
+		}
 
+		// Apply update effect
+		if eff.Update != nil {
+			change := changeFor(effect, m)
 			if eff.Update.EnergyAdd != nil && change.Updated != nil {
 				change.Updated.Energy += *eff.Update.EnergyAdd
 				change.Updated.LastTouchedAt = ctx.EnvTime
 			}
+			if len(eff.Update.PayloadSet) > 0 || len(eff.Update.PayloadIncrement) > 0 {
+				updatePayload(change.Updated, eff.Update, m, partners)
+				change.Updated.LastTouchedAt = ctx.EnvTime
+			}
+		}
+
+		// Apply promote effect
+		if eff.Promote != nil {
+			current := m.Species
+			if change := findChange(effect, m.ID); change != nil && change.Updated != nil {
+				current = change.Updated.Species
+			}
+			if next, ok := nextLadderSpecies(eff.Promote.Ladder, current); ok {
+				change := changeFor(effect, m)
+				changeSpecies(change.Updated, next, eff.Promote.Carry, ctx.EnvTime)
+				// the molecule keeps its energy, unless the new species declares a default
+				if sp, ok := r.species[next]; ok && sp.DefaultEnergy != nil {
+					change.Updated.Energy = *sp.DefaultEnergy
+				}
+			}
+		}
+
+		// Apply transform effect
+		if eff.Transform != nil {
+			change := changeFor(effect, m)
+			changeSpecies(change.Updated, SpeciesName(eff.Transform.Species), nil, ctx.EnvTime)
+			for k, v := range eff.Transform.Payload {
+				change.Updated.Payload[k] = resolveValueRef(v, m, partners...)
+			}
+		}
+
+		// Apply requeue effect
+		if eff.Requeue != nil {
+			effect.Requeue = max(effect.Requeue, eff.Requeue.MaxIterations)
 		}
 
 		// Apply create effect
@@ -378,10 +611,14 @@ func (r *ConfigReaction) applyEffects(effects []EffectConfig, m Molecule, partne
 				map[string]any{},
 				ctx.EnvTime,
 			)
+			nm.ID = "" // named by the environment's ID generator
+			if sp, ok := r.species[nm.Species]; ok {
+				sp.applyDefaults(&nm)
+			}
 
 			// copy payload to the new molecule, resolving references
 			for k, v := range eff.Create.Payload {
-				nm.Payload[k] = resolveValueRef(v, m)
+				nm.Payload[k] = resolveValueRef(v, m, partners...)
 			}
 
 			if eff.Create.Energy != nil {
@@ -392,11 +629,154 @@ func (r *ConfigReaction) applyEffects(effects []EffectConfig, m Molecule, partne
 				nm.Stability = *eff.Create.Stability
 			}
 
+			nm.TTL = eff.Create.TTL
+			// products appear where the reaction happens
+			if m.Position != nil {
+				p := *m.Position
+				nm.Position = &p
+			}
+
+			// route the molecule to other environments if requested
+			if targets := emitTargets(eff.Create); len(targets) > 0 {
+				effect.Emitted = append(effect.Emitted, EmittedMolecule{
+					Molecule: nm,
+					Targets:  targets,
+				})
+				continue
+			}
+
 			effect.NewMolecules = append(effect.NewMolecules, nm)
 		}
 	}
 }
 
+// chooseBranch picks a branch with a probability proportional to its weight, using a
+// single draw from random. Branches without a positive weight are never picked; nil is
+// returned if no branch can be.
+func chooseBranch(branches []WeightedBranch, random func() float64) *WeightedBranch {
+	total := 0.0
+	for _, b := range branches {
+		if b.Weight > 0 {
+			total += b.Weight
+		}
+	}
+	if total <= 0 {
+		return nil
+	}
+	if random == nil {
+		random = rand.Float64
+	}
+
+	draw := random() * total
+	var last *WeightedBranch
+	for i := range branches {
+		if branches[i].Weight <= 0 {
+			continue
+		}
+		last = &branches[i]
+		if draw < branches[i].Weight {
+			return last
+		}
+		draw -= branches[i].Weight
+	}
+	// rounding may leave draw just above the last cumulative weight
+	return last
+}
+
+// findChange returns the pending change for the molecule with the given ID, or nil.
+func findChange(effect *ReactionEffect, id MoleculeID) *MoleculeChange {
+	for i := range effect.Changes {
+		if effect.Changes[i].ID == id {
+			return &effect.Changes[i]
+		}
+	}
+	return nil
+}
+
+// changeFor returns the pending change for m, creating one from a copy of m if needed.
+func changeFor(effect *ReactionEffect, m Molecule) *MoleculeChange {
+	if change := findChange(effect, m.ID); change != nil {
+		return change
+	}
+	copy := m
+	effect.Changes = append(effect.Changes, MoleculeChange{
+		ID:      m.ID,
+		Updated: &copy,
+	})
+	return &effect.Changes[len(effect.Changes)-1]
+}
+
+// updatePayload applies the payload operations of an update effect to mol. The payload
+// is copied first so the snapshot (shared with the original molecule) is never mutated.
+func updatePayload(mol *Molecule, cfg *UpdateEffectConfig, origin Molecule, partners []Molecule) {
+	payload := make(map[string]any, len(mol.Payload)+len(cfg.PayloadSet))
+	maps.Copy(payload, mol.Payload)
+	for k, v := range cfg.PayloadSet {
+		payload[k] = resolveValueRef(v, origin, partners...)
+	}
+	for k, delta := range cfg.PayloadIncrement {
+		current, exists := payload[k]
+		if !exists {
+			payload[k] = delta
+			continue
+		}
+		if f, ok := toFloat64(current); ok {
+			payload[k] = f + delta
+		}
+	}
+	mol.Payload = payload
+}
+
+// changeSpecies moves mol to another species in place. The payload is copied so
+// the snapshot is never mutated; if carry is not empty, only those fields are kept.
+func changeSpecies(mol *Molecule, species SpeciesName, carry []string, envTime int64) {
+	payload := make(map[string]any, len(mol.Payload))
+	if len(carry) == 0 {
+		maps.Copy(payload, mol.Payload)
+	} else {
+		for _, field := range carry {
+			if v, ok := mol.Payload[field]; ok {
+				payload[field] = v
+			}
+		}
+	}
+	mol.Species = species
+	mol.Payload = payload
+	mol.LastTouchedAt = envTime
+}
+
+// nextLadderSpecies returns the species following current in ladder.
+// Returns false if current is not in the ladder or is its last step.
+func nextLadderSpecies(ladder []string, current SpeciesName) (SpeciesName, bool) {
+	idx := slices.Index(ladder, string(current))
+	if idx < 0 || idx == len(ladder)-1 {
+		return "", false
+	}
+	return SpeciesName(ladder[idx+1]), true
+}
+
+// emitTargets returns the deduplicated list of target environments for a create effect,
+// combining EmitTo and EmitToMany. Returns nil if the molecule stays in the current environment.
+func emitTargets(cfg *CreateEffectConfig) []EnvironmentID {
+	if cfg.EmitTo == "" && len(cfg.EmitToMany) == 0 {
+		return nil
+	}
+
+	targets := make([]EnvironmentID, 0, len(cfg.EmitToMany)+1)
+	seen := make(map[string]struct{})
+	for _, id := range append([]string{cfg.EmitTo}, cfg.EmitToMany...) {
+		if id == "" {
+			continue
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		targets = append(targets, EnvironmentID(id))
+	}
+	return targets
+}
+
 // BuildSchemaFromConfig converts a SchemaConfig to a Schema
 func BuildSchemaFromConfig(cfg SchemaConfig) (*Schema, error) {
 	// Validate the configuration first
@@ -404,20 +784,35 @@ func BuildSchemaFromConfig(cfg SchemaConfig) (*Schema, error) {
 		return nil, err
 	}
 
-	s := NewSchema(cfg.Name)
+	s := NewSchema(cfg.Name).WithDecayRate(cfg.DecayRate)
 
 	// Species
 	for _, sp := range cfg.Species {
 		s = s.WithSpecies(Species{
-			Name:        SpeciesName(sp.Name),
-			Description: sp.Description,
-			Meta:        sp.Meta,
+			Name:             SpeciesName(sp.Name),
+			Description:      sp.Description,
+			Meta:             sp.Meta,
+			MaxCount:         sp.MaxCount,
+			EvictOrder:       sp.EvictOrder,
+			EvictNotifiers:   sp.EvictNotifiers,
+			ExpireNotifiers:  sp.ExpireNotifiers,
+			DefaultEnergy:    sp.DefaultEnergy,
+			DefaultStability: sp.DefaultStability,
+			Fields:           sp.Fields,
 		})
 	}
 
+	// keep the original config, so that the schema can be returned as it was loaded
+	s.config = &cfg
+	tolerance := s.floatTolerance()
+
 	// Reactions
 	for _, rc := range cfg.Reactions {
-		cr := &ConfigReaction{cfg: rc}
+		compiled, err := compileReactionRegexes(rc)
+		if err != nil {
+			return nil, fmt.Errorf("reaction '%s': %w", rc.ID, err)
+		}
+		cr := &ConfigReaction{cfg: compiled, tolerance: tolerance, species: s.species}
 		s = s.WithReactions(cr)
 	}
 
@@ -1,12 +1,19 @@
 package achem
 
 import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // testEnvView is a simple EnvView implementation for testing
 type testEnvView struct {
 	molecules []Molecule
+	now       int64
 }
 
 func (v testEnvView) MoleculesBySpecies(species SpeciesName) []Molecule {
@@ -19,6 +26,10 @@ func (v testEnvView) MoleculesBySpecies(species SpeciesName) []Molecule {
 	return result
 }
 
+func (v testEnvView) EnvTime() int64 {
+	return v.now
+}
+
 func (v testEnvView) Find(filter func(Molecule) bool) []Molecule {
 	var result []Molecule
 	for _, m := range v.molecules {
@@ -29,6 +40,10 @@ func (v testEnvView) Find(filter func(Molecule) bool) []Molecule {
 	return result
 }
 
+func (v testEnvView) FindBest(species SpeciesName, less func(a, b Molecule) bool) (Molecule, bool) {
+	return findBest(v.MoleculesBySpecies(species), less)
+}
+
 func TestConfigReaction_IfThenElse_FieldCondition(t *testing.T) {
 	cfg := ReactionConfig{
 		ID:   "test-if",
@@ -168,6 +183,73 @@ func TestConfigReaction_IfThenElse_CountMolecules(t *testing.T) {
 	}
 }
 
+func TestEvaluateCountMolecules_Aggregations(t *testing.T) {
+	origin := NewMolecule("Order", map[string]any{"price": 1000}, 0)
+	orders := []Molecule{origin}
+	for i, price := range []any{10, 20.5, "30", "n/a"} {
+		m := NewMolecule("Order", map[string]any{"price": price}, 0)
+		m.Energy = float64(i + 1)
+		orders = append(orders, m)
+	}
+	orders = append(orders, NewMolecule("Order", nil, 0)) // no price
+	env := testEnvView{molecules: orders}
+
+	tests := []struct {
+		agg, field string
+		op         map[string]any
+		expected   bool
+	}{
+		{"", "", map[string]any{"eq": 5}, true}, // count excludes the origin molecule
+		{AggCount, "", map[string]any{"eq": 5}, true},
+		{AggSum, "price", map[string]any{"eq": 30.5}, true}, // strings are skipped
+		{AggSum, "price", map[string]any{"gt": 30.5}, false},
+		{AggAvg, "price", map[string]any{"eq": 15.25}, true},
+		{AggMin, "price", map[string]any{"eq": 10}, true},
+		{AggMax, "price", map[string]any{"eq": 20.5}, true},
+		{AggSum, "energy", map[string]any{"eq": 11}, true}, // 1+2+3+4 + 1 (default energy)
+		{AggSum, "missing", map[string]any{"eq": 0}, true},
+		{AggAvg, "missing", map[string]any{"eq": 0}, false}, // no values to average
+		{AggMax, "missing", map[string]any{"lt": 1}, false},
+	}
+
+	for _, tt := range tests {
+		cfg := &CountMoleculesConfig{Species: "Order", Op: tt.op, Agg: tt.agg, Field: tt.field}
+		if got := evaluateCountMolecules(cfg, origin, env, DefaultFloatTolerance); got != tt.expected {
+			t.Errorf("agg %q field %q op %v: expected %v, got %v", tt.agg, tt.field, tt.op, tt.expected, got)
+		}
+	}
+}
+
+func TestEvaluateCountMolecules_WithinTicks(t *testing.T) {
+	origin := NewMolecule("LoginFailure", map[string]any{"user": "bob"}, 100)
+	env := testEnvView{molecules: []Molecule{
+		origin,
+		NewMolecule("LoginFailure", map[string]any{"user": "bob"}, 10), // too old
+		NewMolecule("LoginFailure", map[string]any{"user": "bob"}, 50), // exactly at the window start
+		NewMolecule("LoginFailure", map[string]any{"user": "bob"}, 90),
+		NewMolecule("LoginFailure", map[string]any{"user": "alice"}, 95),
+	}, now: 100}
+
+	count := func(within int64, where WhereConfig) bool {
+		cfg := &CountMoleculesConfig{Species: "LoginFailure", Where: where, Op: map[string]any{"gte": 2}, WithinTicks: within}
+		return evaluateCountMolecules(cfg, origin, env, DefaultFloatTolerance)
+	}
+
+	sameUser := WhereConfig{"user": {Eq: "$m.user"}}
+	if !count(0, sameUser) {
+		t.Error("Expected all-time count of 3 to match gte 2")
+	}
+	if !count(50, sameUser) {
+		t.Error("Expected 2 failures in the last 50 ticks (created at 50 and 90)")
+	}
+	if count(49, sameUser) {
+		t.Error("Expected only 1 failure in the last 49 ticks")
+	}
+	if !count(10, nil) {
+		t.Error("Expected 2 failures of any user in the last 10 ticks")
+	}
+}
+
 func TestConfigReaction_Partners_Required(t *testing.T) {
 	cfg := ReactionConfig{
 		ID:   "test-partners",
@@ -234,6 +316,46 @@ func TestConfigReaction_Partners_Required(t *testing.T) {
 	}
 }
 
+func TestConfigReaction_InputAnySpecies(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Error"}, {Name: "Warning"}, {Name: "Info"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "alert",
+				Input:   InputConfig{AnySpecies: []string{"Error", "Warning"}, Where: WhereConfig{"service": {Eq: "api"}}},
+				Rate:    1,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert", Payload: map[string]any{"from": "$m.species"}}}},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	for _, species := range []SpeciesName{"Error", "Warning", "Info"} {
+		env.Insert(NewMolecule(species, map[string]any{"service": "api"}, 0))
+	}
+	env.Insert(NewMolecule("Error", map[string]any{"service": "db"}, 0))
+	env.Step()
+
+	from := []string{}
+	for _, m := range env.QueryMolecules("Alert", nil) {
+		from = append(from, fmt.Sprint(m.Payload["from"]))
+	}
+	slices.Sort(from)
+	if !slices.Equal(from, []string{"Error", "Warning"}) {
+		t.Errorf("Expected alerts from the api Error and Warning, got %v", from)
+	}
+	if n := len(env.QueryMolecules("Info", nil)); n != 1 {
+		t.Errorf("Expected the Info molecule not to react, got %d left", n)
+	}
+	if n := len(env.QueryMolecules("Error", nil)); n != 1 {
+		t.Errorf("Expected the db Error not to match the where condition, got %d left", n)
+	}
+}
+
 func TestConfigReaction_Partners_DifferentIP(t *testing.T) {
 	cfg := ReactionConfig{
 		ID:   "test-partners-diff",
@@ -368,6 +490,41 @@ func TestConfigReaction_FieldComparison_Operators(t *testing.T) {
 	}
 }
 
+func TestEvaluateIfCondition_FieldVsField(t *testing.T) {
+	testCases := []struct {
+		name     string
+		payload  map[string]any
+		field    string
+		op       string
+		value    any
+		expected bool
+	}{
+		{"gt_true", map[string]any{"current_value": 12.0, "threshold_value": 10.0}, "current_value", "gt", "$m.threshold_value", true},
+		{"gt_false", map[string]any{"current_value": 8.0, "threshold_value": 10.0}, "current_value", "gt", "$m.threshold_value", false},
+		{"prefixed_left_field", map[string]any{"current_value": 12.0, "threshold_value": 10.0}, "$m.current_value", "gte", "$m.threshold_value", true},
+		{"int_vs_float", map[string]any{"current_value": 10, "threshold_value": 10.0}, "current_value", "eq", "$m.threshold_value", true},
+		{"numeric_string_vs_number", map[string]any{"current_value": "10", "threshold_value": 9}, "current_value", "gt", "$m.threshold_value", true},
+		{"number_vs_numeric_string", map[string]any{"current_value": 9, "threshold_value": "10"}, "current_value", "lt", "$m.threshold_value", true},
+		{"string_vs_string", map[string]any{"current_value": "b", "threshold_value": "a"}, "current_value", "gt", "$m.threshold_value", true},
+		{"non_numeric_string_vs_number", map[string]any{"current_value": "abc", "threshold_value": 1}, "current_value", "eq", "$m.threshold_value", false},
+		{"missing_ref_gt", map[string]any{"current_value": 12.0}, "current_value", "gt", "$m.threshold_value", false},
+		{"missing_ref_ne", map[string]any{"current_value": 12.0}, "current_value", "ne", "$m.threshold_value", false},
+		{"missing_left", map[string]any{"threshold_value": 10.0}, "current_value", "lt", "$m.threshold_value", false},
+		{"vs_energy", map[string]any{"current_value": 0.5}, "current_value", "lt", "$m.energy", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mol := NewMolecule("Test", tc.payload, 0)
+			mol.Energy = 1.0
+			cond := &IfConditionConfig{Field: tc.field, Op: tc.op, Value: tc.value}
+			if got := evaluateIfCondition(cond, mol, envView{}, DefaultFloatTolerance); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
 func TestConfigReaction_ResolveValueFromMolecule(t *testing.T) {
 	mol := NewMolecule("Test", map[string]any{"ip": "192.168.1.1", "port": 8080}, 100)
 	mol.Energy = 5.0
@@ -521,6 +678,295 @@ func TestConfigReaction_Partners_CountZero(t *testing.T) {
 	}
 }
 
+func TestConfigReaction_ConsumePartners(t *testing.T) {
+	cfg := ReactionConfig{
+		ID: "bind",
+		Input: InputConfig{
+			Species:  "A",
+			Partners: []PartnerConfig{{Species: "B"}},
+		},
+		Rate: 1.0,
+		Effects: []EffectConfig{
+			{Consume: true, ConsumePartners: true},
+			{Create: &CreateEffectConfig{Species: "C"}},
+		},
+		Notify: &NotificationConfig{Enabled: true},
+	}
+
+	env := NewEnvironment(NewSchema("bind").WithReactions(&ConfigReaction{cfg: cfg}))
+	var mu sync.Mutex
+	var events []NotificationEvent
+	env.RegisterCallback("test", func(event NotificationEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	// A + B -> C: with 5 A and 3 B, only 3 pairs can react, each B at most once
+	for i := 0; i < 5; i++ {
+		env.Insert(NewMolecule("A", nil, 0))
+	}
+	for i := 0; i < 3; i++ {
+		env.Insert(NewMolecule("B", nil, 0))
+	}
+
+	env.Step()
+
+	counts := env.SpeciesCounts()
+	if counts["A"] != 2 || counts["B"] != 0 || counts["C"] != 3 {
+		t.Errorf("Expected 2 A, 0 B and 3 C, got %v", counts)
+	}
+
+	// another step can't fire without B
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["A"] != 2 || counts["C"] != 3 {
+		t.Errorf("Expected no reaction without partners, got %v", counts)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+		if n >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 notifications, got %d", len(events))
+	}
+	seen := make(map[MoleculeID]bool)
+	for _, event := range events {
+		if len(event.ConsumedMolecules) != 2 || len(event.Partners) != 1 {
+			t.Fatalf("Expected the input and its partner to be consumed, got %+v", event)
+		}
+		partner := event.Partners[0]
+		if event.ConsumedMolecules[1].ID != partner.ID || partner.Species != "B" {
+			t.Errorf("Expected the consumed partner to be %s, got %+v", partner.ID, event.ConsumedMolecules)
+		}
+		if seen[partner.ID] {
+			t.Errorf("Partner %s consumed twice", partner.ID)
+		}
+		seen[partner.ID] = true
+	}
+}
+
+func TestConfigReaction_InputCount(t *testing.T) {
+	cfg := ReactionConfig{
+		ID:      "trimerize",
+		Input:   InputConfig{Species: "A", Count: 3},
+		Rate:    1.0,
+		Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "B"}}},
+		Notify:  &NotificationConfig{Enabled: true},
+	}
+
+	env := NewEnvironment(NewSchema("trimer").WithReactions(&ConfigReaction{cfg: cfg}))
+	var mu sync.Mutex
+	var events []NotificationEvent
+	env.RegisterCallback("test", func(event NotificationEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	// 3 A -> 1 B: with 7 A, two groups react and the last A is left over
+	for i := 0; i < 7; i++ {
+		env.Insert(NewMolecule("A", nil, 0))
+	}
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["A"] != 1 || counts["B"] != 2 {
+		t.Errorf("Expected 1 A and 2 B, got %v", counts)
+	}
+
+	// a single A can't react on its own
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["A"] != 1 || counts["B"] != 2 {
+		t.Errorf("Expected no reaction with a single A, got %v", counts)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+		if n >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(events))
+	}
+	seen := make(map[MoleculeID]bool)
+	for _, event := range events {
+		if len(event.ConsumedMolecules) != 3 {
+			t.Fatalf("Expected the group of 3 to be consumed, got %+v", event.ConsumedMolecules)
+		}
+		for _, c := range event.ConsumedMolecules {
+			if seen[c.ID] {
+				t.Errorf("Molecule %s consumed by two groups", c.ID)
+			}
+			seen[c.ID] = true
+		}
+	}
+}
+
+func TestConfigReaction_InputCount_WithPartners(t *testing.T) {
+	// 2 A + C -> B: the input group and the partners are distinct molecules, even when
+	// the partner species is the input species
+	cfg := ReactionConfig{
+		ID: "r",
+		Input: InputConfig{
+			Species:  "A",
+			Count:    2,
+			Partners: []PartnerConfig{{Species: "A"}},
+		},
+		Rate:    1.0,
+		Effects: []EffectConfig{{Consume: true, ConsumePartners: true}, {Create: &CreateEffectConfig{Species: "B"}}},
+	}
+	env := NewEnvironment(NewSchema("s").WithReactions(&ConfigReaction{cfg: cfg}))
+	for i := 0; i < 5; i++ {
+		env.Insert(NewMolecule("A", nil, 0))
+	}
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["A"] != 2 || counts["B"] != 1 {
+		t.Errorf("Expected 2 A and 1 B, got %v", counts)
+	}
+}
+
+func TestConfigReaction_PartnerReferences(t *testing.T) {
+	cfg := ReactionConfig{
+		ID: "pair",
+		Input: InputConfig{
+			Species:  "A",
+			Partners: []PartnerConfig{{Species: "B"}},
+		},
+		Rate: 1.0,
+		Effects: []EffectConfig{
+			{Create: &CreateEffectConfig{Species: "Pair", Payload: map[string]any{
+				"a":       "$m.id",
+				"b":       "$p[0].id",
+				"label":   "$partner.label",
+				"missing": "$p[1].id",
+			}}},
+			{Update: &UpdateEffectConfig{PayloadSet: map[string]any{"paired_with": "$partner.label"}}},
+		},
+	}
+
+	a := NewMolecule("A", nil, 0)
+	b := NewMolecule("B", map[string]any{"label": "bee"}, 0)
+	env := testEnvView{molecules: []Molecule{a, b}}
+
+	eff := (&ConfigReaction{cfg: cfg}).Apply(a, env, ReactionContext{EnvTime: 1})
+	if len(eff.NewMolecules) != 1 {
+		t.Fatalf("Expected one Pair, got %+v", eff.NewMolecules)
+	}
+	payload := eff.NewMolecules[0].Payload
+	if payload["a"] != string(a.ID) || payload["b"] != string(b.ID) || payload["label"] != "bee" {
+		t.Errorf("Expected the pair to carry data from both reactants, got %v", payload)
+	}
+	if payload["missing"] != "$p[1].id" {
+		t.Errorf("Expected a missing partner to keep the literal, got %v", payload["missing"])
+	}
+	if len(eff.Changes) != 1 || eff.Changes[0].Updated.Payload["paired_with"] != "bee" {
+		t.Errorf("Expected the update to resolve the partner reference, got %+v", eff.Changes)
+	}
+}
+
+func TestChooseBranch(t *testing.T) {
+	branches := []WeightedBranch{{Weight: 7}, {Weight: 0}, {Weight: 3}}
+	tests := []struct {
+		draw     float64
+		expected int
+	}{
+		{0, 0},
+		{0.69, 0},
+		{0.7, 2}, // the zero-weight branch is never picked
+		{0.99, 2},
+		{1, 2}, // out of range draws fall back to the last branch
+	}
+	for _, tt := range tests {
+		got := chooseBranch(branches, func() float64 { return tt.draw })
+		if got != &branches[tt.expected] {
+			t.Errorf("draw %v: expected branch %d, got %+v", tt.draw, tt.expected, got)
+		}
+	}
+	if got := chooseBranch([]WeightedBranch{{Weight: 0}}, func() float64 { return 0 }); got != nil {
+		t.Errorf("Expected no branch without positive weights, got %+v", got)
+	}
+}
+
+func TestConfigReaction_Choose(t *testing.T) {
+	cfg := ReactionConfig{
+		ID:    "split",
+		Input: InputConfig{Species: "Event"},
+		Rate:  1.0,
+		Effects: []EffectConfig{
+			{Consume: true},
+			{Choose: &ChooseEffectConfig{Branches: []WeightedBranch{
+				{Weight: 70, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "A"}}}},
+				{Weight: 30, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "B"}}}},
+			}}},
+		},
+	}
+
+	env := NewEnvironmentWithSeed(NewSchema("split").WithReactions(&ConfigReaction{cfg: cfg}), 7)
+	const n = 2000
+	for i := 0; i < n; i++ {
+		env.Insert(NewMolecule("Event", nil, 0))
+	}
+	env.Step()
+
+	counts := env.SpeciesCounts()
+	if counts["Event"] != 0 || counts["A"]+counts["B"] != n {
+		t.Fatalf("Expected every event to create exactly one of A or B, got %v", counts)
+	}
+	if share := float64(counts["A"]) / n; share < 0.65 || share > 0.75 {
+		t.Errorf("Expected about 70%% A, got %.1f%% (%v)", share*100, counts)
+	}
+}
+
+func TestConfigReaction_CreateWithExpressions(t *testing.T) {
+	reaction := &ConfigReaction{cfg: ReactionConfig{
+		ID:    "derive",
+		Input: InputConfig{Species: "Order"},
+		Rate:  1.0,
+		Effects: []EffectConfig{{Create: &CreateEffectConfig{
+			Species: "Invoice",
+			Payload: map[string]any{
+				"total":  "$m.price * 1.2",
+				"items":  "$m.items + 1",
+				"note":   "$m.price x 2",
+				"source": "$m.id",
+			},
+		}}},
+	}}
+
+	m := NewMolecule("Order", map[string]any{"price": 50.0, "items": 2}, 0)
+	effect := reaction.Apply(m, testEnvView{molecules: []Molecule{m}}, ReactionContext{EnvTime: 1})
+	if len(effect.NewMolecules) != 1 {
+		t.Fatalf("Expected 1 new molecule, got %d", len(effect.NewMolecules))
+	}
+	payload := effect.NewMolecules[0].Payload
+	if total, ok := payload["total"].(float64); !ok || math.Abs(total-60) > 1e-9 {
+		t.Errorf("Expected total 60, got %v", payload["total"])
+	}
+	if payload["items"] != 3.0 {
+		t.Errorf("Expected items 3, got %v", payload["items"])
+	}
+	if payload["note"] != "$m.price x 2" {
+		t.Errorf("Expected invalid expression to stay literal, got %v", payload["note"])
+	}
+	if payload["source"] != string(m.ID) {
+		t.Errorf("Expected plain reference to resolve, got %v", payload["source"])
+	}
+}
+
 func TestConfigReaction_Catalysts_Basic(t *testing.T) {
 	cfg := ReactionConfig{
 		ID:   "test-catalyst",
@@ -709,6 +1155,95 @@ func TestConfigReaction_Catalysts_Multiple(t *testing.T) {
 	}
 }
 
+func TestConfigReaction_Catalysts_MultiplyMode(t *testing.T) {
+	cfg := ReactionConfig{
+		ID:    "test-catalyst-multiply",
+		Input: InputConfig{Species: "Input"},
+		Rate:  0.1,
+		Catalysts: []CatalystConfig{
+			{Species: "Enzyme", RateBoost: 0.5, Mode: CatalystModeMultiply},
+			{Species: "Booster", RateBoost: 0.1}, // additive, applied before the multiplier
+		},
+	}
+	reaction := &ConfigReaction{cfg: cfg}
+	inputMol := NewMolecule("Input", nil, 0)
+
+	// two enzymes: 0.1 * (1 + 0.5*2) = 0.2
+	view := testEnvView{molecules: []Molecule{
+		inputMol,
+		NewMolecule("Enzyme", nil, 0),
+		NewMolecule("Enzyme", nil, 0),
+	}}
+	if rate := reaction.EffectiveRate(inputMol, view); math.Abs(rate-0.2) > 1e-9 {
+		t.Errorf("Expected effective rate 0.2 with two enzymes, got %f", rate)
+	}
+
+	// plus an additive booster: (0.1 + 0.1) * 2 = 0.4
+	view.molecules = append(view.molecules, NewMolecule("Booster", nil, 0))
+	if rate := reaction.EffectiveRate(inputMol, view); math.Abs(rate-0.4) > 1e-9 {
+		t.Errorf("Expected effective rate 0.4 with enzymes and booster, got %f", rate)
+	}
+
+	// still capped at 1.0
+	for i := 0; i < 10; i++ {
+		view.molecules = append(view.molecules, NewMolecule("Enzyme", nil, 0))
+	}
+	if rate := reaction.EffectiveRate(inputMol, view); rate != 1.0 {
+		t.Errorf("Expected effective rate capped at 1.0, got %f", rate)
+	}
+}
+
+func TestConfigReaction_Inhibitors(t *testing.T) {
+	cfg := ReactionConfig{
+		ID:    "alert",
+		Input: InputConfig{Species: "Suspicion"},
+		Rate:  0.5,
+		Catalysts: []CatalystConfig{
+			{Species: "Evidence", RateBoost: 0.3},
+		},
+		Inhibitors: []InhibitorConfig{
+			{Species: "Suppression", Where: WhereConfig{"ip": EqCondition{Eq: "$m.ip"}}, RateReduction: 0.6},
+			{Species: "Maintenance"}, // default reduction 0.1
+		},
+	}
+	reaction := &ConfigReaction{cfg: cfg}
+	inputMol := NewMolecule("Suspicion", map[string]any{"ip": "1.2.3.4"}, 0)
+
+	view := testEnvView{molecules: []Molecule{
+		inputMol,
+		NewMolecule("Suppression", map[string]any{"ip": "5.6.7.8"}, 0), // other IP: no effect
+	}}
+	if rate := reaction.EffectiveRate(inputMol, view); rate != 0.5 {
+		t.Errorf("Expected base rate 0.5 without matching inhibitors, got %f", rate)
+	}
+
+	// catalyst boost applies first: 0.5 + 0.3 - 0.6 = 0.2
+	view.molecules = append(view.molecules,
+		NewMolecule("Evidence", nil, 0),
+		NewMolecule("Suppression", map[string]any{"ip": "1.2.3.4"}, 0),
+	)
+	if rate := reaction.EffectiveRate(inputMol, view); math.Abs(rate-0.2) > 1e-9 {
+		t.Errorf("Expected effective rate 0.2 with catalyst and inhibitor, got %f", rate)
+	}
+
+	// 0.2 - 0.1 (default) = 0.1
+	view.molecules = append(view.molecules, NewMolecule("Maintenance", nil, 0))
+	if rate := reaction.EffectiveRate(inputMol, view); math.Abs(rate-0.1) > 1e-9 {
+		t.Errorf("Expected effective rate 0.1 with both inhibitors, got %f", rate)
+	}
+
+	// without the catalyst the rate would go negative and is clamped at 0
+	noCatalyst := &ConfigReaction{cfg: ReactionConfig{
+		ID:         "alert",
+		Input:      InputConfig{Species: "Suspicion"},
+		Rate:       0.5,
+		Inhibitors: cfg.Inhibitors,
+	}}
+	if rate := noCatalyst.EffectiveRate(inputMol, view); rate != 0 {
+		t.Errorf("Expected effective rate clamped at 0, got %f", rate)
+	}
+}
+
 func TestConfigReaction_Catalysts_DefaultBoost(t *testing.T) {
 	cfg := ReactionConfig{
 		ID:   "test-catalyst-default",
@@ -1024,3 +1559,237 @@ func TestConfigReaction_TimestampFieldInCondition(t *testing.T) {
 		t.Error("Expected no Output molecule when condition is not met")
 	}
 }
+
+func TestBuildSchemaFromConfig_FloatTolerance(t *testing.T) {
+	tol := 0.5
+	cfg := SchemaConfig{
+		Name:    "tolerance",
+		Species: []SpeciesConfig{{Name: "Metric"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "near_ten",
+				Input:   InputConfig{Species: "Metric", Where: WhereConfig{"value": {Eq: 10.2}}},
+				Effects: []EffectConfig{{Consume: true}},
+			},
+		},
+		FloatTolerance: &tol,
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	reaction := schema.Reactions()[0]
+
+	if !reaction.InputPattern(NewMolecule("Metric", map[string]any{"value": 10.5}, 0)) {
+		t.Error("Expected 10.5 to match 10.2 with tolerance 0.5")
+	}
+	if reaction.InputPattern(NewMolecule("Metric", map[string]any{"value": 11.0}, 0)) {
+		t.Error("Expected 11.0 not to match 10.2 with tolerance 0.5")
+	}
+
+	// default tolerance when not configured
+	cfg.FloatTolerance = nil
+	schema, err = BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	if schema.Reactions()[0].InputPattern(NewMolecule("Metric", map[string]any{"value": 10.5}, 0)) {
+		t.Error("Expected 10.5 not to match 10.2 with the default tolerance")
+	}
+
+	negative := -1.0
+	cfg.FloatTolerance = &negative
+	if _, err := BuildSchemaFromConfig(cfg); err == nil || !strings.Contains(err.Error(), "float_tolerance") {
+		t.Errorf("Expected float_tolerance validation error, got %v", err)
+	}
+}
+
+func TestBuildSchemaFromConfig_CompilesRegexes(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "web",
+		Species: []SpeciesConfig{{Name: "Request"}, {Name: "Block"}},
+		Reactions: []ReactionConfig{{
+			ID: "admin_probe",
+			Input: InputConfig{
+				Species:  "Request",
+				Where:    WhereConfig{"$or": {Clauses: []WhereConfig{{"path": {Regex: `^/admin`}}, {"path": {Regex: `\.php$`}}}}},
+				Partners: []PartnerConfig{{Species: "Block", Where: WhereConfig{"ip": {Regex: `^10\.`}}}},
+			},
+			Rate: 1.0,
+			Effects: []EffectConfig{{
+				If: &IfConditionConfig{CountMolecules: &CountMoleculesConfig{
+					Species: "Request",
+					Where:   WhereConfig{"path": {Regex: `^/admin`}},
+					Op:      map[string]any{"gte": 1},
+				}},
+				Then: []EffectConfig{{Consume: true}},
+			}},
+		}},
+	}
+
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	compiled := schema.Reactions()[0].(*ConfigReaction).cfg
+	if compiled.Input.Where["$or"].Clauses[1]["path"].regex == nil ||
+		compiled.Input.Partners[0].Where["ip"].regex == nil ||
+		compiled.Effects[0].If.CountMolecules.Where["path"].regex == nil {
+		t.Errorf("Expected every regex condition to be compiled, got %+v", compiled)
+	}
+
+	// the caller's config is left untouched
+	if cfg.Reactions[0].Input.Where["$or"].Clauses[0]["path"].regex != nil || cfg.Reactions[0].Input.Partners[0].Where["ip"].regex != nil {
+		t.Error("Expected the original config not to be modified")
+	}
+
+	r := schema.Reactions()[0]
+	if !r.InputPattern(NewMolecule("Request", map[string]any{"path": "/index.php"}, 0)) {
+		t.Error("Expected /index.php to match")
+	}
+	if r.InputPattern(NewMolecule("Request", map[string]any{"path": "/index.html"}, 0)) {
+		t.Error("Expected /index.html not to match")
+	}
+}
+
+func TestConfigReaction_Promote(t *testing.T) {
+	cfg := ReactionConfig{
+		ID:    "escalate",
+		Input: InputConfig{Species: "Suspicion"},
+		Rate:  1.0,
+		Effects: []EffectConfig{
+			{
+				Promote: &PromoteEffectConfig{
+					Ladder: []string{"Event", "Suspicion", "Alert"},
+					Carry:  []string{"ip"},
+				},
+			},
+		},
+	}
+
+	reaction := &ConfigReaction{cfg: cfg}
+	m := NewMolecule("Suspicion", map[string]any{"ip": "1.2.3.4", "score": 7}, 1)
+	m.Energy = 4.0
+
+	eff := reaction.Apply(m, testEnvView{}, ReactionContext{EnvTime: 5})
+
+	if len(eff.Changes) != 1 || eff.Changes[0].Updated == nil {
+		t.Fatalf("Expected 1 change, got %+v", eff.Changes)
+	}
+	promoted := *eff.Changes[0].Updated
+	if promoted.ID != m.ID || promoted.Species != "Alert" {
+		t.Errorf("Expected %s promoted to Alert, got %s as %s", m.ID, promoted.ID, promoted.Species)
+	}
+	if promoted.Energy != 4.0 || promoted.CreatedAt != 1 || promoted.LastTouchedAt != 5 {
+		t.Errorf("Expected energy and CreatedAt kept, got %+v", promoted)
+	}
+	if len(promoted.Payload) != 1 || promoted.Payload["ip"] != "1.2.3.4" {
+		t.Errorf("Expected only 'ip' to be carried, got %v", promoted.Payload)
+	}
+	if len(m.Payload) != 2 {
+		t.Errorf("Expected original payload to be untouched, got %v", m.Payload)
+	}
+
+	// the new species' default energy replaces the molecule's
+	alertEnergy := 2.5
+	species := map[SpeciesName]Species{"Alert": {Name: "Alert", DefaultEnergy: &alertEnergy}}
+	eff = (&ConfigReaction{cfg: cfg, species: species}).Apply(m, testEnvView{}, ReactionContext{EnvTime: 5})
+	if len(eff.Changes) != 1 || eff.Changes[0].Updated.Energy != 2.5 {
+		t.Errorf("Expected the promoted molecule to get Alert's default energy, got %+v", eff.Changes)
+	}
+
+	// top of the ladder: no change
+	top := NewMolecule("Alert", nil, 1)
+	cfg.Input.Species = "Alert"
+	eff = (&ConfigReaction{cfg: cfg}).Apply(top, testEnvView{}, ReactionContext{EnvTime: 5})
+	if len(eff.Changes) != 0 {
+		t.Errorf("Expected no change at the top of the ladder, got %+v", eff.Changes)
+	}
+}
+
+func TestConfigReaction_Transform(t *testing.T) {
+	one := 1.0
+	cfg := ReactionConfig{
+		ID:    "confirm",
+		Input: InputConfig{Species: "Suspicion"},
+		Rate:  1.0,
+		Effects: []EffectConfig{
+			{Update: &UpdateEffectConfig{EnergyAdd: &one}},
+			{
+				Transform: &TransformEffectConfig{
+					Species: "Alert",
+					Payload: map[string]any{"status": "open", "source_ip": "$m.ip"},
+				},
+			},
+		},
+	}
+
+	m := NewMolecule("Suspicion", map[string]any{"ip": "1.2.3.4", "score": 7}, 1)
+	m.Energy = 4.0
+
+	eff := (&ConfigReaction{cfg: cfg}).Apply(m, testEnvView{}, ReactionContext{EnvTime: 5})
+
+	if len(eff.Changes) != 1 || eff.Changes[0].Updated == nil {
+		t.Fatalf("Expected 1 change, got %+v", eff.Changes)
+	}
+	if len(eff.NewMolecules) != 0 || len(eff.ConsumedIDs) != 0 {
+		t.Errorf("Expected no created or consumed molecules, got %+v", eff)
+	}
+	alert := *eff.Changes[0].Updated
+	if alert.ID != m.ID || alert.Species != "Alert" {
+		t.Errorf("Expected %s transformed to Alert, got %s as %s", m.ID, alert.ID, alert.Species)
+	}
+	if alert.Energy != 5.0 || alert.CreatedAt != 1 || alert.LastTouchedAt != 5 {
+		t.Errorf("Expected energy and CreatedAt kept, got %+v", alert)
+	}
+	want := map[string]any{"ip": "1.2.3.4", "score": 7, "status": "open", "source_ip": "1.2.3.4"}
+	if len(alert.Payload) != len(want) {
+		t.Errorf("Expected payload %v, got %v", want, alert.Payload)
+	}
+	for k, v := range want {
+		if alert.Payload[k] != v {
+			t.Errorf("Expected payload %s=%v, got %v", k, v, alert.Payload[k])
+		}
+	}
+	if len(m.Payload) != 2 {
+		t.Errorf("Expected original payload to be untouched, got %v", m.Payload)
+	}
+}
+
+func TestConfigReaction_UpdatePayload(t *testing.T) {
+	cfg := ReactionConfig{
+		ID:    "track",
+		Input: InputConfig{Species: "Session"},
+		Rate:  1.0,
+		Effects: []EffectConfig{
+			{Update: &UpdateEffectConfig{
+				PayloadSet:       map[string]any{"status": "seen", "last_ip": "$m.ip"},
+				PayloadIncrement: map[string]float64{"hits": 1, "score": 0.5, "ip": 1},
+			}},
+			{Update: &UpdateEffectConfig{PayloadIncrement: map[string]float64{"hits": 2}}},
+		},
+	}
+
+	m := NewMolecule("Session", map[string]any{"ip": "1.2.3.4", "hits": 3}, 1)
+	eff := (&ConfigReaction{cfg: cfg}).Apply(m, testEnvView{}, ReactionContext{EnvTime: 5})
+
+	if len(eff.Changes) != 1 || eff.Changes[0].Updated == nil {
+		t.Fatalf("Expected 1 change, got %+v", eff.Changes)
+	}
+	updated := *eff.Changes[0].Updated
+	want := map[string]any{"ip": "1.2.3.4", "hits": 6.0, "score": 0.5, "status": "seen", "last_ip": "1.2.3.4"}
+	if len(updated.Payload) != len(want) {
+		t.Errorf("Expected payload %v, got %v", want, updated.Payload)
+	}
+	for k, v := range want {
+		if updated.Payload[k] != v {
+			t.Errorf("Expected payload %s=%v, got %v", k, v, updated.Payload[k])
+		}
+	}
+	if updated.LastTouchedAt != 5 {
+		t.Errorf("Expected LastTouchedAt 5, got %d", updated.LastTouchedAt)
+	}
+	if len(m.Payload) != 2 || m.Payload["hits"] != 3 {
+		t.Errorf("Expected original payload to be untouched, got %v", m.Payload)
+	}
+}
@@ -0,0 +1,57 @@
+package achem
+
+import "maps"
+
+// reactionCooldownTicks returns the cooldown of a reaction, in ticks (0 = none).
+// Custom reactions have no cooldown.
+func reactionCooldownTicks(r Reaction) int64 {
+	if cr, ok := r.(*ConfigReaction); ok {
+		return int64(cr.cfg.CooldownTicks)
+	}
+	return 0
+}
+
+// coolingLocked returns, per reaction index, a copy of the cooldowns of the reactions
+// that have one, for the compute phase to check and extend. Must be called with e.mu
+// held.
+func (e *Environment) coolingLocked(reactions []Reaction) (ticks []int64, cooling []map[MoleculeID]int64) {
+	ticks = make([]int64, len(reactions))
+	cooling = make([]map[MoleculeID]int64, len(reactions))
+	for i, r := range reactions {
+		ticks[i] = reactionCooldownTicks(r)
+		if ticks[i] > 0 {
+			cooling[i] = maps.Clone(e.cooldowns[r.ID()])
+			if cooling[i] == nil {
+				cooling[i] = make(map[MoleculeID]int64)
+			}
+		}
+	}
+	return ticks, cooling
+}
+
+// startCooldownsLocked records the cooldowns started by a tick, as reaction ID ->
+// molecule ID -> last tick of the cooldown, and forgets the ones that are over or
+// whose molecule is gone. Must be called with e.mu held for writing, after the
+// molecules of the tick were applied.
+func (e *Environment) startCooldownsLocked(started map[string]map[MoleculeID]int64) {
+	for id, mols := range started {
+		if e.cooldowns == nil {
+			e.cooldowns = make(map[string]map[MoleculeID]int64)
+		}
+		if e.cooldowns[id] == nil {
+			e.cooldowns[id] = make(map[MoleculeID]int64)
+		}
+		maps.Copy(e.cooldowns[id], mols)
+	}
+
+	for id, mols := range e.cooldowns {
+		for mid, until := range mols {
+			if _, exists := e.mols[mid]; !exists || until <= e.time {
+				delete(mols, mid)
+			}
+		}
+		if len(mols) == 0 {
+			delete(e.cooldowns, id)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package achem
+
+import "testing"
+
+func TestConfigReaction_Cooldown(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "pulses",
+		Species: []SpeciesConfig{{Name: "Source"}, {Name: "Pulse"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:            "emit",
+				Input:         InputConfig{Species: "Source"},
+				Rate:          1,
+				CooldownTicks: 4,
+				Effects: []EffectConfig{
+					{Create: &CreateEffectConfig{Species: "Pulse"}},
+					{Requeue: &RequeueEffectConfig{MaxIterations: 3}}, // requeuing doesn't bypass the cooldown
+				},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	env.Insert(Molecule{ID: "src", Species: "Source"})
+
+	// fires at ticks 1, 6, 11 and 16, resting 4 ticks in between
+	env.StepN(18)
+	if got := len(env.QueryMolecules("Pulse", nil)); got != 4 {
+		t.Errorf("Expected 4 pulses in 18 ticks, got %d", got)
+	}
+	if until := env.cooldowns["emit"]["src"]; until != 20 {
+		t.Errorf("Expected the source to cool down until tick 20, got %d", until)
+	}
+
+	clone := env.Clone()
+	clone.Step()
+	if got := len(clone.QueryMolecules("Pulse", nil)); got != 4 {
+		t.Errorf("Expected the clone to keep the cooldown, got %d pulses", got)
+	}
+
+	env.DeleteMolecule("src")
+	env.Step()
+	if len(env.cooldowns) != 0 {
+		t.Errorf("Expected the cooldown of a deleted molecule to be forgotten, got %v", env.cooldowns)
+	}
+
+	env.Insert(Molecule{ID: "src", Species: "Source"})
+	env.Step() // t=20: fires, cooling until 24
+	env.Reset()
+	env.Insert(Molecule{ID: "src", Species: "Source"})
+	env.Step()
+	if got := len(env.QueryMolecules("Pulse", nil)); got != 1 {
+		t.Errorf("Expected Reset to clear the cooldowns, got %d pulses", got)
+	}
+}
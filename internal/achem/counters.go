@@ -0,0 +1,51 @@
+package achem
+
+import "maps"
+
+// Counters holds cumulative activity counters of an environment, accumulated by Step
+// since the environment was created or since the last reset.
+type Counters struct {
+	ReactionsFired       map[string]int64 `json:"reactions_fired"`       // reaction ID -> times fired with effects
+	MoleculesCreated     int64            `json:"molecules_created"`     // molecules created by reactions in this environment
+	MoleculesConsumed    int64            `json:"molecules_consumed"`    // molecules consumed by reactions
+	MoleculesEvicted     int64            `json:"molecules_evicted"`     // molecules evicted from capped species
+	MoleculesExpired     int64            `json:"molecules_expired"`     // molecules removed because their TTL elapsed
+	Notifications        int64            `json:"notifications"`         // notification events enqueued
+	NotificationsDropped int64            `json:"notifications_dropped"` // notification events lost to a full queue
+}
+
+func newCounters() Counters {
+	return Counters{ReactionsFired: make(map[string]int64)}
+}
+
+// add accumulates other into c.
+func (c *Counters) add(other Counters) {
+	for id, n := range other.ReactionsFired {
+		c.ReactionsFired[id] += n
+	}
+	c.MoleculesCreated += other.MoleculesCreated
+	c.MoleculesConsumed += other.MoleculesConsumed
+	c.MoleculesEvicted += other.MoleculesEvicted
+	c.MoleculesExpired += other.MoleculesExpired
+	c.Notifications += other.Notifications
+	c.NotificationsDropped += other.NotificationsDropped
+}
+
+// Counters returns the current counter values. If reset is true, the counters are
+// zeroed atomically with the read, so that delta-based collectors never count a
+// tick twice or miss one.
+func (e *Environment) Counters(reset bool) Counters {
+	if !reset {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		out := e.counters
+		out.ReactionsFired = maps.Clone(e.counters.ReactionsFired)
+		return out
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := e.counters
+	e.counters = newCounters()
+	return out
+}
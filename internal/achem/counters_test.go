@@ -0,0 +1,101 @@
+package achem
+
+import "testing"
+
+func TestEnvironment_Counters(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "counters",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "a_to_b",
+				Input: InputConfig{Species: "A"},
+				Rate:  1.0,
+				Effects: []EffectConfig{
+					{Consume: true},
+					{Create: &CreateEffectConfig{Species: "B"}},
+				},
+				Notify: &NotificationConfig{Enabled: true},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	env.RegisterCallback("test", func(NotificationEvent) {})
+
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Step()
+
+	c := env.Counters(false)
+	if c.ReactionsFired["a_to_b"] != 2 || c.MoleculesCreated != 2 || c.MoleculesConsumed != 2 || c.Notifications != 2 {
+		t.Errorf("Expected 2 fired/created/consumed/notifications, got %+v", c)
+	}
+
+	// a plain read does not reset and returns an independent copy
+	c.ReactionsFired["a_to_b"] = 100
+	if again := env.Counters(false); again.ReactionsFired["a_to_b"] != 2 {
+		t.Errorf("Expected counters to be unaffected by callers, got %+v", again)
+	}
+
+	if c := env.Counters(true); c.MoleculesCreated != 2 {
+		t.Errorf("Expected reset read to return current values, got %+v", c)
+	}
+	if c := env.Counters(false); c.MoleculesCreated != 0 || len(c.ReactionsFired) != 0 {
+		t.Errorf("Expected zeroed counters after reset, got %+v", c)
+	}
+
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Step()
+	if c := env.Counters(false); c.ReactionsFired["a_to_b"] != 1 || c.MoleculesConsumed != 1 {
+		t.Errorf("Expected counters to accumulate again after reset, got %+v", c)
+	}
+}
+
+func TestEnvironment_Counters_DroppedNotifications(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "counters",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "consume_a",
+				Input:   InputConfig{Species: "A"},
+				Rate:    1.0,
+				Effects: []EffectConfig{{Consume: true}},
+				Notify:  &NotificationConfig{Enabled: true},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	// the only worker is kept busy, so that the queue holds a single event
+	nm := NewNotificationManagerWithOptions(NotificationManagerOptions{QueueSize: 1, Workers: 1})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	nm.RegisterCallback("block", func(event NotificationEvent) {
+		if event.ReactionID == "block" {
+			close(started)
+			<-release
+		}
+	})
+	defer nm.Close()
+	defer close(release)
+	nm.Enqueue(NotificationEvent{ReactionID: "block"}, nil)
+	<-started
+
+	env := NewEnvironment(schema)
+	env.SetNotificationManager(nm)
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Step()
+
+	if c := env.Counters(false); c.Notifications != 1 || c.NotificationsDropped != 1 {
+		t.Errorf("Expected 1 notification enqueued and 1 dropped, got %+v", c)
+	}
+}
@@ -0,0 +1,27 @@
+package achem
+
+import "time"
+
+// DecayReactionID is the reserved reaction ID of notification events emitted when
+// molecules decay (see Schema.WithDecayRate). The decayed molecules are reported in
+// ConsumedMolecules. Only registered callbacks receive these events.
+const DecayReactionID = "__decay__"
+
+// decayProbability returns the probability that m decays in one tick under the given
+// decay rate: rate * (1 - stability), with stability clamped to [0, 1].
+func decayProbability(m Molecule, rate float64) float64 {
+	stability := min(max(m.Stability, 0), 1)
+	return rate * (1 - stability)
+}
+
+// notifyDecayed enqueues a single notification for the molecules decayed in a step.
+func notifyDecayed(decayed []Molecule, envID EnvironmentID, envTime int64, notifierMgr *NotificationManager) {
+	notifierMgr.Enqueue(NotificationEvent{
+		EnvironmentID:     envID,
+		ReactionID:        DecayReactionID,
+		ReactionName:      "decay",
+		Timestamp:         time.Now().Unix(),
+		EnvTime:           envTime,
+		ConsumedMolecules: decayed,
+	}, nil)
+}
@@ -0,0 +1,98 @@
+package achem
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnvironment_Step_Decay(t *testing.T) {
+	schema := NewSchema("decay").WithSpecies(Species{Name: "Event"}).WithDecayRate(1)
+	env := NewEnvironment(schema)
+
+	var mu sync.Mutex
+	var events []NotificationEvent
+	env.RegisterCallback("test", func(event NotificationEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	unstable := NewMolecule("Event", nil, 0)
+	unstable.Stability = 0
+	stable := NewMolecule("Event", nil, 0)
+	stable.Stability = 1
+	env.Insert(unstable)
+	env.Insert(stable)
+
+	env.Step()
+
+	if _, ok := env.GetMolecule(unstable.ID); ok {
+		t.Error("Expected the unstable molecule to decay with decay rate 1")
+	}
+	if _, ok := env.GetMolecule(stable.ID); !ok {
+		t.Error("Expected the fully stable molecule to never decay")
+	}
+	if c := env.Counters(false); c.MoleculesConsumed != 1 {
+		t.Errorf("Expected the decayed molecule to be counted as consumed, got %d", c.MoleculesConsumed)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+		if n >= 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].ReactionID != DecayReactionID ||
+		len(events[0].ConsumedMolecules) != 1 || events[0].ConsumedMolecules[0].ID != unstable.ID {
+		t.Errorf("Expected one decay event for the unstable molecule, got %+v", events)
+	}
+}
+
+func TestEnvironment_Step_DecayProbability(t *testing.T) {
+	const n = 2000
+	schema := NewSchema("decay").WithSpecies(Species{Name: "Event"}).WithDecayRate(0.5)
+	env := NewEnvironmentWithSeed(schema, 42)
+	for range n {
+		m := NewMolecule("Event", nil, 0)
+		m.Stability = 0.5
+		env.Insert(m)
+	}
+
+	env.Step()
+
+	// each molecule decays with probability 0.5 * (1 - 0.5) = 0.25
+	remaining := len(env.AllMolecules())
+	if decayed := n - remaining; decayed < 400 || decayed > 600 {
+		t.Errorf("Expected about %d decayed molecules, got %d", n/4, decayed)
+	}
+}
+
+func TestSchema_WithDecayRate(t *testing.T) {
+	if got := NewSchema("s").DecayRate(); got != 0 {
+		t.Errorf("Expected decay disabled by default, got %v", got)
+	}
+	if got := NewSchema("s").WithDecayRate(2).DecayRate(); got != 1 {
+		t.Errorf("Expected decay rate clamped to 1, got %v", got)
+	}
+
+	for _, rate := range []float64{-0.1, 1.5} {
+		cfg := SchemaConfig{Name: "s", Species: []SpeciesConfig{{Name: "A"}}, DecayRate: rate}
+		if err := ValidateSchemaConfig(cfg); err == nil {
+			t.Errorf("Expected decay_rate %v to be rejected", rate)
+		}
+	}
+	schema, err := BuildSchemaFromConfig(SchemaConfig{Name: "s", Species: []SpeciesConfig{{Name: "A"}}, DecayRate: 0.2})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	if schema.DecayRate() != 0.2 {
+		t.Errorf("Expected decay rate 0.2 from config, got %v", schema.DecayRate())
+	}
+}
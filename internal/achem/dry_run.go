@@ -0,0 +1,66 @@
+package achem
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// StepReport describes what a step would do, as computed by StepDryRun.
+type StepReport struct {
+	Time           int64            `json:"time"`            // the tick that was simulated
+	ReactionsFired map[string]int64 `json:"reactions_fired"` // reaction ID -> fires with effects
+	Consumed       []MoleculeID     `json:"consumed"`        // molecules consumed by reactions
+	Decayed        []MoleculeID     `json:"decayed"`         // molecules removed by decay
+	Changed        []Molecule       `json:"changed"`         // molecules updated in place, with their new state
+	Created        []Molecule       `json:"created"`         // new molecules; IDs are assigned on apply
+	Emitted        int              `json:"emitted"`         // molecules routed to other environments
+}
+
+// StepDryRun runs the snapshot and compute phases of Step without applying the
+// results, and reports what the tick would change. The environment is not modified:
+// time doesn't advance, no notifications are sent, and random draws come from a
+// separate source, so the environment's random sequence (and seeded replays) are not
+// affected. For the same reason, probabilistic reactions may fire differently in the
+// next real Step. TTL expiration and eviction, which happen in the apply phase, are
+// not included.
+func (e *Environment) StepDryRun() StepReport {
+	e.mu.RLock()
+	st := e.snapshotLocked(e.time+1, rand.New(rand.NewSource(time.Now().UnixNano())).Float64)
+	e.mu.RUnlock()
+	st.tracer = nil
+	st.newID = nil // don't use up IDs of the generator
+
+	res := e.compute(st, false)
+
+	report := StepReport{
+		Time:           st.ctx.EnvTime,
+		ReactionsFired: res.counters.ReactionsFired,
+		Consumed:       []MoleculeID{},
+		Decayed:        []MoleculeID{},
+		Changed:        []Molecule{},
+		Created:        res.newMolecules,
+		Emitted:        len(res.emitted),
+	}
+
+	decayed := make(map[MoleculeID]struct{}, len(res.decayed))
+	for _, m := range res.decayed {
+		decayed[m.ID] = struct{}{}
+		report.Decayed = append(report.Decayed, m.ID)
+	}
+	for id := range res.consumed {
+		if _, ok := decayed[id]; !ok {
+			report.Consumed = append(report.Consumed, id)
+		}
+	}
+	for id, m := range res.changes {
+		if _, removed := res.consumed[id]; !removed {
+			report.Changed = append(report.Changed, m)
+		}
+	}
+
+	sort.Slice(report.Consumed, func(i, j int) bool { return report.Consumed[i] < report.Consumed[j] })
+	sort.Slice(report.Decayed, func(i, j int) bool { return report.Decayed[i] < report.Decayed[j] })
+	sort.Slice(report.Changed, func(i, j int) bool { return report.Changed[i].ID < report.Changed[j].ID })
+	return report
+}
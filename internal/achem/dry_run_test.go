@@ -0,0 +1,115 @@
+package achem
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func dryRunSchema(t *testing.T) *Schema {
+	t.Helper()
+	one := 1.0
+	schema, err := BuildSchemaFromConfig(SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}, {Name: "Counter"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "escalate",
+				Input:   InputConfig{Species: "Event"},
+				Rate:    1.0,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}},
+				Notify:  &NotificationConfig{Enabled: true, Notifiers: []string{"test"}},
+			},
+			{
+				ID:      "bump",
+				Input:   InputConfig{Species: "Counter"},
+				Rate:    1.0,
+				Effects: []EffectConfig{{Update: &UpdateEffectConfig{EnergyAdd: &one}}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	return schema
+}
+
+func TestEnvironment_StepDryRun(t *testing.T) {
+	env := NewEnvironment(dryRunSchema(t))
+	var notified atomic.Int32
+	env.RegisterCallback("test", func(NotificationEvent) { notified.Add(1) })
+
+	event := Molecule{ID: "e1", Species: "Event"}
+	counter := Molecule{ID: "c1", Species: "Counter", Energy: 2}
+	env.Insert(event)
+	env.Insert(counter)
+
+	report := env.StepDryRun()
+
+	if report.Time != 1 || report.ReactionsFired["escalate"] != 1 || report.ReactionsFired["bump"] != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Consumed) != 1 || report.Consumed[0] != event.ID {
+		t.Errorf("Expected %s to be consumed, got %v", event.ID, report.Consumed)
+	}
+	if len(report.Created) != 1 || report.Created[0].Species != "Alert" {
+		t.Errorf("Expected one Alert to be created, got %+v", report.Created)
+	}
+	if len(report.Changed) != 1 || report.Changed[0].ID != counter.ID || report.Changed[0].Energy != 3 {
+		t.Errorf("Expected %s to be changed to energy 3, got %+v", counter.ID, report.Changed)
+	}
+
+	// nothing was applied
+	if env.Time() != 0 {
+		t.Errorf("Expected time to stay at 0, got %d", env.Time())
+	}
+	if counts := env.SpeciesCounts(); counts["Event"] != 1 || counts["Alert"] != 0 {
+		t.Errorf("Expected molecules to be unchanged, got %v", counts)
+	}
+	if m, _ := env.GetMolecule(counter.ID); m.Energy != 2 {
+		t.Errorf("Expected the counter energy to stay 2, got %v", m.Energy)
+	}
+	if c := env.Counters(false); len(c.ReactionsFired) != 0 {
+		t.Errorf("Expected counters to be unchanged, got %+v", c)
+	}
+
+	// the real step still fires and notifies
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["Event"] != 0 || counts["Alert"] != 1 {
+		t.Errorf("Expected the real step to apply, got %v", counts)
+	}
+	deadline := time.Now().Add(time.Second)
+	for notified.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := notified.Load(); n != 1 {
+		t.Errorf("Expected exactly one notification, from the real step, got %d", n)
+	}
+}
+
+func TestEnvironment_StepDryRun_KeepsRandomSequence(t *testing.T) {
+	schema := NewSchema("dice").WithReactions(&mockReaction{
+		id:           "roll",
+		rate:         0.5,
+		inputPattern: func(m Molecule) bool { return true },
+		apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+			return ReactionEffect{ConsumedIDs: []MoleculeID{m.ID}}
+		},
+	})
+
+	run := func(dryRun bool) map[SpeciesName]int {
+		env := NewEnvironmentWithSeed(schema, 42)
+		for i := 0; i < 50; i++ {
+			env.Insert(NewMolecule("Die", nil, 0))
+		}
+		if dryRun {
+			env.StepDryRun()
+		}
+		env.Step()
+		return env.SpeciesCounts()
+	}
+
+	if a, b := run(false), run(true); a["Die"] != b["Die"] {
+		t.Errorf("Expected a dry run not to change the seeded outcome, got %d and %d", a["Die"], b["Die"])
+	}
+}
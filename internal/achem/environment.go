@@ -1,11 +1,17 @@
 package achem
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,15 +23,58 @@ type Environment struct {
 	schema              *Schema
 	time                int64
 	mols                map[MoleculeID]Molecule
+	version             uint64 // last molecule version handed out, see putMoleculeLocked
 	rand                *rand.Rand
+	seeded              bool  // set by SetRandomSeed: snapshots are ordered deterministically
+	seed                int64 // the seed of rand, if seeded
 	stopCh              chan struct{}
 	isRunning           bool
+	paused              atomic.Bool // checked by the run loop on every tick, see Pause
 	envID               EnvironmentID
+	manager             *EnvironmentManager // owning manager, used to route emitted molecules
 	notifierMgr         *NotificationManager
 	snapshotDir         string
 	snapshotEveryNTicks int
+	snapshotRetention   int  // number of timestamped snapshots kept (0 keeps a single file)
+	snapshotCompression bool // write gzip-compressed snapshots
 	snapshotMu          sync.Mutex
 	logger              Logger
+	metrics             Metrics
+	diffs               []StepDiff    // recent per-tick diffs, oldest first
+	diffHistorySize     int           // max number of retained diffs (0 disables recording)
+	tickCh              chan struct{} // closed and replaced after every tick to wake up watchers
+	insertLimiter       *tokenBucket  // nil means unlimited inserts
+	insertLimit         InsertRateLimit
+	maxMolecules        int        // cap on the total number of molecules (0 means unlimited)
+	evictionPolicy      string     // order in which molecules above maxMolecules are evicted
+	rngTrace            *rngTracer // nil unless RNG tracing is enabled
+	counters            Counters
+	matchCache          bool                            // memoize matching decisions for identical molecules within a tick
+	resetGen            uint64                          // incremented by Reset, so in-flight steps can detect it
+	disabledReactions   map[string]struct{}             // IDs of reactions skipped by Step, see DisableReaction
+	reactionFires       map[string]reactionFires        // cumulative fires per reaction ID, see ReactionStats
+	indexes             map[IndexField]fieldIndex       // persistent secondary indexes, see SetIndexField
+	cooldowns           map[string]map[MoleculeID]int64 // reaction ID -> molecule ID -> last tick of its cooldown
+	newID               func() string                   // generates the IDs of new molecules, see SetIDGenerator
+	tickHooks           []tickHook                      // run after every tick, in registration order, see OnTick
+}
+
+// InsertRateLimit describes the insert rate limit of an environment.
+// A zero PerSecond means inserts are unlimited.
+type InsertRateLimit struct {
+	PerSecond float64 `json:"per_second"`
+	Burst     int     `json:"burst"`
+}
+
+// defaultDiffHistorySize is the number of recent step diffs retained for watchers.
+const defaultDiffHistorySize = 64
+
+// StepDiff describes the changes applied to the environment by a single tick.
+type StepDiff struct {
+	Time     int64        `json:"time"`
+	Created  []Molecule   `json:"created,omitempty"`
+	Consumed []MoleculeID `json:"consumed,omitempty"`
+	Updated  []Molecule   `json:"updated,omitempty"`
 }
 
 // NewEnvironment creates a new environment with the given schema.
@@ -35,6 +84,15 @@ func NewEnvironment(schema *Schema) *Environment {
 	return NewEnvironmentWithLogger(schema, nil)
 }
 
+// NewEnvironmentWithSeed creates a new environment whose random generator is seeded
+// with seed, so that runs from the same initial state are reproducible.
+// See SetRandomSeed.
+func NewEnvironmentWithSeed(schema *Schema, seed int64) *Environment {
+	env := NewEnvironmentWithLogger(schema, nil)
+	env.SetRandomSeed(seed)
+	return env
+}
+
 // NewEnvironmentWithLogger creates a new environment with the given schema and logger.
 // The environment starts at time 0 with no molecules.
 // If logger is nil, a NoOpLogger will be used.
@@ -45,6 +103,7 @@ func NewEnvironmentWithLogger(schema *Schema, logger Logger) *Environment {
 	return &Environment{
 		schema:              schema,
 		mols:                make(map[MoleculeID]Molecule),
+		newID:               NewRandomID,
 		rand:                rand.New(rand.NewSource(time.Now().UnixNano())),
 		time:                0,
 		stopCh:              make(chan struct{}),
@@ -52,6 +111,11 @@ func NewEnvironmentWithLogger(schema *Schema, logger Logger) *Environment {
 		notifierMgr:         NewNotificationManagerWithLogger(logger),
 		snapshotEveryNTicks: 1000, // default value
 		logger:              logger,
+		metrics:             NewNoOpMetrics(),
+		diffHistorySize:     defaultDiffHistorySize,
+		tickCh:              make(chan struct{}),
+		counters:            newCounters(),
+		evictionPolicy:      EvictOldest,
 	}
 }
 
@@ -67,6 +131,18 @@ func (e *Environment) SetLogger(logger Logger) {
 	e.notifierMgr.SetLogger(logger)
 }
 
+// SetMetrics sets the metrics recorder for this environment and its notification manager.
+// If metrics is nil, a NoOpMetrics will be used.
+func (e *Environment) SetMetrics(metrics Metrics) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if metrics == nil {
+		metrics = NewNoOpMetrics()
+	}
+	e.metrics = metrics
+	e.notifierMgr.SetMetrics(metrics)
+}
+
 // SetEnvironmentID sets the environment ID (used for notifications)
 func (e *Environment) SetEnvironmentID(id EnvironmentID) {
 	e.mu.Lock()
@@ -105,6 +181,318 @@ func (e *Environment) SetSnapshotEveryNTicks(n int) {
 	e.snapshotEveryNTicks = n
 }
 
+// SetSnapshotRetention enables snapshot history: each snapshot is written to its own
+// "<envID>.history/<time>.snapshot.json" file and only the n most recent ones are kept.
+// If set to 0 or negative, a single "<envID>.snapshot.json" file is overwritten instead.
+func (e *Environment) SetSnapshotRetention(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	e.snapshotRetention = n
+}
+
+// SetSnapshotCompression enables gzip compression of snapshot files, which are then
+// written with a ".gz" suffix. LoadSnapshot reads both formats regardless of this setting.
+func (e *Environment) SetSnapshotCompression(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshotCompression = enabled
+}
+
+// SetDiffHistorySize sets how many recent step diffs are retained for watchers.
+// If set to 0 or negative, diffs are no longer recorded and the history is cleared.
+func (e *Environment) SetDiffHistorySize(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	e.diffHistorySize = n
+	if len(e.diffs) > n {
+		e.diffs = append([]StepDiff(nil), e.diffs[len(e.diffs)-n:]...)
+	}
+}
+
+// SetInsertRateLimit limits how many molecules per second can be inserted through
+// AllowInsert, with bursts of up to burst molecules. If perSecond is 0 or negative,
+// inserts are unlimited (the default). If burst is less than 1, it defaults to
+// perSecond rounded up.
+func (e *Environment) SetInsertRateLimit(perSecond float64, burst int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if perSecond <= 0 {
+		e.insertLimiter = nil
+		e.insertLimit = InsertRateLimit{}
+		return
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(perSecond))
+	}
+	e.insertLimiter = newTokenBucket(perSecond, burst)
+	e.insertLimit = InsertRateLimit{PerSecond: perSecond, Burst: burst}
+}
+
+// SetMaxMolecules caps the total number of molecules in the environment. At the end
+// of every step, molecules above the cap are evicted according to the eviction policy
+// (see SetEvictionPolicy) and reported like the evictions of capped species. If n is 0
+// or negative, the environment is unbounded (the default).
+func (e *Environment) SetMaxMolecules(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxMolecules = max(n, 0)
+}
+
+// MaxMolecules returns the cap on the total number of molecules (0 means unlimited).
+func (e *Environment) MaxMolecules() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.maxMolecules
+}
+
+// SchemaConfig returns the configuration of the environment's current schema.
+// Returns false if the schema was not built from a SchemaConfig.
+func (e *Environment) SchemaConfig() (SchemaConfig, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.schema.Config()
+}
+
+// orphanedMoleculesLocked counts the molecules, per species, whose species the current
+// schema defines but schema doesn't. Must be called with e.mu held.
+func (e *Environment) orphanedMoleculesLocked(schema *Schema) OrphanedMolecules {
+	orphaned := make(OrphanedMolecules)
+	if e.schema == nil {
+		return orphaned
+	}
+	for _, m := range e.mols {
+		if _, defined := e.schema.Species(m.Species); !defined {
+			continue
+		}
+		if schema != nil {
+			if _, kept := schema.Species(m.Species); kept {
+				continue
+			}
+		}
+		orphaned[m.Species]++
+	}
+	return orphaned
+}
+
+// SetEvictionPolicy sets the order in which molecules above the MaxMolecules cap are
+// evicted: EvictOldest (the default) or EvictLowestEnergy.
+func (e *Environment) SetEvictionPolicy(policy string) error {
+	if policy != EvictOldest && policy != EvictLowestEnergy {
+		return fmt.Errorf("invalid eviction policy '%s' (expected '%s' or '%s')", policy, EvictOldest, EvictLowestEnergy)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.evictionPolicy = policy
+	return nil
+}
+
+// EvictionPolicy returns the order in which molecules above the MaxMolecules cap are evicted.
+func (e *Environment) EvictionPolicy() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.evictionPolicy
+}
+
+// SetRNGTrace enables logging of every random draw made during Step to w, one
+// JSON-encoded RNGTraceEntry per line. Each entry records the tick, reaction,
+// molecule, the drawn value and, for the draw that gates a reaction, the effective
+// rate and whether the reaction fired. Draws made by reactions through
+// ReactionContext.Random and the draws deciding whether molecules decay are logged
+// too. Passing nil disables tracing.
+//
+// Tracing does not change the sequence of draws, so two traced runs with the same
+// seed can be diffed line by line. It is intended for debugging only: every
+// candidate (molecule, reaction) pair produces a line, so the trace grows quickly
+// and the encoding and writing cost dominates Step on large environments.
+func (e *Environment) SetRNGTrace(w io.Writer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if w == nil {
+		e.rngTrace = nil
+		return
+	}
+	e.rngTrace = newRNGTracer(w)
+}
+
+// SetRandomSeed reinitializes the environment's random generator with seed. Besides
+// seeding the draws made during Step, it makes Step process molecules in a
+// deterministic order (by content, then ID) instead of map order, so that two
+// environments seeded alike and loaded with the same molecules evolve identically.
+// Ordering the snapshot adds a sort per tick, so unseeded environments skip it.
+func (e *Environment) SetRandomSeed(seed int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rand = rand.New(rand.NewSource(seed))
+	e.seeded = true
+	e.seed = seed
+}
+
+// SetIDGenerator makes the environment name new molecules (inserted without an ID or
+// created by reactions) with gen instead of NewRandomID, e.g. SequentialIDs("m"), so
+// that IDs are stable across seeded runs and snapshots diff cleanly. gen is called
+// both under the environment lock and from Step without it, so it must be safe for
+// concurrent use and never return the same ID twice. IDs already held by the
+// environment, e.g. loaded from a snapshot, are skipped. Passing nil restores
+// NewRandomID.
+func (e *Environment) SetIDGenerator(gen func() string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if gen == nil {
+		gen = NewRandomID
+	}
+	e.newID = gen
+}
+
+// newIDLocked returns an ID for a new molecule, skipping the IDs already in use: a
+// generator such as SequentialIDs restarts in every process, while the environment may
+// hold molecules restored from a snapshot, imported or cloned. Must be called with e.mu
+// held.
+func (e *Environment) newIDLocked() MoleculeID {
+	return uniqueID(e.newID, e.mols)
+}
+
+// uniqueID calls gen until it returns an ID that is not a key of inUse.
+func uniqueID(gen func() string, inUse map[MoleculeID]Molecule) MoleculeID {
+	for {
+		id := MoleculeID(gen())
+		if _, exists := inUse[id]; !exists {
+			return id
+		}
+	}
+}
+
+// RandomSeed returns the seed last passed to SetRandomSeed, and false if the
+// environment was never seeded.
+func (e *Environment) RandomSeed() (int64, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.seed, e.seeded
+}
+
+// Reset removes all molecules and sets the time back to 0, so that a scenario can be
+// rerun without recreating the environment. The schema, environment ID, notification
+// manager, snapshot, rate limit and secondary index settings are kept; counters,
+// reaction statistics, reaction cooldowns and recorded diffs are cleared. It is safe to call while the
+// environment is running: ticks continue from 0, and a step in progress when Reset is
+// called discards its results. The timestamped snapshots of the retention mode are
+// deleted, since their times would be mistaken for the times of the new run.
+func (e *Environment) Reset() {
+	// hold the snapshot lock throughout, so that no snapshot of the old run is written
+	// once the history is cleared
+	e.snapshotMu.Lock()
+	defer e.snapshotMu.Unlock()
+	e.clearSnapshotHistory()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.mols = make(map[MoleculeID]Molecule)
+	e.rebuildIndexesLocked()
+	e.time = 0
+	e.diffs = nil
+	e.counters = newCounters()
+	e.reactionFires = nil
+	e.cooldowns = nil
+	e.resetGen++
+
+	// wake up watchers so they notice the time went back
+	close(e.tickCh)
+	e.tickCh = make(chan struct{})
+}
+
+// SetMatchCache enables or disables the per-tick matching cache. When enabled, Step
+// computes a content signature for every molecule and reuses the InputPattern and
+// EffectiveRate results of config reactions across molecules with the same species,
+// payload, energy, stability, timestamps and tags. This pays off when many identical
+// molecules are present (e.g. floods of identical events), but adds the cost of the
+// signature for every molecule otherwise. Results are identical to the uncached path.
+func (e *Environment) SetMatchCache(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.matchCache = enabled
+}
+
+// GetInsertRateLimit returns the current insert rate limit configuration.
+func (e *Environment) GetInsertRateLimit() InsertRateLimit {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.insertLimit
+}
+
+// AllowInsert reports whether n molecules may be inserted now according to the insert
+// rate limit, consuming the allowance if so. When rejected, the returned duration is
+// how long the caller should wait before retrying (zero if n exceeds the burst size).
+// Insert itself is never limited: callers ingesting external data (e.g. the HTTP server)
+// are expected to check AllowInsert first.
+func (e *Environment) AllowInsert(n int) (bool, time.Duration) {
+	e.mu.RLock()
+	limiter := e.insertLimiter
+	e.mu.RUnlock()
+
+	if limiter == nil {
+		return true, 0
+	}
+	return limiter.take(n)
+}
+
+// Time returns the current environment time (number of ticks executed).
+func (e *Environment) Time() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.time
+}
+
+// DiffsSince returns the retained step diffs for ticks after the given time, along with
+// the current environment time. The complete flag is false when the history no longer
+// covers every tick since the requested time (the caller should resync with a full read).
+func (e *Environment) DiffsSince(since int64) (diffs []StepDiff, now int64, complete bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	now = e.time
+	if since >= now {
+		return nil, now, true
+	}
+
+	// the gap can be far larger than the retained history, e.g. after an import
+	diffs = make([]StepDiff, 0, min(now-since, int64(len(e.diffs))))
+	for _, d := range e.diffs {
+		if d.Time > since {
+			diffs = append(diffs, d)
+		}
+	}
+	complete = int64(len(diffs)) == now-since
+	return diffs, now, complete
+}
+
+// WaitForTick blocks until the environment time advances past since, or ctx is done.
+// Returns true if the environment is past since, false if ctx expired first.
+func (e *Environment) WaitForTick(ctx context.Context, since int64) bool {
+	for {
+		e.mu.RLock()
+		now := e.time
+		ch := e.tickCh
+		e.mu.RUnlock()
+
+		if now > since {
+			return true
+		}
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
 // envView is a private adapter that exposes read-only methods
 type envView struct {
 	molecules []Molecule
@@ -112,6 +500,12 @@ type envView struct {
 
 	// Optional: per-tick index to speed up simple equality where filters
 	bySpeciesFieldValue map[SpeciesName]map[string]map[string][]Molecule
+
+	now int64 // time of the tick being computed
+}
+
+func (v envView) EnvTime() int64 {
+	return v.now
 }
 
 func (v envView) MoleculesBySpecies(species SpeciesName) []Molecule {
@@ -147,21 +541,300 @@ func (v envView) Find(filter func(Molecule) bool) []Molecule {
 	return out
 }
 
+func (v envView) FindBest(species SpeciesName, less func(a, b Molecule) bool) (Molecule, bool) {
+	if v.bySpecies == nil {
+		return findBest(v.Find(func(m Molecule) bool { return m.Species == species }), less)
+	}
+	return findBest(v.bySpecies[species], less)
+}
+
 func (e *Environment) now() int64 {
 	return e.time
 }
 
-func (e *Environment) Insert(m Molecule) {
+// InsertOption customizes how Insert, InsertBatch and Upsert store new molecules.
+type InsertOption func(*insertConfig)
+
+type insertConfig struct {
+	speciesDefaults bool
+}
+
+// WithSpeciesDefaults replaces the energy and stability of new molecules with the
+// defaults of their species, where declared (see Species.DefaultEnergy). Use it when the
+// caller didn't choose these values, e.g. for molecules built by NewMolecule.
+func WithSpeciesDefaults() InsertOption {
+	return func(c *insertConfig) {
+		c.speciesDefaults = true
+	}
+}
+
+func newInsertConfig(opts []InsertOption) insertConfig {
+	var c insertConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Insert adds m to the environment, assigning an ID and timestamps when missing.
+// Returns an error, without inserting m, if its payload doesn't conform to the fields
+// declared by its species (see Schema.ValidateMolecule).
+func (e *Environment) Insert(m Molecule, opts ...InsertOption) error {
+	cfg := newInsertConfig(opts)
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	if err := e.schema.ValidateMolecule(m); err != nil {
+		return err
+	}
 	if m.ID == "" {
-		m.ID = MoleculeID(NewRandomID())
+		m.ID = e.newIDLocked()
 	}
 	if m.CreatedAt == 0 {
 		m.CreatedAt = e.now()
 		m.LastTouchedAt = e.now()
 	}
-	e.mols[m.ID] = m
+	if cfg.speciesDefaults {
+		e.schema.applySpeciesDefaults(&m)
+	}
+	e.putMoleculeLocked(m)
+	return nil
+}
+
+// InsertBatch inserts all mols under a single write lock, assigning IDs and timestamps
+// like Insert. Returns the IDs of the inserted molecules, in the same order as mols.
+// If any molecule is invalid (see Insert), none is inserted.
+func (e *Environment) InsertBatch(mols []Molecule, opts ...InsertOption) ([]MoleculeID, error) {
+	cfg := newInsertConfig(opts)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, m := range mols {
+		if err := e.schema.ValidateMolecule(m); err != nil {
+			return nil, fmt.Errorf("molecule at index %d: %w", i, err)
+		}
+	}
+
+	ids := make([]MoleculeID, len(mols))
+	for i, m := range mols {
+		if m.ID == "" {
+			m.ID = e.newIDLocked()
+		}
+		if m.CreatedAt == 0 {
+			m.CreatedAt = e.now()
+			m.LastTouchedAt = e.now()
+		}
+		if cfg.speciesDefaults {
+			e.schema.applySpeciesDefaults(&m)
+		}
+		e.putMoleculeLocked(m)
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+// Upsert inserts m unless a live molecule of the same species already has the same
+// payload values for all the match fields, in which case that molecule's payload is
+// replaced by m's (keeping its ID, energy and creation time) and it is touched.
+// If several molecules match, the oldest one is updated. Returns the stored molecule
+// and whether it was inserted. Numeric match values are compared with the schema's
+// float tolerance, like in reaction conditions. Like Insert, m must conform to the
+// fields declared by its species, and opts apply to an inserted molecule.
+func (e *Environment) Upsert(m Molecule, match []string, opts ...InsertOption) (Molecule, bool, error) {
+	if len(match) == 0 {
+		return Molecule{}, false, fmt.Errorf("upsert requires at least one match field")
+	}
+	where := make(WhereConfig, len(match))
+	for _, field := range match {
+		v, ok := m.Payload[field]
+		if !ok {
+			return Molecule{}, false, fmt.Errorf("match field %q is missing from the payload", field)
+		}
+		where[field] = EqCondition{Eq: v}
+	}
+
+	cfg := newInsertConfig(opts)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.schema.ValidateMolecule(m); err != nil {
+		return Molecule{}, false, err
+	}
+
+	var existing *Molecule
+	for _, candidate := range e.mols {
+		if candidate.Species != m.Species || !matchWhere(where, candidate, m, e.schema.floatTolerance()) {
+			continue
+		}
+		if existing == nil || candidate.CreatedAt < existing.CreatedAt ||
+			(candidate.CreatedAt == existing.CreatedAt && candidate.ID < existing.ID) {
+			existing = &candidate
+		}
+	}
+
+	if existing == nil {
+		if m.ID == "" {
+			m.ID = e.newIDLocked()
+		}
+		if m.CreatedAt == 0 {
+			m.CreatedAt = e.now()
+			m.LastTouchedAt = e.now()
+		}
+		if cfg.speciesDefaults {
+			e.schema.applySpeciesDefaults(&m)
+		}
+		e.putMoleculeLocked(m)
+		return m, true, nil
+	}
+
+	updated := *existing
+	updated.Payload = m.Payload
+	updated.LastTouchedAt = e.now()
+	e.putMoleculeLocked(updated)
+	return updated, false, nil
+}
+
+// ErrMoleculeNotFound is returned by UpdateMoleculeCAS when the molecule doesn't exist.
+var ErrMoleculeNotFound = errors.New("molecule not found")
+
+// UpdateMoleculeCAS atomically replaces the molecule with the given ID by fn(molecule),
+// provided its Version still equals expectedVersion, so that an external
+// read-modify-write doesn't overwrite changes made by reactions (or other writers) in
+// the meantime. Returns false, without calling fn, if the molecule was written since.
+// fn can't change the molecule's ID or creation time. The update touches the molecule
+// (LastTouchedAt becomes the environment time) and gives it a new version. A tick
+// computing in the meantime drops its changes to the molecule rather than overwrite
+// the update. Returns ErrMoleculeNotFound if the molecule doesn't exist, and the
+// validation error, without storing it, if the updated molecule doesn't conform to the
+// fields declared by its species (see Schema.ValidateMolecule).
+func (e *Environment) UpdateMoleculeCAS(id MoleculeID, expectedVersion uint64, fn func(Molecule) Molecule) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	m, ok := e.mols[id]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrMoleculeNotFound, id)
+	}
+	if m.Version != expectedVersion {
+		return false, nil
+	}
+
+	updated := fn(cloneMolecule(m))
+	updated.ID = m.ID
+	updated.CreatedAt = m.CreatedAt
+	if err := e.schema.ValidateMolecule(updated); err != nil {
+		return false, err
+	}
+	updated.LastTouchedAt = e.now()
+	e.putMoleculeLocked(updated)
+	return true, nil
+}
+
+// syncVersionLocked makes the versions handed out next greater than the version of
+// every molecule, after molecules were stored with their own versions (e.g. restored
+// from a snapshot). Must be called with e.mu held for writing.
+func (e *Environment) syncVersionLocked() {
+	for _, m := range e.mols {
+		e.version = max(e.version, m.Version)
+	}
+}
+
+// QueryOption adds a filter to QueryMolecules.
+type QueryOption func(*moleculeQuery)
+
+// moleculeQuery holds the optional filters of QueryMolecules
+type moleculeQuery struct {
+	olderThan *int64
+	newerThan *int64
+}
+
+// OlderThan keeps the molecules created more than ticks ticks before the current
+// environment time.
+func OlderThan(ticks int64) QueryOption {
+	return func(q *moleculeQuery) {
+		q.olderThan = &ticks
+	}
+}
+
+// NewerThan keeps the molecules created less than ticks ticks before the current
+// environment time.
+func NewerThan(ticks int64) QueryOption {
+	return func(q *moleculeQuery) {
+		q.newerThan = &ticks
+	}
+}
+
+// matchAge reports whether m passes the age filters at time now
+func (q moleculeQuery) matchAge(m Molecule, now int64) bool {
+	age := now - m.CreatedAt
+	if q.olderThan != nil && age <= *q.olderThan {
+		return false
+	}
+	if q.newerThan != nil && age >= *q.newerThan {
+		return false
+	}
+	return true
+}
+
+// QueryMolecules returns the molecules of the given species (any species if empty)
+// matching where and opts, sorted by CreatedAt then ID so that results can be paged
+// deterministically. Equality conditions on fields indexed with SetIndexField are
+// served from the index. Ages are computed against the environment time under the same
+// lock as the scan, so all the molecules are filtered at the same time.
+func (e *Environment) QueryMolecules(species SpeciesName, where WhereConfig, opts ...QueryOption) []Molecule {
+	var q moleculeQuery
+	for _, opt := range opts {
+		opt(&q)
+	}
+
+	e.mu.RLock()
+	now := e.now()
+	out := make([]Molecule, 0)
+	keep := func(m Molecule) {
+		if species != "" && m.Species != species {
+			return
+		}
+		if q.matchAge(m, now) && matchWhere(where, m, Molecule{}, DefaultFloatTolerance) {
+			out = append(out, m)
+		}
+	}
+	if candidates, indexed := e.indexedCandidatesLocked(species, where, DefaultFloatTolerance); indexed {
+		for _, m := range candidates {
+			keep(m)
+		}
+	} else {
+		for _, m := range e.mols {
+			keep(m)
+		}
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// GetMolecule returns the molecule with the given ID, if present.
+func (e *Environment) GetMolecule(id MoleculeID) (Molecule, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	m, ok := e.mols[id]
+	return m, ok
+}
+
+// DeleteMolecule removes the molecule with the given ID.
+// Returns false if there was no such molecule.
+func (e *Environment) DeleteMolecule(id MoleculeID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.mols[id]; !ok {
+		return false
+	}
+	e.deleteMoleculeLocked(id)
+	return true
 }
 
 func (e *Environment) AllMolecules() []Molecule {
@@ -196,15 +869,263 @@ func (e *Environment) UnregisterCallback(id string) {
 // The apply phase is where we reconcile data, and apply all those changes to the environment.
 // Since we are working on the actual environment, we need to lock it again.
 func (e *Environment) Step() {
+	e.step(nil)
+}
+
+// step runs a single tick, then the tick hooks (see OnTick). If sum is not nil, the
+// applied changes are added to it.
+func (e *Environment) step(sum *StepSummary) {
+	hooks := e.tickHookFuncs()
+	if len(hooks) == 0 {
+		e.tick(sum, nil, false)
+		return
+	}
+
+	tick := newStepSummary(e.Time())
+	e.tick(&tick, nil, false)
+	if tick.Ticks == 0 {
+		return // the environment was reset during the tick
+	}
+	if sum != nil {
+		sum.add(tick)
+	}
+	for _, hook := range hooks {
+		hook(tick.Time, tick)
+	}
+}
+
+// tick runs the phases of a single tick. If sum is not nil, the applied changes are
+// added to it. st is the snapshot of the tick, if it was already taken by the previous
+// one. With chain, the snapshot of the next tick is taken before releasing the lock of
+// the apply phase and returned, so that consecutive ticks take the lock once each;
+// unless molecules were emitted, as they must be delivered before the next snapshot, or
+// the environment is being saved.
+func (e *Environment) tick(sum *StepSummary, st *tickState, chain bool) *tickState {
 	// 1) SNAPSHOT PHASE (under lock)
+	if st == nil {
+		e.mu.Lock()
+		e.time++
+		st = e.snapshotLocked(e.time, e.rand.Float64)
+		e.mu.Unlock()
+	}
+
+	// 2) COMPUTE PHASE (no lock)
+	res := e.compute(st, true)
+	consumed, changes, newMolecules, emitted := res.consumed, res.changes, res.newMolecules, res.emitted
+	tickCounters, decayed := res.counters, res.decayed
+	envID, notifierMgr, manager, gen := st.envID, st.notifierMgr, st.manager, st.gen
+
+	// 3) APPLY PHASE (under lock again)
 	e.mu.Lock()
-	e.time++
 
+	// the environment was reset during the compute phase: the results are stale
+	if e.resetGen != gen {
+		e.mu.Unlock()
+		return nil
+	}
+
+	recordDiff := e.diffHistorySize > 0
+	diff := StepDiff{Time: e.time}
+
+	// 3.0 - expire molecules whose TTL elapsed; they are gone before any effect applies
+	expired := e.expireLocked()
+	var expiredIDs map[MoleculeID]struct{}
+	if len(expired) > 0 {
+		expiredIDs = make(map[MoleculeID]struct{})
+	}
+	for _, batch := range expired {
+		tickCounters.MoleculesExpired += int64(len(batch.molecules))
+		for _, m := range batch.molecules {
+			expiredIDs[m.ID] = struct{}{}
+			if recordDiff {
+				diff.Consumed = append(diff.Consumed, m.ID)
+			}
+		}
+	}
+
+	// 3.1 - remove consumed molecules, decayed ones included
+	var decayedIDs map[MoleculeID]struct{}
+	if sum != nil && len(decayed) > 0 {
+		decayedIDs = make(map[MoleculeID]struct{}, len(decayed))
+		for _, m := range decayed {
+			decayedIDs[m.ID] = struct{}{}
+		}
+	}
+	for id := range consumed {
+		if m, exists := e.mols[id]; exists {
+			tickCounters.MoleculesConsumed++
+			if _, decay := decayedIDs[id]; sum != nil && !decay {
+				sum.Consumed[m.Species]++
+			}
+			if recordDiff {
+				diff.Consumed = append(diff.Consumed, id)
+			}
+		}
+		e.deleteMoleculeLocked(id)
+	}
+
+	// 3.2 - apply changes, unless the molecule was written or deleted during the compute
+	// phase: the change was computed from a stale state and would overwrite that write.
+	// Changes that make the molecule not conform to its species are dropped.
+	for id, m := range changes {
+		if _, removed := consumed[id]; removed {
+			continue
+		}
+		if _, gone := expiredIDs[id]; gone {
+			continue
+		}
+		if cur, ok := e.mols[id]; !ok || cur.Version != st.snapshotByID[id].Version {
+			continue
+		}
+		if err := e.schema.ValidateMolecule(m); err != nil {
+			e.logger.Warnf("update failed: env_id=%s id=%s error=%v", envID, id, err)
+			continue
+		}
+		e.putMoleculeLocked(m)
+		if recordDiff {
+			diff.Updated = append(diff.Updated, m)
+		}
+	}
+
+	// 3.3 - insert new molecules, dropping those that don't conform to their species
+	created := 0
+	for _, nm := range newMolecules {
+		if err := e.schema.ValidateMolecule(nm); err != nil {
+			e.logger.Warnf("create failed: env_id=%s error=%v", envID, err)
+			continue
+		}
+		created++
+		if nm.ID == "" {
+			nm.ID = e.newIDLocked()
+		}
+		if nm.CreatedAt == 0 {
+			nm.CreatedAt = e.time
+			nm.LastTouchedAt = e.time
+		}
+		e.putMoleculeLocked(nm)
+		if recordDiff {
+			diff.Created = append(diff.Created, nm)
+		}
+		if sum != nil {
+			sum.Created[nm.Species]++
+		}
+	}
+
+	tickCounters.MoleculesCreated = int64(created)
+
+	// 3.4 - evict the excess of capped species, then of the environment
+	evicted := e.evictExcessLocked()
+	evicted = append(evicted, e.evictOverCapLocked()...)
+	for _, batch := range evicted {
+		tickCounters.MoleculesEvicted += int64(len(batch.molecules))
+	}
+	if recordDiff && len(evicted) > 0 {
+		diff = diff.withEvicted(evicted)
+	}
+
+	e.counters.add(tickCounters)
+	e.recordReactionFiresLocked(tickCounters.ReactionsFired, e.time)
+	e.startCooldownsLocked(res.cooldowns)
+	metrics := e.metrics
+	if sum != nil {
+		sum.Ticks++
+		sum.Time = e.time
+		for id, n := range tickCounters.ReactionsFired {
+			sum.ReactionsFired[id] += n
+		}
+	}
+
+	// 3.5 - record the diff and wake up watchers
+	if recordDiff {
+		e.diffs = append(e.diffs, diff)
+		if len(e.diffs) > e.diffHistorySize {
+			e.diffs = e.diffs[len(e.diffs)-e.diffHistorySize:]
+		}
+	}
+	close(e.tickCh)
+	e.tickCh = make(chan struct{})
+
+	// 4) SNAPSHOT PHASE (if needed, non-blocking)
+	saving := e.snapshotDir != "" && e.snapshotEveryNTicks > 0 && e.time%int64(e.snapshotEveryNTicks) == 0
+	if saving {
+		go e.SaveSnapshot()
+	}
+
+	// the snapshot saved in the background must not see the time of the next tick
+	var next *tickState
+	if chain && len(emitted) == 0 && !saving {
+		e.time++
+		next = e.snapshotLocked(e.time, e.rand.Float64)
+	}
+
+	e.mu.Unlock()
+
+	metrics.ObserveStep(envID, tickCounters)
+
+	if len(expired) > 0 {
+		notifyExpired(expired, envID, diff.Time, notifierMgr)
+	}
+	if len(evicted) > 0 {
+		notifyEvicted(evicted, envID, diff.Time, notifierMgr)
+	}
+	if len(decayed) > 0 {
+		notifyDecayed(decayed, envID, diff.Time, notifierMgr)
+	}
+
+	// 5) EMIT PHASE (no lock): deliver molecules routed to other environments.
+	// This must run without holding e.mu, since inserting takes the target's lock
+	// (which may be this same environment).
+	if len(emitted) > 0 {
+		e.routeEmitted(emitted, envID, manager)
+	}
+	return next
+}
+
+// tickState is the private copy of the environment taken by the snapshot phase of a
+// step, along with everything the compute phase needs.
+type tickState struct {
+	snapshot     []Molecule
+	snapshotByID map[MoleculeID]Molecule
+	view         envView
+	ctx          ReactionContext
+	reactions    []Reaction
+	prioritized  bool                   // the schema uses priorities: consumed molecules leave the remaining reactions
+	maxFires     []int                  // per reaction index, 0 = unlimited
+	cooldown     []int64                // per reaction index, 0 = none
+	cooling      []map[MoleculeID]int64 // per reaction index, molecule ID -> last tick of its cooldown
+	decayRate    float64
+	cache        *matchCache
+
+	envID       EnvironmentID
+	notifierMgr *NotificationManager
+	manager     *EnvironmentManager
+	tracer      *rngTracer
+	newID       func() string // names the molecules created by the tick; nil leaves them unnamed
+	gen         uint64
+}
+
+// tickResult holds the changes computed for a tick, to be applied to the environment.
+type tickResult struct {
+	consumed     map[MoleculeID]struct{}
+	changes      map[MoleculeID]Molecule
+	newMolecules []Molecule
+	emitted      []EmittedMolecule
+	counters     Counters
+	decayed      []Molecule
+	cooldowns    map[string]map[MoleculeID]int64 // cooldowns started by the tick, see startCooldownsLocked
+}
+
+// snapshotLocked copies the molecules and builds the per-tick indexes for a step at
+// time now, drawing random numbers from random. Must be called with e.mu held.
+func (e *Environment) snapshotLocked(now int64, random func() float64) *tickState {
 	// snapshot
 	snapshot := make([]Molecule, 0, len(e.mols))
 	for _, m := range e.mols {
 		snapshot = append(snapshot, m)
 	}
+	if e.seeded {
+		sortForReplay(snapshot)
+	}
 
 	// build per-species index for fast lookup
 	bySpecies := make(map[SpeciesName][]Molecule)
@@ -246,27 +1167,70 @@ func (e *Environment) Step() {
 		molecules:           snapshot,
 		bySpecies:           bySpecies,
 		bySpeciesFieldValue: bySpeciesFieldValue,
+		now:                 now,
 	}
 
 	ctx := ReactionContext{
-		EnvTime: e.time,
-		Random:  e.rand.Float64,
+		EnvTime: now,
+		Random:  random,
 	}
 
-	// capture reactions once (schema is immutable once loaded)
-	reactions := e.schema.Reactions()
+	// capture reactions once (schema is immutable once loaded), higher priority first,
+	// skipping the disabled ones
+	reactions := e.enabledReactionsLocked(sortReactionsByPriority(e.schema.Reactions()))
+	prioritized := usesPriorities(e.schema.Reactions())
+	decayRate := e.schema.DecayRate()
+	maxFires := make([]int, len(reactions))
+	for i, r := range reactions {
+		maxFires[i] = reactionMaxFiresPerTick(r)
+	}
+	cooldown, cooling := e.coolingLocked(reactions)
+
+	var cache *matchCache
+	if e.matchCache {
+		cache = newMatchCache(reactions)
+	}
 
 	// capture envID and notifierMgr for use in compute phase (to avoid data races)
-	envID := e.envID
-	notifierMgr := e.notifierMgr
+	return &tickState{
+		snapshot:     snapshot,
+		snapshotByID: snapshotByID,
+		view:         view,
+		ctx:          ctx,
+		reactions:    reactions,
+		prioritized:  prioritized,
+		maxFires:     maxFires,
+		cooldown:     cooldown,
+		cooling:      cooling,
+		decayRate:    decayRate,
+		cache:        cache,
+		envID:        e.envID,
+		notifierMgr:  e.notifierMgr,
+		manager:      e.manager,
+		tracer:       e.rngTrace,
+		newID:        e.newID,
+		gen:          e.resetGen,
+	}
 
-	e.mu.Unlock()
+}
+
+// compute applies the reactions to the snapshot and collects the resulting changes,
+// without touching the environment. Notifications are sent only if notify is true.
+func (e *Environment) compute(st *tickState, notify bool) tickResult {
+	snapshot, snapshotByID, view, ctx := st.snapshot, st.snapshotByID, st.view, st.ctx
+	reactions, maxFires, decayRate, cache, tracer := st.reactions, st.maxFires, st.decayRate, st.cache, st.tracer
+	cooldown, cooling := st.cooldown, st.cooling
 
-	// 2) COMPUTE PHASE (no lock)
 	consumed := make(map[MoleculeID]struct{})
+	ctx.consumed = consumed // partners consumed earlier in the tick can't be consumed again
 	consumedMolecules := make(map[MoleculeID]Molecule)
 	changes := make(map[MoleculeID]Molecule)
 	newMolecules := make([]Molecule, 0)
+	emitted := make([]EmittedMolecule, 0)
+	tickCounters := newCounters()
+	fires := make([]int, len(reactions)) // per reaction index, to enforce MaxFiresPerTick
+	var decayed []Molecule
+	var cooldowns map[string]map[MoleculeID]int64
 
 	for _, m := range snapshot {
 		// skip molecules already marked as consumed
@@ -274,82 +1238,179 @@ func (e *Environment) Step() {
 			continue
 		}
 
-		for _, r := range reactions {
-			if !r.InputPattern(m) {
+		// unstable molecules may decay before reacting; they are removed like consumed ones
+		if decayRate > 0 {
+			draw, probability := ctx.Random(), decayProbability(m, decayRate)
+			decays := draw < probability
+			if tracer != nil {
+				tracer.decay(ctx.EnvTime, m, draw, probability, decays)
+			}
+			if decays {
+				consumed[m.ID] = struct{}{}
+				decayed = append(decayed, m)
 				continue
 			}
+		}
 
-			// Use effective rate (base rate + catalyst effects)
-			effectiveRate := r.EffectiveRate(m, view)
-			if ctx.Random() > effectiveRate {
-				continue
+		// a requeued molecule re-enters the reaction loop within this tick, with its
+		// pending changes applied, up to the bound requested by its effects
+		for pass := 0; ; pass++ {
+			requeue := 0
+
+			var sig string
+			var sigOK bool
+			if cache != nil {
+				sig, sigOK = moleculeSignature(m)
 			}
 
-			eff := r.Apply(m, view, ctx)
+			for i, r := range reactions {
+				// with priorities, a consumed molecule is no longer available to lower-priority
+				// reactions; without, every matching reaction sees it, as it always has
+				if _, ok := consumed[m.ID]; ok && st.prioritized {
+					break
+				}
+				if maxFires[i] > 0 && fires[i] >= maxFires[i] {
+					continue
+				}
+				if until, ok := cooling[i][m.ID]; ok && ctx.EnvTime <= until {
+					continue
+				}
+				if !cache.inputPattern(i, r, m, sig, sigOK) {
+					continue
+				}
 
-			// Check if reaction produced any effects (non-empty effect)
-			hasEffects := len(eff.ConsumedIDs) > 0 || len(eff.Changes) > 0 || len(eff.NewMolecules) > 0
+				// Use effective rate (base rate + catalyst effects)
+				effectiveRate := cache.effectiveRate(i, r, m, view, sig, sigOK)
+				draw := ctx.Random()
+				fired := draw <= effectiveRate
+				if tracer != nil {
+					tracer.gate(ctx.EnvTime, r, m, draw, effectiveRate, fired)
+				}
+				if !fired {
+					continue
+				}
 
-			// collect consumed molecules using the snapshot, not e.mols
-			for _, id := range eff.ConsumedIDs {
-				if mol, exists := snapshotByID[id]; exists {
-					consumedMolecules[id] = mol
+				applyCtx := ctx
+				if tracer != nil {
+					applyCtx.Random = tracer.wrap(ctx.Random, ctx.EnvTime, r, m)
+				}
+				eff := r.Apply(m, view, applyCtx)
+				if st.newID != nil {
+					// name the new molecules now, so that notifications carry their final IDs
+					for j := range eff.NewMolecules {
+						if eff.NewMolecules[j].ID == "" {
+							eff.NewMolecules[j].ID = uniqueID(st.newID, st.snapshotByID)
+						}
+					}
 				}
-			}
 
-			// Send notification if reaction fired and has effects
-			if hasEffects {
-				e.sendNotificationWithContext(r, m, view, eff, ctx, consumedMolecules, envID, notifierMgr)
-			}
+				// Check if reaction produced any effects (non-empty effect)
+				hasEffects := len(eff.ConsumedIDs) > 0 || len(eff.Changes) > 0 || len(eff.NewMolecules) > 0 || len(eff.Emitted) > 0
 
-			// mark consumed
-			for _, id := range eff.ConsumedIDs {
-				consumed[id] = struct{}{}
-			}
+				// collect consumed molecules using the snapshot, not e.mols
+				for _, id := range eff.ConsumedIDs {
+					if mol, exists := snapshotByID[id]; exists {
+						consumedMolecules[id] = mol
+					}
+				}
 
-			// apply changes (last-wins)
-			for _, ch := range eff.Changes {
-				if ch.Updated != nil {
-					changes[ch.ID] = *ch.Updated
+				// Send notification if reaction fired and has effects
+				if hasEffects {
+					fires[i]++
+					if cooldown[i] > 0 {
+						until := ctx.EnvTime + cooldown[i]
+						cooling[i][m.ID] = until
+						if cooldowns == nil {
+							cooldowns = make(map[string]map[MoleculeID]int64)
+						}
+						if cooldowns[r.ID()] == nil {
+							cooldowns[r.ID()] = make(map[MoleculeID]int64)
+						}
+						cooldowns[r.ID()][m.ID] = until
+					}
+					tickCounters.ReactionsFired[r.ID()]++
+					if notify {
+						switch e.sendNotificationWithContext(r, m, view, eff, ctx, consumedMolecules, st.envID, st.notifierMgr) {
+						case notificationEnqueued:
+							tickCounters.Notifications++
+						case notificationDropped:
+							tickCounters.NotificationsDropped++
+						}
+					}
 				}
-			}
 
-			newMolecules = append(newMolecules, eff.NewMolecules...)
-		}
-	}
+				// mark consumed
+				for _, id := range eff.ConsumedIDs {
+					consumed[id] = struct{}{}
+				}
 
-	// 3) APPLY PHASE (under lock again)
-	e.mu.Lock()
-	defer e.mu.Unlock()
+				// apply changes (last-wins)
+				for _, ch := range eff.Changes {
+					if ch.Updated != nil {
+						changes[ch.ID] = *ch.Updated
+					}
+				}
 
-	// 3.1 - remove consumed molecules
-	for id := range consumed {
-		delete(e.mols, id)
-	}
+				newMolecules = append(newMolecules, eff.NewMolecules...)
+				emitted = append(emitted, eff.Emitted...)
+				requeue = max(requeue, eff.Requeue)
+			}
 
-	// 3.2 - apply changes
-	for id, m := range changes {
-		if _, removed := consumed[id]; removed {
-			continue
+			if pass >= min(requeue, MaxRequeueIterations) {
+				break
+			}
+			if _, ok := consumed[m.ID]; ok {
+				break
+			}
+			if updated, ok := changes[m.ID]; ok {
+				m = updated
+			}
 		}
-		e.mols[id] = m
 	}
 
-	// 3.3 - insert new molecules
-	for _, nm := range newMolecules {
-		if nm.ID == "" {
-			nm.ID = MoleculeID(NewRandomID())
-		}
-		if nm.CreatedAt == 0 {
-			nm.CreatedAt = e.time
-			nm.LastTouchedAt = e.time
-		}
-		e.mols[nm.ID] = nm
+	return tickResult{
+		consumed:     consumed,
+		changes:      changes,
+		newMolecules: newMolecules,
+		emitted:      emitted,
+		counters:     tickCounters,
+		decayed:      decayed,
+		cooldowns:    cooldowns,
 	}
+}
 
-	// 4) SNAPSHOT PHASE (if needed, non-blocking)
-	if e.snapshotDir != "" && e.snapshotEveryNTicks > 0 && e.time%int64(e.snapshotEveryNTicks) == 0 {
-		go e.SaveSnapshot()
+// routeEmitted inserts emitted molecules into each of their target environments.
+// Missing targets are logged and skipped so that one bad target doesn't block the others.
+func (e *Environment) routeEmitted(emitted []EmittedMolecule, envID EnvironmentID, manager *EnvironmentManager) {
+	for _, em := range emitted {
+		for _, target := range em.Targets {
+			var targetEnv *Environment
+			switch {
+			case target == envID:
+				targetEnv = e
+			case manager != nil:
+				if env, ok := manager.GetEnvironment(target); ok {
+					targetEnv = env
+				}
+			}
+
+			if targetEnv == nil {
+				e.logger.Warnf("emit failed: env_id=%s target=%s error=target environment not found", envID, target)
+				continue
+			}
+
+			// each target gets its own copy, stamped with the target's clock
+			m := em.Molecule
+			m.Payload = make(map[string]any, len(em.Molecule.Payload))
+			for k, v := range em.Molecule.Payload {
+				m.Payload[k] = v
+			}
+			m.CreatedAt = 0
+			m.LastTouchedAt = 0
+			if err := targetEnv.Insert(m); err != nil {
+				e.logger.Warnf("emit failed: env_id=%s target=%s error=%v", envID, target, err)
+			}
+		}
 	}
 }
 
@@ -357,35 +1418,99 @@ func (e *Environment) Step() {
 // run until the stop channel is closed. It can be called multiple times to restart
 // after stopping.
 func (e *Environment) Run(interval time.Duration) {
+	e.startLoop(func(stopCh <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if !e.paused.Load() {
+					e.Step()
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	})
+}
+
+// RunAligned is like Run, but aligns ticks to wall-clock boundaries instead of
+// firing interval after the call: a tick fires whenever the time since the Unix epoch,
+// minus offset, is a multiple of interval. For example RunAligned(time.Minute, 0)
+// steps exactly on every minute and RunAligned(time.Hour, 15*time.Minute) at quarter
+// past every hour. Alignment is recomputed after each tick, so slow steps and
+// restarts never shift the schedule. Does nothing if interval is not positive.
+func (e *Environment) RunAligned(interval, offset time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	e.startLoop(func(stopCh <-chan struct{}) {
+		next := nextAlignedTick(time.Now(), interval, offset)
+		timer := time.NewTimer(time.Until(next))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				if !e.paused.Load() {
+					e.Step()
+				}
+				// never schedule the same boundary twice, even if the step was fast
+				next = nextAlignedTick(maxTime(time.Now(), next), interval, offset)
+				timer.Reset(time.Until(next))
+			case <-stopCh:
+				return
+			}
+		}
+	})
+}
+
+// startLoop marks the environment as running and runs loop in a goroutine until
+// the stop channel is closed. It is a no-op if the environment is already running.
+func (e *Environment) startLoop(loop func(stopCh <-chan struct{})) {
 	e.mu.Lock()
 	if e.isRunning {
 		e.mu.Unlock()
 		return
 	}
 	// Create a new stop channel for this run (allows restart after stop)
-	e.stopCh = make(chan struct{})
+	stopCh := make(chan struct{})
+	e.stopCh = stopCh
 	e.isRunning = true
 	e.mu.Unlock()
 
 	// Run in a goroutine so it doesn't block the caller
 	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				e.Step()
-			case <-e.stopCh:
-				e.mu.Lock()
-				e.isRunning = false
-				e.mu.Unlock()
-				return
-			}
-		}
+		loop(stopCh)
+		e.mu.Lock()
+		e.isRunning = false
+		e.mu.Unlock()
 	}()
 }
 
+// nextAlignedTick returns the first instant strictly after now where the time since
+// the Unix epoch, minus offset, is a multiple of interval.
+func nextAlignedTick(now time.Time, interval, offset time.Duration) time.Time {
+	iv := int64(interval)
+	off := int64(offset) % iv
+	n := now.UnixNano() - off
+	// floor division, so that instants before the epoch align too
+	q := n / iv
+	if n%iv < 0 {
+		q--
+	}
+	return time.Unix(0, (q+1)*iv+off)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
 // Stop will stop the environment by closing the stop channel.
 // After stopping, Run() can be called again to restart.
 func (e *Environment) Stop() {
@@ -400,13 +1525,46 @@ func (e *Environment) Stop() {
 	close(e.stopCh)
 }
 
+// IsRunning reports whether the environment is auto-running (see Run and RunAligned).
+func (e *Environment) IsRunning() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isRunning
+}
+
+// Pause makes the run loop skip ticks until Resume is called, without stopping it: the
+// interval (or alignment) is kept and ticks resume on the same schedule. Manual calls
+// to Step are not affected. The paused state survives Stop and Run.
+func (e *Environment) Pause() {
+	e.paused.Store(true)
+}
+
+// Resume lets the run loop step again after Pause.
+func (e *Environment) Resume() {
+	e.paused.Store(false)
+}
+
+// IsPaused reports whether the run loop is paused.
+func (e *Environment) IsPaused() bool {
+	return e.paused.Load()
+}
+
+// notificationOutcome is what became of the notification of a fired reaction
+type notificationOutcome int
+
+const (
+	notificationSkipped  notificationOutcome = iota // the reaction doesn't notify anyone
+	notificationEnqueued                            // the event was enqueued
+	notificationDropped                             // the event was lost, the queue being full or closed
+)
+
 // sendNotificationWithContext sends a notification using the provided envID and notifierMgr
 // This version is safe to call without holding the environment lock
-func (e *Environment) sendNotificationWithContext(r Reaction, m Molecule, view EnvView, eff ReactionEffect, ctx ReactionContext, consumedMolecules map[MoleculeID]Molecule, envID EnvironmentID, notifierMgr *NotificationManager) {
+func (e *Environment) sendNotificationWithContext(r Reaction, m Molecule, view EnvView, eff ReactionEffect, ctx ReactionContext, consumedMolecules map[MoleculeID]Molecule, envID EnvironmentID, notifierMgr *NotificationManager) notificationOutcome {
 	// Get notification config from reaction if it's a ConfigReaction
 	notifyCfg := e.getNotificationConfig(r)
 	if notifyCfg == nil || !notifyCfg.Enabled {
-		return
+		return notificationSkipped
 	}
 
 	// Check if there are callbacks registered - if so, we should enqueue even without notifiers
@@ -414,11 +1572,11 @@ func (e *Environment) sendNotificationWithContext(r Reaction, m Molecule, view E
 
 	// If there are no notifiers and no callbacks, skip enqueuing
 	if len(notifyCfg.Notifiers) == 0 && !hasCallbacks {
-		return
+		return notificationSkipped
 	}
 
 	// Find partners if this was a partner-based reaction
-	partners := e.findPartnersForNotification(r, m, view)
+	partners := e.findPartnersForNotification(r, m, view, ctx)
 
 	// Collect consumed molecules for the notification
 	consumed := make([]Molecule, 0, len(eff.ConsumedIDs))
@@ -440,7 +1598,10 @@ func (e *Environment) sendNotificationWithContext(r Reaction, m Molecule, view E
 	)
 
 	// Enqueue notification for async processing (non-blocking)
-	notifierMgr.Enqueue(event, notifyCfg.Notifiers)
+	if !notifierMgr.Enqueue(event, notifyCfg.Notifiers) {
+		return notificationDropped
+	}
+	return notificationEnqueued
 }
 
 // getNotificationConfig extracts notification config from a reaction
@@ -454,51 +1615,63 @@ func (e *Environment) getNotificationConfig(r Reaction) *NotificationConfig {
 	return nil
 }
 
-// findPartnersForNotification finds partners that were used in the reaction
-func (e *Environment) findPartnersForNotification(r Reaction, m Molecule, view EnvView) []Molecule {
+// reactionPriority returns the priority of r; reactions without one have priority 0
+func reactionPriority(r Reaction) int {
 	if cr, ok := r.(*ConfigReaction); ok {
-		partners := make([]Molecule, 0)
-		for _, partnerCfg := range cr.cfg.Input.Partners {
-			// Use the findPartners function from config_schema_builder
-			foundPartners := findPartnersForReaction(partnerCfg, m, view)
-			partners = append(partners, foundPartners...)
-		}
-		return partners
+		return cr.cfg.Priority
 	}
-	return nil
+	return 0
 }
 
-// findPartnersForReaction is a wrapper to access the private findPartners function
-// We need to make findPartners accessible or create a public wrapper
-func findPartnersForReaction(partnerCfg PartnerConfig, m Molecule, env EnvView) []Molecule {
-	// Get all molecules of the specified species that match where conditions
-	candidates := filterBySpeciesAndWhere(env, SpeciesName(partnerCfg.Species), partnerCfg.Where, m)
-
-	// Filter out the molecule itself
-	var matches []Molecule
-	for _, candidate := range candidates {
-		if candidate.ID != m.ID {
-			matches = append(matches, candidate)
+// usesPriorities reports whether some reaction has a non-zero priority
+func usesPriorities(reactions []Reaction) bool {
+	for _, r := range reactions {
+		if reactionPriority(r) != 0 {
+			return true
 		}
 	}
+	return false
+}
 
-	// Return up to the required count
-	count := partnerCfg.Count
-	if count <= 0 {
-		count = 1 // default
+// sortReactionsByPriority returns the reactions ordered by descending priority. Ties keep
+// declaration order. The input slice is returned as is when no reaction has a priority.
+func sortReactionsByPriority(reactions []Reaction) []Reaction {
+	if !usesPriorities(reactions) {
+		return reactions
 	}
-	if len(matches) > count {
-		return matches[:count]
+
+	sorted := make([]Reaction, len(reactions))
+	copy(sorted, reactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return reactionPriority(sorted[i]) > reactionPriority(sorted[j])
+	})
+	return sorted
+}
+
+// reactionMaxFiresPerTick returns the per-tick fire limit of r, or 0 if unlimited
+func reactionMaxFiresPerTick(r Reaction) int {
+	if cr, ok := r.(*ConfigReaction); ok {
+		return cr.cfg.MaxFiresPerTick
 	}
-	return matches
+	return 0
+}
+
+// findPartnersForNotification finds partners that were used in the reaction
+func (e *Environment) findPartnersForNotification(r Reaction, m Molecule, view EnvView, ctx ReactionContext) []Molecule {
+	if cr, ok := r.(*ConfigReaction); ok {
+		group, _ := cr.matchInputGroup(m, view, ctx)
+		partners, _ := cr.matchPartners(m, group, view, ctx)
+		return partners
+	}
+	return nil
 }
 
 // SnapshotPath returns the file path for the snapshot based on the environment ID.
-// Format: "<SnapshotDir>/<envID>.snapshot.json"
+// Format: "<SnapshotDir>/<envID>.snapshot.json", with a ".gz" suffix when compression is enabled.
 func (e *Environment) SnapshotPath() string {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	return filepath.Join(e.snapshotDir, string(e.envID)+".snapshot.json")
+	return filepath.Join(e.snapshotDir, string(e.envID)+snapshotFileSuffix+e.snapshotExtLocked())
 }
 
 // createSnapshot creates a snapshot of the current environment state.
@@ -512,11 +1685,17 @@ func (e *Environment) createSnapshot() (Snapshot, error) {
 		molecules = append(molecules, m)
 	}
 
-	return Snapshot{
+	snapshot := Snapshot{
 		EnvironmentID: e.envID,
 		Time:          e.time,
 		Molecules:     molecules,
-	}, nil
+	}
+	if e.schema != nil {
+		if cfg, ok := e.schema.Config(); ok {
+			snapshot.Schema = &cfg
+		}
+	}
+	return snapshot, nil
 }
 
 // SaveSnapshot saves the current environment state to disk atomically.
@@ -532,6 +1711,19 @@ func (e *Environment) SaveSnapshot() error {
 		return nil // Snapshot disabled, silently skip
 	}
 
+	start := time.Now()
+	err := e.writeSnapshot()
+
+	e.mu.RLock()
+	metrics, envID := e.metrics, e.envID
+	e.mu.RUnlock()
+	metrics.ObserveSnapshot(envID, time.Since(start), err)
+	return err
+}
+
+// writeSnapshot captures the environment state and writes it to its snapshot file.
+// Must be called with e.snapshotMu held.
+func (e *Environment) writeSnapshot() error {
 	// Create snapshot
 	snapshot, err := e.createSnapshot()
 	if err != nil {
@@ -547,7 +1739,14 @@ func (e *Environment) SaveSnapshot() error {
 	}
 
 	// Get snapshot path
+	e.mu.RLock()
+	retention := e.snapshotRetention
+	compress := e.snapshotCompression
+	e.mu.RUnlock()
 	path := e.SnapshotPath()
+	if retention > 0 {
+		path = e.timestampedSnapshotPath(snapshot.Time)
+	}
 
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
@@ -557,7 +1756,8 @@ func (e *Environment) SaveSnapshot() error {
 
 	// Write atomically using temp file + rename
 	tempPath := path + ".tmp"
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+	if err := writeSnapshotFile(tempPath, data, compress); err != nil {
+		os.Remove(tempPath)
 		e.logger.Errorf("snapshot failed: failed to write temp file: %v", err)
 		return err
 	}
@@ -569,16 +1769,26 @@ func (e *Environment) SaveSnapshot() error {
 		return err
 	}
 
+	if retention > 0 {
+		e.pruneSnapshots(retention)
+	}
+
 	e.logger.Infof("snapshot created: env_id=%s time=%d molecules=%d path=%s", snapshot.EnvironmentID, snapshot.Time, len(snapshot.Molecules), path)
 	return nil
 }
 
 // LoadSnapshot loads a snapshot from disk and restores the environment state.
-// If the snapshot directory is not configured or the snapshot file does not exist, this is a no-op and returns nil.
+// The most recent timestamped snapshot (see SetSnapshotRetention) is loaded if there is one,
+// otherwise the single "<envID>.snapshot.json" file.
+// If the snapshot directory is not configured or no snapshot file exists, this is a no-op and returns nil.
 // The snapshot is validated to ensure:
 //   - The snapshot's EnvironmentID matches the environment's ID
 //   - All molecule species exist in the schema
 //
+// If the environment has no schema and the snapshot recorded one, the schema is rebuilt
+// from the snapshot's configuration. Otherwise the environment's schema is kept, and the
+// snapshot's is ignored.
+//
 // On success, the environment's time and molecules are restored from the snapshot.
 func (e *Environment) LoadSnapshot() error {
 	// Check if snapshot directory is configured
@@ -591,15 +1801,15 @@ func (e *Environment) LoadSnapshot() error {
 	}
 
 	// Get snapshot path
-	path := e.SnapshotPath()
+	path := e.LatestSnapshotPath()
 
 	// Check if file exists - if not, no-op
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil // Snapshot doesn't exist, nothing to load
 	}
 
-	// Read snapshot file
-	data, err := os.ReadFile(path)
+	// Read snapshot file (decompressing it if needed)
+	data, err := ReadSnapshotFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read snapshot file: %w", err)
 	}
@@ -620,6 +1830,15 @@ func (e *Environment) LoadSnapshot() error {
 		return fmt.Errorf("snapshot environment ID mismatch: expected %s, got %s", envID, snapshot.EnvironmentID)
 	}
 
+	// Rebuild the schema recorded in the snapshot if the environment has none
+	restoredSchema := false
+	if schema == nil && snapshot.Schema != nil {
+		if schema, err = BuildSchemaFromConfig(*snapshot.Schema); err != nil {
+			return fmt.Errorf("failed to rebuild schema from snapshot: %w", err)
+		}
+		restoredSchema = true
+	}
+
 	// Validate snapshot (checks species exist in schema)
 	if err := ValidateSnapshot(snapshot, schema); err != nil {
 		return fmt.Errorf("snapshot validation failed: %w", err)
@@ -630,13 +1849,36 @@ func (e *Environment) LoadSnapshot() error {
 	defer e.mu.Unlock()
 
 	e.time = snapshot.Time
+	if restoredSchema && e.schema == nil {
+		e.schema = schema
+	}
 
 	// Restore molecules
 	e.mols = make(map[MoleculeID]Molecule, len(snapshot.Molecules))
 	for _, m := range snapshot.Molecules {
 		e.mols[m.ID] = m
 	}
+	e.syncVersionLocked()
+	e.rebuildIndexesLocked()
 
 	e.logger.Infof("snapshot loaded: env_id=%s time=%d molecules=%d path=%s", snapshot.EnvironmentID, snapshot.Time, len(snapshot.Molecules), path)
 	return nil
 }
+
+// sortForReplay orders molecules by content, falling back to ID for identical ones.
+// Molecule IDs are random, so ordering by content keeps seeded runs reproducible:
+// molecules that only differ by ID are interchangeable.
+func sortForReplay(mols []Molecule) {
+	keys := make(map[MoleculeID]string, len(mols))
+	for _, m := range mols {
+		sig, _ := moleculeSignature(m)
+		keys[m.ID] = sig
+	}
+	sort.Slice(mols, func(i, j int) bool {
+		ki, kj := keys[mols[i].ID], keys[mols[j].ID]
+		if ki != kj {
+			return ki < kj
+		}
+		return mols[i].ID < mols[j].ID
+	})
+}
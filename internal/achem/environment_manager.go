@@ -2,6 +2,9 @@ package achem
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 )
 
@@ -13,6 +16,7 @@ type EnvironmentManager struct {
 	mu           sync.RWMutex
 	environments map[EnvironmentID]*Environment
 	logger       Logger
+	metrics      Metrics
 }
 
 // NewEnvironmentManager creates a new environment manager.
@@ -30,6 +34,7 @@ func NewEnvironmentManagerWithLogger(logger Logger) *EnvironmentManager {
 	return &EnvironmentManager{
 		environments: make(map[EnvironmentID]*Environment),
 		logger:       logger,
+		metrics:      NewNoOpMetrics(),
 	}
 }
 
@@ -43,6 +48,20 @@ func (em *EnvironmentManager) SetLogger(logger Logger) {
 	em.logger = logger
 }
 
+// SetMetrics sets the metrics recorder for this manager's environments, including
+// the ones already created. If metrics is nil, a NoOpMetrics will be used.
+func (em *EnvironmentManager) SetMetrics(metrics Metrics) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	if metrics == nil {
+		metrics = NewNoOpMetrics()
+	}
+	em.metrics = metrics
+	for _, env := range em.environments {
+		env.SetMetrics(metrics)
+	}
+}
+
 // CreateEnvironment creates a new environment with the given ID and schema
 // Returns an error if an environment with that ID already exists
 // After creating the environment, it attempts to load a snapshot if one exists.
@@ -56,6 +75,8 @@ func (em *EnvironmentManager) CreateEnvironment(id EnvironmentID, schema *Schema
 
 	env := NewEnvironmentWithLogger(schema, em.logger)
 	env.SetEnvironmentID(id)
+	env.SetMetrics(em.metrics)
+	env.manager = em
 
 	// Attempt to load snapshot (no-op if snapshot doesn't exist)
 	if err := env.LoadSnapshot(); err != nil {
@@ -68,6 +89,30 @@ func (em *EnvironmentManager) CreateEnvironment(id EnvironmentID, schema *Schema
 	return nil
 }
 
+// CloneEnvironment creates the environment dst as a copy of src (see Environment.Clone).
+// The clone starts stopped, with its own notification manager. Returns an error if src
+// doesn't exist or dst already exists.
+func (em *EnvironmentManager) CloneEnvironment(src, dst EnvironmentID) error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	source, exists := em.environments[src]
+	if !exists {
+		return fmt.Errorf("environment with id %s does not exist", src)
+	}
+	if _, exists := em.environments[dst]; exists {
+		return fmt.Errorf("environment with id %s already exists", dst)
+	}
+
+	env := source.Clone()
+	env.SetEnvironmentID(dst)
+	env.SetMetrics(em.metrics)
+	env.manager = em
+
+	em.environments[dst] = env
+	return nil
+}
+
 // GetEnvironment retrieves an environment by ID
 // Returns the environment and a boolean indicating if it was found
 func (em *EnvironmentManager) GetEnvironment(id EnvironmentID) (*Environment, bool) {
@@ -111,18 +156,56 @@ func (em *EnvironmentManager) ListEnvironments() []EnvironmentID {
 // UpdateEnvironmentSchema updates the schema of an existing environment
 // This will replace the schema but keep all existing molecules
 func (em *EnvironmentManager) UpdateEnvironmentSchema(id EnvironmentID, schema *Schema) error {
+	_, err := em.UpdateEnvironmentSchemaChecked(id, schema, false)
+	return err
+}
+
+// UpdateEnvironmentSchemaChecked updates the schema of an existing environment like
+// UpdateEnvironmentSchema, and returns the number of molecules, per species, whose
+// species the current schema defines but the new one doesn't. Such molecules are kept,
+// but the new schema no longer defines their species. If strict is set and
+// molecules would be orphaned, the schema is not replaced and an
+// *OrphanedMoleculesError is returned.
+func (em *EnvironmentManager) UpdateEnvironmentSchemaChecked(id EnvironmentID, schema *Schema, strict bool) (OrphanedMolecules, error) {
 	em.mu.RLock()
 	env, exists := em.environments[id]
 	em.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("environment with id %s does not exist", id)
+		return nil, fmt.Errorf("environment with id %s does not exist", id)
 	}
 
-	// Update the schema (we're in the same package, so we can access the field directly)
 	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	orphaned := env.orphanedMoleculesLocked(schema)
+	if strict && len(orphaned) > 0 {
+		return orphaned, &OrphanedMoleculesError{Orphaned: orphaned}
+	}
 	env.schema = schema
-	env.mu.Unlock()
+	return orphaned, nil
+}
 
-	return nil
+// OrphanedMolecules counts, per species, the molecules left without a species
+// definition by a schema update
+type OrphanedMolecules map[SpeciesName]int
+
+// String lists the species and counts, e.g. "Alert (3), Event (12)"
+func (o OrphanedMolecules) String() string {
+	species := slices.Sorted(maps.Keys(o))
+	parts := make([]string, len(species))
+	for i, sp := range species {
+		parts[i] = fmt.Sprintf("%s (%d)", sp, o[sp])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// OrphanedMoleculesError is returned by strict schema updates that would leave
+// molecules of removed species in the environment
+type OrphanedMoleculesError struct {
+	Orphaned OrphanedMolecules
+}
+
+func (e *OrphanedMoleculesError) Error() string {
+	return "schema update would orphan molecules of removed species: " + e.Orphaned.String()
 }
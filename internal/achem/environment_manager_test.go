@@ -1,10 +1,12 @@
 package achem
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewEnvironmentManager(t *testing.T) {
@@ -324,6 +326,52 @@ func TestEnvironmentManager_UpdateEnvironmentSchema_PreservesMolecules(t *testin
 	}
 }
 
+func TestEnvironmentManager_UpdateEnvironmentSchemaChecked(t *testing.T) {
+	em := NewEnvironmentManager()
+	envID := EnvironmentID("test-env")
+	schema1 := NewSchema("schema-1").WithSpecies(Species{Name: "A"}, Species{Name: "B"}, Species{Name: "C"})
+	if err := em.CreateEnvironment(envID, schema1); err != nil {
+		t.Fatalf("Expected no error creating environment, got: %v", err)
+	}
+	env, _ := em.GetEnvironment(envID)
+	env.InsertBatch([]Molecule{
+		NewMolecule("A", nil, 0),
+		NewMolecule("B", nil, 0),
+		NewMolecule("B", nil, 0),
+		NewMolecule("Undeclared", nil, 0), // not defined by the old schema either
+	})
+
+	schema2 := NewSchema("schema-2").WithSpecies(Species{Name: "A"})
+
+	// strict updates are rejected, leaving the schema in place
+	orphaned, err := em.UpdateEnvironmentSchemaChecked(envID, schema2, true)
+	var orphanErr *OrphanedMoleculesError
+	if !errors.As(err, &orphanErr) {
+		t.Fatalf("Expected an OrphanedMoleculesError, got: %v", err)
+	}
+	if orphanErr.Error() != "schema update would orphan molecules of removed species: B (2)" {
+		t.Errorf("Unexpected error message: %v", orphanErr)
+	}
+	if len(orphaned) != 1 || orphaned["B"] != 2 {
+		t.Errorf("Expected 2 orphaned B molecules, got %v", orphaned)
+	}
+	if env.schema != schema1 {
+		t.Error("Expected the schema not to change on a rejected update")
+	}
+
+	// lenient updates report the orphans and keep the molecules
+	orphaned, err = em.UpdateEnvironmentSchemaChecked(envID, schema2, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(orphaned) != 1 || orphaned["B"] != 2 {
+		t.Errorf("Expected 2 orphaned B molecules, got %v", orphaned)
+	}
+	if env.schema != schema2 || len(env.AllMolecules()) != 4 {
+		t.Error("Expected the schema to be updated and the molecules kept")
+	}
+}
+
 func TestEnvironmentManager_ConcurrentAccess(t *testing.T) {
 	em := NewEnvironmentManager()
 	schema := NewSchema("test-schema")
@@ -529,3 +577,178 @@ func TestEnvironmentManager_CreateEnvironment_SnapshotLoadFailure(t *testing.T)
 		t.Fatal("Expected error when loading snapshot with mismatched env ID")
 	}
 }
+
+func TestEnvironmentManager_EmitToMany_FansOut(t *testing.T) {
+	em := NewEnvironmentManager()
+
+	cfg := SchemaConfig{
+		Name:    "source",
+		Species: []SpeciesConfig{{Name: "Event"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "broadcast",
+				Input: InputConfig{Species: "Event"},
+				Rate:  1.0,
+				Effects: []EffectConfig{
+					{Consume: true},
+					{
+						Create: &CreateEffectConfig{
+							Species:    "Alert",
+							Payload:    map[string]any{"ip": "$m.ip"},
+							EmitToMany: []string{"team-a", "team-b", "missing"},
+						},
+					},
+				},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	teamSchema := NewSchema("team").WithSpecies(Species{Name: "Alert"})
+	for _, id := range []EnvironmentID{"source", "team-a", "team-b"} {
+		s := teamSchema
+		if id == "source" {
+			s = schema
+		}
+		if err := em.CreateEnvironment(id, s); err != nil {
+			t.Fatalf("CreateEnvironment(%s) failed: %v", id, err)
+		}
+	}
+
+	source, _ := em.GetEnvironment("source")
+	source.Insert(NewMolecule("Event", map[string]any{"ip": "1.2.3.4"}, 0))
+	source.Step()
+
+	if n := len(source.AllMolecules()); n != 0 {
+		t.Errorf("Expected source environment to be empty, got %d molecules", n)
+	}
+
+	for _, id := range []EnvironmentID{"team-a", "team-b"} {
+		env, _ := em.GetEnvironment(id)
+		mols := env.AllMolecules()
+		if len(mols) != 1 {
+			t.Fatalf("Expected 1 molecule in %s, got %d", id, len(mols))
+		}
+		if mols[0].Species != "Alert" || mols[0].Payload["ip"] != "1.2.3.4" {
+			t.Errorf("Unexpected molecule in %s: %+v", id, mols[0])
+		}
+	}
+
+	// each target must own its payload
+	a, _ := em.GetEnvironment("team-a")
+	b, _ := em.GetEnvironment("team-b")
+	a.AllMolecules()[0].Payload["ip"] = "changed"
+	if b.AllMolecules()[0].Payload["ip"] != "1.2.3.4" {
+		t.Error("Expected emitted copies to have independent payloads")
+	}
+}
+
+func TestEnvironmentManager_EmitTo_Self(t *testing.T) {
+	em := NewEnvironmentManager()
+
+	cfg := SchemaConfig{
+		Name:    "self",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "a_to_b",
+				Input: InputConfig{Species: "A"},
+				Rate:  1.0,
+				Effects: []EffectConfig{
+					{Consume: true},
+					{Create: &CreateEffectConfig{Species: "B", EmitTo: "self"}},
+				},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	if err := em.CreateEnvironment("self", schema); err != nil {
+		t.Fatalf("CreateEnvironment failed: %v", err)
+	}
+
+	env, _ := em.GetEnvironment("self")
+	env.Insert(NewMolecule("A", map[string]any{}, 0))
+	env.Step()
+
+	mols := env.AllMolecules()
+	if len(mols) != 1 || mols[0].Species != "B" {
+		t.Fatalf("Expected a single B molecule, got %+v", mols)
+	}
+	if mols[0].CreatedAt != 1 {
+		t.Errorf("Expected emitted molecule to be stamped with env time 1, got %d", mols[0].CreatedAt)
+	}
+}
+
+func TestEnvironmentManager_CloneEnvironment(t *testing.T) {
+	em := NewEnvironmentManager()
+	schema := NewSchema("test-schema").WithSpecies(Species{Name: "Event"})
+	if err := em.CreateEnvironment("src", schema); err != nil {
+		t.Fatalf("Failed to create environment: %v", err)
+	}
+	src, _ := em.GetEnvironment("src")
+	src.SetMaxMolecules(10)
+	m := NewMolecule("Event", map[string]any{"ip": "1.2.3.4", "ports": []any{22.0, 80.0}}, 0)
+	m.Tags = []string{"a"}
+	src.Insert(m)
+	src.Step()
+	src.Step()
+	src.Run(time.Hour)
+	defer src.Stop()
+
+	if err := em.CloneEnvironment("missing", "dst"); err == nil {
+		t.Error("Expected an error when the source doesn't exist")
+	}
+	if err := em.CloneEnvironment("src", "src"); err == nil {
+		t.Error("Expected an error when the destination already exists")
+	}
+	if err := em.CloneEnvironment("src", "dst"); err != nil {
+		t.Fatalf("CloneEnvironment failed: %v", err)
+	}
+
+	dst, exists := em.GetEnvironment("dst")
+	if !exists {
+		t.Fatal("Expected the clone to be registered")
+	}
+	if dst.schema != schema || dst.Time() != 2 || dst.MaxMolecules() != 10 {
+		t.Errorf("Expected same schema, time 2 and cap 10, got time %d cap %d", dst.Time(), dst.MaxMolecules())
+	}
+	if dst.isRunning {
+		t.Error("Expected the clone to start stopped")
+	}
+	if dst.stopCh == src.stopCh || dst.GetNotificationManager() == src.GetNotificationManager() {
+		t.Error("Expected the clone to have its own stop channel and notification manager")
+	}
+	if dst.envID != "dst" || dst.manager != em {
+		t.Errorf("Expected the clone to be owned by the manager as dst, got %s", dst.envID)
+	}
+
+	// mutating one side doesn't affect the other
+	cloned, ok := dst.GetMolecule(m.ID)
+	if !ok {
+		t.Fatal("Expected the molecule to be copied")
+	}
+	cloned.Payload["ip"] = "5.6.7.8"
+	cloned.Payload["ports"].([]any)[0] = 443.0
+	cloned.Tags[0] = "b"
+	dst.Insert(NewMolecule("Event", nil, 0))
+
+	original, _ := src.GetMolecule(m.ID)
+	if original.Payload["ip"] != "1.2.3.4" || original.Payload["ports"].([]any)[0] != 22.0 || original.Tags[0] != "a" {
+		t.Errorf("Expected the source molecule to be untouched, got %+v", original)
+	}
+	if n := len(src.AllMolecules()); n != 1 {
+		t.Errorf("Expected 1 molecule in the source, got %d", n)
+	}
+	if !src.DeleteMolecule(m.ID) {
+		t.Fatal("Failed to delete the source molecule")
+	}
+	if _, ok := dst.GetMolecule(m.ID); !ok {
+		t.Error("Expected the clone to keep its copy after the source molecule is deleted")
+	}
+}
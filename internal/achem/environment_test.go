@@ -1,9 +1,16 @@
 package achem
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -112,6 +119,265 @@ func TestEnvironment_Insert_Multiple(t *testing.T) {
 	}
 }
 
+func TestEnvironment_InsertBatch(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.Step()
+	env.Step()
+
+	ids, err := env.InsertBatch([]Molecule{
+		{Species: "A", Payload: map[string]any{"n": 1}},
+		{ID: "fixed", Species: "B"},
+		NewMolecule("C", nil, 0),
+	})
+	if err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+
+	if len(ids) != 3 || ids[1] != "fixed" || ids[0] == "" {
+		t.Fatalf("Expected 3 IDs with the given one kept, got %v", ids)
+	}
+	for i, id := range ids {
+		m, ok := env.GetMolecule(id)
+		if !ok {
+			t.Fatalf("Expected molecule %d to be inserted", i)
+		}
+		if m.CreatedAt != 2 || m.LastTouchedAt != 2 {
+			t.Errorf("Expected timestamps to be set to the current time, got %+v", m)
+		}
+	}
+	if m, _ := env.GetMolecule(ids[0]); m.Species != "A" {
+		t.Errorf("Expected IDs in input order, got %+v", m)
+	}
+}
+
+func TestEnvironment_Upsert(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.Step()
+
+	first, inserted, err := env.Upsert(NewMolecule("Threshold", map[string]any{"metric": "cpu", "limit": 80}, 0), []string{"metric"})
+	if err != nil || !inserted {
+		t.Fatalf("Expected first upsert to insert, got inserted=%v err=%v", inserted, err)
+	}
+
+	env.Step()
+	updated, inserted, err := env.Upsert(NewMolecule("Threshold", map[string]any{"metric": "cpu", "limit": 90}, 0), []string{"metric"})
+	if err != nil || inserted {
+		t.Fatalf("Expected second upsert to update, got inserted=%v err=%v", inserted, err)
+	}
+	if updated.ID != first.ID || updated.Payload["limit"] != 90 {
+		t.Errorf("Expected molecule %s to be updated with limit 90, got %+v", first.ID, updated)
+	}
+	if updated.CreatedAt != first.CreatedAt || updated.LastTouchedAt != env.Time() {
+		t.Errorf("Expected creation time to be kept and the molecule touched, got %+v", updated)
+	}
+
+	// different match value or species inserts
+	if _, inserted, _ := env.Upsert(NewMolecule("Threshold", map[string]any{"metric": "mem", "limit": 70}, 0), []string{"metric"}); !inserted {
+		t.Error("Expected upsert with a different match value to insert")
+	}
+	if _, inserted, _ := env.Upsert(NewMolecule("Other", map[string]any{"metric": "cpu"}, 0), []string{"metric"}); !inserted {
+		t.Error("Expected upsert of another species to insert")
+	}
+	if n := len(env.AllMolecules()); n != 3 {
+		t.Errorf("Expected 3 molecules, got %d", n)
+	}
+
+	if _, _, err := env.Upsert(NewMolecule("Threshold", map[string]any{}, 0), []string{"metric"}); err == nil {
+		t.Error("Expected error for a match field missing from the payload")
+	}
+	if _, _, err := env.Upsert(NewMolecule("Threshold", map[string]any{"metric": "cpu"}, 0), nil); err == nil {
+		t.Error("Expected error without match fields")
+	}
+}
+
+func TestEnvironment_Upsert_SchemaTolerance(t *testing.T) {
+	tolerance := 0.01
+	schema, err := BuildSchemaFromConfig(SchemaConfig{
+		Name:           "test",
+		Species:        []SpeciesConfig{{Name: "Threshold"}},
+		FloatTolerance: &tolerance,
+	})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	first, _, err := env.Upsert(NewMolecule("Threshold", map[string]any{"level": 0.5, "limit": 80}, 0), []string{"level"})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	updated, inserted, err := env.Upsert(NewMolecule("Threshold", map[string]any{"level": 0.505, "limit": 90}, 0), []string{"level"})
+	if err != nil || inserted || updated.ID != first.ID {
+		t.Errorf("Expected a level within the schema tolerance to update %s, got %+v inserted=%v err=%v", first.ID, updated, inserted, err)
+	}
+	if _, inserted, _ := env.Upsert(NewMolecule("Threshold", map[string]any{"level": 0.52}, 0), []string{"level"}); !inserted {
+		t.Error("Expected a level outside the schema tolerance to insert")
+	}
+}
+
+func TestEnvironment_UpdateMoleculeCAS(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.Insert(Molecule{ID: "m", Species: "Counter", CreatedAt: 1, LastTouchedAt: 3, Payload: map[string]any{"n": 1.0}})
+	env.time = 5
+
+	bump := func(m Molecule) Molecule {
+		m.Payload["n"] = m.Payload["n"].(float64) + 1
+		m.ID = "renamed" // ignored
+		return m
+	}
+
+	v1, _ := env.GetMolecule("m")
+	if v1.Version == 0 {
+		t.Fatal("Expected the inserted molecule to have a version")
+	}
+	if ok, err := env.UpdateMoleculeCAS("m", v1.Version+1, bump); ok || err != nil {
+		t.Errorf("Expected a wrong version to be rejected, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := env.UpdateMoleculeCAS("m", v1.Version, bump); !ok || err != nil {
+		t.Fatalf("Expected the update to apply, got ok=%v err=%v", ok, err)
+	}
+	v2, _ := env.GetMolecule("m")
+	if v2.Payload["n"] != 2.0 || v2.CreatedAt != 1 || v2.LastTouchedAt != 5 || v2.Version <= v1.Version {
+		t.Errorf("Expected n=2 touched at 5 with a new version, got %+v", v2)
+	}
+	if _, exists := env.GetMolecule("renamed"); exists {
+		t.Error("Expected the ID not to change")
+	}
+
+	// a second update within the same tick still gets a new version
+	if ok, _ := env.UpdateMoleculeCAS("m", v2.Version, bump); !ok {
+		t.Fatal("Expected the second update to apply")
+	}
+	if ok, _ := env.UpdateMoleculeCAS("m", v2.Version, bump); ok {
+		t.Error("Expected the version read before the second update to be stale")
+	}
+	v3, _ := env.GetMolecule("m")
+	if v3.Payload["n"] != 3.0 || v3.Version <= v2.Version {
+		t.Errorf("Expected n=3 with a new version, got %+v", v3)
+	}
+
+	// any other write, here by a reaction, also makes the version stale
+	env.Step()
+	if ok, _ := env.UpdateMoleculeCAS("m", v3.Version, bump); !ok {
+		t.Fatal("Expected a tick without changes to keep the version")
+	}
+	v4, _ := env.GetMolecule("m")
+	env.mu.Lock()
+	env.putMoleculeLocked(v4)
+	env.mu.Unlock()
+	if ok, _ := env.UpdateMoleculeCAS("m", v4.Version, bump); ok {
+		t.Error("Expected the version to change on every write")
+	}
+
+	if _, err := env.UpdateMoleculeCAS("missing", 0, bump); err == nil {
+		t.Error("Expected an error for a missing molecule")
+	}
+}
+
+func TestEnvironment_Step_KeepsConcurrentWrites(t *testing.T) {
+	// the reaction's apply runs during the compute phase, without the lock: an update
+	// made meanwhile must not be overwritten by the change computed from the snapshot
+	var env *Environment
+	r := &mockReaction{
+		id:           "r",
+		rate:         1.0,
+		inputPattern: func(m Molecule) bool { return m.Species == "Counter" },
+		apply: func(m Molecule, _ EnvView, _ ReactionContext) ReactionEffect {
+			if _, err := env.UpdateMoleculeCAS(m.ID, m.Version, func(c Molecule) Molecule {
+				c.Payload = map[string]any{"n": 10.0}
+				return c
+			}); err != nil {
+				t.Errorf("UpdateMoleculeCAS failed: %v", err)
+			}
+			updated := cloneMolecule(m)
+			updated.Payload = map[string]any{"n": 2.0}
+			return ReactionEffect{Changes: []MoleculeChange{{ID: m.ID, Updated: &updated}}}
+		},
+	}
+	env = NewEnvironment(NewSchema("test").WithReactions(r))
+	env.Insert(Molecule{ID: "m", Species: "Counter", Payload: map[string]any{"n": 1.0}})
+
+	env.Step()
+	if m, _ := env.GetMolecule("m"); m.Payload["n"] != 10.0 {
+		t.Errorf("Expected the concurrent update to win, got %v", m.Payload)
+	}
+}
+
+func TestEnvironment_GetAndDeleteMolecule(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	m := NewMolecule("A", map[string]any{"k": "v"}, 0)
+	env.Insert(m)
+
+	got, ok := env.GetMolecule(m.ID)
+	if !ok || got.ID != m.ID || got.Payload["k"] != "v" {
+		t.Fatalf("Expected to get molecule %s, got %+v (found=%v)", m.ID, got, ok)
+	}
+	if _, ok := env.GetMolecule("missing"); ok {
+		t.Error("Expected missing molecule not to be found")
+	}
+
+	if !env.DeleteMolecule(m.ID) {
+		t.Error("Expected delete to report the molecule as removed")
+	}
+	if env.DeleteMolecule(m.ID) {
+		t.Error("Expected second delete to report nothing removed")
+	}
+	if _, ok := env.GetMolecule(m.ID); ok {
+		t.Error("Expected molecule to be gone after delete")
+	}
+}
+
+func TestEnvironment_QueryMolecules(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.Insert(Molecule{ID: "b", Species: "Event", CreatedAt: 2, Payload: map[string]any{"ip": "1.2.3.4"}})
+	env.Insert(Molecule{ID: "a", Species: "Event", CreatedAt: 2, Payload: map[string]any{"ip": "1.2.3.4"}})
+	env.Insert(Molecule{ID: "c", Species: "Event", CreatedAt: 1, Payload: map[string]any{"ip": "1.2.3.4"}})
+	env.Insert(Molecule{ID: "d", Species: "Event", CreatedAt: 1, Payload: map[string]any{"ip": "5.6.7.8"}})
+	env.Insert(Molecule{ID: "e", Species: "Alert", CreatedAt: 1, Payload: map[string]any{"ip": "1.2.3.4"}})
+
+	mols := env.QueryMolecules("Event", WhereConfig{"ip": {Eq: "1.2.3.4"}})
+	var ids []MoleculeID
+	for _, m := range mols {
+		ids = append(ids, m.ID)
+	}
+	if len(ids) != 3 || ids[0] != "c" || ids[1] != "a" || ids[2] != "b" {
+		t.Errorf("Expected [c a b] sorted by created_at then ID, got %v", ids)
+	}
+
+	if n := len(env.QueryMolecules("", nil)); n != 5 {
+		t.Errorf("Expected all 5 molecules without filters, got %d", n)
+	}
+}
+
+func TestEnvironment_QueryMolecules_Age(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	for i := int64(1); i <= 5; i++ {
+		env.Insert(Molecule{ID: MoleculeID(fmt.Sprintf("m%d", i)), Species: "Event", CreatedAt: i})
+	}
+	env.time = 10 // ages: m1 9, m2 8, m3 7, m4 6, m5 5
+
+	ids := func(mols []Molecule) []MoleculeID {
+		out := []MoleculeID{}
+		for _, m := range mols {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	if got := ids(env.QueryMolecules("", nil, OlderThan(7))); !slices.Equal(got, []MoleculeID{"m1", "m2"}) {
+		t.Errorf("Expected [m1 m2] older than 7 ticks, got %v", got)
+	}
+	if got := ids(env.QueryMolecules("", nil, NewerThan(7))); !slices.Equal(got, []MoleculeID{"m4", "m5"}) {
+		t.Errorf("Expected [m4 m5] newer than 7 ticks, got %v", got)
+	}
+	if got := ids(env.QueryMolecules("Event", nil, OlderThan(5), NewerThan(9))); !slices.Equal(got, []MoleculeID{"m2", "m3", "m4"}) {
+		t.Errorf("Expected [m2 m3 m4] between 5 and 9 ticks old, got %v", got)
+	}
+	if got := ids(env.QueryMolecules("Alert", nil, OlderThan(0))); len(got) != 0 {
+		t.Errorf("Expected the species filter to still apply, got %v", got)
+	}
+}
+
 func TestEnvironment_AllMolecules(t *testing.T) {
 	schema := NewSchema("test")
 	env := NewEnvironment(schema)
@@ -149,6 +415,34 @@ func TestEnvironment_Step_IncrementsTime(t *testing.T) {
 	}
 }
 
+func TestEnvironment_Step_ViewEnvTime(t *testing.T) {
+	var viewTimes, ctxTimes []int64
+	schema := NewSchema("test").WithReactions(&mockReaction{
+		id:           "clock",
+		rate:         1.0,
+		inputPattern: func(m Molecule) bool { return true },
+		apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+			viewTimes = append(viewTimes, env.EnvTime())
+			ctxTimes = append(ctxTimes, ctx.EnvTime)
+			return ReactionEffect{}
+		},
+	})
+	env := NewEnvironment(schema)
+	env.Insert(NewMolecule("Tick", nil, 0))
+
+	env.Step()
+	env.Step()
+
+	if len(viewTimes) != 2 || viewTimes[0] != 1 || viewTimes[1] != 2 {
+		t.Errorf("Expected the view to report ticks 1 and 2, got %v", viewTimes)
+	}
+	for i := range viewTimes {
+		if viewTimes[i] != ctxTimes[i] {
+			t.Errorf("Expected the view time %d to match the context time %d", viewTimes[i], ctxTimes[i])
+		}
+	}
+}
+
 func TestEnvironment_Step_WithReaction(t *testing.T) {
 	schema := NewSchema("test")
 
@@ -373,6 +667,87 @@ func TestEnvironment_Step_NewMoleculeAutoID(t *testing.T) {
 	}
 }
 
+func TestEnvironment_SetIDGenerator(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "split",
+		Species: []SpeciesConfig{{Name: "Cell"}},
+		Reactions: []ReactionConfig{
+			{ID: "divide", Input: InputConfig{Species: "Cell"}, Rate: 0.5, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Cell"}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	run := func() []MoleculeID {
+		env := NewEnvironmentWithSeed(schema, 7)
+		env.SetIDGenerator(SequentialIDs("m"))
+		env.Insert(Molecule{Species: "Cell"})
+		env.StepDryRun() // doesn't use up IDs
+		env.StepN(5)
+		ids := []MoleculeID{}
+		for _, m := range env.QueryMolecules("Cell", nil) {
+			ids = append(ids, m.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	first, second := run(), run()
+	if !slices.Equal(first, second) {
+		t.Errorf("Expected identical IDs across seeded runs, got %v and %v", first, second)
+	}
+	for i, id := range first {
+		if want := MoleculeID(fmt.Sprintf("m%06d", i+1)); id != want {
+			t.Errorf("Expected IDs m000001..m%06d, got %v", len(first), first)
+			break
+		}
+	}
+
+	env := NewEnvironment(schema)
+	env.SetIDGenerator(SequentialIDs("m"))
+	env.SetIDGenerator(nil)
+	env.Insert(Molecule{Species: "Cell"})
+	if id := env.AllMolecules()[0].ID; len(id) != 16 {
+		t.Errorf("Expected nil to restore random IDs, got %s", id)
+	}
+}
+
+func TestEnvironment_SetIDGenerator_SkipsIDsInUse(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "split",
+		Species: []SpeciesConfig{{Name: "Cell"}},
+		Reactions: []ReactionConfig{
+			{ID: "divide", Input: InputConfig{Species: "Cell"}, Rate: 1, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Cell"}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	// the molecules a previous process created with the same generator
+	env := NewEnvironmentWithSeed(schema, 7)
+	env.Insert(Molecule{ID: "m000001", Species: "Cell", Payload: map[string]any{"gen": "old"}})
+	env.Insert(Molecule{ID: "m000002", Species: "Cell", Payload: map[string]any{"gen": "old"}})
+
+	env.SetIDGenerator(SequentialIDs("m"))
+	env.Insert(Molecule{Species: "Cell"})
+	env.Step()
+
+	mols := env.AllMolecules()
+	if len(mols) != 6 {
+		t.Fatalf("Expected 6 molecules (3 inserted, 3 created), got %d", len(mols))
+	}
+	for _, id := range []MoleculeID{"m000001", "m000002"} {
+		m, ok := env.GetMolecule(id)
+		if !ok || m.Payload["gen"] != "old" {
+			t.Errorf("Expected %s to be kept, got %+v", id, m)
+		}
+	}
+}
+
 func TestEnvView_MoleculesBySpecies(t *testing.T) {
 	molecules := []Molecule{
 		NewMolecule("A", nil, 0),
@@ -507,6 +882,48 @@ func TestEnvironment_Run_CanBeStopped(t *testing.T) {
 	}
 }
 
+func TestEnvironment_Run_PauseResume(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.Run(5 * time.Millisecond)
+	defer env.Stop()
+
+	waitFor := func(cond func() bool) bool {
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				return false
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return true
+	}
+	if !waitFor(func() bool { return env.Time() > 0 }) {
+		t.Fatal("Expected time to advance while running")
+	}
+
+	env.Pause()
+	if !env.IsPaused() {
+		t.Error("Expected IsPaused to report true after Pause")
+	}
+	time.Sleep(20 * time.Millisecond) // let an in-flight tick finish
+	paused := env.Time()
+	time.Sleep(50 * time.Millisecond)
+	if env.Time() != paused {
+		t.Errorf("Expected time to stay at %d while paused, got %d", paused, env.Time())
+	}
+
+	// manual steps still work while paused
+	env.Step()
+	if env.Time() != paused+1 {
+		t.Errorf("Expected a manual step while paused, got time %d", env.Time())
+	}
+
+	env.Resume()
+	if !waitFor(func() bool { return env.Time() > paused+1 }) {
+		t.Error("Expected time to advance again after Resume")
+	}
+}
+
 func TestEnvironment_Run_CanBeRestarted(t *testing.T) {
 	schema := NewSchema("test")
 	env := NewEnvironment(schema)
@@ -1218,6 +1635,88 @@ func TestEnvironment_LoadSnapshot_UnknownSpecies(t *testing.T) {
 	}
 }
 
+func TestEnvironment_LoadSnapshot_RestoresSchema(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "escalate",
+				Input:   InputConfig{Species: "Event"},
+				Rate:    1.0,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(tmpDir)
+	env.Insert(NewMolecule("Event", nil, 0))
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	// an environment without a schema takes the one recorded in the snapshot
+	restored := NewEnvironment(nil)
+	restored.SetEnvironmentID("test-env")
+	restored.SetSnapshotDir(tmpDir)
+	if err := restored.LoadSnapshot(); err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	got, ok := restored.SchemaConfig()
+	if !ok || got.Name != "alerts" || len(got.Reactions) != 1 {
+		t.Fatalf("Expected the schema to be restored, got %+v (ok=%v)", got, ok)
+	}
+	restored.Step()
+	if counts := restored.SpeciesCounts(); counts["Alert"] != 1 {
+		t.Errorf("Expected the restored schema to react, got %v", counts)
+	}
+
+	// an environment with a schema keeps it
+	other := NewSchema("other").WithSpecies(Species{Name: "Event"})
+	kept := NewEnvironment(other)
+	kept.SetEnvironmentID("test-env")
+	kept.SetSnapshotDir(tmpDir)
+	if err := kept.LoadSnapshot(); err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if _, ok := kept.SchemaConfig(); ok {
+		t.Error("Expected the environment's own schema to be kept")
+	}
+}
+
+func TestEnvironment_LoadSnapshot_InvalidSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	snapshot := Snapshot{
+		EnvironmentID: "test-env",
+		Schema: &SchemaConfig{
+			Name:      "broken",
+			Species:   []SpeciesConfig{{Name: "Event"}},
+			Reactions: []ReactionConfig{{ID: "r", Input: InputConfig{Species: "Missing"}, Rate: 1.0}},
+		},
+	}
+	data, err := EncodeSnapshotJSON(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to encode snapshot: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "test-env.snapshot.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	env := NewEnvironment(nil)
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(tmpDir)
+	if err := env.LoadSnapshot(); err == nil || !strings.Contains(err.Error(), "rebuild schema") {
+		t.Errorf("Expected a schema rebuild error, got %v", err)
+	}
+}
+
 func TestEnvironment_LoadSnapshot_NoFile(t *testing.T) {
 	schema := NewSchema("test")
 	env := NewEnvironment(schema)
@@ -1255,3 +1754,585 @@ func TestEnvironment_LoadSnapshot_NoSnapshotDir(t *testing.T) {
 		t.Errorf("Expected no error when snapshot dir is not set, got %v", err)
 	}
 }
+
+func TestEnvironment_StepDiffs(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "diffs",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "a_to_b",
+				Input: InputConfig{Species: "A"},
+				Rate:  1.0,
+				Effects: []EffectConfig{
+					{Consume: true},
+					{Create: &CreateEffectConfig{Species: "B"}},
+				},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	a := NewMolecule("A", map[string]any{}, 0)
+	env.Insert(a)
+	env.Step() // A -> B
+	env.Step() // nothing happens
+
+	diffs, now, complete := env.DiffsSince(0)
+	if now != 2 || !complete {
+		t.Fatalf("Expected now=2 complete=true, got now=%d complete=%v", now, complete)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 diffs, got %d", len(diffs))
+	}
+	if diffs[0].Time != 1 || len(diffs[0].Consumed) != 1 || diffs[0].Consumed[0] != a.ID {
+		t.Errorf("Expected first diff to consume %s at time 1, got %+v", a.ID, diffs[0])
+	}
+	if len(diffs[0].Created) != 1 || diffs[0].Created[0].Species != "B" {
+		t.Errorf("Expected first diff to create a B molecule, got %+v", diffs[0].Created)
+	}
+	if len(diffs[1].Created)+len(diffs[1].Consumed)+len(diffs[1].Updated) != 0 {
+		t.Errorf("Expected second diff to be empty, got %+v", diffs[1])
+	}
+
+	// already up to date
+	diffs, _, complete = env.DiffsSince(2)
+	if len(diffs) != 0 || !complete {
+		t.Errorf("Expected no diffs since current time, got %d (complete=%v)", len(diffs), complete)
+	}
+}
+
+func TestEnvironment_StepDiffs_Bounded(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.SetDiffHistorySize(3)
+
+	for i := 0; i < 10; i++ {
+		env.Step()
+	}
+
+	diffs, now, complete := env.DiffsSince(0)
+	if now != 10 {
+		t.Fatalf("Expected now=10, got %d", now)
+	}
+	if len(diffs) != 3 || diffs[0].Time != 8 {
+		t.Fatalf("Expected last 3 diffs starting at time 8, got %+v", diffs)
+	}
+	if complete {
+		t.Error("Expected history to be reported as incomplete")
+	}
+
+	if _, _, complete := env.DiffsSince(7); !complete {
+		t.Error("Expected history since 7 to be complete")
+	}
+
+	// the result is sized by the retained history, not by the gap
+	env.time = 1_000_000_000_000
+	diffs, _, complete = env.DiffsSince(0)
+	if len(diffs) != 3 || complete {
+		t.Errorf("Expected the 3 retained diffs, incomplete, got %d (complete=%v)", len(diffs), complete)
+	}
+}
+
+func TestEnvironment_WaitForTick(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+
+	// times out when nothing happens
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if env.WaitForTick(ctx, 0) {
+		t.Fatal("Expected WaitForTick to time out")
+	}
+
+	// wakes up on the next tick
+	done := make(chan bool)
+	go func() {
+		done <- env.WaitForTick(context.Background(), 0)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	env.Step()
+
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("Expected WaitForTick to return true")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForTick did not wake up after Step")
+	}
+
+	// returns immediately if already past since
+	if !env.WaitForTick(context.Background(), 0) {
+		t.Error("Expected immediate return when already past since")
+	}
+}
+
+func TestEnvironment_RNGTrace(t *testing.T) {
+	schema := NewSchema("trace").WithReactions(
+		&mockReaction{
+			id:           "never",
+			rate:         0.0,
+			inputPattern: func(m Molecule) bool { return true },
+			apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+				return ReactionEffect{}
+			},
+		},
+		&mockReaction{
+			id:           "always",
+			rate:         1.0,
+			inputPattern: func(m Molecule) bool { return true },
+			apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+				ctx.Random()
+				return ReactionEffect{}
+			},
+		},
+	)
+
+	run := func(traced bool) (float64, []RNGTraceEntry) {
+		env := NewEnvironment(schema)
+		env.rand = rand.New(rand.NewSource(42))
+		var buf bytes.Buffer
+		if traced {
+			env.SetRNGTrace(&buf)
+		}
+		env.Insert(Molecule{ID: "m1", Species: "A"})
+		env.Step()
+
+		var entries []RNGTraceEntry
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var entry RNGTraceEntry
+			if err := dec.Decode(&entry); err != nil {
+				t.Fatalf("Failed to decode trace line: %v", err)
+			}
+			entries = append(entries, entry)
+		}
+		// next draw reveals whether the RNG sequence was consumed identically
+		return env.rand.Float64(), entries
+	}
+
+	plainNext, plainEntries := run(false)
+	tracedNext, entries := run(true)
+
+	if len(plainEntries) != 0 {
+		t.Errorf("Expected no trace without SetRNGTrace, got %d entries", len(plainEntries))
+	}
+	if plainNext != tracedNext {
+		t.Errorf("Expected tracing not to alter the RNG sequence")
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 trace entries (2 gates + 1 apply), got %d: %+v", len(entries), entries)
+	}
+
+	never, always, apply := entries[0], entries[1], entries[2]
+	if never.Phase != RNGTraceGate || never.ReactionID != "never" || never.Fired == nil || *never.Fired {
+		t.Errorf("Expected unfired gate entry for 'never', got %+v", never)
+	}
+	if always.Phase != RNGTraceGate || always.ReactionID != "always" || always.Fired == nil || !*always.Fired {
+		t.Errorf("Expected fired gate entry for 'always', got %+v", always)
+	}
+	if always.EffectiveRate == nil || *always.EffectiveRate != 1.0 || always.Tick != 1 || always.MoleculeID != "m1" {
+		t.Errorf("Expected rate 1.0, tick 1 and molecule m1, got %+v", always)
+	}
+	if apply.Phase != RNGTraceApply || apply.ReactionID != "always" || apply.EffectiveRate != nil || apply.Fired != nil {
+		t.Errorf("Expected apply entry for 'always', got %+v", apply)
+	}
+}
+
+func TestEnvironment_RNGTrace_Decay(t *testing.T) {
+	env := NewEnvironment(NewSchema("trace").WithDecayRate(0.5))
+	env.SetRandomSeed(42)
+	var buf bytes.Buffer
+	env.SetRNGTrace(&buf)
+	env.Insert(Molecule{ID: "unstable", Species: "A", Stability: 0})
+	env.Insert(Molecule{ID: "stable", Species: "A", Stability: 1})
+	env.Step()
+
+	entries := make(map[MoleculeID]RNGTraceEntry)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry RNGTraceEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("Failed to decode trace line: %v", err)
+		}
+		if entry.Phase != RNGTraceDecay || entry.ReactionID != DecayReactionID || entry.Tick != 1 {
+			t.Errorf("Expected a decay entry at tick 1, got %+v", entry)
+		}
+		entries[entry.MoleculeID] = entry
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected a decay draw per molecule, got %+v", entries)
+	}
+
+	_, stillThere := env.GetMolecule("unstable")
+	unstable := entries["unstable"]
+	if unstable.EffectiveRate == nil || *unstable.EffectiveRate != 0.5 || unstable.Fired == nil || *unstable.Fired == stillThere {
+		t.Errorf("Expected probability 0.5 and the outcome of the draw, got %+v (molecule kept: %v)", unstable, stillThere)
+	}
+	stable := entries["stable"]
+	if stable.EffectiveRate == nil || *stable.EffectiveRate != 0 || stable.Fired == nil || *stable.Fired {
+		t.Errorf("Expected probability 0 and no decay for the stable molecule, got %+v", stable)
+	}
+}
+
+func TestEnvironment_SetRandomSeed(t *testing.T) {
+	schema := NewSchema("seeded").WithReactions(&mockReaction{
+		id:           "decay",
+		rate:         0.5,
+		inputPattern: func(m Molecule) bool { return m.Species == "A" },
+		apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+			return ReactionEffect{ConsumedIDs: []MoleculeID{m.ID}}
+		},
+	})
+
+	// run returns the surviving payload values after every tick
+	run := func(seed int64) []string {
+		env := NewEnvironmentWithSeed(schema, seed)
+		for i := 0; i < 50; i++ {
+			env.Insert(NewMolecule("A", map[string]any{"n": i}, 0))
+		}
+		var history []string
+		for tick := 0; tick < 5; tick++ {
+			env.Step()
+			var ns []int
+			for _, m := range env.AllMolecules() {
+				ns = append(ns, int(m.Payload["n"].(int)))
+			}
+			sort.Ints(ns)
+			history = append(history, fmt.Sprint(ns))
+		}
+		return history
+	}
+
+	first, second := run(7), run(7)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected identical runs with the same seed, tick %d differs:\n%s\n%s", i+1, first[i], second[i])
+		}
+	}
+
+	other := run(8)
+	same := true
+	for i := range first {
+		if first[i] != other[i] {
+			same = false
+		}
+	}
+	if same {
+		t.Error("Expected a different seed to produce a different run")
+	}
+}
+
+func TestEnvironment_Reset(t *testing.T) {
+	schema := NewSchema("reset").WithReactions(&mockReaction{
+		id:           "consume",
+		rate:         1.0,
+		inputPattern: func(m Molecule) bool { return m.Species == "A" },
+		apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+			return ReactionEffect{ConsumedIDs: []MoleculeID{m.ID}}
+		},
+	})
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("reset-env")
+	mgr := env.GetNotificationManager()
+
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Insert(NewMolecule("B", nil, 0))
+	env.Step()
+	env.Step()
+
+	env.Reset()
+
+	if stats := env.Stats(); stats.Time != 0 || stats.Molecules != 0 {
+		t.Errorf("Expected empty environment at time 0, got %+v", stats)
+	}
+	if c := env.Counters(false); c.MoleculesConsumed != 0 || len(c.ReactionsFired) != 0 {
+		t.Errorf("Expected counters to be cleared, got %+v", c)
+	}
+	if diffs, _, _ := env.DiffsSince(0); len(diffs) != 0 {
+		t.Errorf("Expected diffs to be cleared, got %d", len(diffs))
+	}
+	if env.GetNotificationManager() != mgr || env.schema != schema || env.envID != "reset-env" {
+		t.Error("Expected schema, environment ID and notification manager to be kept")
+	}
+
+	// the scenario can be rerun
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Step()
+	if stats := env.Stats(); stats.Time != 1 || stats.Molecules != 0 {
+		t.Errorf("Expected the rerun to consume A at tick 1, got %+v", stats)
+	}
+}
+
+func TestEnvironment_Reset_WhileRunning(t *testing.T) {
+	schema := NewSchema("reset").WithReactions(&mockReaction{
+		id:           "spawn",
+		rate:         1.0,
+		inputPattern: func(m Molecule) bool { return true },
+		apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+			return ReactionEffect{NewMolecules: []Molecule{NewMolecule("B", nil, 0)}}
+		},
+	})
+	env := NewEnvironment(schema)
+	env.Insert(NewMolecule("A", nil, 0))
+	env.Run(time.Millisecond)
+	defer env.Stop()
+
+	time.Sleep(10 * time.Millisecond)
+	env.Reset()
+
+	// with no molecules left, nothing can spawn again unless a stale step leaked
+	time.Sleep(10 * time.Millisecond)
+	if n := len(env.AllMolecules()); n != 0 {
+		t.Errorf("Expected no molecules after reset, got %d", n)
+	}
+}
+
+func TestNextAlignedTick(t *testing.T) {
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		interval time.Duration
+		offset   time.Duration
+		want     time.Time
+	}{
+		{"mid-minute", base.Add(20 * time.Second), time.Minute, 0, base.Add(time.Minute)},
+		{"on boundary is strictly after", base, time.Minute, 0, base.Add(time.Minute)},
+		{"with offset", base.Add(5 * time.Minute), time.Hour, 15 * time.Minute, base.Add(15 * time.Minute)},
+		{"offset larger than interval", base.Add(20 * time.Second), time.Minute, 90 * time.Second, base.Add(30 * time.Second)},
+		{"before epoch", time.Unix(-90, 0), time.Minute, 0, time.Unix(-60, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextAlignedTick(tt.now, tt.interval, tt.offset)
+			if !got.Equal(tt.want) {
+				t.Errorf("nextAlignedTick(%v, %v, %v) = %v, want %v", tt.now, tt.interval, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironment_RunAligned(t *testing.T) {
+	const (
+		interval = 100 * time.Millisecond
+		offset   = 30 * time.Millisecond
+		slack    = 40 * time.Millisecond
+	)
+
+	var mu sync.Mutex
+	var ticks []time.Time
+	schema := NewSchema("test").WithReactions(&mockReaction{
+		id:           "record",
+		rate:         1.0,
+		inputPattern: func(m Molecule) bool { return true },
+		apply: func(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+			mu.Lock()
+			ticks = append(ticks, time.Now())
+			mu.Unlock()
+			return ReactionEffect{}
+		},
+	})
+	env := NewEnvironment(schema)
+	env.Insert(NewMolecule("A", nil, 0))
+
+	// run, stop and restart at an unaligned instant: the phase must not drift
+	for run := 0; run < 2; run++ {
+		env.RunAligned(interval, offset)
+		time.Sleep(250 * time.Millisecond)
+		env.Stop()
+		time.Sleep(interval/2 + 17*time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ticks) < 2 {
+		t.Fatalf("Expected at least 2 ticks, got %d", len(ticks))
+	}
+	for _, tick := range ticks {
+		phase := time.Duration((tick.UnixNano() - int64(offset)) % int64(interval))
+		if phase > slack {
+			t.Errorf("Expected tick within %v of an aligned boundary, got %v late", slack, phase)
+		}
+	}
+}
+
+func TestEnvironment_Step_Requeue(t *testing.T) {
+	one := 1.0
+	build := func(maxIterations int) *Environment {
+		cfg := SchemaConfig{
+			Name:    "requeue",
+			Species: []SpeciesConfig{{Name: "Counter"}},
+			Reactions: []ReactionConfig{
+				{
+					ID:    "increment",
+					Input: InputConfig{Species: "Counter"},
+					Rate:  1.0,
+					Effects: []EffectConfig{
+						{
+							If: &IfConditionConfig{Field: "energy", Op: "lt", Value: 5.0},
+							Then: []EffectConfig{
+								{Update: &UpdateEffectConfig{EnergyAdd: &one}},
+								{Requeue: &RequeueEffectConfig{MaxIterations: maxIterations}},
+							},
+						},
+					},
+				},
+			},
+		}
+		schema, err := BuildSchemaFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+		}
+		env := NewEnvironment(schema)
+		env.Insert(Molecule{ID: "c", Species: "Counter", Energy: 1})
+		return env
+	}
+
+	energy := func(env *Environment) float64 {
+		for _, m := range env.AllMolecules() {
+			return m.Energy
+		}
+		return -1
+	}
+
+	// loop ends by itself once the condition no longer holds
+	env := build(10)
+	env.Step()
+	if got := energy(env); got != 5 {
+		t.Errorf("Expected energy 5 after a single tick, got %v", got)
+	}
+
+	// loop is cut at the bound: 1 pass + 2 requeues
+	env = build(2)
+	env.Step()
+	if got := energy(env); got != 4 {
+		t.Errorf("Expected energy 4 with max_iterations=2, got %v", got)
+	}
+	if c := env.Counters(false); c.ReactionsFired["increment"] != 3 {
+		t.Errorf("Expected 3 passes, got %d", c.ReactionsFired["increment"])
+	}
+}
+
+func TestEnvironment_Step_UpdatePayloadLastWins(t *testing.T) {
+	update := func(id, status string) ReactionConfig {
+		return ReactionConfig{
+			ID:    id,
+			Input: InputConfig{Species: "Ticket"},
+			Rate:  1.0,
+			Effects: []EffectConfig{{Update: &UpdateEffectConfig{
+				PayloadSet:       map[string]any{"status": status},
+				PayloadIncrement: map[string]float64{"updates": 1},
+			}}},
+		}
+	}
+	cfg := SchemaConfig{
+		Name:      "tickets",
+		Species:   []SpeciesConfig{{Name: "Ticket"}},
+		Reactions: []ReactionConfig{update("first", "triaged"), update("second", "assigned")},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	env.Insert(Molecule{ID: "t", Species: "Ticket", Payload: map[string]any{"updates": 0}})
+	env.Step()
+
+	// both reactions see the snapshot; the change of the last one to fire wins
+	mols := env.AllMolecules()
+	if len(mols) != 1 {
+		t.Fatalf("Expected 1 molecule, got %d", len(mols))
+	}
+	if got := mols[0].Payload; got["status"] != "assigned" || got["updates"] != 1.0 {
+		t.Errorf("Expected status=assigned updates=1, got %v", got)
+	}
+}
+
+func TestEnvironment_Step_MaxFiresPerTick(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "queue",
+		Species: []SpeciesConfig{{Name: "Job"}, {Name: "Done"}},
+		Reactions: []ReactionConfig{{
+			ID:              "process",
+			Input:           InputConfig{Species: "Job"},
+			Rate:            1.0,
+			MaxFiresPerTick: 3,
+			Effects:         []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Done"}}},
+		}},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	for i := 0; i < 10; i++ {
+		env.Insert(Molecule{Species: "Job"})
+	}
+
+	env.Step()
+	if got := env.SpeciesCounts()["Done"]; got != 3 {
+		t.Errorf("Expected 3 fires in the first tick, got %d", got)
+	}
+	if got := env.Counters(false).ReactionsFired["process"]; got != 3 {
+		t.Errorf("Expected fired counter 3, got %d", got)
+	}
+
+	// the limit is per tick
+	env.Step()
+	counts := env.SpeciesCounts()
+	if counts["Done"] != 6 || counts["Job"] != 4 {
+		t.Errorf("Expected 6 Done and 4 Job after two ticks, got %v", counts)
+	}
+}
+
+func TestEnvironment_Step_ReactionPriority(t *testing.T) {
+	claim := func(id, species string, priority int) ReactionConfig {
+		return ReactionConfig{
+			ID:       id,
+			Input:    InputConfig{Species: "Task"},
+			Rate:     1.0,
+			Priority: priority,
+			Effects:  []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: species}}},
+		}
+	}
+	run := func(reactions ...ReactionConfig) map[SpeciesName]int {
+		schema, err := BuildSchemaFromConfig(SchemaConfig{
+			Name:      "claims",
+			Species:   []SpeciesConfig{{Name: "Task"}, {Name: "Low"}, {Name: "High"}},
+			Reactions: reactions,
+		})
+		if err != nil {
+			t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+		}
+		env := NewEnvironment(schema)
+		for i := 0; i < 5; i++ {
+			env.Insert(Molecule{Species: "Task"})
+		}
+		env.Step()
+		return env.SpeciesCounts()
+	}
+
+	// the higher-priority reaction wins every contested molecule, despite being declared last
+	counts := run(claim("low", "Low", 0), claim("high", "High", 10))
+	if counts["High"] != 5 || counts["Low"] != 0 {
+		t.Errorf("Expected all tasks claimed by the high priority reaction, got %v", counts)
+	}
+
+	// ties keep declaration order
+	counts = run(claim("low", "Low", 1), claim("high", "High", 1))
+	if counts["Low"] != 5 || counts["High"] != 0 {
+		t.Errorf("Expected declaration order to break ties, got %v", counts)
+	}
+
+	// without priorities, a consumed molecule is still seen by the remaining reactions
+	counts = run(claim("low", "Low", 0), claim("high", "High", 0))
+	if counts["Low"] != 5 || counts["High"] != 5 || counts["Task"] != 0 {
+		t.Errorf("Expected both reactions to fire on every task, got %v", counts)
+	}
+}
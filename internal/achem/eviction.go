@@ -0,0 +1,167 @@
+package achem
+
+import (
+	"sort"
+	"time"
+)
+
+// Eviction orders for capped species.
+const (
+	// EvictOldest evicts the molecules with the lowest CreatedAt first.
+	EvictOldest = "oldest"
+	// EvictLowestEnergy evicts the molecules with the lowest Energy first.
+	EvictLowestEnergy = "lowest_energy"
+)
+
+// EvictionReactionID is the reserved reaction ID of notification events emitted when
+// molecules are evicted because their species exceeded its MaxCount. The evicted
+// molecules are reported in ConsumedMolecules.
+const EvictionReactionID = "__evict__"
+
+// sortForEviction orders mols so that the ones to evict first come first.
+// Ties are broken by ID to keep eviction deterministic.
+func sortForEviction(mols []Molecule, order string) {
+	sort.Slice(mols, func(i, j int) bool {
+		a, b := mols[i], mols[j]
+		if order == EvictLowestEnergy && a.Energy != b.Energy {
+			return a.Energy < b.Energy
+		}
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.ID < b.ID
+	})
+}
+
+// evictionBatch holds the molecules removed from a species in one step, either
+// evicted because the species is capped or expired because of their TTL.
+type evictionBatch struct {
+	species   Species
+	molecules []Molecule
+}
+
+// evictExcessLocked removes the molecules of capped species above their MaxCount and
+// returns them grouped by species. Callers must hold e.mu.
+func (e *Environment) evictExcessLocked() []evictionBatch {
+	capped := e.schema.cappedSpecies()
+	if len(capped) == 0 {
+		return nil
+	}
+
+	bySpecies := make(map[SpeciesName][]Molecule, len(capped))
+	for _, sp := range capped {
+		bySpecies[sp.Name] = nil
+	}
+	for _, m := range e.mols {
+		if mols, ok := bySpecies[m.Species]; ok {
+			bySpecies[m.Species] = append(mols, m)
+		}
+	}
+
+	var evicted []evictionBatch
+	for _, sp := range capped {
+		mols := bySpecies[sp.Name]
+		excess := len(mols) - sp.MaxCount
+		if excess <= 0 {
+			continue
+		}
+		sortForEviction(mols, sp.EvictOrder)
+		for _, m := range mols[:excess] {
+			e.deleteMoleculeLocked(m.ID)
+		}
+		evicted = append(evicted, evictionBatch{species: sp, molecules: mols[:excess]})
+	}
+	return evicted
+}
+
+// evictOverCapLocked removes the molecules above the environment's MaxMolecules cap,
+// in the order of its eviction policy, and returns them grouped by species in order of
+// first eviction. Callers must hold e.mu.
+func (e *Environment) evictOverCapLocked() []evictionBatch {
+	excess := len(e.mols) - e.maxMolecules
+	if e.maxMolecules <= 0 || excess <= 0 {
+		return nil
+	}
+
+	mols := make([]Molecule, 0, len(e.mols))
+	for _, m := range e.mols {
+		mols = append(mols, m)
+	}
+	sortForEviction(mols, e.evictionPolicy)
+
+	return e.removeInBatchesLocked(mols[:excess])
+}
+
+// removeInBatchesLocked deletes mols from the environment and returns them grouped by
+// species, in order of first appearance. Callers must hold e.mu.
+func (e *Environment) removeInBatchesLocked(mols []Molecule) []evictionBatch {
+	var batches []evictionBatch
+	index := make(map[SpeciesName]int)
+	for _, m := range mols {
+		e.deleteMoleculeLocked(m.ID)
+		i, ok := index[m.Species]
+		if !ok {
+			sp, found := e.schema.Species(m.Species)
+			if !found {
+				sp = Species{Name: m.Species}
+			}
+			i = len(batches)
+			index[m.Species] = i
+			batches = append(batches, evictionBatch{species: sp})
+		}
+		batches[i].molecules = append(batches[i].molecules, m)
+	}
+	return batches
+}
+
+// notifyEvicted enqueues one eviction notification per species. Registered callbacks
+// always receive it; notifiers only if listed in the species' EvictNotifiers.
+func notifyEvicted(evicted []evictionBatch, envID EnvironmentID, envTime int64, notifierMgr *NotificationManager) {
+	for _, batch := range evicted {
+		notifierMgr.Enqueue(NotificationEvent{
+			EnvironmentID:     envID,
+			ReactionID:        EvictionReactionID,
+			ReactionName:      "evict " + string(batch.species.Name),
+			Timestamp:         time.Now().Unix(),
+			EnvTime:           envTime,
+			ConsumedMolecules: batch.molecules,
+		}, batch.species.EvictNotifiers)
+	}
+}
+
+// withEvicted returns the diff amended with evicted molecules: they are reported as
+// consumed, unless they were created in the same step, in which case they are simply
+// dropped from Created. Evicted molecules are also dropped from Updated.
+func (d StepDiff) withEvicted(evicted []evictionBatch) StepDiff {
+	ids := make(map[MoleculeID]struct{})
+	for _, batch := range evicted {
+		for _, m := range batch.molecules {
+			ids[m.ID] = struct{}{}
+		}
+	}
+
+	created := d.Created[:0:0]
+	for _, m := range d.Created {
+		if _, ok := ids[m.ID]; ok {
+			delete(ids, m.ID)
+			continue
+		}
+		created = append(created, m)
+	}
+	updated := d.Updated[:0:0]
+	for _, m := range d.Updated {
+		if _, ok := ids[m.ID]; !ok {
+			updated = append(updated, m)
+		}
+	}
+	d.Created, d.Updated = created, updated
+
+	for _, batch := range evicted {
+		for _, m := range batch.molecules {
+			if _, ok := ids[m.ID]; ok {
+				d.Consumed = append(d.Consumed, m.ID)
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,185 @@
+package achem
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnvironment_Step_EvictsOldest(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:      "sink",
+		Species:   []SpeciesConfig{{Name: "Event", MaxCount: 3}, {Name: "Other"}},
+		Reactions: []ReactionConfig{},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	var mu sync.Mutex
+	var events []NotificationEvent
+	env.RegisterCallback("test", func(event NotificationEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	for i := range 5 {
+		env.Insert(Molecule{ID: MoleculeID("e" + string(rune('0'+i))), Species: "Event", CreatedAt: int64(i + 1)})
+	}
+	for range 5 {
+		env.Insert(NewMolecule("Other", nil, 0))
+	}
+
+	env.Step()
+
+	remaining := make(map[MoleculeID]bool)
+	others := 0
+	for _, m := range env.AllMolecules() {
+		if m.Species == "Event" {
+			remaining[m.ID] = true
+		} else {
+			others++
+		}
+	}
+	if len(remaining) != 3 || !remaining["e2"] || !remaining["e3"] || !remaining["e4"] {
+		t.Errorf("Expected the 3 newest events to remain, got %v", remaining)
+	}
+	if others != 5 {
+		t.Errorf("Expected uncapped species to be untouched, got %d", others)
+	}
+	if c := env.Counters(false); c.MoleculesEvicted != 2 {
+		t.Errorf("Expected 2 evicted molecules, got %d", c.MoleculesEvicted)
+	}
+
+	diffs, _, _ := env.DiffsSince(0)
+	if len(diffs) != 1 || len(diffs[0].Consumed) != 2 {
+		t.Errorf("Expected evictions to be reported as consumed in the diff, got %+v", diffs)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].ReactionID != EvictionReactionID || len(events[0].ConsumedMolecules) != 2 {
+		t.Fatalf("Expected one eviction event with 2 molecules, got %+v", events)
+	}
+}
+
+func TestEnvironment_Step_EvictsLowestEnergy(t *testing.T) {
+	schema := NewSchema("sink").WithSpecies(Species{Name: "Event", MaxCount: 2, EvictOrder: EvictLowestEnergy})
+	env := NewEnvironment(schema)
+
+	for i, energy := range []float64{0.5, 0.1, 0.9, 0.3} {
+		env.Insert(Molecule{ID: MoleculeID("e" + string(rune('0'+i))), Species: "Event", Energy: energy})
+	}
+
+	env.Step()
+
+	remaining := make(map[MoleculeID]bool)
+	for _, m := range env.AllMolecules() {
+		remaining[m.ID] = true
+	}
+	if len(remaining) != 2 || !remaining["e0"] || !remaining["e2"] {
+		t.Errorf("Expected the highest-energy events to remain, got %v", remaining)
+	}
+}
+
+func TestStepDiff_WithEvicted(t *testing.T) {
+	diff := StepDiff{
+		Created:  []Molecule{{ID: "new"}, {ID: "kept"}},
+		Updated:  []Molecule{{ID: "old"}},
+		Consumed: []MoleculeID{"gone"},
+	}
+	diff = diff.withEvicted([]evictionBatch{{molecules: []Molecule{{ID: "new"}, {ID: "old"}}}})
+
+	if len(diff.Created) != 1 || diff.Created[0].ID != "kept" {
+		t.Errorf("Expected molecules created and evicted in the same step to be dropped, got %+v", diff.Created)
+	}
+	if len(diff.Updated) != 0 {
+		t.Errorf("Expected evicted molecules to be dropped from updates, got %+v", diff.Updated)
+	}
+	if len(diff.Consumed) != 2 || diff.Consumed[1] != "old" {
+		t.Errorf("Expected only pre-existing evicted molecules to be consumed, got %v", diff.Consumed)
+	}
+}
+
+func TestEnvironment_Step_MaxMolecules(t *testing.T) {
+	schema := NewSchema("capped").WithSpecies(
+		Species{Name: "Event", EvictNotifiers: []string{"evictions"}},
+		Species{Name: "Other"},
+	)
+	env := NewEnvironment(schema)
+	env.SetMaxMolecules(4)
+
+	var mu sync.Mutex
+	var events []NotificationEvent
+	env.RegisterCallback("test", func(event NotificationEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	for i := range 3 {
+		env.Insert(Molecule{ID: MoleculeID("e" + string(rune('0'+i))), Species: "Event", CreatedAt: int64(i + 1), Energy: float64(10 - i)})
+		env.Insert(Molecule{ID: MoleculeID("o" + string(rune('0'+i))), Species: "Other", CreatedAt: int64(i + 4), Energy: float64(i)})
+	}
+
+	env.Step()
+
+	remaining := make(map[MoleculeID]bool)
+	for _, m := range env.AllMolecules() {
+		remaining[m.ID] = true
+	}
+	if len(remaining) != 4 || remaining["e0"] || remaining["e1"] {
+		t.Errorf("Expected the 2 oldest molecules to be evicted, got %v", remaining)
+	}
+	if c := env.Counters(false); c.MoleculesEvicted != 2 {
+		t.Errorf("Expected 2 evicted molecules, got %d", c.MoleculesEvicted)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+		if n >= 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	if len(events) != 1 || events[0].ReactionID != EvictionReactionID || len(events[0].ConsumedMolecules) != 2 {
+		t.Errorf("Expected one eviction event with 2 molecules, got %+v", events)
+	}
+	mu.Unlock()
+
+	// lowest energy first: the remaining Other molecules have energy 0..2
+	if err := env.SetEvictionPolicy(EvictLowestEnergy); err != nil {
+		t.Fatalf("SetEvictionPolicy failed: %v", err)
+	}
+	env.SetMaxMolecules(2)
+	env.Step()
+	remaining = make(map[MoleculeID]bool)
+	for _, m := range env.AllMolecules() {
+		remaining[m.ID] = true
+	}
+	if len(remaining) != 2 || !remaining["e2"] || !remaining["o2"] {
+		t.Errorf("Expected the 2 highest energy molecules to remain, got %v", remaining)
+	}
+
+	if err := env.SetEvictionPolicy("random"); err == nil {
+		t.Error("Expected an unknown eviction policy to be rejected")
+	}
+
+	// unlimited
+	env.SetMaxMolecules(0)
+	for range 5 {
+		env.Insert(NewMolecule("Other", nil, 0))
+	}
+	env.Step()
+	if n := len(env.AllMolecules()); n != 7 {
+		t.Errorf("Expected no eviction without a cap, got %d molecules", n)
+	}
+}
@@ -0,0 +1,152 @@
+package achem
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema version used by SchemaConfigJSONSchema
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchemaOptional lists the fields without omitempty that may still be left out,
+// because they have a default, as "<Go type>.<JSON name>"
+var jsonSchemaOptional = map[string]bool{
+	"InputConfig.species":          true, // or any_species
+	"ReactionConfig.name":          true,
+	"ReactionConfig.rate":          true, // default 1.0
+	"PartnerConfig.count":          true, // default 1
+	"WeightedBranch.effects":       true,
+	"NotificationConfig.notifiers": true,
+}
+
+// jsonSchemaKeywords adds keywords to the schema of a field, as "<Go type>.<JSON name>".
+// It holds the constraints that the Go types can't express, such as enums.
+func jsonSchemaKeywords() map[string]map[string]any {
+	operators := slices.Sorted(maps.Keys(validOperators))
+	return map[string]map[string]any{
+		"SpeciesConfig.evict_order":          {"enum": []string{EvictOldest, EvictLowestEnergy}},
+		"FieldSpec.type":                     {"enum": slices.Sorted(maps.Keys(fieldTypes))},
+		"PartnerConfig.select":               {"enum": slices.Sorted(maps.Keys(partnerOrders))},
+		"CatalystConfig.mode":                {"enum": []string{CatalystModeAdd, CatalystModeMultiply}},
+		"IfConditionConfig.op":               {"enum": operators},
+		"CountMoleculesConfig.op":            {"propertyNames": map[string]any{"enum": operators}},
+		"CountMoleculesConfig.agg":           {"enum": []string{AggCount, AggSum, AggAvg, AggMin, AggMax}},
+		"RequeueEffectConfig.max_iterations": {"minimum": 1, "maximum": MaxRequeueIterations},
+		"WeightedBranch.weight":              {"exclusiveMinimum": 0},
+	}
+}
+
+// SchemaConfigJSONSchema returns a JSON Schema describing SchemaConfig, for editor
+// validation and autocompletion of hand-written schemas. It is generated from the Go
+// types, so it follows the DSL as it changes. Objects don't allow unknown properties,
+// so misspelled fields are reported. Semantic checks, such as references to undefined
+// species, are left to ValidateSchemaConfig.
+func SchemaConfigJSONSchema() map[string]any {
+	g := &jsonSchemaGenerator{
+		defs:     make(map[string]any),
+		keywords: jsonSchemaKeywords(),
+	}
+	root := g.schemaFor(reflect.TypeFor[SchemaConfig]())
+	// schema files may point editors to the JSON Schema themselves
+	def := g.defs["SchemaConfig"].(map[string]any)
+	def["properties"].(map[string]any)["$schema"] = map[string]any{"type": "string"}
+	root["$schema"] = JSONSchemaDialect
+	root["title"] = "AChemDB schema"
+	root["$defs"] = g.defs
+	return root
+}
+
+// jsonSchemaGenerator builds JSON Schemas from Go types, collecting named types in defs
+type jsonSchemaGenerator struct {
+	defs     map[string]any
+	keywords map[string]map[string]any
+}
+
+// schemaFor returns the schema of t, as a reference for named structs and maps
+func (g *jsonSchemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaFor(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		if t.Name() != "" {
+			return g.ref(t, func() map[string]any { return g.mapSchema(t) })
+		}
+		return g.mapSchema(t)
+	case reflect.Struct:
+		return g.ref(t, func() map[string]any { return g.structSchema(t) })
+	default:
+		return map[string]any{} // any value
+	}
+}
+
+// ref registers the definition of the named type t, built by build, and returns a
+// reference to it. Recursive types are registered before they are built.
+func (g *jsonSchemaGenerator) ref(t reflect.Type, build func() map[string]any) map[string]any {
+	if _, ok := g.defs[t.Name()]; !ok {
+		g.defs[t.Name()] = nil
+		g.defs[t.Name()] = build()
+	}
+	return map[string]any{"$ref": "#/$defs/" + t.Name()}
+}
+
+func (g *jsonSchemaGenerator) mapSchema(t reflect.Type) map[string]any {
+	return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+}
+
+func (g *jsonSchemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schemaFor(f.Type)
+		key := t.Name() + "." + name
+		for k, v := range g.keywords[key] {
+			prop[k] = v
+		}
+		properties[name] = prop
+
+		if !strings.Contains(opts, "omitempty") && !jsonSchemaOptional[key] {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	// a condition can also be a "$or"/"$and" list of where clauses (see EqCondition.MarshalJSON)
+	if t == reflect.TypeFor[EqCondition]() {
+		return map[string]any{"anyOf": []any{
+			schema,
+			map[string]any{"type": "array", "items": g.schemaFor(reflect.TypeFor[WhereConfig]())},
+		}}
+	}
+	return schema
+}
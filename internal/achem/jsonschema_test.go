@@ -0,0 +1,73 @@
+package achem
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSchemaConfigJSONSchema(t *testing.T) {
+	// round-trip through JSON to inspect the schema as a client would
+	data, err := json.Marshal(SchemaConfigJSONSchema())
+	if err != nil {
+		t.Fatalf("Failed to marshal the JSON Schema: %v", err)
+	}
+	var schema struct {
+		Schema string                    `json:"$schema"`
+		Ref    string                    `json:"$ref"`
+		Defs   map[string]map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to unmarshal the JSON Schema: %v", err)
+	}
+
+	if schema.Schema != JSONSchemaDialect || schema.Ref != "#/$defs/SchemaConfig" {
+		t.Errorf("Unexpected root: $schema %q, $ref %q", schema.Schema, schema.Ref)
+	}
+	for _, name := range []string{"SchemaConfig", "SpeciesConfig", "ReactionConfig", "EffectConfig", "WhereConfig", "EqCondition", "ChooseEffectConfig", "CountMoleculesConfig"} {
+		if schema.Defs[name] == nil {
+			t.Errorf("Expected a definition for %s", name)
+		}
+	}
+
+	properties := func(def string) map[string]any {
+		props, _ := schema.Defs[def]["properties"].(map[string]any)
+		return props
+	}
+
+	if properties("SchemaConfig")["$schema"] == nil {
+		t.Error("Expected schema files to be allowed a $schema property")
+	}
+
+	reaction := schema.Defs["ReactionConfig"]
+	if reaction["additionalProperties"] != false {
+		t.Error("Expected objects to reject unknown properties")
+	}
+	required, _ := reaction["required"].([]any)
+	if !slices.Contains(required, any("id")) || slices.Contains(required, any("name")) || slices.Contains(required, any("priority")) {
+		t.Errorf("Expected id to be required, and name and priority optional, got %v", required)
+	}
+
+	// recursive effects and nested types are referenced
+	then, _ := properties("EffectConfig")["then"].(map[string]any)
+	if items, _ := then["items"].(map[string]any); items["$ref"] != "#/$defs/EffectConfig" {
+		t.Errorf("Expected then to reference EffectConfig, got %v", then)
+	}
+
+	// operators come from the validation rules
+	op, _ := properties("IfConditionConfig")["op"].(map[string]any)
+	enum, _ := op["enum"].([]any)
+	if len(enum) != len(validOperators) {
+		t.Errorf("Expected the %d valid operators, got %v", len(validOperators), enum)
+	}
+	for _, v := range enum {
+		if !validOperators[v.(string)] {
+			t.Errorf("Unexpected operator %v", v)
+		}
+	}
+
+	// where conditions are operator objects or $or/$and clause lists
+	if anyOf, _ := schema.Defs["EqCondition"]["anyOf"].([]any); len(anyOf) != 2 {
+		t.Errorf("Expected two alternatives for EqCondition, got %v", schema.Defs["EqCondition"])
+	}
+}
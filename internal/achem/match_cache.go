@@ -0,0 +1,146 @@
+package achem
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// matchCache memoizes InputPattern and EffectiveRate results within a single tick,
+// so that molecules with identical content reuse the decisions computed for the first
+// one. It must be rebuilt every tick, since effective rates depend on the snapshot.
+// A nil *matchCache is valid and simply evaluates the reactions.
+type matchCache struct {
+	cacheable []bool // per reaction index
+	input     map[matchCacheKey]bool
+	rate      map[matchCacheKey]float64
+}
+
+type matchCacheKey struct {
+	reaction  int
+	signature string
+}
+
+func newMatchCache(reactions []Reaction) *matchCache {
+	c := &matchCache{
+		cacheable: make([]bool, len(reactions)),
+		input:     make(map[matchCacheKey]bool),
+		rate:      make(map[matchCacheKey]float64),
+	}
+	for i, r := range reactions {
+		c.cacheable[i] = isCacheableReaction(r)
+	}
+	return c
+}
+
+// isCacheableReaction reports whether a reaction's InputPattern and EffectiveRate only
+// depend on the content of the molecule (not its ID), so that they can be shared by
+// molecules with the same signature. Custom reactions are never cached.
+func isCacheableReaction(r Reaction) bool {
+	cr, ok := r.(*ConfigReaction)
+	if !ok {
+		return false
+	}
+	if whereRefersToID(cr.cfg.Input.Where) {
+		return false
+	}
+	for _, c := range cr.cfg.Catalysts {
+		if whereRefersToID(c.Where) {
+			return false
+		}
+	}
+	for _, in := range cr.cfg.Inhibitors {
+		if whereRefersToID(in.Where) {
+			return false
+		}
+	}
+	return true
+}
+
+func whereRefersToID(where WhereConfig) bool {
+	isID := func(v any) bool {
+		s, ok := v.(string)
+		return ok && s == "$m.id"
+	}
+	for _, cond := range where {
+		for _, clause := range cond.Clauses {
+			if whereRefersToID(clause) {
+				return true
+			}
+		}
+		if isID(cond.Eq) || isID(cond.Ne) || isID(cond.Gt) || isID(cond.Gte) || isID(cond.Lt) || isID(cond.Lte) {
+			return true
+		}
+		for _, list := range [][]any{cond.In, cond.Nin} {
+			for _, v := range list {
+				if isID(v) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// moleculeSignature returns a key identifying the content of m (everything but its ID).
+// Returns false if the payload can't be encoded, in which case m is not cached.
+func moleculeSignature(m Molecule) (string, bool) {
+	payload, err := json.Marshal(m.Payload) // map keys are sorted, so the encoding is canonical
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(string(m.Species))
+	b.WriteByte(0)
+	b.Write(payload)
+	b.WriteByte(0)
+	b.WriteString(strconv.FormatFloat(m.Energy, 'g', -1, 64))
+	b.WriteByte(0)
+	b.WriteString(strconv.FormatFloat(m.Stability, 'g', -1, 64))
+	b.WriteByte(0)
+	b.WriteString(strconv.FormatInt(m.CreatedAt, 10))
+	b.WriteByte(0)
+	b.WriteString(strconv.FormatInt(m.LastTouchedAt, 10))
+	b.WriteByte(0)
+	b.WriteString(strings.Join(m.Tags, "\x01"))
+	b.WriteByte(0)
+	b.WriteString(strconv.FormatInt(m.TTL, 10))
+	if m.Position != nil {
+		b.WriteByte(0)
+		b.WriteString(strconv.FormatFloat(m.Position.X, 'g', -1, 64))
+		b.WriteByte(0)
+		b.WriteString(strconv.FormatFloat(m.Position.Y, 'g', -1, 64))
+	}
+	return b.String(), true
+}
+
+// inputPattern returns r.InputPattern(m), using the cached result for molecules with
+// the same signature when possible. i is the index of r in the tick's reactions.
+func (c *matchCache) inputPattern(i int, r Reaction, m Molecule, sig string, sigOK bool) bool {
+	if c == nil || !sigOK || !c.cacheable[i] {
+		return r.InputPattern(m)
+	}
+	key := matchCacheKey{reaction: i, signature: sig}
+	if matched, ok := c.input[key]; ok {
+		return matched
+	}
+	matched := r.InputPattern(m)
+	c.input[key] = matched
+	return matched
+}
+
+// effectiveRate returns r.EffectiveRate(m, view), using the cached result for
+// molecules with the same signature when possible.
+func (c *matchCache) effectiveRate(i int, r Reaction, m Molecule, view EnvView, sig string, sigOK bool) float64 {
+	if c == nil || !sigOK || !c.cacheable[i] {
+		return r.EffectiveRate(m, view)
+	}
+	key := matchCacheKey{reaction: i, signature: sig}
+	if rate, ok := c.rate[key]; ok {
+		return rate
+	}
+	rate := r.EffectiveRate(m, view)
+	c.rate[key] = rate
+	return rate
+}
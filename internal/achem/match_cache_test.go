@@ -0,0 +1,89 @@
+package achem
+
+import "testing"
+
+func TestMatchCache_MatchesUncached(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "cache",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Flag"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:        "flagged_failures",
+				Input:     InputConfig{Species: "Event", Where: WhereConfig{"type": {Eq: "login_failed"}}},
+				Rate:      0.2,
+				Catalysts: []CatalystConfig{{Species: "Flag", Where: WhereConfig{"ip": {Eq: "$m.ip"}}, RateBoost: 0.5}},
+			},
+			{
+				ID:    "self_ref",
+				Input: InputConfig{Species: "Event", Where: WhereConfig{"owner": {Eq: "$m.id"}}},
+				Rate:  1.0,
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	var mols []Molecule
+	for i := 0; i < 3; i++ {
+		mols = append(mols,
+			NewMolecule("Event", map[string]any{"type": "login_failed", "ip": "1.2.3.4"}, 0),
+			NewMolecule("Event", map[string]any{"type": "login_failed", "ip": "5.6.7.8"}, 0),
+			NewMolecule("Event", map[string]any{"type": "login_ok", "ip": "1.2.3.4"}, 0),
+		)
+	}
+	self := NewMolecule("Event", map[string]any{}, 0)
+	self.Payload["owner"] = string(self.ID)
+	mols = append(mols, self, NewMolecule("Flag", map[string]any{"ip": "1.2.3.4"}, 0))
+
+	bySpecies := make(map[SpeciesName][]Molecule)
+	for _, m := range mols {
+		bySpecies[m.Species] = append(bySpecies[m.Species], m)
+	}
+	view := envView{molecules: mols, bySpecies: bySpecies}
+
+	reactions := schema.Reactions()
+	cache := newMatchCache(reactions)
+	if !cache.cacheable[0] || cache.cacheable[1] {
+		t.Fatalf("Expected only the first reaction to be cacheable, got %v", cache.cacheable)
+	}
+
+	for _, m := range mols {
+		sig, sigOK := moleculeSignature(m)
+		for i, r := range reactions {
+			if got, want := cache.inputPattern(i, r, m, sig, sigOK), r.InputPattern(m); got != want {
+				t.Errorf("InputPattern mismatch for reaction %s on %v: cached=%v uncached=%v", r.ID(), m.Payload, got, want)
+			}
+			if got, want := cache.effectiveRate(i, r, m, view, sig, sigOK), r.EffectiveRate(m, view); got != want {
+				t.Errorf("EffectiveRate mismatch for reaction %s on %v: cached=%v uncached=%v", r.ID(), m.Payload, got, want)
+			}
+		}
+	}
+
+	// 3 distinct Event payloads + the self-referencing one + the Flag
+	if len(cache.input) != 5 {
+		t.Errorf("Expected 5 cached input decisions for 11 molecules, got %d", len(cache.input))
+	}
+}
+
+func TestEnvironment_Step_MatchCache(t *testing.T) {
+	_, schema := loadSchemaFromExamples(t, "security.json")
+	env := NewEnvironment(schema)
+	env.SetMatchCache(true)
+
+	for range 3 {
+		env.Insert(NewMolecule("Event", map[string]any{"type": "login_failed", "ip": "1.2.3.4"}, 0))
+	}
+	for range 10 {
+		env.Step()
+	}
+
+	counts := make(map[SpeciesName]int)
+	for _, m := range env.AllMolecules() {
+		counts[m.Species]++
+	}
+	if counts["Event"] != 0 {
+		t.Errorf("Expected all events to be processed with the cache enabled, got %v", counts)
+	}
+}
@@ -1,14 +1,81 @@
 package achem
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
-// indexKeyFromValue converts a value to a string key for indexing.
-// This is a simple approach; we accept that different types with the same
-// string representation might collide (it's an optimization, not a strict semantic guarantee).
+// DefaultFloatTolerance is the absolute tolerance used when comparing non-integral
+// numeric values for equality. Schemas can override it with SchemaConfig.FloatTolerance.
+const DefaultFloatTolerance = 1e-9
+
+// numericEqual reports whether two numbers are equal. Integral values (e.g. 42 and 42.0)
+// are compared exactly; otherwise values are equal if they differ by at most tol.
+func numericEqual(a, b, tol float64) bool {
+	if a == b {
+		return true
+	}
+	if a == math.Trunc(a) && b == math.Trunc(b) {
+		return false
+	}
+	return math.Abs(a-b) <= tol
+}
+
+// valuesEqual compares two values for equality. Numeric values are coerced to float64
+// (JSON numbers decode as float64 while Go callers often use int) and compared with
+// numericEqual; any other values must be deeply equal.
+func valuesEqual(a, b any, tol float64) bool {
+	af, aIsNum := toFloat64(a)
+	bf, bIsNum := toFloat64(b)
+	if aIsNum && bIsNum {
+		return numericEqual(af, bf, tol)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// maxNumericIndexBuckets bounds the index buckets read for a numeric equality: with a
+// tolerance spanning more buckets, a scan is used instead.
+const maxNumericIndexBuckets = 16
+
+// indexKeyFromValue converts a value to a string key for indexing. Numbers are bucketed
+// by their integral part, whatever their Go type, so that the values equal within a
+// tolerance are found in the same or neighbouring buckets (see indexKeysForEq). Other
+// values use their string representation. Different values may share a key (it's an
+// optimization, not a strict semantic guarantee): candidates found through an index must
+// still be checked.
 func indexKeyFromValue(v any) string {
+	if f, ok := toFloat64(v); ok {
+		return numericIndexKey(f)
+	}
 	return fmt.Sprintf("%v", v)
 }
 
+func numericIndexKey(f float64) string {
+	return "#" + strconv.FormatFloat(math.Floor(f), 'g', -1, 64)
+}
+
+// indexKeysForEq returns the index keys under which the values equal to v within tol
+// are stored. Returns false if they span too many buckets to be worth looking up.
+func indexKeysForEq(v any, tol float64) ([]string, bool) {
+	f, ok := toFloat64(v)
+	if !ok || math.IsInf(f, 0) || math.IsNaN(f) {
+		return []string{indexKeyFromValue(v)}, true
+	}
+	lo, hi := math.Floor(f-tol), math.Floor(f+tol)
+	if hi-lo >= maxNumericIndexBuckets {
+		return nil, false
+	}
+	n := int(hi - lo)
+	keys := make([]string, 0, n+1)
+	for i := 0; i <= n; i++ {
+		keys = append(keys, numericIndexKey(lo+float64(i)))
+	}
+	return keys, true
+}
+
 // resolveValueRef resolves $m.* references into values from the origin molecule.
 // It supports:
 //   - $m.energy
@@ -18,58 +85,229 @@ func indexKeyFromValue(v any) string {
 //   - $m.created_at / $m.createdAt / $m.CreatedAt
 //   - $m.last_touched_at / $m.lastTouchedAt / $m.LastTouchedAt
 //   - $m.<payloadField>
+//   - simple arithmetic between a reference and a numeric literal, e.g.
+//     "$m.energy * 2", "$m.price + 10" or "100 - $m.score" (see evalExpression)
+//
+// When partners are given (in reaction effects), $p[<i>].<field> references the
+// field of the i-th matched partner, and $partner.<field> is short for $p[0].<field>.
 //
-// Any non-string value is returned as-is.
-func resolveValueRef(val any, origin Molecule) any {
+// Any non-string value is returned as-is. A reference to a missing payload field or
+// partner is returned unresolved (see lookupValueRef to detect it), and so is an
+// expression that can't be evaluated.
+func resolveValueRef(val any, origin Molecule, partners ...Molecule) any {
+	if v, ok := lookupValueRef(val, origin, partners...); ok {
+		return v
+	}
+	return val
+}
+
+// lookupValueRef is like resolveValueRef, but reports false when val is a reference
+// to a payload field or partner that doesn't exist.
+func lookupValueRef(val any, origin Molecule, partners ...Molecule) (any, bool) {
 	s, ok := val.(string)
 	if !ok {
-		return val
-	}
-	if len(s) > 3 && s[:3] == "$m." {
-		field := s[3:]
-		// Check if it's a molecule field (energy, stability, etc.)
-		switch field {
-		case "energy":
-			return origin.Energy
-		case "stability":
-			return origin.Stability
-		case "id":
-			return string(origin.ID)
-		case "species":
-			return string(origin.Species)
-		case "created_at", "createdAt", "CreatedAt":
-			return origin.CreatedAt
-		case "last_touched_at", "lastTouchedAt", "LastTouchedAt":
-			return origin.LastTouchedAt
-		default:
-			// Otherwise, check payload
-			if v, ok := origin.Payload[field]; ok {
-				return v
-			}
+		return val, true
+	}
+	if ref, op, literal, refFirst, ok := parseExpression(s); ok {
+		refValue, found := lookupValueRef(ref, origin, partners...)
+		if !found {
+			return val, false
+		}
+		if result, ok := evalExpression(refValue, op, literal, refFirst); ok {
+			return result, true
 		}
+		return val, true
 	}
-	return val
+	index, field, ok := parseValueRef(s)
+	if !ok {
+		return val, true
+	}
+	if index < 0 {
+		return moleculeField(origin, field)
+	}
+	if index >= len(partners) {
+		return val, false
+	}
+	return moleculeField(partners[index], field)
+}
+
+// parseValueRef splits a reference into the molecule it points to (-1 for $m, or the
+// partner index) and the field name. ok is false if s is not a well-formed reference.
+func parseValueRef(s string) (index int, field string, ok bool) {
+	switch {
+	case strings.HasPrefix(s, "$m."):
+		index, field = -1, s[len("$m."):]
+	case strings.HasPrefix(s, "$partner."):
+		index, field = 0, s[len("$partner."):]
+	case strings.HasPrefix(s, "$p["):
+		end := strings.Index(s, "].")
+		if end < 0 {
+			return 0, "", false
+		}
+		n, err := strconv.Atoi(s[len("$p["):end])
+		if err != nil || n < 0 {
+			return 0, "", false
+		}
+		index, field = n, s[end+len("]."):]
+	default:
+		return 0, "", false
+	}
+	return index, field, field != ""
+}
+
+// moleculeField returns a molecule field (energy, stability, etc.) or payload field of mol
+func moleculeField(mol Molecule, field string) (any, bool) {
+	switch field {
+	case "energy":
+		return mol.Energy, true
+	case "stability":
+		return mol.Stability, true
+	case "id":
+		return string(mol.ID), true
+	case "species":
+		return string(mol.Species), true
+	case "created_at", "createdAt", "CreatedAt":
+		return mol.CreatedAt, true
+	case "last_touched_at", "lastTouchedAt", "LastTouchedAt":
+		return mol.LastTouchedAt, true
+	default:
+		// Otherwise, check payload
+		v, ok := mol.Payload[field]
+		return v, ok
+	}
+}
+
+// parseExpression splits s into a reference, an arithmetic operator (+ - * /) and
+// a numeric literal, in either order, e.g. "$m.energy * 2" or "100 - $p[0].score".
+// Operands and operator must be separated by spaces. refFirst reports whether the
+// reference is the left operand.
+func parseExpression(s string) (ref string, op byte, literal float64, refFirst bool, ok bool) {
+	parts := strings.Fields(s)
+	if len(parts) != 3 || len(parts[1]) != 1 || !strings.Contains("+-*/", parts[1]) {
+		return "", 0, 0, false, false
+	}
+	left, right := parts[0], parts[2]
+	_, _, refFirst = parseValueRef(left)
+	if refFirst {
+		ref = left
+		literal, ok = parseNumericString(right)
+	} else if _, _, isRef := parseValueRef(right); isRef {
+		ref = right
+		literal, ok = parseNumericString(left)
+	}
+	if !ok {
+		return "", 0, 0, false, false
+	}
+	return ref, parts[1][0], literal, refFirst, true
+}
+
+// evalExpression applies op to a resolved reference value and a literal. The value is
+// coerced with toFloat64, so int and float payload fields both work, and the result is
+// always a float64. Returns false if the value isn't numeric or on division by zero.
+func evalExpression(refValue any, op byte, literal float64, refFirst bool) (float64, bool) {
+	v, ok := toFloat64(refValue)
+	if !ok {
+		return 0, false
+	}
+	a, b := v, literal
+	if !refFirst {
+		a, b = literal, v
+	}
+	switch op {
+	case '+':
+		return a + b, true
+	case '-':
+		return a - b, true
+	case '*':
+		return a * b, true
+	case '/':
+		if b == 0 {
+			return 0, false
+		}
+		return a / b, true
+	}
+	return 0, false
 }
 
 // matchWhere checks if a candidate molecule matches the WhereConfig conditions.
 // The origin molecule is used for resolving $m.* references in the conditions.
+// Numeric values are compared with the given tolerance (see numericEqual).
 // Returns true only if all conditions match.
-func matchWhere(where WhereConfig, candidate Molecule, origin Molecule) bool {
+func matchWhere(where WhereConfig, candidate Molecule, origin Molecule, tol float64) bool {
 	for field, cond := range where {
-		// Resolve the condition value (might be "$m.field")
-		condValue := resolveValueRef(cond.Eq, origin)
-
+		if isWhereGroupKey(field) {
+			if !matchWhereGroup(field, cond.Clauses, candidate, origin, tol) {
+				return false
+			}
+			continue
+		}
 		candidateValue, ok := candidate.Payload[field]
-		if !ok || candidateValue != condValue {
+		if !ok || !matchCondition(cond, candidateValue, origin, tol) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWhereGroup evaluates the clauses of a "$or" (any must match) or "$and" (all
+// must match) group.
+func matchWhereGroup(key string, clauses []WhereConfig, candidate Molecule, origin Molecule, tol float64) bool {
+	for _, clause := range clauses {
+		matched := matchWhere(clause, candidate, origin, tol)
+		if key == WhereKeyOr && matched {
+			return true
+		}
+		if key == WhereKeyAnd && !matched {
+			return false
+		}
+	}
+	return key == WhereKeyAnd
+}
+
+// matchCondition checks a payload value against every operator set in cond.
+// Condition values (including list elements) may be $m.* references.
+func matchCondition(cond EqCondition, value any, origin Molecule, tol float64) bool {
+	if cond.Eq != nil || cond.isEq() {
+		if !valuesEqual(value, resolveValueRef(cond.Eq, origin), tol) {
+			return false
+		}
+	}
+	if cond.Ne != nil && valuesEqual(value, resolveValueRef(cond.Ne, origin), tol) {
+		return false
+	}
+	if cond.In != nil && !valueInList(value, cond.In, origin, tol) {
+		return false
+	}
+	if cond.Nin != nil && valueInList(value, cond.Nin, origin, tol) {
+		return false
+	}
+	if cond.Regex != "" && !matchRegex(cond, value) {
+		return false
+	}
+	for _, r := range [...]struct {
+		op    string
+		bound any
+	}{{"gt", cond.Gt}, {"gte", cond.Gte}, {"lt", cond.Lt}, {"lte", cond.Lte}} {
+		if r.bound != nil && !compareValues(value, resolveValueRef(r.bound, origin), r.op, tol) {
 			return false
 		}
 	}
 	return true
 }
 
+// valueInList reports whether value equals one of the (resolved) list elements.
+func valueInList(value any, list []any, origin Molecule, tol float64) bool {
+	for _, item := range list {
+		if valuesEqual(value, resolveValueRef(item, origin), tol) {
+			return true
+		}
+	}
+	return false
+}
+
 // filterBySpeciesAndWhere returns molecules of a given species that match the given where,
 // using indexes when possible, and falling back to a linear scan otherwise.
-func filterBySpeciesAndWhere(env EnvView, species SpeciesName, where WhereConfig, origin Molecule) []Molecule {
+func filterBySpeciesAndWhere(env EnvView, species SpeciesName, where WhereConfig, origin Molecule, tol float64) []Molecule {
 	// If where is empty, just return by species
 	if len(where) == 0 {
 		return env.MoleculesBySpecies(species)
@@ -77,19 +315,39 @@ func filterBySpeciesAndWhere(env EnvView, species SpeciesName, where WhereConfig
 
 	// Try to use index only for the simple case:
 	// - underlying env is our concrete envView
-	// - where has exactly one field
+	// - where has exactly one field, with a plain equality condition
+	// - for numbers, the tolerance spans few index buckets
 	if v, ok := env.(envView); ok && v.bySpeciesFieldValue != nil && len(where) == 1 {
 		for field, cond := range where {
+			if !cond.isEq() {
+				break
+			}
 			// resolve the comparison value (may involve $m.*)
 			targetValue := resolveValueRef(cond.Eq, origin)
-			key := indexKeyFromValue(targetValue)
+			keys, ok := indexKeysForEq(targetValue, tol)
+			if !ok {
+				break
+			}
 
 			if fieldMap, ok := v.bySpeciesFieldValue[species]; ok {
 				if mols, ok := fieldMap[field]; ok {
-					if indexed, ok := mols[key]; ok {
-						// Return a copy to avoid external modification
-						out := make([]Molecule, len(indexed))
-						copy(out, indexed)
+					var indexed []Molecule
+					found := false
+					for _, key := range keys {
+						if bucket, ok := mols[key]; ok {
+							indexed = append(indexed, bucket...)
+							found = true
+						}
+					}
+					if found {
+						// Return a copy to avoid external modification, re-checking each
+						// candidate since index keys are shared by nearby values
+						out := make([]Molecule, 0, len(indexed))
+						for _, candidate := range indexed {
+							if matchWhere(where, candidate, origin, tol) {
+								out = append(out, candidate)
+							}
+						}
 						return out
 					}
 				}
@@ -102,7 +360,7 @@ func filterBySpeciesAndWhere(env EnvView, species SpeciesName, where WhereConfig
 	candidates := env.MoleculesBySpecies(species)
 	out := make([]Molecule, 0, len(candidates))
 	for _, candidate := range candidates {
-		if matchWhere(where, candidate, origin) {
+		if matchWhere(where, candidate, origin, tol) {
 			out = append(out, candidate)
 		}
 	}
@@ -1,6 +1,9 @@
 package achem
 
 import (
+	"encoding/json"
+	"math"
+	"reflect"
 	"testing"
 )
 
@@ -11,16 +14,21 @@ func TestIndexKeyFromValue(t *testing.T) {
 		t.Errorf("Expected 'test', got '%s'", key)
 	}
 
-	// Test with int
+	// Numbers are bucketed by their integral part, whatever their type
 	key = indexKeyFromValue(42)
-	if key != "42" {
-		t.Errorf("Expected '42', got '%s'", key)
+	if key != "#42" {
+		t.Errorf("Expected '#42', got '%s'", key)
+	}
+	if other := indexKeyFromValue(float64(42)); other != key {
+		t.Errorf("Expected int and float64 42 to share a key, got '%s' and '%s'", key, other)
 	}
-
-	// Test with float
 	key = indexKeyFromValue(3.14)
-	if key != "3.14" {
-		t.Errorf("Expected '3.14', got '%s'", key)
+	if key != "#3" {
+		t.Errorf("Expected '#3', got '%s'", key)
+	}
+	key = indexKeyFromValue(-0.5)
+	if key != "#-1" {
+		t.Errorf("Expected '#-1', got '%s'", key)
 	}
 
 	// Test with bool
@@ -141,6 +149,87 @@ func TestResolveValueRef_TimestampFields(t *testing.T) {
 	}
 }
 
+func TestResolveValueRef_Expressions(t *testing.T) {
+	mol := NewMolecule("Test", map[string]any{
+		"count": 3,        // int payload, coerced via toFloat64
+		"price": 9.5,      // float payload
+		"total": int64(8), // int64 payload
+		"name":  "widget",
+	}, 0)
+	mol.Energy = 1.5
+
+	tests := []struct {
+		expr     string
+		expected any
+	}{
+		{"$m.energy * 2", 3.0},
+		{"$m.count + 1", 4.0},
+		{"$m.price + 10", 19.5},
+		{"$m.total / 4", 2.0},
+		{"$m.count - 0.5", 2.5},
+		{"100 - $m.count", 97.0},
+		{"10 / $m.total", 1.25},
+		{"$m.count * -2", -6.0},
+		// invalid expressions fall back to the literal string
+		{"$m.name * 2", "$m.name * 2"},
+		{"$m.count / 0", "$m.count / 0"},
+		{"$m.count % 2", "$m.count % 2"},
+		{"$m.count + $m.price", "$m.count + $m.price"},
+		{"2 + 3", "2 + 3"},
+		{"$m.count+1", "$m.count+1"},
+		{"$m.missing + 1", "$m.missing + 1"},
+	}
+
+	for _, tt := range tests {
+		if result := resolveValueRef(tt.expr, mol); result != tt.expected {
+			t.Errorf("resolveValueRef(%q) = %v (%T), expected %v (%T)", tt.expr, result, result, tt.expected, tt.expected)
+		}
+	}
+
+	// a missing field is reported like a plain reference
+	if _, ok := lookupValueRef("$m.missing + 1", mol); ok {
+		t.Error("Expected lookupValueRef to report a missing field in an expression")
+	}
+}
+
+func TestResolveValueRef_Partners(t *testing.T) {
+	mol := NewMolecule("A", map[string]any{"name": "input"}, 0)
+	first := NewMolecule("B", map[string]any{"name": "first", "score": 2}, 0)
+	second := NewMolecule("B", map[string]any{"name": "second"}, 0)
+	partners := []Molecule{first, second}
+
+	tests := []struct {
+		expr     string
+		expected any
+	}{
+		{"$m.name", "input"},
+		{"$p[0].name", "first"},
+		{"$p[1].name", "second"},
+		{"$p[1].id", string(second.ID)},
+		{"$partner.name", "first"},
+		{"$p[0].score * 10", 20.0},
+		{"1 + $partner.score", 3.0},
+		// missing partners, fields and malformed references fall back to the literal
+		{"$p[2].name", "$p[2].name"},
+		{"$p[0].missing", "$p[0].missing"},
+		{"$p[x].name", "$p[x].name"},
+		{"$p[-1].name", "$p[-1].name"},
+		{"$p[0]", "$p[0]"},
+		{"$partner.", "$partner."},
+	}
+
+	for _, tt := range tests {
+		if result := resolveValueRef(tt.expr, mol, partners...); result != tt.expected {
+			t.Errorf("resolveValueRef(%q) = %v (%T), expected %v (%T)", tt.expr, result, result, tt.expected, tt.expected)
+		}
+	}
+
+	// without partners (e.g. in where conditions), partner references are unresolved
+	if _, ok := lookupValueRef("$partner.name", mol); ok {
+		t.Error("Expected lookupValueRef to report a partner reference without partners")
+	}
+}
+
 func TestMatchWhere(t *testing.T) {
 	origin := NewMolecule("Origin", map[string]any{
 		"value": 100,
@@ -154,7 +243,7 @@ func TestMatchWhere(t *testing.T) {
 
 	// Test empty where (should match)
 	where := WhereConfig{}
-	if !matchWhere(where, candidate, origin) {
+	if !matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected match with empty where")
 	}
 
@@ -162,7 +251,7 @@ func TestMatchWhere(t *testing.T) {
 	where = WhereConfig{
 		"value": EqCondition{Eq: 100},
 	}
-	if !matchWhere(where, candidate, origin) {
+	if !matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected match when value equals 100")
 	}
 
@@ -170,7 +259,7 @@ func TestMatchWhere(t *testing.T) {
 	where = WhereConfig{
 		"value": EqCondition{Eq: 200},
 	}
-	if matchWhere(where, candidate, origin) {
+	if matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected no match when value doesn't equal 200")
 	}
 
@@ -178,7 +267,7 @@ func TestMatchWhere(t *testing.T) {
 	where = WhereConfig{
 		"missing": EqCondition{Eq: "value"},
 	}
-	if matchWhere(where, candidate, origin) {
+	if matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected no match when field is missing")
 	}
 
@@ -186,7 +275,7 @@ func TestMatchWhere(t *testing.T) {
 	where = WhereConfig{
 		"value": EqCondition{Eq: "$m.value"},
 	}
-	if !matchWhere(where, candidate, origin) {
+	if !matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected match when using $m.value reference")
 	}
 
@@ -195,7 +284,7 @@ func TestMatchWhere(t *testing.T) {
 		"value": EqCondition{Eq: 100},
 		"name":  EqCondition{Eq: "test"},
 	}
-	if !matchWhere(where, candidate, origin) {
+	if !matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected match when all conditions match")
 	}
 
@@ -204,7 +293,7 @@ func TestMatchWhere(t *testing.T) {
 		"value": EqCondition{Eq: 100},
 		"name":  EqCondition{Eq: "wrong"},
 	}
-	if matchWhere(where, candidate, origin) {
+	if matchWhere(where, candidate, origin, DefaultFloatTolerance) {
 		t.Error("Expected no match when one condition doesn't match")
 	}
 }
@@ -227,7 +316,7 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 	origin := NewMolecule("Origin", map[string]any{"value": 1}, 0)
 
 	// Test with empty where (should return all molecules of species)
-	results := filterBySpeciesAndWhere(env, "Species1", WhereConfig{}, origin)
+	results := filterBySpeciesAndWhere(env, "Species1", WhereConfig{}, origin, DefaultFloatTolerance)
 	if len(results) != 3 {
 		t.Errorf("Expected 3 molecules, got %d", len(results))
 	}
@@ -236,7 +325,7 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 	where := WhereConfig{
 		"value": EqCondition{Eq: 1},
 	}
-	results = filterBySpeciesAndWhere(env, "Species1", where, origin)
+	results = filterBySpeciesAndWhere(env, "Species1", where, origin, DefaultFloatTolerance)
 	if len(results) != 2 {
 		t.Errorf("Expected 2 molecules with value=1, got %d", len(results))
 	}
@@ -245,13 +334,13 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 	where = WhereConfig{
 		"value": EqCondition{Eq: "$m.value"},
 	}
-	results = filterBySpeciesAndWhere(env, "Species1", where, origin)
+	results = filterBySpeciesAndWhere(env, "Species1", where, origin, DefaultFloatTolerance)
 	if len(results) != 2 {
 		t.Errorf("Expected 2 molecules matching origin value, got %d", len(results))
 	}
 
 	// Test with non-existent species
-	results = filterBySpeciesAndWhere(env, "NonExistent", WhereConfig{}, origin)
+	results = filterBySpeciesAndWhere(env, "NonExistent", WhereConfig{}, origin, DefaultFloatTolerance)
 	if len(results) != 0 {
 		t.Errorf("Expected 0 molecules for non-existent species, got %d", len(results))
 	}
@@ -275,7 +364,7 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 	where = WhereConfig{
 		"value": EqCondition{Eq: 1},
 	}
-	results = filterBySpeciesAndWhere(indexedEnv, "Species1", where, origin)
+	results = filterBySpeciesAndWhere(indexedEnv, "Species1", where, origin, DefaultFloatTolerance)
 	if len(results) != 2 {
 		t.Errorf("Expected 2 molecules using index, got %d", len(results))
 	}
@@ -284,7 +373,7 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 	where = WhereConfig{
 		"value": EqCondition{Eq: "$m.value"},
 	}
-	results = filterBySpeciesAndWhere(indexedEnv, "Species1", where, origin)
+	results = filterBySpeciesAndWhere(indexedEnv, "Species1", where, origin, DefaultFloatTolerance)
 	if len(results) != 2 {
 		t.Errorf("Expected 2 molecules using index with $m. reference, got %d", len(results))
 	}
@@ -294,7 +383,7 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 		"value": EqCondition{Eq: 1},
 		"tag":   EqCondition{Eq: "a"},
 	}
-	results = filterBySpeciesAndWhere(indexedEnv, "Species1", where, origin)
+	results = filterBySpeciesAndWhere(indexedEnv, "Species1", where, origin, DefaultFloatTolerance)
 	if len(results) != 1 {
 		t.Errorf("Expected 1 molecule with both conditions, got %d", len(results))
 	}
@@ -304,6 +393,11 @@ func TestFilterBySpeciesAndWhere(t *testing.T) {
 type mockEnvView struct {
 	molecules []Molecule
 	bySpecies map[SpeciesName][]Molecule
+	now       int64
+}
+
+func (m *mockEnvView) EnvTime() int64 {
+	return m.now
 }
 
 func (m *mockEnvView) MoleculesBySpecies(species SpeciesName) []Molecule {
@@ -323,3 +417,296 @@ func (m *mockEnvView) Find(filter func(Molecule) bool) []Molecule {
 	}
 	return results
 }
+
+func (m *mockEnvView) FindBest(species SpeciesName, less func(a, b Molecule) bool) (Molecule, bool) {
+	return findBest(m.MoleculesBySpecies(species), less)
+}
+
+func TestNumericEqual(t *testing.T) {
+	a, b := 0.1, 0.2 // computed at runtime: 0.30000000000000004
+	tests := []struct {
+		a, b, tol float64
+		want      bool
+	}{
+		{42, 42, DefaultFloatTolerance, true},
+		{42, 43, 0.5, false}, // integral values are always exact
+		{a + b, 0.3, DefaultFloatTolerance, true},
+		{a + b, 0.3, 0, false},
+		{1.5, 1.6, 0.2, true},
+		{1.5, 1.6, 0.01, false},
+	}
+	for _, tt := range tests {
+		if got := numericEqual(tt.a, tt.b, tt.tol); got != tt.want {
+			t.Errorf("numericEqual(%v, %v, %v) = %v, want %v", tt.a, tt.b, tt.tol, got, tt.want)
+		}
+	}
+}
+
+func TestMatchWhere_NumericCoercion(t *testing.T) {
+	origin := NewMolecule("Test", map[string]any{}, 0)
+
+	// JSON decoded payloads hold float64 while Go callers often use int
+	a, b := 0.1, 0.2
+	candidate := NewMolecule("Test", map[string]any{"count": float64(42), "ratio": a + b}, 0)
+
+	if !matchWhere(WhereConfig{"count": {Eq: 42}}, candidate, origin, DefaultFloatTolerance) {
+		t.Error("Expected int 42 to match float64 42")
+	}
+	if !matchWhere(WhereConfig{"count": {Eq: int64(42)}}, candidate, origin, 0) {
+		t.Error("Expected int64 42 to match float64 42 with exact tolerance")
+	}
+	if !matchWhere(WhereConfig{"ratio": {Eq: 0.3}}, candidate, origin, DefaultFloatTolerance) {
+		t.Error("Expected 0.1+0.2 to match 0.3 within the default tolerance")
+	}
+	if matchWhere(WhereConfig{"ratio": {Eq: 0.3}}, candidate, origin, 0) {
+		t.Error("Expected 0.1+0.2 not to match 0.3 with exact tolerance")
+	}
+	if matchWhere(WhereConfig{"count": {Eq: "42"}}, candidate, origin, DefaultFloatTolerance) {
+		t.Error("Expected string '42' not to match numeric 42")
+	}
+}
+
+func TestFilterBySpeciesAndWhere_IndexedNumericTolerance(t *testing.T) {
+	a, b := 0.1, 0.2
+	m1 := NewMolecule("Metric", map[string]any{"value": a + b}, 0)
+	m2 := NewMolecule("Metric", map[string]any{"value": 0.3}, 0)
+	// just below a bucket boundary, and a whole number
+	m3 := NewMolecule("Metric", map[string]any{"value": 2.9999999999}, 0)
+	m4 := NewMolecule("Metric", map[string]any{"value": 3}, 0)
+	index := map[string][]Molecule{}
+	for _, m := range []Molecule{m1, m2, m3, m4} {
+		key := indexKeyFromValue(m.Payload["value"])
+		index[key] = append(index[key], m)
+	}
+	// a fake molecule only reachable through the index, to tell lookups from scans
+	marker := NewMolecule("Metric", map[string]any{"value": 3.0}, 0)
+	index["#3"] = append(index["#3"], marker)
+	view := envView{
+		molecules:           []Molecule{m1, m2, m3, m4},
+		bySpecies:           map[SpeciesName][]Molecule{"Metric": {m1, m2, m3, m4}},
+		bySpeciesFieldValue: map[SpeciesName]map[string]map[string][]Molecule{"Metric": {"value": index}},
+	}
+
+	where := WhereConfig{"value": {Eq: 0.3}}
+	if got := filterBySpeciesAndWhere(view, "Metric", where, m1, DefaultFloatTolerance); len(got) != 2 {
+		t.Errorf("Expected both molecules within tolerance, got %d", len(got))
+	}
+	if got := filterBySpeciesAndWhere(view, "Metric", where, m1, 0); len(got) != 1 {
+		t.Errorf("Expected exact match only, got %d", len(got))
+	}
+
+	// the neighbouring bucket is read when the tolerance crosses a boundary
+	got := filterBySpeciesAndWhere(view, "Metric", WhereConfig{"value": {Eq: 3.0}}, m1, 1e-6)
+	ids := map[MoleculeID]bool{}
+	for _, m := range got {
+		ids[m.ID] = true
+	}
+	if len(got) != 3 || !ids[m3.ID] || !ids[m4.ID] || !ids[marker.ID] {
+		t.Errorf("Expected m3, m4 and the index marker, got %v", got)
+	}
+
+	// a tolerance spanning many buckets scans instead
+	got = filterBySpeciesAndWhere(view, "Metric", WhereConfig{"value": {Eq: 3.0}}, m1, 100)
+	if len(got) != 4 {
+		t.Errorf("Expected a scan to find all 4 molecules, got %d", len(got))
+	}
+}
+
+func TestIndexKeysForEq(t *testing.T) {
+	tests := []struct {
+		value any
+		tol   float64
+		want  []string
+		ok    bool
+	}{
+		{"paid", 0.5, []string{"paid"}, true},
+		{42, DefaultFloatTolerance, []string{"#41", "#42"}, true},
+		{2.5, DefaultFloatTolerance, []string{"#2"}, true},
+		{2.5, 0, []string{"#2"}, true},
+		{2.5, 1, []string{"#1", "#2", "#3"}, true},
+		{2.5, maxNumericIndexBuckets, nil, false},
+		{math.Inf(1), 1, []string{"#+Inf"}, true},
+	}
+	for _, tt := range tests {
+		keys, ok := indexKeysForEq(tt.value, tt.tol)
+		if ok != tt.ok || !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("indexKeysForEq(%v, %v) = %v, %v; want %v, %v", tt.value, tt.tol, keys, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchWhere_SetOperators(t *testing.T) {
+	origin := NewMolecule("Origin", map[string]any{"ip": "1.2.3.4"}, 0)
+	candidate := NewMolecule("Event", map[string]any{"type": "login_failed", "code": 401, "ip": "1.2.3.4"}, 0)
+
+	tests := []struct {
+		name  string
+		where WhereConfig
+		want  bool
+	}{
+		{"ne different", WhereConfig{"type": {Ne: "logout"}}, true},
+		{"ne equal", WhereConfig{"type": {Ne: "login_failed"}}, false},
+		{"ne numeric coercion", WhereConfig{"code": {Ne: 401.0}}, false},
+		{"ne missing field", WhereConfig{"missing": {Ne: "x"}}, false},
+		{"in match", WhereConfig{"type": {In: []any{"logout", "login_failed"}}}, true},
+		{"in numeric", WhereConfig{"code": {In: []any{float64(401), float64(403)}}}, true},
+		{"in no match", WhereConfig{"type": {In: []any{"logout"}}}, false},
+		{"in empty", WhereConfig{"type": {In: []any{}}}, false},
+		{"in reference", WhereConfig{"ip": {In: []any{"$m.ip"}}}, true},
+		{"nin match", WhereConfig{"code": {Nin: []any{200, 204}}}, true},
+		{"nin excluded", WhereConfig{"code": {Nin: []any{401}}}, false},
+		{"nin reference", WhereConfig{"ip": {Nin: []any{"$m.ip"}}}, false},
+		{"eq and ne combined", WhereConfig{"code": {Eq: 401, Ne: 403}}, true},
+		{"in and nin combined", WhereConfig{"code": {In: []any{401, 403}, Nin: []any{401}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchWhere(tt.where, candidate, origin, DefaultFloatTolerance); got != tt.want {
+				t.Errorf("matchWhere(%+v) = %v, want %v", tt.where, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterBySpeciesAndWhere_SetOperatorsSkipIndex(t *testing.T) {
+	m1 := NewMolecule("Event", map[string]any{"type": "a"}, 0)
+	m2 := NewMolecule("Event", map[string]any{"type": "b"}, 0)
+	view := envView{
+		molecules: []Molecule{m1, m2},
+		bySpecies: map[SpeciesName][]Molecule{"Event": {m1, m2}},
+		bySpeciesFieldValue: map[SpeciesName]map[string]map[string][]Molecule{
+			"Event": {"type": {"a": {m1}, "b": {m2}}},
+		},
+	}
+
+	// the zero-value Eq of a ne condition must not be looked up in the index
+	got := filterBySpeciesAndWhere(view, "Event", WhereConfig{"type": {Ne: "a"}}, m1, DefaultFloatTolerance)
+	if len(got) != 1 || got[0].ID != m2.ID {
+		t.Errorf("Expected only the 'b' molecule, got %v", got)
+	}
+	got = filterBySpeciesAndWhere(view, "Event", WhereConfig{"type": {In: []any{"a", "b"}}}, m1, DefaultFloatTolerance)
+	if len(got) != 2 {
+		t.Errorf("Expected both molecules, got %d", len(got))
+	}
+}
+
+func TestMatchWhere_RangeOperators(t *testing.T) {
+	origin := NewMolecule("Origin", map[string]any{"limit": 5}, 0)
+	candidate := NewMolecule("Event", map[string]any{"score": 7.5, "name": "b"}, 0)
+
+	tests := []struct {
+		name  string
+		where WhereConfig
+		want  bool
+	}{
+		{"gt", WhereConfig{"score": {Gt: 7}}, true},
+		{"gt equal", WhereConfig{"score": {Gt: 7.5}}, false},
+		{"gte equal", WhereConfig{"score": {Gte: 7.5}}, true},
+		{"lt", WhereConfig{"score": {Lt: 10}}, true},
+		{"lte below", WhereConfig{"score": {Lte: 7}}, false},
+		{"range inside", WhereConfig{"score": {Gte: 5, Lt: 10}}, true},
+		{"range outside", WhereConfig{"score": {Gte: 8, Lt: 10}}, false},
+		{"reference", WhereConfig{"score": {Gt: "$m.limit"}}, true},
+		{"expression", WhereConfig{"score": {Gt: "$m.limit * 2"}}, false},
+		{"string", WhereConfig{"name": {Gt: "a"}}, true},
+		{"missing field", WhereConfig{"missing": {Lt: 100}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchWhere(tt.where, candidate, origin, DefaultFloatTolerance); got != tt.want {
+				t.Errorf("matchWhere(%+v) = %v, want %v", tt.where, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchWhere_Groups(t *testing.T) {
+	origin := NewMolecule("Origin", map[string]any{"ip": "10.0.0.1"}, 0)
+	candidate := NewMolecule("Event", map[string]any{"type": "password_reset", "ip": "10.0.0.1", "score": 3}, 0)
+
+	typeIs := func(v string) WhereConfig { return WhereConfig{"type": {Eq: v}} }
+
+	tests := []struct {
+		name  string
+		where WhereConfig
+		want  bool
+	}{
+		{"or any", WhereConfig{"$or": {Clauses: []WhereConfig{typeIs("login_failed"), typeIs("password_reset")}}}, true},
+		{"or none", WhereConfig{"$or": {Clauses: []WhereConfig{typeIs("login_failed"), typeIs("logout")}}}, false},
+		{"and all", WhereConfig{"$and": {Clauses: []WhereConfig{typeIs("password_reset"), {"score": {Lt: 5}}}}}, true},
+		{"and one fails", WhereConfig{"$and": {Clauses: []WhereConfig{typeIs("password_reset"), {"score": {Gt: 5}}}}}, false},
+		{"combined with fields", WhereConfig{
+			"ip":  {Eq: "$m.ip"},
+			"$or": {Clauses: []WhereConfig{typeIs("login_failed"), typeIs("password_reset")}},
+		}, true},
+		{"nested", WhereConfig{"$or": {Clauses: []WhereConfig{
+			typeIs("login_failed"),
+			{"$and": {Clauses: []WhereConfig{typeIs("password_reset"), {"score": {Gte: 3}}}}},
+		}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchWhere(tt.where, candidate, origin, DefaultFloatTolerance); got != tt.want {
+				t.Errorf("matchWhere(%+v) = %v, want %v", tt.where, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchWhere_Regex(t *testing.T) {
+	candidate := NewMolecule("Request", map[string]any{"ip": "10.1.2.3", "path": "/admin/users", "status": 403}, 0)
+
+	tests := []struct {
+		name  string
+		where WhereConfig
+		want  bool
+	}{
+		{"prefix", WhereConfig{"ip": {Regex: `^10\.1\.`}}, true},
+		{"no match", WhereConfig{"ip": {Regex: `^192\.168\.`}}, false},
+		{"unanchored", WhereConfig{"path": {Regex: `admin`}}, true},
+		{"number as string", WhereConfig{"status": {Regex: `^4\d\d$`}}, true},
+		{"combined with ne", WhereConfig{"path": {Regex: `^/admin/`, Ne: "/admin/users"}}, false},
+		{"invalid pattern", WhereConfig{"path": {Regex: `(`}}, false},
+		{"missing field", WhereConfig{"missing": {Regex: `.*`}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchWhere(tt.where, candidate, candidate, DefaultFloatTolerance); got != tt.want {
+				t.Errorf("matchWhere(%+v) = %v, want %v", tt.where, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqCondition_UnmarshalJSON(t *testing.T) {
+	var where WhereConfig
+	if err := json.Unmarshal([]byte(`{"score": {"gte": 1, "lt": 10}, "type": {"like": "x", "eq": "a"}}`), &where); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if where["score"].Gte != float64(1) || where["score"].Lt != float64(10) || len(where["score"].unknownOps) != 0 {
+		t.Errorf("Unexpected score condition: %+v", where["score"])
+	}
+	if where["type"].Eq != "a" || len(where["type"].unknownOps) != 1 || where["type"].unknownOps[0] != "like" {
+		t.Errorf("Expected unknown operator 'like' to be recorded, got %+v", where["type"])
+	}
+}
+
+func TestEqCondition_GroupJSON(t *testing.T) {
+	input := `{"$or":[{"type":{"eq":"login_failed"}},{"$and":[{"type":{"eq":"password_reset"}},{"score":{"gte":3}}]}]}`
+	var where WhereConfig
+	if err := json.Unmarshal([]byte(input), &where); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	clauses := where["$or"].Clauses
+	if len(clauses) != 2 || clauses[0]["type"].Eq != "login_failed" || len(clauses[1]["$and"].Clauses) != 2 {
+		t.Fatalf("Unexpected groups: %+v", where)
+	}
+
+	data, err := json.Marshal(where)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != input {
+		t.Errorf("Expected groups to round-trip as %s, got %s", input, data)
+	}
+}
@@ -0,0 +1,28 @@
+package achem
+
+import "time"
+
+// Metrics receives engine activity so that it can be exported to a monitoring system.
+// Implementations must be safe for concurrent use. They are never called with an
+// environment lock held.
+type Metrics interface {
+	// ObserveStep is called after every applied step with the counters of that tick.
+	ObserveStep(envID EnvironmentID, tick Counters)
+	// ObserveNotification is called once per notifier delivery, with the last error
+	// if every attempt failed.
+	ObserveNotification(notifierID string, err error)
+	// ObserveSnapshot is called after every snapshot write attempt.
+	ObserveSnapshot(envID EnvironmentID, duration time.Duration, err error)
+}
+
+// NoOpMetrics is a Metrics implementation that discards everything (used when metrics are disabled)
+type NoOpMetrics struct{}
+
+func (n *NoOpMetrics) ObserveStep(envID EnvironmentID, tick Counters)                         {}
+func (n *NoOpMetrics) ObserveNotification(notifierID string, err error)                       {}
+func (n *NoOpMetrics) ObserveSnapshot(envID EnvironmentID, duration time.Duration, err error) {}
+
+// NewNoOpMetrics creates a no-op metrics recorder
+func NewNoOpMetrics() Metrics {
+	return &NoOpMetrics{}
+}
@@ -0,0 +1,106 @@
+package achem
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics is a Metrics implementation that keeps every observation
+type recordingMetrics struct {
+	mu            sync.Mutex
+	steps         []Counters
+	notifications map[string][]error
+	snapshots     int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{notifications: make(map[string][]error)}
+}
+
+func (r *recordingMetrics) ObserveStep(_ EnvironmentID, tick Counters) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, tick)
+}
+
+func (r *recordingMetrics) ObserveNotification(notifierID string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications[notifierID] = append(r.notifications[notifierID], err)
+}
+
+func (r *recordingMetrics) ObserveSnapshot(EnvironmentID, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshots++
+}
+
+func TestEnvironment_Metrics(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "metrics",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "a_to_b",
+				Input:   InputConfig{Species: "A"},
+				Rate:    1.0,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "B"}}},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	metrics := newRecordingMetrics()
+	env := NewEnvironment(schema)
+	env.SetMetrics(metrics)
+	env.SetSnapshotDir(t.TempDir())
+	env.Insert(NewMolecule("A", nil, 0))
+
+	env.Step()
+	env.Step()
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if len(metrics.steps) != 2 {
+		t.Fatalf("Expected 2 observed steps, got %d", len(metrics.steps))
+	}
+	first := metrics.steps[0]
+	if first.ReactionsFired["a_to_b"] != 1 || first.MoleculesCreated != 1 || first.MoleculesConsumed != 1 {
+		t.Errorf("Unexpected first step counters: %+v", first)
+	}
+	if len(metrics.steps[1].ReactionsFired) != 0 {
+		t.Errorf("Expected no reaction in the second step, got %+v", metrics.steps[1])
+	}
+	if metrics.snapshots != 1 {
+		t.Errorf("Expected 1 observed snapshot, got %d", metrics.snapshots)
+	}
+}
+
+func TestNotificationManager_Metrics(t *testing.T) {
+	nm := NewNotificationManager()
+	defer nm.Close()
+
+	metrics := newRecordingMetrics()
+	nm.SetMetrics(metrics)
+	_ = nm.RegisterNotifier(&mockNotifier{id: "ok"})
+
+	nm.notifyWithRetry(context.Background(), "ok", NotificationEvent{})
+	nm.notifyWithRetry(context.Background(), "missing", NotificationEvent{})
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if errs := metrics.notifications["ok"]; len(errs) != 1 || errs[0] != nil {
+		t.Errorf("Expected one successful delivery to 'ok', got %v", errs)
+	}
+	if errs := metrics.notifications["missing"]; len(errs) != 1 || errs[0] == nil {
+		t.Errorf("Expected one failed delivery to 'missing', got %v", errs)
+	}
+}
@@ -14,21 +14,45 @@ type Molecule struct {
 	Tags          []string
 	CreatedAt     int64
 	LastTouchedAt int64
+	TTL           int64     `json:",omitempty"` // ticks after CreatedAt before the molecule expires (0 = never)
+	Position      *Position `json:",omitempty"` // location for spatial reactions (nil = no location)
+	Version       uint64    `json:",omitempty"` // set by the environment on every write, see UpdateMoleculeCAS
+}
+
+// Energy and stability of molecules created by NewMolecule. Species can override them
+// with their own defaults (see Species.DefaultEnergy).
+const (
+	DefaultMoleculeEnergy    = 1.0
+	DefaultMoleculeStability = 1.0
+)
+
+// MoleculeOption customizes a molecule created by NewMolecule.
+type MoleculeOption func(*Molecule)
+
+// WithTTL makes the molecule expire ttl ticks after its creation.
+func WithTTL(ttl int64) MoleculeOption {
+	return func(m *Molecule) {
+		m.TTL = ttl
+	}
 }
 
 // NewMolecule creates a new molecule with the specified species and payload.
 // The molecule is assigned a random ID and initialized with default energy
 // and stability values of 1.0. The time parameter sets both CreatedAt and
 // LastTouchedAt timestamps.
-func NewMolecule(species SpeciesName, payload map[string]any, time int64) Molecule {
-	return Molecule{
+func NewMolecule(species SpeciesName, payload map[string]any, time int64, opts ...MoleculeOption) Molecule {
+	m := Molecule{
 		ID:            MoleculeID(NewRandomID()),
 		Species:       species,
 		Payload:       payload,
-		Energy:        1.0,
-		Stability:     1.0,
+		Energy:        DefaultMoleculeEnergy,
+		Stability:     DefaultMoleculeStability,
 		Tags:          nil,
 		CreatedAt:     time,
 		LastTouchedAt: time,
 	}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
 }
@@ -94,6 +94,19 @@ func TestNewMolecule(t *testing.T) {
 	}
 }
 
+func TestNewMolecule_WithTTL(t *testing.T) {
+	if m := NewMolecule("Event", nil, 5); m.TTL != 0 {
+		t.Errorf("Expected no TTL by default, got %d", m.TTL)
+	}
+	m := NewMolecule("Event", nil, 5, WithTTL(10))
+	if m.TTL != 10 {
+		t.Errorf("Expected TTL 10, got %d", m.TTL)
+	}
+	if m.expired(14) || !m.expired(15) {
+		t.Error("Expected the molecule to expire at CreatedAt + TTL")
+	}
+}
+
 func TestNewMolecule_UniqueIDs(t *testing.T) {
 	// Test that each molecule gets a unique ID
 	ids := make(map[MoleculeID]bool)
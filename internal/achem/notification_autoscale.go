@@ -0,0 +1,151 @@
+package achem
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotificationAutoscale configures adaptive scaling of notification workers. When the
+// queue stays at or above HighWaterMark for SustainFor, the manager starts an extra
+// worker (one per CheckInterval) up to MaxWorkers in total. Extra workers are stopped
+// one per CheckInterval once the queue is empty again.
+type NotificationAutoscale struct {
+	HighWaterMark int           // queue length that counts as overloaded
+	SustainFor    time.Duration // how long the queue must stay overloaded before scaling up
+	MaxWorkers    int           // cap on the total number of workers, including the base ones
+	CheckInterval time.Duration // how often the queue length is sampled
+}
+
+// DefaultNotificationAutoscale returns a configuration suited to the default queue size.
+func DefaultNotificationAutoscale() NotificationAutoscale {
+	return NotificationAutoscale{
+		HighWaterMark: DefaultNotificationQueueSize * 3 / 4,
+		SustainFor:    time.Second,
+		MaxWorkers:    8,
+		CheckInterval: 250 * time.Millisecond,
+	}
+}
+
+// EnableAutoscale starts a monitor goroutine that adds temporary workers while the
+// queue is persistently full, and removes them when the backlog clears. Calling it
+// again replaces the configuration. Extra workers are stopped on Close.
+func (nm *NotificationManager) EnableAutoscale(cfg NotificationAutoscale) error {
+	if cfg.HighWaterMark <= 0 || cfg.HighWaterMark > cap(nm.jobs) {
+		return fmt.Errorf("high water mark must be between 1 and %d", cap(nm.jobs))
+	}
+	if cfg.MaxWorkers < nm.baseWorkers {
+		return fmt.Errorf("max workers must be at least %d", nm.baseWorkers)
+	}
+	if cfg.SustainFor < 0 || cfg.CheckInterval <= 0 {
+		return fmt.Errorf("sustain duration must be non-negative and check interval positive")
+	}
+
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+
+	if nm.closed {
+		return fmt.Errorf("notification manager is closed")
+	}
+
+	nm.autoscale = cfg
+	if nm.monitorStop == nil {
+		nm.monitorStop = make(chan struct{})
+		nm.monitorDone = make(chan struct{})
+		go nm.monitor(nm.monitorStop, nm.monitorDone)
+	}
+	return nil
+}
+
+// Workers returns the current number of notification workers.
+func (nm *NotificationManager) Workers() int {
+	nm.mu.RLock()
+	defer nm.mu.RUnlock()
+	return nm.baseWorkers + nm.extraWorkers
+}
+
+// monitor samples the queue length and scales workers according to nm.autoscale.
+func (nm *NotificationManager) monitor(stopCh <-chan struct{}, doneCh chan<- struct{}) {
+	defer close(doneCh)
+
+	var overloadedSince time.Time
+	for {
+		nm.mu.RLock()
+		cfg := nm.autoscale
+		nm.mu.RUnlock()
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(cfg.CheckInterval):
+		}
+
+		depth := len(nm.jobs)
+		switch {
+		case depth >= cfg.HighWaterMark:
+			if overloadedSince.IsZero() {
+				overloadedSince = time.Now()
+			}
+			if time.Since(overloadedSince) >= cfg.SustainFor {
+				nm.scaleUp(cfg.MaxWorkers, depth)
+			}
+		case depth == 0:
+			overloadedSince = time.Time{}
+			nm.scaleDown()
+		default:
+			overloadedSince = time.Time{}
+		}
+	}
+}
+
+// scaleUp starts one extra worker unless the cap is reached.
+func (nm *NotificationManager) scaleUp(maxWorkers, depth int) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+
+	if nm.closed || nm.baseWorkers+nm.extraWorkers >= maxWorkers {
+		return
+	}
+	nm.extraWorkers++
+	nm.wg.Add(1)
+	go nm.extraWorker()
+	nm.logger.Warnf("notification queue overloaded, scaling up: queue_depth=%d workers=%d", depth, nm.baseWorkers+nm.extraWorkers)
+}
+
+// scaleDown stops one idle extra worker, if any.
+func (nm *NotificationManager) scaleDown() {
+	nm.mu.RLock()
+	extra := nm.extraWorkers
+	nm.mu.RUnlock()
+	if extra == 0 {
+		return
+	}
+
+	// only an idle worker can receive the signal, so busy ones finish their job first
+	select {
+	case nm.scaleDownCh <- struct{}{}:
+	default:
+		return
+	}
+
+	nm.mu.Lock()
+	nm.extraWorkers--
+	workers := nm.baseWorkers + nm.extraWorkers
+	nm.mu.Unlock()
+	nm.logger.Infof("notification backlog cleared, scaling down: workers=%d", workers)
+}
+
+// extraWorker processes jobs like worker, but also stops when asked to scale down.
+func (nm *NotificationManager) extraWorker() {
+	defer nm.wg.Done()
+	for {
+		select {
+		case job, ok := <-nm.jobs:
+			if !ok {
+				return
+			}
+			nm.dispatchJob(job)
+		case <-nm.scaleDownCh:
+			return
+		}
+	}
+}
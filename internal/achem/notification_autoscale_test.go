@@ -0,0 +1,126 @@
+package achem
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the timeout expires.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestNotificationManager_Autoscale(t *testing.T) {
+	nm := NewNotificationManager()
+	release := make(chan struct{})
+	notifier := &mockNotifier{
+		id: "slow",
+		notifyFunc: func(ctx context.Context, _ NotificationEvent) error {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}
+	if err := nm.RegisterNotifier(notifier); err != nil {
+		t.Fatalf("RegisterNotifier failed: %v", err)
+	}
+
+	err := nm.EnableAutoscale(NotificationAutoscale{
+		HighWaterMark: 5,
+		SustainFor:    0,
+		MaxWorkers:    3,
+		CheckInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("EnableAutoscale failed: %v", err)
+	}
+
+	for range 20 {
+		nm.Enqueue(NotificationEvent{ReactionID: "r"}, []string{"slow"})
+	}
+
+	if !waitFor(t, 2*time.Second, func() bool { return nm.Workers() == 3 }) {
+		t.Fatalf("Expected to scale up to 3 workers, got %d", nm.Workers())
+	}
+	// the cap must hold while the queue stays full
+	time.Sleep(50 * time.Millisecond)
+	if nm.Workers() != 3 {
+		t.Errorf("Expected workers to stay capped at 3, got %d", nm.Workers())
+	}
+
+	close(release)
+	if !waitFor(t, 2*time.Second, func() bool { return nm.Workers() == 1 }) {
+		t.Errorf("Expected to scale back down to 1 worker, got %d", nm.Workers())
+	}
+	if got := notifier.getNotifyCount(); got != 20 {
+		t.Errorf("Expected all 20 notifications to be delivered, got %d", got)
+	}
+
+	if err := nm.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestNotificationManager_Autoscale_CloseStopsExtraWorkers(t *testing.T) {
+	nm := NewNotificationManager()
+	release := make(chan struct{})
+	nm.RegisterNotifier(&mockNotifier{
+		id: "slow",
+		notifyFunc: func(ctx context.Context, _ NotificationEvent) error {
+			<-release
+			return nil
+		},
+	})
+	if err := nm.EnableAutoscale(NotificationAutoscale{HighWaterMark: 1, MaxWorkers: 4, CheckInterval: 5 * time.Millisecond}); err != nil {
+		t.Fatalf("EnableAutoscale failed: %v", err)
+	}
+	for range 10 {
+		nm.Enqueue(NotificationEvent{ReactionID: "r"}, []string{"slow"})
+	}
+	if !waitFor(t, 2*time.Second, func() bool { return nm.Workers() > 1 }) {
+		t.Fatal("Expected extra workers to be started")
+	}
+
+	close(release)
+	done := make(chan struct{})
+	go func() {
+		nm.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return: extra workers were not stopped")
+	}
+
+	if err := nm.EnableAutoscale(DefaultNotificationAutoscale()); err == nil {
+		t.Error("Expected EnableAutoscale to fail on a closed manager")
+	}
+}
+
+func TestNotificationManager_EnableAutoscale_Invalid(t *testing.T) {
+	nm := NewNotificationManager()
+	defer nm.Close()
+
+	for _, cfg := range []NotificationAutoscale{
+		{HighWaterMark: 0, MaxWorkers: 2, CheckInterval: time.Second},
+		{HighWaterMark: 2000, MaxWorkers: 2, CheckInterval: time.Second},
+		{HighWaterMark: 10, MaxWorkers: 0, CheckInterval: time.Second},
+		{HighWaterMark: 10, MaxWorkers: 2, CheckInterval: 0},
+	} {
+		if err := nm.EnableAutoscale(cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
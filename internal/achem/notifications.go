@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 )
@@ -49,6 +50,76 @@ type NotificationConfig struct {
 	Notifiers []string `json:"notifiers"` // List of notifier IDs to trigger
 }
 
+// NotifierFilter restricts the events delivered to a notifier. An event passes when its
+// reaction is listed in ReactionIDs and its input or one of its created molecules has a
+// species listed in Species. An empty list doesn't restrict anything.
+type NotifierFilter struct {
+	ReactionIDs []string      `json:"reaction_ids,omitempty"`
+	Species     []SpeciesName `json:"species,omitempty"`
+}
+
+// Matches reports whether event passes the filter
+func (f NotifierFilter) Matches(event NotificationEvent) bool {
+	if len(f.ReactionIDs) > 0 && !slices.Contains(f.ReactionIDs, event.ReactionID) {
+		return false
+	}
+	if len(f.Species) == 0 || slices.Contains(f.Species, event.InputMolecule.Species) {
+		return true
+	}
+	for _, m := range event.CreatedMolecules {
+		if slices.Contains(f.Species, m.Species) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy controls how the notification manager delivers an event to a notifier:
+// up to MaxRetries retries after the first attempt, waiting Backoff before the first
+// retry and doubling the wait after each one, all within Timeout.
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt (0 = a single attempt)
+	Backoff    time.Duration // wait before the first retry
+	Timeout    time.Duration // bound on all attempts of one delivery, backoff included
+}
+
+// Default RetryPolicy values, used for notifiers without an explicit policy
+const (
+	DefaultNotifyMaxRetries = 3
+	DefaultNotifyBackoff    = 100 * time.Millisecond
+	DefaultNotifyTimeout    = 30 * time.Second
+)
+
+// Bounds accepted by RetryPolicy.Validate
+const (
+	MaxNotifyRetries = 100
+	MaxNotifyBackoff = time.Minute
+	MaxNotifyTimeout = 10 * time.Minute
+)
+
+// DefaultRetryPolicy returns the policy applied to notifiers registered without one
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultNotifyMaxRetries,
+		Backoff:    DefaultNotifyBackoff,
+		Timeout:    DefaultNotifyTimeout,
+	}
+}
+
+// Validate checks that the policy values are within sane bounds
+func (p RetryPolicy) Validate() error {
+	if p.MaxRetries < 0 || p.MaxRetries > MaxNotifyRetries {
+		return fmt.Errorf("max retries must be between 0 and %d", MaxNotifyRetries)
+	}
+	if p.Backoff <= 0 || p.Backoff > MaxNotifyBackoff {
+		return fmt.Errorf("backoff must be positive and at most %s", MaxNotifyBackoff)
+	}
+	if p.Timeout <= 0 || p.Timeout > MaxNotifyTimeout {
+		return fmt.Errorf("timeout must be positive and at most %s", MaxNotifyTimeout)
+	}
+	return nil
+}
+
 // notificationJob represents a job to be processed by the notification queue
 type notificationJob struct {
 	Event       NotificationEvent
@@ -59,11 +130,41 @@ type notificationJob struct {
 type NotificationManager struct {
 	mu        sync.RWMutex
 	notifiers map[string]Notifier
+	filters   map[string]NotifierFilter // only notifiers registered with a filter
+	policies  map[string]RetryPolicy    // only notifiers with a non-default retry policy
 	callbacks map[string]func(NotificationEvent)
 	jobs      chan notificationJob
 	closed    bool
 	wg        sync.WaitGroup
 	logger    Logger
+	metrics   Metrics
+
+	// fallback resolves the notifier IDs that aren't registered here, see SetFallback
+	fallback *NotificationManager
+
+	// deadLetter receives events whose delivery failed for good (nil drops them)
+	deadLetter func(event NotificationEvent, notifierID string, lastErr error)
+
+	// adaptive worker scaling, see EnableAutoscale
+	baseWorkers  int // workers started with the manager, always running
+	autoscale    NotificationAutoscale
+	extraWorkers int
+	scaleDownCh  chan struct{}
+	monitorStop  chan struct{} // nil until autoscaling is enabled
+	monitorDone  chan struct{}
+}
+
+// Default NotificationManagerOptions values
+const (
+	DefaultNotificationQueueSize = 1024
+	DefaultNotificationWorkers   = 1
+)
+
+// NotificationManagerOptions configures a NotificationManager. Zero values select the defaults.
+type NotificationManagerOptions struct {
+	QueueSize int    // capacity of the job queue; events are dropped when it is full
+	Workers   int    // number of workers delivering jobs concurrently
+	Logger    Logger // if nil, a NoOpLogger will be used
 }
 
 // NewNotificationManager creates a new notification manager.
@@ -75,17 +176,36 @@ func NewNotificationManager() *NotificationManager {
 // NewNotificationManagerWithLogger creates a new notification manager with the given logger.
 // If logger is nil, a NoOpLogger will be used.
 func NewNotificationManagerWithLogger(logger Logger) *NotificationManager {
-	if logger == nil {
-		logger = NewNoOpLogger()
+	return NewNotificationManagerWithOptions(NotificationManagerOptions{Logger: logger})
+}
+
+// NewNotificationManagerWithOptions creates a new notification manager with the given
+// queue size and number of workers. With several workers, jobs are delivered concurrently
+// and may complete out of order; each job is still processed (and its callbacks invoked)
+// exactly once.
+func NewNotificationManagerWithOptions(opts NotificationManagerOptions) *NotificationManager {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultNotificationQueueSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultNotificationWorkers
+	}
+	if opts.Logger == nil {
+		opts.Logger = NewNoOpLogger()
 	}
 	mgr := &NotificationManager{
-		notifiers: make(map[string]Notifier),
-		jobs:      make(chan notificationJob, 1024),
-		closed:    false,
-		callbacks: make(map[string]func(NotificationEvent)),
-		logger:    logger,
+		notifiers:   make(map[string]Notifier),
+		filters:     make(map[string]NotifierFilter),
+		policies:    make(map[string]RetryPolicy),
+		jobs:        make(chan notificationJob, opts.QueueSize),
+		closed:      false,
+		callbacks:   make(map[string]func(NotificationEvent)),
+		logger:      opts.Logger,
+		metrics:     NewNoOpMetrics(),
+		baseWorkers: opts.Workers,
+		scaleDownCh: make(chan struct{}),
 	}
-	mgr.startWorkers(1)
+	mgr.startWorkers(mgr.baseWorkers)
 	return mgr
 }
 
@@ -99,8 +219,70 @@ func (nm *NotificationManager) SetLogger(logger Logger) {
 	nm.logger = logger
 }
 
+// SetMetrics sets the metrics recorder for this notification manager.
+// If metrics is nil, a NoOpMetrics will be used.
+func (nm *NotificationManager) SetMetrics(metrics Metrics) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	if metrics == nil {
+		metrics = NewNoOpMetrics()
+	}
+	nm.metrics = metrics
+}
+
+// SetDeadLetterHandler sets a function called with every event that couldn't be
+// delivered to a notifier after all retries, e.g. to persist it for a later replay.
+// It runs on the notification worker, so it should not block for long.
+// A nil handler (the default) drops failed events after logging them.
+func (nm *NotificationManager) SetDeadLetterHandler(handler func(event NotificationEvent, notifierID string, lastErr error)) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nm.deadLetter = handler
+}
+
+// SetFallback sets a manager whose notifiers are used for the notifier IDs that are not
+// registered in nm, e.g. a server-wide manager behind an environment's own. Local
+// notifiers take precedence over fallback notifiers with the same ID. Events delivered
+// through the fallback use its filters, retry policies, metrics and dead-letter handler,
+// but not its callbacks: jobs are processed by nm's workers. A nil fallback (the
+// default) disables the lookup.
+func (nm *NotificationManager) SetFallback(fallback *NotificationManager) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nm.fallback = fallback
+}
+
+// Fallback returns the manager set with SetFallback, or nil
+func (nm *NotificationManager) Fallback() *NotificationManager {
+	nm.mu.RLock()
+	defer nm.mu.RUnlock()
+	return nm.fallback
+}
+
+// resolve returns the manager that owns notifier id: nm if it is registered locally,
+// otherwise the fallback if it is registered there, otherwise nm.
+func (nm *NotificationManager) resolve(id string) *NotificationManager {
+	nm.mu.RLock()
+	_, local := nm.notifiers[id]
+	fallback := nm.fallback
+	nm.mu.RUnlock()
+	if local || fallback == nil || fallback == nm {
+		return nm
+	}
+	if _, ok := fallback.GetNotifier(id); ok {
+		return fallback
+	}
+	return nm
+}
+
 // RegisterNotifier registers a notifier with the manager
 func (nm *NotificationManager) RegisterNotifier(notifier Notifier) error {
+	return nm.RegisterNotifierWithFilter(notifier, NotifierFilter{})
+}
+
+// RegisterNotifierWithFilter registers a notifier that only receives the events
+// matching filter, even when a reaction lists it among its notifiers.
+func (nm *NotificationManager) RegisterNotifierWithFilter(notifier Notifier, filter NotifierFilter) error {
 	if notifier == nil {
 		return fmt.Errorf("notifier cannot be nil")
 	}
@@ -113,11 +295,18 @@ func (nm *NotificationManager) RegisterNotifier(notifier Notifier) error {
 	nm.mu.Lock()
 	defer nm.mu.Unlock()
 
+	if nm.closed {
+		return fmt.Errorf("notification manager is closed")
+	}
+
 	if _, exists := nm.notifiers[id]; exists {
 		return fmt.Errorf("notifier with ID %s already exists", id)
 	}
 
 	nm.notifiers[id] = notifier
+	if len(filter.ReactionIDs) > 0 || len(filter.Species) > 0 {
+		nm.filters[id] = filter
+	}
 	return nil
 }
 
@@ -158,6 +347,8 @@ func (nm *NotificationManager) UnregisterNotifier(id string) error {
 
 	nm.mu.Lock()
 	delete(nm.notifiers, id)
+	delete(nm.filters, id)
+	delete(nm.policies, id)
 	nm.mu.Unlock()
 
 	return nil
@@ -171,6 +362,54 @@ func (nm *NotificationManager) GetNotifier(id string) (Notifier, bool) {
 	return notifier, exists
 }
 
+// GetNotifierFilter returns the filter a notifier was registered with, if any
+func (nm *NotificationManager) GetNotifierFilter(id string) (NotifierFilter, bool) {
+	nm.mu.RLock()
+	defer nm.mu.RUnlock()
+	filter, exists := nm.filters[id]
+	return filter, exists
+}
+
+// SetRetryPolicy sets the retry policy used to deliver events to notifier id, replacing
+// DefaultRetryPolicy. Returns an error if the notifier isn't registered or the policy is
+// invalid.
+func (nm *NotificationManager) SetRetryPolicy(id string, policy RetryPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	if _, exists := nm.notifiers[id]; !exists {
+		return fmt.Errorf("notifier with ID %s not found", id)
+	}
+	if policy == DefaultRetryPolicy() {
+		delete(nm.policies, id)
+	} else {
+		nm.policies[id] = policy
+	}
+	return nil
+}
+
+// GetRetryPolicy returns the retry policy used for notifier id (DefaultRetryPolicy
+// unless one was set)
+func (nm *NotificationManager) GetRetryPolicy(id string) RetryPolicy {
+	nm.mu.RLock()
+	defer nm.mu.RUnlock()
+	if policy, exists := nm.policies[id]; exists {
+		return policy
+	}
+	return DefaultRetryPolicy()
+}
+
+// accepts reports whether the filter of notifier id (if any) lets event through
+func (nm *NotificationManager) accepts(id string, event NotificationEvent) bool {
+	nm.mu.RLock()
+	filter, exists := nm.filters[id]
+	nm.mu.RUnlock()
+	return !exists || filter.Matches(event)
+}
+
 // ListNotifiers returns a list of all registered notifier IDs
 func (nm *NotificationManager) ListNotifiers() []string {
 	nm.mu.RLock()
@@ -185,7 +424,9 @@ func (nm *NotificationManager) ListNotifiers() []string {
 // Enqueue enqueues a notification event to be processed asynchronously by worker goroutines.
 // This method is non-blocking and will drop notifications if the queue is full.
 // Note: Even if notifierIDs is empty, the event will still be enqueued to allow callbacks to be called.
-func (nm *NotificationManager) Enqueue(event NotificationEvent, notifierIDs []string) {
+// Returns false if the event was not enqueued: nobody would receive it, the manager
+// is closed or the queue is full.
+func (nm *NotificationManager) Enqueue(event NotificationEvent, notifierIDs []string) bool {
 	nm.mu.RLock()
 	closed := nm.closed
 	hasCallbacks := len(nm.callbacks) > 0
@@ -193,21 +434,28 @@ func (nm *NotificationManager) Enqueue(event NotificationEvent, notifierIDs []st
 
 	// If there are no notifiers and no callbacks, skip enqueuing
 	if len(notifierIDs) == 0 && !hasCallbacks {
-		return
+		return false
 	}
 
 	if closed {
-		return
+		return false
 	}
 
 	// Best effort: if channel is full, drop or log and return
 	select {
 	case nm.jobs <- notificationJob{Event: event, NotifierIDs: notifierIDs}:
+		return true
 	default:
 		nm.logger.Warnf("notification queue full, dropping notification: reaction_id=%s", event.ReactionID)
+		return false
 	}
 }
 
+// QueueDepth returns the number of notification jobs waiting to be processed.
+func (nm *NotificationManager) QueueDepth() int {
+	return len(nm.jobs)
+}
+
 // startWorkers starts n worker goroutines to process notification jobs
 func (nm *NotificationManager) startWorkers(n int) {
 	for range n {
@@ -224,14 +472,19 @@ func (nm *NotificationManager) worker() {
 	}
 }
 
-// dispatchJob dispatches a notification job to all specified notifiers
+// dispatchJob dispatches a notification job to all specified notifiers. Each notifier
+// gets its own RetryPolicy.Timeout, so a job can take up to the sum of its notifiers'
+// timeouts.
 func (nm *NotificationManager) dispatchJob(job notificationJob) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// For each notifier ID, attempt delivery with retry/backoff
+	// For each notifier ID, attempt delivery with retry/backoff, within the notifier's timeout
 	for _, id := range job.NotifierIDs {
-		nm.notifyWithRetry(ctx, id, job.Event)
+		owner := nm.resolve(id)
+		if !owner.accepts(id, job.Event) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), owner.GetRetryPolicy(id).Timeout)
+		owner.notifyWithRetry(ctx, id, job.Event)
+		cancel()
 	}
 
 	// After all external notifications are dispatched, call any registered callbacks
@@ -246,20 +499,27 @@ func (nm *NotificationManager) dispatchJob(job notificationJob) {
 func (nm *NotificationManager) notifyWithRetry(ctx context.Context, notifierID string, event NotificationEvent) {
 	nm.mu.RLock()
 	notifier, ok := nm.notifiers[notifierID]
+	metrics := nm.metrics
+	deadLetter := nm.deadLetter
+	policy, custom := nm.policies[notifierID]
 	nm.mu.RUnlock()
+	if !custom {
+		policy = DefaultRetryPolicy()
+	}
 
 	if !ok {
 		nm.logger.Errorf("notification failed: notifier=%s error=notifier not found", notifierID)
+		metrics.ObserveNotification(notifierID, fmt.Errorf("notifier not found"))
 		return
 	}
 
-	// Basic retry/backoff policy
-	const maxRetries = 3
-	backoff := 100 * time.Millisecond
+	maxRetries := policy.MaxRetries
+	backoff := policy.Backoff
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		err := notifier.Notify(ctx, event)
 		if err == nil {
+			metrics.ObserveNotification(notifierID, nil)
 			return
 		}
 
@@ -269,6 +529,10 @@ func (nm *NotificationManager) notifyWithRetry(ctx context.Context, notifierID s
 		if attempt == maxRetries {
 			// Max retries reached, give up
 			nm.logger.Errorf("notification failed after %d attempts: notifier=%s", maxRetries+1, notifierID)
+			metrics.ObserveNotification(notifierID, err)
+			if deadLetter != nil {
+				deadLetter(event, notifierID, err)
+			}
 			return
 		}
 
@@ -276,6 +540,10 @@ func (nm *NotificationManager) notifyWithRetry(ctx context.Context, notifierID s
 		select {
 		case <-ctx.Done():
 			// Context cancelled or timed out
+			metrics.ObserveNotification(notifierID, ctx.Err())
+			if deadLetter != nil {
+				deadLetter(event, notifierID, err)
+			}
 			return
 		case <-time.After(backoff):
 			backoff *= 2 // exponential backoff
@@ -292,14 +560,16 @@ func (nm *NotificationManager) Notify(ctx context.Context, event NotificationEve
 
 	var errors []error
 	for _, id := range notifierIDs {
-		nm.mu.RLock()
-		notifier, exists := nm.notifiers[id]
-		nm.mu.RUnlock()
+		owner := nm.resolve(id)
+		notifier, exists := owner.GetNotifier(id)
 
 		if !exists {
 			errors = append(errors, fmt.Errorf("notifier %s not found", id))
 			continue
 		}
+		if !owner.accepts(id, event) {
+			continue
+		}
 
 		if err := notifier.Notify(ctx, event); err != nil {
 			errors = append(errors, fmt.Errorf("notifier %s failed: %w", id, err))
@@ -323,9 +593,16 @@ func (nm *NotificationManager) Close() error {
 	}
 	nm.closed = true
 	close(nm.jobs)
+	monitorStop, monitorDone := nm.monitorStop, nm.monitorDone
 	nm.mu.Unlock()
 
-	// Wait for all workers to finish processing
+	// Stop the autoscaling monitor so that no more workers are started
+	if monitorStop != nil {
+		close(monitorStop)
+		<-monitorDone
+	}
+
+	// Wait for all workers (including extra ones) to finish processing
 	nm.wg.Wait()
 
 	// Close all registered notifiers
@@ -337,6 +614,7 @@ func (nm *NotificationManager) Close() error {
 		}
 	}
 	nm.notifiers = make(map[string]Notifier)
+	nm.filters = make(map[string]NotifierFilter)
 	nm.mu.Unlock()
 
 	if len(errors) > 0 {
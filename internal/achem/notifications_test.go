@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -650,3 +651,318 @@ type testError struct {
 func (e *testError) Error() string {
 	return e.msg
 }
+
+func TestNotificationManager_DeadLetterHandler(t *testing.T) {
+	nm := NewNotificationManager()
+	defer nm.Close()
+
+	type deadLetter struct {
+		event      NotificationEvent
+		notifierID string
+		err        error
+	}
+	received := make(chan deadLetter, 2)
+	nm.SetDeadLetterHandler(func(event NotificationEvent, notifierID string, lastErr error) {
+		received <- deadLetter{event, notifierID, lastErr}
+	})
+
+	failing := &mockNotifier{
+		id: "failing",
+		notifyFunc: func(ctx context.Context, event NotificationEvent) error {
+			return &testError{msg: "endpoint down"}
+		},
+	}
+	nm.RegisterNotifier(failing)
+	nm.RegisterNotifier(&mockNotifier{id: "ok"})
+
+	nm.Enqueue(NotificationEvent{ReactionID: "r1"}, []string{"ok", "failing"})
+
+	select {
+	case dl := <-received:
+		if dl.notifierID != "failing" {
+			t.Errorf("Expected notifier ID 'failing', got %q", dl.notifierID)
+		}
+		if dl.event.ReactionID != "r1" {
+			t.Errorf("Expected event r1, got %q", dl.event.ReactionID)
+		}
+		if dl.err == nil || dl.err.Error() != "endpoint down" {
+			t.Errorf("Expected last error 'endpoint down', got %v", dl.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dead-letter handler was not called")
+	}
+
+	// all attempts were made before giving up
+	if n := failing.getNotifyCount(); n != 4 {
+		t.Errorf("Expected 4 delivery attempts, got %d", n)
+	}
+
+	// the successful delivery isn't dead-lettered
+	nm.Close()
+	select {
+	case dl := <-received:
+		t.Errorf("Unexpected dead letter for %q", dl.notifierID)
+	default:
+	}
+}
+
+func TestNotificationManager_RetryPolicy(t *testing.T) {
+	nm := NewNotificationManager()
+	defer nm.Close()
+
+	deadLetters := make(chan string, 2)
+	nm.SetDeadLetterHandler(func(event NotificationEvent, notifierID string, lastErr error) {
+		deadLetters <- notifierID
+	})
+
+	failing := &mockNotifier{
+		id: "flaky",
+		notifyFunc: func(ctx context.Context, event NotificationEvent) error {
+			return &testError{msg: "endpoint down"}
+		},
+	}
+	nm.RegisterNotifier(failing)
+
+	if err := nm.SetRetryPolicy("missing", DefaultRetryPolicy()); err == nil {
+		t.Error("Expected an error setting the policy of an unknown notifier")
+	}
+	for _, invalid := range []RetryPolicy{
+		{MaxRetries: -1, Backoff: time.Millisecond, Timeout: time.Second},
+		{MaxRetries: MaxNotifyRetries + 1, Backoff: time.Millisecond, Timeout: time.Second},
+		{MaxRetries: 1, Backoff: 0, Timeout: time.Second},
+		{MaxRetries: 1, Backoff: time.Millisecond, Timeout: 0},
+		{MaxRetries: 1, Backoff: time.Millisecond, Timeout: MaxNotifyTimeout + time.Second},
+	} {
+		if err := nm.SetRetryPolicy("flaky", invalid); err == nil {
+			t.Errorf("Expected an error for policy %+v", invalid)
+		}
+	}
+	if got := nm.GetRetryPolicy("flaky"); got != DefaultRetryPolicy() {
+		t.Errorf("Expected the default policy, got %+v", got)
+	}
+
+	policy := RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond, Timeout: time.Second}
+	if err := nm.SetRetryPolicy("flaky", policy); err != nil {
+		t.Fatalf("SetRetryPolicy failed: %v", err)
+	}
+	if got := nm.GetRetryPolicy("flaky"); got != policy {
+		t.Errorf("Expected policy %+v, got %+v", policy, got)
+	}
+
+	nm.Enqueue(NotificationEvent{ReactionID: "r1"}, []string{"flaky"})
+	select {
+	case <-deadLetters:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dead-letter handler was not called")
+	}
+	if n := failing.getNotifyCount(); n != 2 {
+		t.Errorf("Expected 2 delivery attempts with max_retries=1, got %d", n)
+	}
+
+	// the timeout bounds the delivery, backoff included
+	slow := &mockNotifier{
+		id: "slow",
+		notifyFunc: func(ctx context.Context, event NotificationEvent) error {
+			return &testError{msg: "endpoint down"}
+		},
+	}
+	nm.RegisterNotifier(slow)
+	if err := nm.SetRetryPolicy("slow", RetryPolicy{MaxRetries: 5, Backoff: time.Minute, Timeout: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("SetRetryPolicy failed: %v", err)
+	}
+	start := time.Now()
+	nm.Enqueue(NotificationEvent{ReactionID: "r2"}, []string{"slow"})
+	select {
+	case <-deadLetters:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the delivery to give up on timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the timeout to cut the backoff short, took %s", elapsed)
+	}
+	if n := slow.getNotifyCount(); n != 1 {
+		t.Errorf("Expected a single attempt before the timeout, got %d", n)
+	}
+
+	// unregistering drops the policy
+	nm.UnregisterNotifier("flaky")
+	nm.RegisterNotifier(&mockNotifier{id: "flaky"})
+	if got := nm.GetRetryPolicy("flaky"); got != DefaultRetryPolicy() {
+		t.Errorf("Expected the default policy after re-registering, got %+v", got)
+	}
+}
+
+func TestNewNotificationManagerWithOptions(t *testing.T) {
+	nm := NewNotificationManagerWithOptions(NotificationManagerOptions{QueueSize: 16, Workers: 3})
+	defer nm.Close()
+
+	if got := cap(nm.jobs); got != 16 {
+		t.Errorf("Expected queue size 16, got %d", got)
+	}
+	if got := nm.Workers(); got != 3 {
+		t.Errorf("Expected 3 workers, got %d", got)
+	}
+	if err := nm.EnableAutoscale(NotificationAutoscale{HighWaterMark: 8, MaxWorkers: 2, CheckInterval: time.Second}); err == nil {
+		t.Error("Expected autoscale max workers below the base workers to be rejected")
+	}
+
+	defaults := NewNotificationManager()
+	defer defaults.Close()
+	if cap(defaults.jobs) != DefaultNotificationQueueSize || defaults.Workers() != DefaultNotificationWorkers {
+		t.Errorf("Expected default queue size and workers, got %d and %d", cap(defaults.jobs), defaults.Workers())
+	}
+}
+
+func TestNotificationManager_ConcurrentWorkers(t *testing.T) {
+	const workers = 3
+	nm := NewNotificationManagerWithOptions(NotificationManagerOptions{Workers: workers})
+
+	// the notifier only returns once all workers are busy at the same time
+	var inFlight sync.WaitGroup
+	inFlight.Add(workers)
+	release := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(release)
+	}()
+	var started atomic.Int32
+	nm.RegisterNotifier(&mockNotifier{
+		id: "slow",
+		notifyFunc: func(ctx context.Context, event NotificationEvent) error {
+			if started.Add(1) <= workers {
+				inFlight.Done()
+			}
+			select {
+			case <-release:
+				return nil
+			case <-time.After(2 * time.Second):
+				return &testError{msg: "workers did not run concurrently"}
+			}
+		},
+	})
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	nm.RegisterCallback("count", func(event NotificationEvent) {
+		mu.Lock()
+		calls[event.ReactionID]++
+		mu.Unlock()
+	})
+
+	ids := []string{"r1", "r2", "r3", "r4", "r5", "r6"}
+	for _, id := range ids[:workers] {
+		nm.Enqueue(NotificationEvent{ReactionID: id}, []string{"slow"})
+	}
+	select {
+	case <-release:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the jobs to be processed concurrently")
+	}
+	for _, id := range ids[workers:] {
+		nm.Enqueue(NotificationEvent{ReactionID: id}, []string{"slow"})
+	}
+
+	// Close waits for the queue to drain
+	nm.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range ids {
+		if calls[id] != 1 {
+			t.Errorf("Expected callback called once for %s, got %d", id, calls[id])
+		}
+	}
+}
+
+func TestNotificationManager_NotifierFilter(t *testing.T) {
+	nm := NewNotificationManager()
+
+	all := &mockNotifier{id: "all"}
+	alerts := &mockNotifier{id: "alerts"}
+	escalate := &mockNotifier{id: "escalate"}
+	if err := nm.RegisterNotifier(all); err != nil {
+		t.Fatal(err)
+	}
+	if err := nm.RegisterNotifierWithFilter(alerts, NotifierFilter{Species: []SpeciesName{"Alert"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nm.RegisterNotifierWithFilter(escalate, NotifierFilter{ReactionIDs: []string{"escalate"}, Species: []SpeciesName{"Alert"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nm.GetNotifierFilter("all"); ok {
+		t.Error("Expected no filter for a notifier registered without one")
+	}
+
+	ids := []string{"all", "alerts", "escalate"}
+	// created Alert from the escalate reaction: everyone
+	nm.Enqueue(NotificationEvent{
+		ReactionID:       "escalate",
+		InputMolecule:    Molecule{Species: "Suspicion"},
+		CreatedMolecules: []Molecule{{Species: "Alert"}},
+	}, ids)
+	// input Alert from another reaction: not escalate
+	nm.Enqueue(NotificationEvent{ReactionID: "decay", InputMolecule: Molecule{Species: "Alert"}}, ids)
+	// no Alert involved: only all
+	nm.Enqueue(NotificationEvent{ReactionID: "escalate", InputMolecule: Molecule{Species: "Event"}}, ids)
+	nm.Close()
+
+	if got := all.getNotifyCount(); got != 3 {
+		t.Errorf("Expected 3 notifications without filter, got %d", got)
+	}
+	if got := alerts.getNotifyCount(); got != 2 {
+		t.Errorf("Expected 2 notifications with species filter, got %d", got)
+	}
+	if got := escalate.getNotifyCount(); got != 1 {
+		t.Errorf("Expected 1 notification with reaction and species filter, got %d", got)
+	}
+}
+
+func TestNotificationManager_Fallback(t *testing.T) {
+	global := NewNotificationManager()
+	defer global.Close()
+	local := NewNotificationManager()
+	defer local.Close()
+	local.SetFallback(global)
+
+	globalShared := &mockNotifier{id: "shared"}
+	globalOnly := &mockNotifier{id: "global-only"}
+	filtered := &mockNotifier{id: "filtered"}
+	localShared := &mockNotifier{id: "shared"}
+	global.RegisterNotifier(globalShared)
+	global.RegisterNotifier(globalOnly)
+	global.RegisterNotifierWithFilter(filtered, NotifierFilter{ReactionIDs: []string{"other"}})
+	local.RegisterNotifier(localShared)
+
+	err := local.Notify(context.Background(), NotificationEvent{ReactionID: "r1"}, []string{"shared", "global-only", "filtered"})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if localShared.getNotifyCount() != 1 || globalShared.getNotifyCount() != 0 {
+		t.Errorf("Expected the local notifier to take precedence, got local=%d global=%d", localShared.getNotifyCount(), globalShared.getNotifyCount())
+	}
+	if globalOnly.getNotifyCount() != 1 {
+		t.Errorf("Expected the fallback notifier to be used, got %d", globalOnly.getNotifyCount())
+	}
+	if filtered.getNotifyCount() != 0 {
+		t.Errorf("Expected the fallback's filter to apply, got %d", filtered.getNotifyCount())
+	}
+	if err := local.Notify(context.Background(), NotificationEvent{}, []string{"missing"}); err == nil {
+		t.Error("Expected an error for a notifier registered nowhere")
+	}
+
+	// jobs enqueued on the local manager are delivered the same way
+	local.Enqueue(NotificationEvent{ReactionID: "r2"}, []string{"global-only"})
+	deadline := time.Now().Add(5 * time.Second)
+	for globalOnly.getNotifyCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if globalOnly.getNotifyCount() != 2 {
+		t.Errorf("Expected the queued event to reach the fallback notifier, got %d", globalOnly.getNotifyCount())
+	}
+
+	// the global manager doesn't see the local notifiers
+	global.Notify(context.Background(), NotificationEvent{}, []string{"shared"})
+	if localShared.getNotifyCount() != 1 || globalShared.getNotifyCount() != 1 {
+		t.Errorf("Expected the global manager to use its own notifier, got local=%d global=%d", localShared.getNotifyCount(), globalShared.getNotifyCount())
+	}
+}
@@ -0,0 +1,122 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// fileSyncInterval is how often appended events are flushed to stable storage
+const fileSyncInterval = time.Second
+
+// errFileClosed is returned by Notify once the notifier is closed
+var errFileClosed = errors.New("file notifier is closed")
+
+// FileNotifier appends each notification event as one JSON line to a file, as a
+// persistent audit trail. Writes go to the file immediately, so the file can be tailed;
+// they are synced to disk in the background, at most fileSyncInterval after being written.
+type FileNotifier struct {
+	id   string
+	path string
+
+	mu     sync.Mutex // guards the fields below, so lines are never interleaved
+	file   *os.File
+	dirty  bool // written since the last sync
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFileNotifier opens path for appending, creating it if needed, and creates a
+// notifier writing to it. It returns an error if the file cannot be opened.
+func NewFileNotifier(id, path string) (*FileNotifier, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	fn := &FileNotifier{
+		id:   id,
+		path: path,
+		file: f,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go fn.syncLoop()
+	return fn, nil
+}
+
+// ID returns the notifier ID
+func (fn *FileNotifier) ID() string {
+	return fn.id
+}
+
+// Type returns the notifier type
+func (fn *FileNotifier) Type() string {
+	return "file"
+}
+
+// Notify appends the event to the file, as a single write of one JSON line
+func (fn *FileNotifier) Notify(ctx context.Context, event achem.NotificationEvent) error {
+	data, err := event.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	data = append(data, '\n')
+
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	if fn.closed {
+		return errFileClosed
+	}
+	if _, err := fn.file.Write(data); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", fn.path, err)
+	}
+	fn.dirty = true
+	return nil
+}
+
+// syncLoop periodically syncs the events written since the last sync, until Close
+func (fn *FileNotifier) syncLoop() {
+	defer close(fn.done)
+	ticker := time.NewTicker(fileSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-fn.stop:
+			return
+		case <-ticker.C:
+			fn.mu.Lock()
+			if fn.dirty && !fn.closed {
+				// a failed sync is retried on the next tick
+				fn.dirty = fn.file.Sync() != nil
+			}
+			fn.mu.Unlock()
+		}
+	}
+}
+
+// Close syncs the pending events to disk and closes the file
+func (fn *FileNotifier) Close() error {
+	fn.mu.Lock()
+	if fn.closed {
+		fn.mu.Unlock()
+		return nil
+	}
+	fn.closed = true
+	fn.mu.Unlock()
+
+	close(fn.stop)
+	<-fn.done
+
+	syncErr := fn.file.Sync()
+	if err := fn.file.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
@@ -0,0 +1,89 @@
+package notifiers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+func TestFileNotifier_IDAndType(t *testing.T) {
+	notifier, err := NewFileNotifier("test-file", filepath.Join(t.TempDir(), "events.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	if notifier.ID() != "test-file" {
+		t.Errorf("Expected ID 'test-file', got '%s'", notifier.ID())
+	}
+	if notifier.Type() != "file" {
+		t.Errorf("Expected type 'file', got '%s'", notifier.Type())
+	}
+}
+
+func TestFileNotifier_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte(`{"reaction_id":"earlier"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	notifier, err := NewFileNotifier("audit", path)
+	if err != nil {
+		t.Fatalf("NewFileNotifier failed: %v", err)
+	}
+
+	// concurrent notifications never interleave their lines
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			event := achem.NotificationEvent{EnvironmentID: "test-env", ReactionID: "r1", EnvTime: int64(i)}
+			if err := notifier.Notify(context.Background(), event); err != nil {
+				t.Errorf("Notify failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := notifier.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+	var events []achem.NotificationEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event achem.NotificationEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Line is not a JSON event: %q (%v)", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 51 || events[0].ReactionID != "earlier" {
+		t.Errorf("Expected the existing line followed by 50 events, got %d lines", len(events))
+	}
+
+	if err := notifier.Notify(context.Background(), achem.NotificationEvent{}); !errors.Is(err, errFileClosed) {
+		t.Errorf("Expected an error notifying after Close, got %v", err)
+	}
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Expected closing twice to succeed, got %v", err)
+	}
+}
+
+func TestNewFileNotifier_InvalidPath(t *testing.T) {
+	if _, err := NewFileNotifier("test", filepath.Join(t.TempDir(), "missing", "events.jsonl")); err == nil {
+		t.Error("Expected an error when the directory doesn't exist")
+	}
+}
@@ -0,0 +1,201 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GRPCPublishMethod is the streaming RPC defined in proto/notifications.proto
+const GRPCPublishMethod = "/achemdb.notifications.v1.NotificationService/Publish"
+
+// grpcPublishStream describes the client-streaming Publish RPC
+var grpcPublishStream = &grpc.StreamDesc{StreamName: "Publish", ClientStreams: true}
+
+const (
+	grpcInitialBackoff = 100 * time.Millisecond
+	grpcMaxBackoff     = 10 * time.Second
+	grpcCloseTimeout   = 5 * time.Second // how long Close waits for the receiver's ack
+)
+
+// GRPCNotifier streams notifications to a gRPC receiver over a single long-lived
+// Publish stream, so events arrive in order without a request per event. When the
+// stream fails it is reopened on the next notification, after an exponential backoff.
+type GRPCNotifier struct {
+	id      string
+	target  string
+	conn    *grpc.ClientConn
+	closeCh chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex // serializes sends and guards the fields below
+	stream  grpc.ClientStream
+	cancel  context.CancelFunc // cancels the current stream
+	backoff time.Duration
+	retryAt time.Time
+	closed  bool
+}
+
+// NewGRPCNotifier creates a new gRPC notifier for target ("host:port"). Without dial
+// options, a plaintext connection is used. The connection is established lazily.
+func NewGRPCNotifier(id, target string, opts ...grpc.DialOption) (*GRPCNotifier, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+	return &GRPCNotifier{
+		id:      id,
+		target:  target,
+		conn:    conn,
+		closeCh: make(chan struct{}),
+	}, nil
+}
+
+// ID returns the notifier ID
+func (gn *GRPCNotifier) ID() string {
+	return gn.id
+}
+
+// Type returns the notifier type
+func (gn *GRPCNotifier) Type() string {
+	return "grpc"
+}
+
+// Notify sends the event on the Publish stream, opening it first if needed. If the
+// previous attempt failed, it waits for the backoff delay (or until ctx is done). A send
+// still blocked when ctx is done, e.g. by a receiver that stopped reading, aborts the
+// stream, which is reopened on the next notification.
+func (gn *GRPCNotifier) Notify(ctx context.Context, event achem.NotificationEvent) error {
+	data, err := event.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	msg := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("failed to convert event: %w", err)
+	}
+
+	gn.mu.Lock()
+	defer gn.mu.Unlock()
+
+	if gn.closed {
+		return fmt.Errorf("notifier closed")
+	}
+
+	if gn.stream == nil {
+		if err := gn.waitBackoff(ctx); err != nil {
+			return err
+		}
+		if err := gn.openStream(ctx); err != nil {
+			gn.failed()
+			return fmt.Errorf("failed to open stream to %s: %w", gn.target, err)
+		}
+	}
+
+	stop := context.AfterFunc(ctx, gn.cancel)
+	err = gn.stream.SendMsg(msg)
+	if !stop() {
+		// the stream was cancelled: whether the event got through is unknown
+		gn.resetStream()
+		gn.failed()
+		return fmt.Errorf("failed to send event to %s: %w", gn.target, ctx.Err())
+	}
+	if err != nil {
+		if err == io.EOF {
+			// the stream was ended by the receiver: the actual status comes from RecvMsg
+			if recvErr := gn.stream.RecvMsg(&emptypb.Empty{}); recvErr != nil {
+				err = recvErr
+			}
+		}
+		gn.resetStream()
+		gn.failed()
+		return fmt.Errorf("failed to send event to %s: %w", gn.target, err)
+	}
+	gn.backoff = 0
+	return nil
+}
+
+// waitBackoff sleeps until the next reconnection attempt is allowed.
+// Must be called with gn.mu held.
+func (gn *GRPCNotifier) waitBackoff(ctx context.Context) error {
+	wait := time.Until(gn.retryAt)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-gn.closeCh:
+		return fmt.Errorf("notifier closed")
+	}
+}
+
+// openStream opens a new Publish stream. The stream outlives ctx, which only bounds
+// how long opening it may take. Must be called with gn.mu held.
+func (gn *GRPCNotifier) openStream(ctx context.Context) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+	stream, err := gn.conn.NewStream(streamCtx, grpcPublishStream, GRPCPublishMethod)
+	if !stop() || err != nil {
+		cancel()
+		if err == nil {
+			err = ctx.Err()
+		}
+		return err
+	}
+	gn.stream, gn.cancel = stream, cancel
+	return nil
+}
+
+// resetStream aborts the current stream. Must be called with gn.mu held.
+func (gn *GRPCNotifier) resetStream() {
+	if gn.cancel != nil {
+		gn.cancel()
+	}
+	gn.stream, gn.cancel = nil, nil
+}
+
+// failed schedules the next reconnection attempt. Must be called with gn.mu held.
+func (gn *GRPCNotifier) failed() {
+	gn.backoff = min(max(gn.backoff*2, grpcInitialBackoff), grpcMaxBackoff)
+	gn.retryAt = time.Now().Add(gn.backoff)
+}
+
+// Close ends the stream, waiting briefly for the receiver to acknowledge it, and
+// closes the connection. It is safe to call more than once.
+func (gn *GRPCNotifier) Close() error {
+	gn.once.Do(func() { close(gn.closeCh) })
+
+	gn.mu.Lock()
+	defer gn.mu.Unlock()
+	if gn.closed {
+		return nil
+	}
+	gn.closed = true
+
+	if gn.stream != nil {
+		timer := time.AfterFunc(grpcCloseTimeout, gn.cancel)
+		if err := gn.stream.CloseSend(); err == nil {
+			_ = gn.stream.RecvMsg(&emptypb.Empty{})
+		}
+		timer.Stop()
+		gn.resetStream()
+	}
+	return gn.conn.Close()
+}
@@ -0,0 +1,253 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcReceiver is a NotificationService implementation recording received events
+type grpcReceiver struct {
+	mu      sync.Mutex
+	events  []*structpb.Struct
+	streams int
+	ended   int // streams closed by the client
+
+	stalled chan struct{} // if set, the first stream isn't read until it is closed
+}
+
+func (r *grpcReceiver) publish(_ any, stream grpc.ServerStream) error {
+	r.mu.Lock()
+	r.streams++
+	stall := r.stalled != nil && r.streams == 1
+	r.mu.Unlock()
+	if stall {
+		select {
+		case <-r.stalled:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+	for {
+		msg := &structpb.Struct{}
+		if err := stream.RecvMsg(msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				r.mu.Lock()
+				r.ended++
+				r.mu.Unlock()
+				return stream.SendMsg(&emptypb.Empty{})
+			}
+			return err
+		}
+		r.mu.Lock()
+		r.events = append(r.events, msg)
+		r.mu.Unlock()
+	}
+}
+
+func (r *grpcReceiver) snapshot() (events []*structpb.Struct, streams, ended int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*structpb.Struct(nil), r.events...), r.streams, r.ended
+}
+
+// startGRPCReceiver serves r on addr ("127.0.0.1:0" for any port)
+func startGRPCReceiver(t *testing.T, r *grpcReceiver, addr string) (*grpc.Server, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "achemdb.notifications.v1.NotificationService",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{
+			{StreamName: "Publish", Handler: r.publish, ClientStreams: true},
+		},
+	}, r)
+	go srv.Serve(lis)
+	return srv, lis.Addr().String()
+}
+
+// waitForGRPCEvents polls until the receiver has n events or the timeout expires
+func waitForGRPCEvents(r *grpcReceiver, n int) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if events, _, _ := r.snapshot(); len(events) >= n {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestGRPCNotifier_IDAndType(t *testing.T) {
+	notifier, err := NewGRPCNotifier("test-grpc", "localhost:1")
+	if err != nil {
+		t.Fatalf("NewGRPCNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	if notifier.ID() != "test-grpc" {
+		t.Errorf("Expected ID 'test-grpc', got '%s'", notifier.ID())
+	}
+	if notifier.Type() != "grpc" {
+		t.Errorf("Expected type 'grpc', got '%s'", notifier.Type())
+	}
+}
+
+func TestGRPCNotifier_StreamsEventsInOrder(t *testing.T) {
+	receiver := &grpcReceiver{}
+	srv, addr := startGRPCReceiver(t, receiver, "127.0.0.1:0")
+	defer srv.Stop()
+
+	notifier, err := NewGRPCNotifier("test", addr)
+	if err != nil {
+		t.Fatalf("NewGRPCNotifier failed: %v", err)
+	}
+
+	for _, id := range []string{"r1", "r2", "r3"} {
+		event := achem.NotificationEvent{EnvironmentID: "test-env", ReactionID: id, EnvTime: 7}
+		if err := notifier.Notify(context.Background(), event); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+	if !waitForGRPCEvents(receiver, 3) {
+		t.Fatal("Expected 3 events to be received")
+	}
+
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+
+	events, streams, ended := receiver.snapshot()
+	if streams != 1 || ended != 1 {
+		t.Errorf("Expected a single stream closed by the client, got streams=%d ended=%d", streams, ended)
+	}
+	for i, want := range []string{"r1", "r2", "r3"} {
+		fields := events[i].GetFields()
+		if got := fields["reaction_id"].GetStringValue(); got != want {
+			t.Errorf("Event %d: expected reaction_id %q, got %q", i, want, got)
+		}
+		if got := fields["environment_id"].GetStringValue(); got != "test-env" {
+			t.Errorf("Event %d: expected environment_id 'test-env', got %q", i, got)
+		}
+	}
+
+	if err := notifier.Notify(context.Background(), achem.NotificationEvent{}); err == nil {
+		t.Error("Expected error when notifying a closed notifier")
+	}
+}
+
+func TestGRPCNotifier_Reconnects(t *testing.T) {
+	receiver := &grpcReceiver{}
+	srv, addr := startGRPCReceiver(t, receiver, "127.0.0.1:0")
+
+	notifier, err := NewGRPCNotifier("test", addr)
+	if err != nil {
+		t.Fatalf("NewGRPCNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	if err := notifier.Notify(context.Background(), achem.NotificationEvent{ReactionID: "before"}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if !waitForGRPCEvents(receiver, 1) {
+		t.Fatal("Expected the first event to be received")
+	}
+
+	// the receiver goes away: sends fail until it is back
+	srv.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var sendErr error
+	for sendErr == nil && ctx.Err() == nil {
+		sendErr = notifier.Notify(ctx, achem.NotificationEvent{ReactionID: "lost"})
+	}
+	if sendErr == nil {
+		t.Fatal("Expected Notify to fail while the receiver is down")
+	}
+
+	srv, _ = startGRPCReceiver(t, receiver, addr)
+	defer srv.Stop()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for {
+		if err := notifier.Notify(ctx, achem.NotificationEvent{ReactionID: "after"}); err == nil {
+			break
+		} else if ctx.Err() != nil {
+			t.Fatalf("Expected the notifier to reconnect, last error: %v", err)
+		}
+	}
+	if !waitForGRPCEvents(receiver, 2) {
+		t.Fatal("Expected an event after reconnecting")
+	}
+	events, streams, _ := receiver.snapshot()
+	if streams < 2 {
+		t.Errorf("Expected a new stream after reconnecting, got %d streams", streams)
+	}
+	if got := events[len(events)-1].GetFields()["reaction_id"].GetStringValue(); got != "after" {
+		t.Errorf("Expected last event 'after', got %q", got)
+	}
+}
+
+func TestGRPCNotifier_SendBoundedByContext(t *testing.T) {
+	receiver := &grpcReceiver{stalled: make(chan struct{})}
+	defer close(receiver.stalled)
+	srv, addr := startGRPCReceiver(t, receiver, "127.0.0.1:0")
+	defer srv.Stop()
+
+	notifier, err := NewGRPCNotifier("test", addr)
+	if err != nil {
+		t.Fatalf("NewGRPCNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	// large events fill the flow-control window of the stream nobody reads
+	big := achem.NotificationEvent{ReactionID: "big", InputMolecule: achem.Molecule{Payload: map[string]any{"data": strings.Repeat("x", 256<<10)}}}
+	start := time.Now()
+	var sendErr error
+	for i := 0; i < 1000 && sendErr == nil; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		sendErr = notifier.Notify(ctx, big)
+		cancel()
+	}
+	if !errors.Is(sendErr, context.DeadlineExceeded) {
+		t.Fatalf("Expected a send blocked past the deadline to fail, got %v", sendErr)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected Notify to return at its deadline, took %v", elapsed)
+	}
+
+	// the aborted stream is replaced by a new one, which the receiver reads
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for {
+		if err := notifier.Notify(ctx, achem.NotificationEvent{ReactionID: "after"}); err == nil {
+			break
+		} else if ctx.Err() != nil {
+			t.Fatalf("Expected the notifier to reopen the stream, last error: %v", err)
+		}
+	}
+	if !waitForGRPCEvents(receiver, 1) {
+		t.Fatal("Expected an event on the new stream")
+	}
+	if _, streams, _ := receiver.snapshot(); streams < 2 {
+		t.Errorf("Expected a new stream, got %d streams", streams)
+	}
+}
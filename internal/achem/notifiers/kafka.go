@@ -0,0 +1,76 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaNotifier produces one Kafka message per notification event. Messages are keyed
+// by environment ID, so the events of an environment land on the same partition and
+// keep their order.
+type KafkaNotifier struct {
+	id     string
+	topic  string
+	writer *kafka.Writer
+}
+
+// NewKafkaNotifier creates a new Kafka notifier producing to topic on the given brokers
+func NewKafkaNotifier(id string, brokers []string, topic string) *KafkaNotifier {
+	return &KafkaNotifier{
+		id:    id,
+		topic: topic,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireOne,
+			BatchTimeout: 10 * time.Millisecond,
+			WriteTimeout: 5 * time.Second,
+			// retries are left to the notification manager, see notifyWithRetry
+			MaxAttempts: 1,
+		},
+	}
+}
+
+// ID returns the notifier ID
+func (kn *KafkaNotifier) ID() string {
+	return kn.id
+}
+
+// Type returns the notifier type
+func (kn *KafkaNotifier) Type() string {
+	return "kafka"
+}
+
+// Notify produces the event to the topic and waits for the broker's acknowledgement
+func (kn *KafkaNotifier) Notify(ctx context.Context, event achem.NotificationEvent) error {
+	msg, err := kafkaMessage(event)
+	if err != nil {
+		return err
+	}
+	if err := kn.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to produce to topic %s: %w", kn.topic, err)
+	}
+	return nil
+}
+
+// kafkaMessage builds the message for event: its JSON encoding, keyed by environment ID
+func kafkaMessage(event achem.NotificationEvent) (kafka.Message, error) {
+	data, err := event.JSON()
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return kafka.Message{
+		Key:   []byte(event.EnvironmentID),
+		Value: data,
+	}, nil
+}
+
+// Close flushes pending messages and closes the producer
+func (kn *KafkaNotifier) Close() error {
+	return kn.writer.Close()
+}
@@ -0,0 +1,53 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+func TestKafkaNotifier_IDAndType(t *testing.T) {
+	notifier := NewKafkaNotifier("test-kafka", []string{"localhost:9092"}, "events")
+	defer notifier.Close()
+
+	if notifier.ID() != "test-kafka" {
+		t.Errorf("Expected ID 'test-kafka', got '%s'", notifier.ID())
+	}
+	if notifier.Type() != "kafka" {
+		t.Errorf("Expected type 'kafka', got '%s'", notifier.Type())
+	}
+}
+
+func TestKafkaMessage(t *testing.T) {
+	event := achem.NotificationEvent{EnvironmentID: "test-env", ReactionID: "r1", EnvTime: 3}
+	msg, err := kafkaMessage(event)
+	if err != nil {
+		t.Fatalf("kafkaMessage failed: %v", err)
+	}
+	if string(msg.Key) != "test-env" {
+		t.Errorf("Expected key 'test-env', got %q", msg.Key)
+	}
+
+	var decoded achem.NotificationEvent
+	if err := json.Unmarshal(msg.Value, &decoded); err != nil {
+		t.Fatalf("Message value is not a JSON event: %v", err)
+	}
+	if decoded.ReactionID != "r1" || decoded.EnvTime != 3 {
+		t.Errorf("Unexpected decoded event: %+v", decoded)
+	}
+}
+
+func TestKafkaNotifier_SurfacesProduceErrors(t *testing.T) {
+	// nothing listens on port 1: the error must reach the caller so that it can retry
+	notifier := NewKafkaNotifier("test", []string{"127.0.0.1:1"}, "events")
+	defer notifier.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, achem.NotificationEvent{EnvironmentID: "test-env"}); err == nil {
+		t.Error("Expected an error when the broker is unreachable")
+	}
+}
@@ -0,0 +1,120 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+	"github.com/nats-io/nats.go"
+)
+
+// natsFlushTimeout bounds the wait for the server's acknowledgement when the context
+// passed to Notify has no deadline
+const natsFlushTimeout = 5 * time.Second
+
+// NATSNotifier publishes one NATS message per notification event, with the event's
+// JSON as payload. With per-environment subjects enabled, events are published to
+// "<subject>.<environment ID>" so subscribers can pick environments with wildcards
+// (see subjectToken for how IDs are escaped).
+type NATSNotifier struct {
+	id             string
+	subject        string
+	perEnvironment bool
+	conn           *nats.Conn
+	closed         chan struct{}
+}
+
+// NewNATSNotifier connects to the NATS server at url and creates a notifier publishing
+// to subject. It returns an error if the connection cannot be established.
+func NewNATSNotifier(id, url, subject string) (*NATSNotifier, error) {
+	nn := &NATSNotifier{
+		id:      id,
+		subject: subject,
+		closed:  make(chan struct{}),
+	}
+	conn, err := nats.Connect(url,
+		nats.Name("achemdb-"+id),
+		nats.ClosedHandler(func(*nats.Conn) { close(nn.closed) }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", url, err)
+	}
+	nn.conn = conn
+	return nn, nil
+}
+
+// SetPerEnvironmentSubject makes the notifier publish to "<subject>.<environment ID>"
+// instead of subject. Must be called before the notifier is registered.
+func (nn *NATSNotifier) SetPerEnvironmentSubject(enabled bool) {
+	nn.perEnvironment = enabled
+}
+
+// ID returns the notifier ID
+func (nn *NATSNotifier) ID() string {
+	return nn.id
+}
+
+// Type returns the notifier type
+func (nn *NATSNotifier) Type() string {
+	return "nats"
+}
+
+// Notify publishes the event and waits until the server has processed it, so that
+// failures reach the notification manager's retries
+func (nn *NATSNotifier) Notify(ctx context.Context, event achem.NotificationEvent) error {
+	data, err := event.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	subject := nn.eventSubject(event)
+	if err := nn.conn.Publish(subject, data); err != nil {
+		return fmt.Errorf("failed to publish to subject %s: %w", subject, err)
+	}
+
+	// FlushWithContext requires a deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, natsFlushTimeout)
+		defer cancel()
+	}
+	if err := nn.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to publish to subject %s: %w", subject, err)
+	}
+	return nil
+}
+
+// eventSubject returns the subject event is published to
+func (nn *NATSNotifier) eventSubject(event achem.NotificationEvent) string {
+	if nn.perEnvironment && event.EnvironmentID != "" {
+		return nn.subject + "." + subjectToken(string(event.EnvironmentID))
+	}
+	return nn.subject
+}
+
+// subjectToken makes s usable as a single subject token, replacing the token
+// separator, the wildcards and whitespace with "_". Otherwise an environment ID like
+// "team.a" would publish one level deeper and "*" would be an invalid subject.
+func subjectToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// Close drains the connection, publishing buffered messages, and waits for it to close
+func (nn *NATSNotifier) Close() error {
+	if err := nn.conn.Drain(); err != nil {
+		if err == nats.ErrConnectionClosed {
+			return nil
+		}
+		return err
+	}
+	<-nn.closed
+	return nil
+}
@@ -0,0 +1,141 @@
+package notifiers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// runNATSServer starts an in-process NATS server on a random port
+func runNATSServer(t *testing.T) *natsserver.Server {
+	t.Helper()
+	srv, err := natsserver.NewServer(&natsserver.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("Failed to create NATS server: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestNATSNotifier_IDAndType(t *testing.T) {
+	srv := runNATSServer(t)
+
+	notifier, err := NewNATSNotifier("test-nats", srv.ClientURL(), "events")
+	if err != nil {
+		t.Fatalf("NewNATSNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	if notifier.ID() != "test-nats" {
+		t.Errorf("Expected ID 'test-nats', got '%s'", notifier.ID())
+	}
+	if notifier.Type() != "nats" {
+		t.Errorf("Expected type 'nats', got '%s'", notifier.Type())
+	}
+}
+
+func TestNATSNotifier_Publishes(t *testing.T) {
+	srv := runNATSServer(t)
+
+	sub, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("Failed to connect subscriber: %v", err)
+	}
+	defer sub.Close()
+	msgs := make(chan *nats.Msg, 4)
+	if _, err := sub.ChanSubscribe("events.>", msgs); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if _, err := sub.ChanSubscribe("events", msgs); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatalf("Failed to flush subscriptions: %v", err)
+	}
+
+	notifier, err := NewNATSNotifier("test", srv.ClientURL(), "events")
+	if err != nil {
+		t.Fatalf("NewNATSNotifier failed: %v", err)
+	}
+	defer notifier.Close()
+
+	receive := func() *nats.Msg {
+		t.Helper()
+		select {
+		case msg := <-msgs:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the message")
+			return nil
+		}
+	}
+
+	event := achem.NotificationEvent{EnvironmentID: "prod", ReactionID: "r1", EnvTime: 3}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	msg := receive()
+	if msg.Subject != "events" {
+		t.Errorf("Expected subject 'events', got %q", msg.Subject)
+	}
+	var decoded achem.NotificationEvent
+	if err := json.Unmarshal(msg.Data, &decoded); err != nil {
+		t.Fatalf("Message is not a JSON event: %v", err)
+	}
+	if decoded.ReactionID != "r1" || decoded.EnvTime != 3 {
+		t.Errorf("Unexpected decoded event: %+v", decoded)
+	}
+
+	notifier.SetPerEnvironmentSubject(true)
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if msg := receive(); msg.Subject != "events.prod" {
+		t.Errorf("Expected subject 'events.prod', got %q", msg.Subject)
+	}
+
+	// IDs can't add tokens or wildcards to the subject
+	event.EnvironmentID = "team.a>*"
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if msg := receive(); msg.Subject != "events.team_a__" {
+		t.Errorf("Expected subject 'events.team_a__', got %q", msg.Subject)
+	}
+}
+
+func TestNATSNotifier_SurfacesPublishErrors(t *testing.T) {
+	srv := runNATSServer(t)
+
+	notifier, err := NewNATSNotifier("test", srv.ClientURL(), "events")
+	if err != nil {
+		t.Fatalf("NewNATSNotifier failed: %v", err)
+	}
+	if err := notifier.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// the error must reach the caller so that it can retry
+	if err := notifier.Notify(context.Background(), achem.NotificationEvent{EnvironmentID: "test-env"}); err == nil {
+		t.Error("Expected an error publishing on a closed connection")
+	}
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Expected closing twice to succeed, got %v", err)
+	}
+}
+
+func TestNewNATSNotifier_Unreachable(t *testing.T) {
+	// nothing listens on port 1
+	if _, err := NewNATSNotifier("test", "nats://127.0.0.1:1", "events"); err == nil {
+		t.Error("Expected an error when the server is unreachable")
+	}
+}
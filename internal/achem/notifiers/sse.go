@@ -0,0 +1,142 @@
+package notifiers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// sseClientBuffer is the number of events queued per client before it is
+// considered too slow and dropped
+const sseClientBuffer = 16
+
+// SSENotifier streams notifications to HTTP clients as Server-Sent Events
+type SSENotifier struct {
+	id      string
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	closed  bool
+}
+
+// NewSSENotifier creates a new Server-Sent Events notifier
+func NewSSENotifier(id string) *SSENotifier {
+	return &SSENotifier{
+		id:      id,
+		clients: make(map[chan []byte]struct{}),
+	}
+}
+
+// ID returns the notifier ID
+func (sn *SSENotifier) ID() string {
+	return sn.id
+}
+
+// Type returns the notifier type
+func (sn *SSENotifier) Type() string {
+	return "sse"
+}
+
+// Notify queues the event for every connected client. It never blocks: clients whose
+// queue is full are dropped, so a slow reader can't stall the notification workers.
+func (sn *SSENotifier) Notify(_ context.Context, event achem.NotificationEvent) error {
+	data, err := event.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	if sn.closed {
+		return fmt.Errorf("notifier closed")
+	}
+
+	for ch := range sn.clients {
+		select {
+		case ch <- data:
+		default:
+			// slow client: disconnect it rather than block
+			delete(sn.clients, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// ServeHTTP streams events to the client as text/event-stream until the client goes
+// away, it is dropped for being too slow, or the notifier is closed.
+func (sn *SSENotifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, sseClientBuffer)
+	sn.mu.Lock()
+	if sn.closed {
+		sn.mu.Unlock()
+		http.Error(w, "notifier closed", http.StatusGone)
+		return
+	}
+	sn.clients[ch] = struct{}{}
+	sn.mu.Unlock()
+	defer sn.removeClient(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				// dropped or notifier closed
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// removeClient unregisters ch if it is still registered
+func (sn *SSENotifier) removeClient(ch chan []byte) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	if _, ok := sn.clients[ch]; ok {
+		delete(sn.clients, ch)
+		close(ch)
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (sn *SSENotifier) ClientCount() int {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	return len(sn.clients)
+}
+
+// Close disconnects all clients. It is safe to call more than once.
+func (sn *SSENotifier) Close() error {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	if sn.closed {
+		return nil
+	}
+	sn.closed = true
+	for ch := range sn.clients {
+		delete(sn.clients, ch)
+		close(ch)
+	}
+	return nil
+}
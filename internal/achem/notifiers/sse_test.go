@@ -0,0 +1,138 @@
+package notifiers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// waitForSSEClients polls until the notifier has n clients or the timeout expires
+func waitForSSEClients(notifier *SSENotifier, n int) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if notifier.ClientCount() == n {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestSSENotifier_IDAndType(t *testing.T) {
+	notifier := NewSSENotifier("test-sse")
+	defer notifier.Close()
+
+	if notifier.ID() != "test-sse" {
+		t.Errorf("Expected ID 'test-sse', got '%s'", notifier.ID())
+	}
+	if notifier.Type() != "sse" {
+		t.Errorf("Expected type 'sse', got '%s'", notifier.Type())
+	}
+}
+
+func TestSSENotifier_Stream(t *testing.T) {
+	notifier := NewSSENotifier("test")
+	defer notifier.Close()
+
+	server := httptest.NewServer(notifier)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+	if !waitForSSEClients(notifier, 1) {
+		t.Fatal("Expected client to be registered")
+	}
+
+	event := achem.NotificationEvent{EnvironmentID: "test-env", ReactionID: "r1"}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+	if !ok {
+		t.Fatalf("Expected a data line, got %q", line)
+	}
+	expected, _ := event.JSON()
+	if data != string(expected) {
+		t.Errorf("Expected data %s, got %s", expected, data)
+	}
+	var received achem.NotificationEvent
+	if err := json.Unmarshal([]byte(data), &received); err != nil || received.ReactionID != "r1" {
+		t.Errorf("Expected event for r1, got %s (err=%v)", data, err)
+	}
+}
+
+func TestSSENotifier_DropsSlowClients(t *testing.T) {
+	notifier := NewSSENotifier("test")
+	defer notifier.Close()
+
+	// a registered client that never reads
+	ch := make(chan []byte, sseClientBuffer)
+	notifier.mu.Lock()
+	notifier.clients[ch] = struct{}{}
+	notifier.mu.Unlock()
+
+	for range sseClientBuffer + 1 {
+		done := make(chan error, 1)
+		go func() { done <- notifier.Notify(context.Background(), achem.NotificationEvent{ReactionID: "r"}) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Notify failed: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Notify blocked on a slow client")
+		}
+	}
+
+	if notifier.ClientCount() != 0 {
+		t.Errorf("Expected the slow client to be dropped, got %d clients", notifier.ClientCount())
+	}
+}
+
+func TestSSENotifier_Close(t *testing.T) {
+	notifier := NewSSENotifier("test")
+	server := httptest.NewServer(notifier)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if !waitForSSEClients(notifier, 1) {
+		t.Fatal("Expected client to be registered")
+	}
+
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Expected no error on close, got %v", err)
+	}
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Expected no error on double close, got %v", err)
+	}
+	if err := notifier.Notify(context.Background(), achem.NotificationEvent{}); err == nil {
+		t.Error("Expected error when notifying a closed notifier")
+	}
+
+	// the stream ends once the notifier is closed
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err == nil {
+		t.Error("Expected the stream to be closed")
+	}
+}
@@ -3,6 +3,9 @@ package notifiers
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,12 +13,22 @@ import (
 	"github.com/daniacca/achemdb/internal/achem"
 )
 
+// WebhookSignatureHeader is the header carrying the HMAC signature of signed webhook
+// requests, see WebhookNotifier.SetSigningSecret
+const WebhookSignatureHeader = "X-AChemDB-Signature"
+
+// webhookTimeout bounds a request when the context passed to Notify has no deadline.
+// Otherwise the context alone bounds it, so that the notifier's retry policy timeout
+// applies as configured.
+const webhookTimeout = 5 * time.Second
+
 // WebhookNotifier sends notifications via HTTP POST to a webhook URL
 type WebhookNotifier struct {
 	id      string
 	url     string
 	client  *http.Client
 	headers map[string]string
+	secret  []byte
 }
 
 // NewWebhookNotifier creates a new webhook notifier
@@ -23,7 +36,7 @@ func NewWebhookNotifier(id, url string) *WebhookNotifier {
 	return &WebhookNotifier{
 		id:      id,
 		url:     url,
-		client:  &http.Client{Timeout: 5 * time.Second},
+		client:  &http.Client{},
 		headers: make(map[string]string),
 	}
 }
@@ -36,6 +49,13 @@ func (wn *WebhookNotifier) SetHeader(key, value string) {
 	wn.headers[key] = value
 }
 
+// SetSigningSecret enables request signing: every request carries an
+// X-AChemDB-Signature header of the form "sha256=<hex>", the HMAC-SHA256 of the raw
+// request body keyed with secret. An empty secret disables signing.
+func (wn *WebhookNotifier) SetSigningSecret(secret string) {
+	wn.secret = []byte(secret)
+}
+
 // ID returns the notifier ID
 func (wn *WebhookNotifier) ID() string {
 	return wn.id
@@ -53,6 +73,12 @@ func (wn *WebhookNotifier) Notify(ctx context.Context, event achem.NotificationE
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, webhookTimeout)
+		defer cancel()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.url, bytes.NewReader(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -62,6 +88,9 @@ func (wn *WebhookNotifier) Notify(ctx context.Context, event achem.NotificationE
 	for key, value := range wn.headers {
 		req.Header.Set(key, value)
 	}
+	if len(wn.secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, signWebhookBody(wn.secret, jsonData))
+	}
 
 	resp, err := wn.client.Do(req)
 	if err != nil {
@@ -76,6 +105,14 @@ func (wn *WebhookNotifier) Notify(ctx context.Context, event achem.NotificationE
 	return nil
 }
 
+// signWebhookBody returns the signature header value of body: "sha256=" followed by
+// the hex-encoded HMAC-SHA256 of body keyed with secret
+func signWebhookBody(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 // Close closes the notifier (no-op for webhook)
 func (wn *WebhookNotifier) Close() error {
 	return nil
@@ -2,7 +2,14 @@ package notifiers
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/daniacca/achemdb/internal/achem"
 )
@@ -44,6 +51,49 @@ func TestWebhookNotifier(t *testing.T) {
 	}
 }
 
+func TestWebhookNotifier_Signature(t *testing.T) {
+	// known vector: HMAC-SHA256 of `{"a":1}` keyed with "s3cr3t"
+	want := "sha256=d42927434049e0b8c73ce887062238cc1c6bb6644bfe66e66d8dd0f30b85679e"
+	if got := signWebhookBody([]byte("s3cr3t"), []byte(`{"a":1}`)); got != want {
+		t.Errorf("Expected signature %s, got %s", want, got)
+	}
+
+	type request struct {
+		body      []byte
+		signature string
+		hasHeader bool
+	}
+	requests := make(chan request, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, hasHeader := r.Header[http.CanonicalHeaderKey(WebhookSignatureHeader)]
+		requests <- request{body: body, signature: r.Header.Get(WebhookSignatureHeader), hasHeader: hasHeader}
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhookNotifier("signed", srv.URL)
+	event := achem.NotificationEvent{EnvironmentID: "test-env", ReactionID: "r1"}
+
+	// unsigned by default
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if req := <-requests; req.hasHeader {
+		t.Errorf("Expected no signature header without a secret, got %q", req.signature)
+	}
+
+	notifier.SetSigningSecret("s3cr3t")
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	req := <-requests
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(req.body)
+	if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.signature != expected {
+		t.Errorf("Expected signature %s over the raw body, got %q", expected, req.signature)
+	}
+}
+
 // mockReaction is a minimal reaction implementation for testing
 type mockReaction struct {
 	id string
@@ -57,3 +107,31 @@ func (m *mockReaction) InputPattern(mol achem.Molecule) bool
 func (m *mockReaction) Apply(mol achem.Molecule, env achem.EnvView, ctx achem.ReactionContext) achem.ReactionEffect {
 	return achem.ReactionEffect{}
 }
+
+func TestWebhookNotifier_TimeoutFromContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// reading the body lets the server notice when the client gives up
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-time.After(webhookTimeout + 500*time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	// a longer deadline, as set from the retry policy timeout, lets the request finish
+	notifier := NewWebhookNotifier("slow", srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*webhookTimeout)
+	defer cancel()
+	if err := notifier.Notify(ctx, achem.NotificationEvent{ReactionID: "r1"}); err != nil {
+		t.Errorf("Expected the request to outlast %s within the context deadline, got %v", webhookTimeout, err)
+	}
+
+	// a shorter one cuts it off
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := notifier.Notify(ctx, achem.NotificationEvent{ReactionID: "r1"}); err == nil {
+		t.Error("Expected an error once the context deadline expired")
+	}
+}
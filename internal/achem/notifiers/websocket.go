@@ -3,6 +3,7 @@ package notifiers
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -20,6 +21,7 @@ type WebSocketNotifier struct {
 	register   chan *websocket.Conn
 	unregister chan *websocket.Conn
 	done       chan struct{}
+	closeOnce  sync.Once
 	wg         sync.WaitGroup
 }
 
@@ -73,13 +75,43 @@ func (wsn *WebSocketNotifier) UnregisterClient(conn *websocket.Conn) {
 	}
 }
 
+// ServeHTTP upgrades the request to a WebSocket connection and streams notification
+// events to it until the client disconnects or the notifier is closed. Messages sent
+// by the client are ignored.
+func (wsn *WebSocketNotifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsn.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already wrote an HTTP error response
+		return
+	}
+
+	wsn.RegisterClient(conn)
+	defer wsn.UnregisterClient(conn)
+
+	// Read until the client goes away, so that disconnects are noticed even when
+	// no events are being broadcast
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
 // Notify sends the notification event to all connected WebSocket clients
 func (wsn *WebSocketNotifier) Notify(ctx context.Context, event achem.NotificationEvent) error {
+	select {
+	case <-wsn.done:
+		return fmt.Errorf("notifier closed")
+	default:
+	}
+
 	select {
 	case wsn.broadcast <- event:
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
+	case <-wsn.done:
+		return fmt.Errorf("notifier closed")
 	case <-time.After(1 * time.Second):
 		return fmt.Errorf("notification queue full")
 	}
@@ -113,11 +145,7 @@ func (wsn *WebSocketNotifier) run() {
 			}
 			wsn.mu.Unlock()
 
-		case event, ok := <-wsn.broadcast:
-			if !ok {
-				// Channel closed, exit
-				return
-			}
+		case event := <-wsn.broadcast:
 			jsonData, err := event.JSON()
 			if err != nil {
 				continue
@@ -166,30 +194,32 @@ func (wsn *WebSocketNotifier) run() {
 	}
 }
 
-// Close closes all WebSocket connections and stops the goroutine
+// Close closes all WebSocket connections and stops the goroutine.
+// It is safe to call more than once.
 func (wsn *WebSocketNotifier) Close() error {
-	// Signal the goroutine to stop
-	close(wsn.done)
-
-	// Close all client connections
-	wsn.mu.Lock()
-	for conn := range wsn.clients {
-		conn.Close()
-		delete(wsn.clients, conn)
-	}
-	wsn.mu.Unlock()
-
-	// Close channels (this will cause the goroutine to exit if it's waiting on them)
-	close(wsn.broadcast)
-	close(wsn.register)
-	close(wsn.unregister)
-
-	// Wait for the goroutine to finish
-	wsn.wg.Wait()
-
+	wsn.closeOnce.Do(func() {
+		// Signal the goroutine to stop and wait for it, so that no write is in flight
+		close(wsn.done)
+		wsn.wg.Wait()
+
+		// Close all client connections
+		wsn.mu.Lock()
+		for conn := range wsn.clients {
+			conn.Close()
+			delete(wsn.clients, conn)
+		}
+		wsn.mu.Unlock()
+	})
 	return nil
 }
 
+// ClientCount returns the number of connected clients
+func (wsn *WebSocketNotifier) ClientCount() int {
+	wsn.mu.RLock()
+	defer wsn.mu.RUnlock()
+	return len(wsn.clients)
+}
+
 // GetUpgrader returns the WebSocket upgrader for HTTP handlers
 func (wsn *WebSocketNotifier) GetUpgrader() websocket.Upgrader {
 	return wsn.upgrader
@@ -2,10 +2,14 @@ package notifiers
 
 import (
 	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/daniacca/achemdb/internal/achem"
+	"github.com/gorilla/websocket"
 )
 
 func TestNewWebSocketNotifier(t *testing.T) {
@@ -125,8 +129,15 @@ func TestWebSocketNotifier_Close(t *testing.T) {
 		t.Errorf("Expected no error on close, got %v", err)
 	}
 
-	// Note: Double close will panic due to closing already-closed channels
-	// This is expected behavior - Close should only be called once
+	// Double close is a no-op
+	if err := notifier.Close(); err != nil {
+		t.Errorf("Expected no error on double close, got %v", err)
+	}
+
+	// Notify after close fails instead of panicking
+	if err := notifier.Notify(context.Background(), achem.NotificationEvent{}); err == nil {
+		t.Error("Expected error when notifying a closed notifier")
+	}
 }
 
 func TestWebSocketNotifier_MultipleClients(t *testing.T) {
@@ -159,3 +170,75 @@ func TestWebSocketNotifier_MultipleClients(t *testing.T) {
 		t.Errorf("Expected no error on close, got %v", err)
 	}
 }
+
+// waitForClients polls until the notifier has n clients or the timeout expires
+func waitForClients(notifier *WebSocketNotifier, n int) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if notifier.ClientCount() == n {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWebSocketNotifier_ServeHTTP(t *testing.T) {
+	notifier := NewWebSocketNotifier("test")
+	defer notifier.Close()
+
+	server := httptest.NewServer(notifier)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	if !waitForClients(notifier, 1) {
+		t.Fatalf("Expected 1 client, got %d", notifier.ClientCount())
+	}
+
+	event := achem.NotificationEvent{EnvironmentID: "test-env", ReactionID: "r1"}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	var received achem.NotificationEvent
+	if err := json.Unmarshal(data, &received); err != nil || received.ReactionID != "r1" {
+		t.Errorf("Expected event for r1, got %s (err=%v)", data, err)
+	}
+
+	// a disconnecting client is dropped from the set
+	conn.Close()
+	if !waitForClients(notifier, 0) {
+		t.Errorf("Expected disconnected client to be dropped, got %d clients", notifier.ClientCount())
+	}
+}
+
+func TestWebSocketNotifier_CloseDisconnectsClients(t *testing.T) {
+	notifier := NewWebSocketNotifier("test")
+	server := httptest.NewServer(notifier)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if !waitForClients(notifier, 1) {
+		t.Fatal("Expected client to be registered")
+	}
+
+	notifier.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("Expected the connection to be closed by the notifier")
+	}
+}
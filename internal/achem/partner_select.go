@@ -0,0 +1,100 @@
+package achem
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// Partner selection orders: which partners a reaction picks when more molecules match
+// than it needs. By default it takes the first ones found.
+const (
+	SelectLowestEnergy  = "lowest_energy"
+	SelectHighestEnergy = "highest_energy"
+	SelectOldest        = "oldest"
+	SelectNewest        = "newest"
+	SelectNearest       = "nearest" // closest to the input molecule, see Molecule.Position
+)
+
+// partnerOrders maps the partner selection orders to comparators, given the input
+// molecule m
+var partnerOrders = map[string]func(m Molecule) func(a, b Molecule) bool{
+	SelectLowestEnergy: func(Molecule) func(a, b Molecule) bool {
+		return func(a, b Molecule) bool { return a.Energy < b.Energy }
+	},
+	SelectHighestEnergy: func(Molecule) func(a, b Molecule) bool {
+		return func(a, b Molecule) bool { return a.Energy > b.Energy }
+	},
+	SelectOldest: func(Molecule) func(a, b Molecule) bool {
+		return func(a, b Molecule) bool { return a.CreatedAt < b.CreatedAt }
+	},
+	SelectNewest: func(Molecule) func(a, b Molecule) bool {
+		return func(a, b Molecule) bool { return a.CreatedAt > b.CreatedAt }
+	},
+	SelectNearest: func(m Molecule) func(a, b Molecule) bool {
+		distance := func(c Molecule) float64 {
+			if m.Position == nil || c.Position == nil {
+				return math.Inf(1)
+			}
+			return m.Position.Distance(*c.Position)
+		}
+		return func(a, b Molecule) bool { return distance(a) < distance(b) }
+	},
+}
+
+// findBest returns the molecule of mols that sorts first according to less, breaking
+// ties by ID, and false if mols is empty
+func findBest(mols []Molecule, less func(a, b Molecule) bool) (Molecule, bool) {
+	if len(mols) == 0 {
+		return Molecule{}, false
+	}
+	best := mols[0]
+	for _, m := range mols[1:] {
+		if less(m, best) || (!less(best, m) && m.ID < best.ID) {
+			best = m
+		}
+	}
+	return best, true
+}
+
+// bestPartner returns the single partner of species picked by the selection order,
+// straight from EnvView.FindBest, for partners filtered by species alone. The input
+// molecule and the molecules in exclude sort after all the others, so that they are
+// only found when nothing else is, and are then dropped.
+func bestPartner(species SpeciesName, order string, m Molecule, env EnvView, exclude map[MoleculeID]struct{}) []Molecule {
+	less := partnerOrders[order](m)
+	eligible := func(c Molecule) bool {
+		_, excluded := exclude[c.ID]
+		return c.ID != m.ID && !excluded
+	}
+	best, ok := env.FindBest(species, func(a, b Molecule) bool {
+		if ea, eb := eligible(a), eligible(b); ea != eb {
+			return ea
+		}
+		return less(a, b)
+	})
+	if !ok || !eligible(best) {
+		return nil
+	}
+	return []Molecule{best}
+}
+
+// selectPartners returns the first n candidates according to the selection order,
+// breaking ties by ID. The candidates may be reordered.
+func selectPartners(candidates []Molecule, order string, m Molecule, n int) []Molecule {
+	less := partnerOrders[order](m)
+	if n == 1 {
+		best, _ := findBest(candidates, less)
+		return []Molecule{best}
+	}
+	slices.SortFunc(candidates, func(a, b Molecule) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		}
+		return strings.Compare(string(a.ID), string(b.ID))
+	})
+	return candidates[:n]
+}
@@ -0,0 +1,100 @@
+package achem
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEnvView_FindBest(t *testing.T) {
+	mols := []Molecule{
+		{ID: "c", Species: "Worker", Energy: 2},
+		{ID: "b", Species: "Worker", Energy: 1},
+		{ID: "a", Species: "Worker", Energy: 1},
+		{ID: "z", Species: "Job", Energy: 0},
+	}
+	bySpecies := make(map[SpeciesName][]Molecule)
+	for _, m := range mols {
+		bySpecies[m.Species] = append(bySpecies[m.Species], m)
+	}
+	lowestEnergy := func(a, b Molecule) bool { return a.Energy < b.Energy }
+
+	for _, view := range []EnvView{envView{molecules: mols, bySpecies: bySpecies}, envView{molecules: mols}} {
+		// b and a tie on energy: the lowest ID wins
+		if best, ok := view.FindBest("Worker", lowestEnergy); !ok || best.ID != "a" {
+			t.Errorf("Expected a, got %v (found=%v)", best.ID, ok)
+		}
+		if _, ok := view.FindBest("Missing", lowestEnergy); ok {
+			t.Error("Expected no molecule of a missing species")
+		}
+	}
+}
+
+func TestFindPartners_Select(t *testing.T) {
+	m := Molecule{ID: "job", Species: "Job", Position: &Position{X: 0, Y: 0}}
+	view := testEnvView{molecules: []Molecule{
+		m,
+		{ID: "w1", Species: "Worker", Energy: 3, CreatedAt: 5, Position: &Position{X: 1, Y: 0}},
+		{ID: "w2", Species: "Worker", Energy: 1, CreatedAt: 9},
+		{ID: "w3", Species: "Worker", Energy: 2, CreatedAt: 2, Position: &Position{X: 0, Y: 3}},
+		{ID: "w4", Species: "Worker", Energy: 1, CreatedAt: 7, Position: &Position{X: 2, Y: 0}},
+	}}
+
+	tests := []struct {
+		order string
+		count int
+		want  []MoleculeID
+	}{
+		{SelectLowestEnergy, 1, []MoleculeID{"w2"}},
+		{SelectLowestEnergy, 3, []MoleculeID{"w2", "w4", "w3"}},
+		{SelectHighestEnergy, 2, []MoleculeID{"w1", "w3"}},
+		{SelectOldest, 1, []MoleculeID{"w3"}},
+		{SelectNewest, 2, []MoleculeID{"w2", "w4"}},
+		{SelectNearest, 3, []MoleculeID{"w1", "w4", "w3"}},
+		{"", 4, []MoleculeID{"w1", "w2", "w3", "w4"}}, // all of them, in the order found
+	}
+	for _, tt := range tests {
+		cfg := PartnerConfig{Species: "Worker", Count: tt.count, Select: tt.order}
+		var got []MoleculeID
+		for _, p := range findPartners(cfg, m, view, 0, nil) {
+			got = append(got, p.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("select %q, count %d: expected %v, got %v", tt.order, tt.count, tt.want, got)
+		}
+	}
+}
+
+// findBestRecorder counts the FindBest calls made on the view
+type findBestRecorder struct {
+	testEnvView
+	calls int
+}
+
+func (v *findBestRecorder) FindBest(species SpeciesName, less func(a, b Molecule) bool) (Molecule, bool) {
+	v.calls++
+	return v.testEnvView.FindBest(species, less)
+}
+
+func TestFindPartners_SelectSingleUsesFindBest(t *testing.T) {
+	m := Molecule{ID: "w0", Species: "Worker", Energy: 0}
+	view := &findBestRecorder{testEnvView: testEnvView{molecules: []Molecule{
+		m,
+		{ID: "w1", Species: "Worker", Energy: 1},
+		{ID: "w2", Species: "Worker", Energy: 2},
+	}}}
+	cfg := PartnerConfig{Species: "Worker", Select: SelectLowestEnergy}
+
+	// the input molecule itself and excluded molecules are never picked
+	if got := findPartners(cfg, m, view, 0, nil); len(got) != 1 || got[0].ID != "w1" {
+		t.Errorf("Expected w1, got %v", got)
+	}
+	if got := findPartners(cfg, m, view, 0, map[MoleculeID]struct{}{"w1": {}}); len(got) != 1 || got[0].ID != "w2" {
+		t.Errorf("Expected w2 with w1 excluded, got %v", got)
+	}
+	if got := findPartners(cfg, m, view, 0, map[MoleculeID]struct{}{"w1": {}, "w2": {}}); len(got) != 0 {
+		t.Errorf("Expected no partner when all are excluded, got %v", got)
+	}
+	if view.calls != 3 {
+		t.Errorf("Expected every lookup to go through FindBest, got %d calls", view.calls)
+	}
+}
@@ -0,0 +1,88 @@
+package achem
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+)
+
+// Types of the payload fields declared by SpeciesConfig.Fields
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeInteger = "integer" // a number without a fractional part
+	FieldTypeBoolean = "boolean"
+	FieldTypeObject  = "object"
+	FieldTypeArray   = "array"
+	FieldTypeAny     = "any" // any value, e.g. to only require the field
+)
+
+// FieldSpec declares the type of a payload field of a species, and whether the
+// molecules of the species must set it.
+type FieldSpec struct {
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// fieldTypes checks, per field type, whether a payload value has the type
+var fieldTypes = map[string]func(v any) bool{
+	FieldTypeString: func(v any) bool { _, ok := v.(string); return ok },
+	FieldTypeNumber: func(v any) bool { _, ok := toFloat64(v); return ok },
+	FieldTypeInteger: func(v any) bool {
+		f, ok := toFloat64(v)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	},
+	FieldTypeBoolean: func(v any) bool { _, ok := v.(bool); return ok },
+	FieldTypeObject:  func(v any) bool { return reflect.TypeOf(v).Kind() == reflect.Map },
+	FieldTypeArray: func(v any) bool {
+		k := reflect.TypeOf(v).Kind()
+		return k == reflect.Slice || k == reflect.Array
+	},
+	FieldTypeAny: func(v any) bool { return true },
+}
+
+// payloadTypeName describes the type of a payload value in error messages
+func payloadTypeName(v any) string {
+	for _, t := range []string{FieldTypeString, FieldTypeNumber, FieldTypeBoolean, FieldTypeObject, FieldTypeArray} {
+		if fieldTypes[t](v) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// validatePayload checks payload against the fields declared by the species: required
+// fields must be set, and declared fields must have their type. A null value counts as
+// unset. Fields that are not declared are accepted.
+func (sp Species) validatePayload(payload map[string]any) error {
+	for _, name := range slices.Sorted(maps.Keys(sp.Fields)) {
+		spec := sp.Fields[name]
+		v, ok := payload[name]
+		if !ok || v == nil {
+			if spec.Required {
+				return fmt.Errorf("species %s: required field %q is missing", sp.Name, name)
+			}
+			continue
+		}
+		if check, known := fieldTypes[spec.Type]; known && !check(v) {
+			return fmt.Errorf("species %s: field %q must be %s, got %s", sp.Name, name, spec.Type, payloadTypeName(v))
+		}
+	}
+	return nil
+}
+
+// ValidateMolecule checks the payload of m against the fields declared by its species
+// (see SpeciesConfig.Fields). Molecules of species that declare no fields, or that the
+// schema doesn't define, are always valid.
+func (s *Schema) ValidateMolecule(m Molecule) error {
+	if s == nil {
+		return nil
+	}
+	sp, ok := s.species[m.Species]
+	if !ok || len(sp.Fields) == 0 {
+		return nil
+	}
+	return sp.validatePayload(m.Payload)
+}
@@ -0,0 +1,157 @@
+package achem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchema_ValidateMolecule(t *testing.T) {
+	schema, err := BuildSchemaFromConfig(SchemaConfig{
+		Name: "shop",
+		Species: []SpeciesConfig{
+			{Name: "Order", Fields: map[string]FieldSpec{
+				"price":    {Type: FieldTypeNumber, Required: true},
+				"quantity": {Type: FieldTypeInteger},
+				"sku":      {Type: FieldTypeString, Required: true},
+				"gift":     {Type: FieldTypeBoolean},
+				"address":  {Type: FieldTypeObject},
+				"items":    {Type: FieldTypeArray},
+				"trace":    {Type: FieldTypeAny, Required: true},
+			}},
+			{Name: "Free"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	valid := map[string]any{"price": 10.5, "quantity": 2, "sku": "A1", "gift": true, "address": map[string]any{}, "items": []string{"x"}, "trace": 1, "extra": "ok"}
+	if err := schema.ValidateMolecule(Molecule{Species: "Order", Payload: valid}); err != nil {
+		t.Errorf("Expected a valid payload, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		field   string
+		value   any
+		wantErr string
+	}{
+		{"string price", "price", "10", `field "price" must be number, got string`},
+		{"fractional quantity", "quantity", 2.5, `field "quantity" must be integer, got number`},
+		{"numeric sku", "sku", 1.0, `field "sku" must be string, got number`},
+		{"string flag", "gift", "yes", `field "gift" must be boolean, got string`},
+		{"array address", "address", []any{}, `field "address" must be object, got array`},
+		{"object items", "items", map[string]any{}, `field "items" must be array, got object`},
+		{"null required", "price", nil, `required field "price" is missing`},
+	}
+	for _, tt := range tests {
+		payload := make(map[string]any, len(valid))
+		for k, v := range valid {
+			payload[k] = v
+		}
+		payload[tt.field] = tt.value
+		err := schema.ValidateMolecule(Molecule{Species: "Order", Payload: payload})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	if err := schema.ValidateMolecule(Molecule{Species: "Order", Payload: map[string]any{"price": 1}}); err == nil || !strings.Contains(err.Error(), `species Order: required field "sku" is missing`) {
+		t.Errorf("Expected the missing sku to be reported, got %v", err)
+	}
+	if err := schema.ValidateMolecule(Molecule{Species: "Free", Payload: map[string]any{"price": "10"}}); err != nil {
+		t.Errorf("Expected species without fields to accept any payload, got %v", err)
+	}
+}
+
+func TestEnvironment_InsertValidatesFields(t *testing.T) {
+	cfg := SchemaConfig{
+		Name: "shop",
+		Species: []SpeciesConfig{
+			{Name: "Order", Fields: map[string]FieldSpec{"price": {Type: FieldTypeNumber, Required: true}}},
+			{Name: "Invoice", Fields: map[string]FieldSpec{"total": {Type: FieldTypeNumber, Required: true}}},
+		},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "bill",
+				Input: InputConfig{Species: "Order"},
+				Rate:  1,
+				Effects: []EffectConfig{
+					{Consume: true},
+					// the created invoice copies no total: it doesn't conform
+					{Create: &CreateEffectConfig{Species: "Invoice", Payload: map[string]any{"order_price": "$input.price"}}},
+				},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	if err := env.Insert(Molecule{Species: "Order", Payload: map[string]any{"price": "10"}}); err == nil {
+		t.Error("Expected a string price to be rejected")
+	}
+	if _, err := env.InsertBatch([]Molecule{
+		{Species: "Order", Payload: map[string]any{"price": 10}},
+		{Species: "Order"},
+	}); err == nil || !strings.Contains(err.Error(), "molecule at index 1") {
+		t.Errorf("Expected the batch to be rejected at index 1, got %v", err)
+	}
+	if _, _, err := env.Upsert(Molecule{Species: "Order", Payload: map[string]any{"id": 1, "price": false}}, []string{"id"}); err == nil {
+		t.Error("Expected the upsert of a boolean price to be rejected")
+	}
+	if got := len(env.AllMolecules()); got != 0 {
+		t.Fatalf("Expected nothing to be inserted, got %d molecules", got)
+	}
+
+	if err := env.Insert(Molecule{Species: "Order", Payload: map[string]any{"price": 10}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	sum := env.StepN(1)
+	if len(env.AllMolecules()) != 0 || sum.Created["Invoice"] != 0 || env.Counters(false).MoleculesCreated != 0 {
+		t.Errorf("Expected the non-conforming invoice to be dropped, got %+v", sum)
+	}
+}
+
+func TestEnvironment_UpdatesValidateFields(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "shop",
+		Species: []SpeciesConfig{{Name: "Order", Fields: map[string]FieldSpec{"price": {Type: FieldTypeNumber, Required: true}}}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "corrupt",
+				Input:   InputConfig{Species: "Order"},
+				Rate:    1,
+				Effects: []EffectConfig{{Update: &UpdateEffectConfig{PayloadSet: map[string]any{"price": "free"}}}},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	if err := env.Insert(Molecule{ID: "o", Species: "Order", Payload: map[string]any{"price": 10.0}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	m, _ := env.GetMolecule("o")
+	ok, err := env.UpdateMoleculeCAS("o", m.Version, func(m Molecule) Molecule {
+		delete(m.Payload, "price")
+		return m
+	})
+	if ok || err == nil || errors.Is(err, ErrMoleculeNotFound) {
+		t.Errorf("Expected a validation error, got ok=%v err=%v", ok, err)
+	}
+	if _, err := env.UpdateMoleculeCAS("missing", 0, func(m Molecule) Molecule { return m }); !errors.Is(err, ErrMoleculeNotFound) {
+		t.Errorf("Expected ErrMoleculeNotFound, got %v", err)
+	}
+
+	env.Step()
+	if got, _ := env.GetMolecule("o"); got.Payload["price"] != 10.0 || got.Version != m.Version {
+		t.Errorf("Expected the non-conforming update to be dropped, got %+v", got)
+	}
+}
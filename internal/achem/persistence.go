@@ -6,11 +6,13 @@ import (
 )
 
 // Snapshot represents a point-in-time capture of an environment's state.
-// It includes the environment ID, current time, and all molecules.
+// It includes the environment ID, current time, and all molecules, plus the schema
+// configuration when the schema was built from one (nil in older snapshots).
 type Snapshot struct {
 	EnvironmentID EnvironmentID `json:"environment_id"`
 	Time          int64         `json:"time"`
 	Molecules     []Molecule    `json:"molecules"`
+	Schema        *SchemaConfig `json:"schema,omitempty"`
 }
 
 // ValidateSnapshot performs validation checks on a snapshot.
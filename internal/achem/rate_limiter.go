@@ -0,0 +1,55 @@
+package achem
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a thread-safe token bucket rate limiter.
+// Tokens are refilled continuously at `rate` per second up to `burst`.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time // injectable clock, for testing
+}
+
+// newTokenBucket creates a token bucket that starts full.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	now := time.Now
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+		now:    now,
+	}
+}
+
+// take tries to consume n tokens. If not enough tokens are available, nothing is consumed
+// and the returned duration is how long the caller should wait before retrying.
+// Requests larger than the burst size can never succeed and report a zero wait.
+func (b *tokenBucket) take(n int) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+
+	need := float64(n)
+	if need > b.burst {
+		return false, 0
+	}
+	if b.tokens >= need {
+		b.tokens -= need
+		return true, 0
+	}
+
+	wait := time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
@@ -0,0 +1,85 @@
+package achem
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	clock := time.Unix(0, 0)
+	b := newTokenBucket(2, 3) // 2 tokens/s, burst of 3
+	b.now = func() time.Time { return clock }
+	b.last = clock
+
+	// burst is available immediately
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.take(1); !ok {
+			t.Fatalf("Expected take %d to succeed within burst", i)
+		}
+	}
+
+	ok, wait := b.take(1)
+	if ok {
+		t.Fatal("Expected take to fail once the burst is exhausted")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("Expected wait of 500ms, got %v", wait)
+	}
+
+	// refills over time, capped at burst
+	clock = clock.Add(10 * time.Second)
+	if ok, _ := b.take(3); !ok {
+		t.Error("Expected full burst after refill")
+	}
+	if ok, _ := b.take(1); ok {
+		t.Error("Expected tokens to be capped at burst")
+	}
+
+	// requests larger than the burst never succeed
+	clock = clock.Add(10 * time.Second)
+	if ok, wait := b.take(4); ok || wait != 0 {
+		t.Errorf("Expected oversized request to be rejected with zero wait, got ok=%v wait=%v", ok, wait)
+	}
+}
+
+func TestEnvironment_InsertRateLimit(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+
+	// unlimited by default
+	for i := 0; i < 100; i++ {
+		if ok, _ := env.AllowInsert(1); !ok {
+			t.Fatal("Expected inserts to be unlimited by default")
+		}
+	}
+
+	env.SetInsertRateLimit(1, 5)
+	if got := env.GetInsertRateLimit(); got.PerSecond != 1 || got.Burst != 5 {
+		t.Errorf("Unexpected rate limit config: %+v", got)
+	}
+
+	// concurrent callers never exceed the burst
+	var mu sync.Mutex
+	allowed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := env.AllowInsert(1); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 5 {
+		t.Errorf("Expected exactly 5 allowed inserts, got %d", allowed)
+	}
+
+	env.SetInsertRateLimit(0, 0)
+	if ok, _ := env.AllowInsert(1); !ok {
+		t.Error("Expected inserts to be unlimited after disabling the limit")
+	}
+}
@@ -5,6 +5,8 @@ package achem
 type ReactionContext struct {
 	EnvTime int64
 	Random  func() float64
+
+	consumed map[MoleculeID]struct{} // molecules already consumed in this tick, if known
 }
 
 // MoleculeChange represents an update to an existing molecule.
@@ -18,10 +20,23 @@ type MoleculeChange struct {
 // It can consume molecules, update existing ones, create new ones, and
 // perform additional operations.
 type ReactionEffect struct {
-	ConsumedIDs   []MoleculeID     // molecules to remove
-	Changes       []MoleculeChange // molecules to update
-	NewMolecules  []Molecule       // new molecules to insert
-	AdditionalOps []Operation      // extendable in the future (e.g. log, metrics)
+	ConsumedIDs   []MoleculeID      // molecules to remove
+	Changes       []MoleculeChange  // molecules to update
+	NewMolecules  []Molecule        // new molecules to insert
+	Emitted       []EmittedMolecule // new molecules routed to other environments
+	Requeue       int               // re-evaluate the input in the same tick, at most this many times
+	AdditionalOps []Operation       // extendable in the future (e.g. log, metrics)
+}
+
+// MaxRequeueIterations is the upper bound on how many times a molecule can be
+// requeued within a single tick, whatever its effects request.
+const MaxRequeueIterations = 100
+
+// EmittedMolecule is a molecule created by a reaction that must be delivered
+// to one or more target environments instead of the current one.
+type EmittedMolecule struct {
+	Molecule Molecule
+	Targets  []EnvironmentID
 }
 
 // Operation is a placeholder for future extensible operations
@@ -39,6 +54,15 @@ type EnvView interface {
 
 	// Flexible query
 	Find(filter func(Molecule) bool) []Molecule
+
+	// FindBest returns the molecule of a species that sorts first according to less,
+	// e.g. the lowest-energy or the oldest one, and false if there is none. Ties are
+	// broken by ID, so that the choice doesn't depend on the order of the molecules.
+	FindBest(species SpeciesName, less func(a, b Molecule) bool) (Molecule, bool)
+
+	// EnvTime returns the time of the tick being computed (the same as
+	// ReactionContext.EnvTime), e.g. to compare with molecules' CreatedAt
+	EnvTime() int64
 }
 
 // Reaction defines the interface that all reactions must implement.
@@ -0,0 +1,66 @@
+package achem
+
+import "sort"
+
+// ReactionStats describes how often a reaction fired in an environment, since the
+// environment was created or last reset.
+type ReactionStats struct {
+	ReactionID  string `json:"reaction_id"`
+	Fired       int64  `json:"fired"`                   // times fired with effects
+	LastFiredAt *int64 `json:"last_fired_at,omitempty"` // environment time of the last fire, nil if never fired
+}
+
+// reactionFires holds the cumulative fire statistics of a reaction
+type reactionFires struct {
+	fired       int64
+	lastFiredAt int64
+}
+
+// recordReactionFiresLocked adds the fires of the tick at time now to the reaction
+// statistics. Must be called with e.mu held for writing.
+func (e *Environment) recordReactionFiresLocked(fired map[string]int64, now int64) {
+	for id, n := range fired {
+		if n == 0 {
+			continue
+		}
+		if e.reactionFires == nil {
+			e.reactionFires = make(map[string]reactionFires)
+		}
+		f := e.reactionFires[id]
+		f.fired += n
+		f.lastFiredAt = now
+		e.reactionFires[id] = f
+	}
+}
+
+// ReactionStats returns the fire statistics of every reaction of the schema, including
+// reactions that never fired, and of reactions that fired before a schema update
+// removed them, sorted by reaction ID. Unlike Counters, the statistics are not reset
+// by reading them, only by Reset, so they can be used to find rules that never match.
+func (e *Environment) ReactionStats() []ReactionStats {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ids := make(map[string]struct{}, len(e.reactionFires))
+	for id := range e.reactionFires {
+		ids[id] = struct{}{}
+	}
+	if e.schema != nil {
+		for _, r := range e.schema.Reactions() {
+			ids[r.ID()] = struct{}{}
+		}
+	}
+
+	out := make([]ReactionStats, 0, len(ids))
+	for id := range ids {
+		stats := ReactionStats{ReactionID: id}
+		if f, ok := e.reactionFires[id]; ok {
+			stats.Fired = f.fired
+			lastFiredAt := f.lastFiredAt
+			stats.LastFiredAt = &lastFiredAt
+		}
+		out = append(out, stats)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ReactionID < out[j].ReactionID })
+	return out
+}
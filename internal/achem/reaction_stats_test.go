@@ -0,0 +1,45 @@
+package achem
+
+import "testing"
+
+func TestEnvironment_ReactionStats(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "stats",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}, {Name: "Never"}},
+		Reactions: []ReactionConfig{
+			{ID: "alert", Input: InputConfig{Species: "Event"}, Rate: 1, Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}}},
+			{ID: "dead", Input: InputConfig{Species: "Never"}, Rate: 1, Effects: []EffectConfig{{Consume: true}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	env.Insert(NewMolecule("Event", nil, 0))
+	env.Insert(NewMolecule("Event", nil, 0))
+	env.Step() // t=1: alert fires twice
+	env.Step() // t=2: nothing left to react
+	env.Insert(NewMolecule("Event", nil, 0))
+	env.Step() // t=3: alert fires once
+
+	// reading the counters with reset doesn't affect the statistics
+	env.Counters(true)
+
+	stats := env.ReactionStats()
+	if len(stats) != 2 || stats[0].ReactionID != "alert" || stats[1].ReactionID != "dead" {
+		t.Fatalf("Expected stats for [alert dead], got %+v", stats)
+	}
+	if stats[0].Fired != 3 || stats[0].LastFiredAt == nil || *stats[0].LastFiredAt != 3 {
+		t.Errorf("Expected alert to have fired 3 times, last at 3, got %+v", stats[0])
+	}
+	if stats[1].Fired != 0 || stats[1].LastFiredAt != nil {
+		t.Errorf("Expected dead never to have fired, got %+v", stats[1])
+	}
+
+	env.Reset()
+	if stats := env.ReactionStats(); stats[0].Fired != 0 || stats[0].LastFiredAt != nil {
+		t.Errorf("Expected Reset to clear the statistics, got %+v", stats[0])
+	}
+}
@@ -0,0 +1,76 @@
+package achem
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DisableReaction stops the reaction with the given ID from firing until EnableReaction
+// is called, without changing the schema. The flag is kept by ID, so it survives schema
+// updates that keep the reaction, but it is not persisted in snapshots. Returns an error
+// if the environment's schema has no such reaction.
+func (e *Environment) DisableReaction(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.hasReactionLocked(id) {
+		return fmt.Errorf("reaction %s not found", id)
+	}
+	if e.disabledReactions == nil {
+		e.disabledReactions = make(map[string]struct{})
+	}
+	e.disabledReactions[id] = struct{}{}
+	return nil
+}
+
+// EnableReaction lets a reaction disabled with DisableReaction fire again. Returns an
+// error if the environment's schema has no such reaction.
+func (e *Environment) EnableReaction(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.hasReactionLocked(id) {
+		return fmt.Errorf("reaction %s not found", id)
+	}
+	delete(e.disabledReactions, id)
+	return nil
+}
+
+// DisabledReactions returns the IDs of the disabled reactions, sorted.
+func (e *Environment) DisabledReactions() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	ids := make([]string, 0, len(e.disabledReactions))
+	for id := range e.disabledReactions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// hasReactionLocked reports whether the schema has a reaction with the given ID.
+// Callers must hold e.mu.
+func (e *Environment) hasReactionLocked(id string) bool {
+	if e.schema == nil {
+		return false
+	}
+	for _, r := range e.schema.Reactions() {
+		if r.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// enabledReactionsLocked returns reactions without the disabled ones. The input slice
+// is returned as is when no reaction is disabled. Callers must hold e.mu.
+func (e *Environment) enabledReactionsLocked(reactions []Reaction) []Reaction {
+	if len(e.disabledReactions) == 0 {
+		return reactions
+	}
+	enabled := make([]Reaction, 0, len(reactions))
+	for _, r := range reactions {
+		if _, disabled := e.disabledReactions[r.ID()]; !disabled {
+			enabled = append(enabled, r)
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,53 @@
+package achem
+
+import "testing"
+
+func TestEnvironment_DisableReaction(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "toggle",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}, {Name: "Log"}},
+		Reactions: []ReactionConfig{
+			{ID: "alert", Input: InputConfig{Species: "Event"}, Rate: 1, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Alert"}}}},
+			{ID: "log", Input: InputConfig{Species: "Event"}, Rate: 1, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Log"}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	env.Insert(NewMolecule("Event", nil, 0))
+
+	if err := env.DisableReaction("missing"); err == nil {
+		t.Error("Expected an error disabling an unknown reaction")
+	}
+	if err := env.DisableReaction("alert"); err != nil {
+		t.Fatalf("DisableReaction failed: %v", err)
+	}
+	if ids := env.DisabledReactions(); len(ids) != 1 || ids[0] != "alert" {
+		t.Errorf("Expected [alert] to be disabled, got %v", ids)
+	}
+
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["Alert"] != 0 || counts["Log"] != 1 {
+		t.Errorf("Expected only the enabled reaction to fire, got %v", counts)
+	}
+
+	// clones keep the flag
+	clone := env.Clone()
+	clone.Step()
+	if counts := clone.SpeciesCounts(); counts["Alert"] != 0 {
+		t.Errorf("Expected the clone to keep the reaction disabled, got %v", counts)
+	}
+
+	if err := env.EnableReaction("alert"); err != nil {
+		t.Fatalf("EnableReaction failed: %v", err)
+	}
+	env.Step()
+	if counts := env.SpeciesCounts(); counts["Alert"] != 1 || counts["Log"] != 2 {
+		t.Errorf("Expected both reactions to fire once enabled, got %v", counts)
+	}
+	if ids := env.DisabledReactions(); len(ids) != 0 {
+		t.Errorf("Expected no disabled reactions, got %v", ids)
+	}
+}
@@ -0,0 +1,136 @@
+package achem
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// matchRegex reports whether the string form of value matches the condition's
+// pattern. Conditions compiled by BuildSchemaFromConfig reuse their regexp; others
+// (e.g. ad-hoc queries) compile it on the fly, and never match if it is invalid.
+func matchRegex(cond EqCondition, value any) bool {
+	re := cond.regex
+	if re == nil {
+		var err error
+		if re, err = regexp.Compile(cond.Regex); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(fmt.Sprintf("%v", value))
+}
+
+// compileReactionRegexes returns a copy of rc in which every where condition with a
+// regex carries its compiled pattern, so that it is compiled once per schema build
+// rather than on every match. rc itself is left untouched.
+func compileReactionRegexes(rc ReactionConfig) (ReactionConfig, error) {
+	var err error
+	if rc.Input.Where, err = compileWhereRegexes(rc.Input.Where); err != nil {
+		return rc, err
+	}
+
+	partners := make([]PartnerConfig, len(rc.Input.Partners))
+	for i, p := range rc.Input.Partners {
+		if p.Where, err = compileWhereRegexes(p.Where); err != nil {
+			return rc, err
+		}
+		partners[i] = p
+	}
+	if rc.Input.Partners != nil {
+		rc.Input.Partners = partners
+	}
+
+	catalysts := make([]CatalystConfig, len(rc.Catalysts))
+	for i, c := range rc.Catalysts {
+		if c.Where, err = compileWhereRegexes(c.Where); err != nil {
+			return rc, err
+		}
+		catalysts[i] = c
+	}
+	if rc.Catalysts != nil {
+		rc.Catalysts = catalysts
+	}
+
+	inhibitors := make([]InhibitorConfig, len(rc.Inhibitors))
+	for i, in := range rc.Inhibitors {
+		if in.Where, err = compileWhereRegexes(in.Where); err != nil {
+			return rc, err
+		}
+		inhibitors[i] = in
+	}
+	if rc.Inhibitors != nil {
+		rc.Inhibitors = inhibitors
+	}
+
+	rc.Effects, err = compileEffectRegexes(rc.Effects)
+	return rc, err
+}
+
+// compileEffectRegexes compiles the where conditions of count_molecules conditions,
+// recursively through then/else branches.
+func compileEffectRegexes(effects []EffectConfig) ([]EffectConfig, error) {
+	if effects == nil {
+		return nil, nil
+	}
+	out := make([]EffectConfig, len(effects))
+	for i, eff := range effects {
+		if eff.If != nil && eff.If.CountMolecules != nil {
+			cond := *eff.If
+			count := *cond.CountMolecules
+			var err error
+			if count.Where, err = compileWhereRegexes(count.Where); err != nil {
+				return nil, err
+			}
+			cond.CountMolecules = &count
+			eff.If = &cond
+		}
+		var err error
+		if eff.Then, err = compileEffectRegexes(eff.Then); err != nil {
+			return nil, err
+		}
+		if eff.Else, err = compileEffectRegexes(eff.Else); err != nil {
+			return nil, err
+		}
+		if eff.Choose != nil {
+			choose := ChooseEffectConfig{Branches: make([]WeightedBranch, len(eff.Choose.Branches))}
+			for j, branch := range eff.Choose.Branches {
+				if branch.Effects, err = compileEffectRegexes(branch.Effects); err != nil {
+					return nil, err
+				}
+				choose.Branches[j] = branch
+			}
+			eff.Choose = &choose
+		}
+		out[i] = eff
+	}
+	return out, nil
+}
+
+// compileWhereRegexes returns a copy of where with compiled regex conditions,
+// including those nested in "$or"/"$and" groups.
+func compileWhereRegexes(where WhereConfig) (WhereConfig, error) {
+	if where == nil {
+		return nil, nil
+	}
+	out := make(WhereConfig, len(where))
+	for field, cond := range where {
+		if cond.Regex != "" {
+			re, err := regexp.Compile(cond.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("where field '%s' has invalid regex: %w", field, err)
+			}
+			cond.regex = re
+		}
+		if cond.Clauses != nil {
+			clauses := make([]WhereConfig, len(cond.Clauses))
+			for i, clause := range cond.Clauses {
+				var err error
+				if clauses[i], err = compileWhereRegexes(clause); err != nil {
+					return nil, err
+				}
+			}
+			cond.Clauses = clauses
+		}
+		out[field] = cond
+	}
+	return out, nil
+}
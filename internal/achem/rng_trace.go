@@ -0,0 +1,91 @@
+package achem
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// RNG trace phases.
+const (
+	// RNGTraceGate marks the draw that decides whether a reaction fires on a molecule.
+	RNGTraceGate = "gate"
+	// RNGTraceApply marks draws made by a reaction's Apply via ReactionContext.Random.
+	RNGTraceApply = "apply"
+	// RNGTraceDecay marks the draw that decides whether a molecule decays. Its reaction
+	// ID is DecayReactionID, its effective rate the decay probability and Fired whether
+	// the molecule decayed.
+	RNGTraceDecay = "decay"
+)
+
+// RNGTraceEntry is a single line of the RNG trace, written as JSON.
+// EffectiveRate and Fired are only set for gate and decay draws.
+type RNGTraceEntry struct {
+	Tick          int64      `json:"tick"`
+	Phase         string     `json:"phase"`
+	ReactionID    string     `json:"reaction_id"`
+	MoleculeID    MoleculeID `json:"molecule_id"`
+	Draw          float64    `json:"draw"`
+	EffectiveRate *float64   `json:"effective_rate,omitempty"`
+	Fired         *bool      `json:"fired,omitempty"`
+}
+
+// rngTracer writes RNG draws as JSON lines. Writes are serialized so that
+// concurrent steps do not interleave partial lines.
+type rngTracer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newRNGTracer(w io.Writer) *rngTracer {
+	return &rngTracer{enc: json.NewEncoder(w)}
+}
+
+func (t *rngTracer) write(entry RNGTraceEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// tracing is best-effort: a failing writer must not break the simulation
+	_ = t.enc.Encode(entry)
+}
+
+// gate logs a gating draw for reaction r on molecule m.
+func (t *rngTracer) gate(tick int64, r Reaction, m Molecule, draw, rate float64, fired bool) {
+	t.write(RNGTraceEntry{
+		Tick:          tick,
+		Phase:         RNGTraceGate,
+		ReactionID:    r.ID(),
+		MoleculeID:    m.ID,
+		Draw:          draw,
+		EffectiveRate: &rate,
+		Fired:         &fired,
+	})
+}
+
+// decay logs the draw deciding whether m decays with the given probability.
+func (t *rngTracer) decay(tick int64, m Molecule, draw, probability float64, decayed bool) {
+	t.write(RNGTraceEntry{
+		Tick:          tick,
+		Phase:         RNGTraceDecay,
+		ReactionID:    DecayReactionID,
+		MoleculeID:    m.ID,
+		Draw:          draw,
+		EffectiveRate: &probability,
+		Fired:         &decayed,
+	})
+}
+
+// wrap returns a random function that logs every draw as an apply draw of
+// reaction r on molecule m before returning it.
+func (t *rngTracer) wrap(random func() float64, tick int64, r Reaction, m Molecule) func() float64 {
+	return func() float64 {
+		draw := random()
+		t.write(RNGTraceEntry{
+			Tick:       tick,
+			Phase:      RNGTraceApply,
+			ReactionID: r.ID(),
+			MoleculeID: m.ID,
+			Draw:       draw,
+		})
+		return draw
+	}
+}
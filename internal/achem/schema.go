@@ -7,6 +7,8 @@ type Schema struct {
 	Name      string
 	species   map[SpeciesName]Species
 	reactions []Reaction
+	decayRate float64
+	config    *SchemaConfig // set when built from a config, nil otherwise
 }
 
 // NewSchema creates a new schema with the given name.
@@ -35,6 +37,19 @@ func (s *Schema) WithReactions(reactions ...Reaction) *Schema {
 	return s
 }
 
+// WithDecayRate enables stability-based decay: each tick, every molecule is removed
+// with probability rate * (1 - Stability). Rates are clamped to [0, 1]; 0 (the
+// default) disables decay. Returns the schema for method chaining.
+func (s *Schema) WithDecayRate(rate float64) *Schema {
+	s.decayRate = min(max(rate, 0), 1)
+	return s
+}
+
+// DecayRate returns the decay rate of the schema (0 if decay is disabled).
+func (s *Schema) DecayRate() float64 {
+	return s.decayRate
+}
+
 // Species retrieves a species definition by name.
 // Returns the species and a boolean indicating if it was found.
 func (s *Schema) Species(name SpeciesName) (Species, bool) {
@@ -46,3 +61,43 @@ func (s *Schema) Species(name SpeciesName) (Species, bool) {
 func (s *Schema) Reactions() []Reaction {
 	return s.reactions
 }
+
+// Config returns the configuration the schema was built from with BuildSchemaFromConfig.
+// Returns false for schemas assembled programmatically, and for a nil schema. The
+// result must not be modified.
+func (s *Schema) Config() (SchemaConfig, bool) {
+	if s == nil || s.config == nil {
+		return SchemaConfig{}, false
+	}
+	return *s.config, true
+}
+
+// floatTolerance returns the numeric equality tolerance the schema's reactions use:
+// the configured float_tolerance, or DefaultFloatTolerance.
+func (s *Schema) floatTolerance() float64 {
+	if s == nil || s.config == nil || s.config.FloatTolerance == nil {
+		return DefaultFloatTolerance
+	}
+	return *s.config.FloatTolerance
+}
+
+// applySpeciesDefaults applies the defaults of m's species, if any, to m.
+func (s *Schema) applySpeciesDefaults(m *Molecule) {
+	if s == nil {
+		return
+	}
+	if sp, ok := s.species[m.Species]; ok {
+		sp.applyDefaults(m)
+	}
+}
+
+// cappedSpecies returns the species with a MaxCount, in no particular order.
+func (s *Schema) cappedSpecies() []Species {
+	var capped []Species
+	for _, sp := range s.species {
+		if sp.MaxCount > 0 {
+			capped = append(capped, sp)
+		}
+	}
+	return capped
+}
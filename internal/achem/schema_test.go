@@ -1,6 +1,7 @@
 package achem
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -186,3 +187,19 @@ func TestSchema_Reactions(t *testing.T) {
 		t.Errorf("Expected 1 reaction, got %d", len(reactions))
 	}
 }
+
+func TestSchema_Config(t *testing.T) {
+	if _, ok := NewSchema("test").Config(); ok {
+		t.Error("Expected no config for a schema built in code")
+	}
+
+	cfg := SchemaConfig{Name: "test", Species: []SpeciesConfig{{Name: "A"}}, DecayRate: 0.1}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	got, ok := schema.Config()
+	if !ok || !reflect.DeepEqual(got, cfg) {
+		t.Errorf("Expected the original config back, got %+v (ok=%v)", got, ok)
+	}
+}
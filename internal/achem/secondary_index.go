@@ -0,0 +1,166 @@
+package achem
+
+import "sort"
+
+// IndexField identifies a persistent secondary index: a payload field of a species
+type IndexField struct {
+	Species SpeciesName `json:"species"`
+	Field   string      `json:"field"`
+}
+
+// fieldIndex maps indexKeyFromValue(value) to the IDs of the molecules with that value
+type fieldIndex map[string]map[MoleculeID]struct{}
+
+// SetIndexField maintains a persistent index on the payload field of the molecules of
+// species, so that QueryMolecules serves equality filters on it without scanning the
+// environment. Unlike the per-tick index used by reactions, it is kept up to date
+// incrementally by inserts, deletes and steps, which costs a little on every write:
+// index only the fields that are queried often between ticks. Setting an existing
+// index again is a no-op.
+func (e *Environment) SetIndexField(species SpeciesName, field string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := IndexField{Species: species, Field: field}
+	if _, exists := e.indexes[key]; exists {
+		return
+	}
+	if e.indexes == nil {
+		e.indexes = make(map[IndexField]fieldIndex)
+	}
+	e.indexes[key] = nil
+	e.rebuildIndexesLocked()
+}
+
+// DropIndexField removes an index set with SetIndexField.
+func (e *Environment) DropIndexField(species SpeciesName, field string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.indexes, IndexField{Species: species, Field: field})
+}
+
+// IndexFields returns the indexes set with SetIndexField, sorted by species then field.
+func (e *Environment) IndexFields() []IndexField {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make([]IndexField, 0, len(e.indexes))
+	for key := range e.indexes {
+		out = append(out, key)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Species != out[j].Species {
+			return out[i].Species < out[j].Species
+		}
+		return out[i].Field < out[j].Field
+	})
+	return out
+}
+
+func (idx fieldIndex) add(m Molecule, field string) {
+	v, ok := m.Payload[field]
+	if !ok {
+		return
+	}
+	key := indexKeyFromValue(v)
+	ids := idx[key]
+	if ids == nil {
+		ids = make(map[MoleculeID]struct{})
+		idx[key] = ids
+	}
+	ids[m.ID] = struct{}{}
+}
+
+func (idx fieldIndex) remove(m Molecule, field string) {
+	v, ok := m.Payload[field]
+	if !ok {
+		return
+	}
+	key := indexKeyFromValue(v)
+	if ids := idx[key]; ids != nil {
+		delete(ids, m.ID)
+		if len(ids) == 0 {
+			delete(idx, key)
+		}
+	}
+}
+
+// putMoleculeLocked stores m, replacing the molecule with the same ID if any, and
+// keeps the secondary indexes in sync. The stored molecule gets a new version, greater
+// than any other in the environment. Must be called with e.mu held for writing.
+func (e *Environment) putMoleculeLocked(m Molecule) {
+	e.version++
+	m.Version = e.version
+	if len(e.indexes) > 0 {
+		if old, exists := e.mols[m.ID]; exists {
+			e.unindexLocked(old)
+		}
+		for key, idx := range e.indexes {
+			if key.Species == m.Species {
+				idx.add(m, key.Field)
+			}
+		}
+	}
+	e.mols[m.ID] = m
+}
+
+// deleteMoleculeLocked removes the molecule with the given ID, if present, and keeps
+// the secondary indexes in sync. Must be called with e.mu held for writing.
+func (e *Environment) deleteMoleculeLocked(id MoleculeID) {
+	if len(e.indexes) > 0 {
+		if old, exists := e.mols[id]; exists {
+			e.unindexLocked(old)
+		}
+	}
+	delete(e.mols, id)
+}
+
+func (e *Environment) unindexLocked(m Molecule) {
+	for key, idx := range e.indexes {
+		if key.Species == m.Species {
+			idx.remove(m, key.Field)
+		}
+	}
+}
+
+// rebuildIndexesLocked recomputes the secondary indexes from scratch, after e.mols was
+// replaced. Must be called with e.mu held for writing.
+func (e *Environment) rebuildIndexesLocked() {
+	for key := range e.indexes {
+		idx := make(fieldIndex)
+		for _, m := range e.mols {
+			if m.Species == key.Species {
+				idx.add(m, key.Field)
+			}
+		}
+		e.indexes[key] = idx
+	}
+}
+
+// indexedCandidatesLocked returns the molecules of species that may match where with
+// the tolerance tol, using a secondary index on one of its plain equality conditions.
+// Returns false if no index applies, in which case the caller must scan. The candidates
+// must still be checked against where, since index keys are shared by nearby values.
+// Must be called with e.mu held.
+func (e *Environment) indexedCandidatesLocked(species SpeciesName, where WhereConfig, tol float64) ([]Molecule, bool) {
+	if species == "" || len(e.indexes) == 0 {
+		return nil, false
+	}
+	for field, cond := range where {
+		idx, ok := e.indexes[IndexField{Species: species, Field: field}]
+		if !ok || !cond.isEq() {
+			continue
+		}
+		keys, ok := indexKeysForEq(cond.Eq, tol)
+		if !ok {
+			continue
+		}
+		var out []Molecule
+		for _, key := range keys {
+			for id := range idx[key] {
+				out = append(out, e.mols[id])
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
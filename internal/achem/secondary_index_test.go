@@ -0,0 +1,178 @@
+package achem
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// assertIndexesConsistent checks that the incrementally maintained indexes match
+// indexes rebuilt from scratch
+func assertIndexesConsistent(t *testing.T, env *Environment) {
+	t.Helper()
+	env.mu.Lock()
+	got := maps.Clone(env.indexes)
+	env.rebuildIndexesLocked()
+	want := env.indexes
+	env.mu.Unlock()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Indexes out of sync:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestEnvironment_SetIndexField(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "orders",
+		Species: []SpeciesConfig{{Name: "Order"}, {Name: "Invoice"}},
+		Reactions: []ReactionConfig{
+			{ID: "ship", Input: InputConfig{Species: "Order", Where: WhereConfig{"status": {Eq: "paid"}}}, Rate: 1,
+				Effects: []EffectConfig{{Update: &UpdateEffectConfig{PayloadSet: map[string]any{"status": "shipped"}}}}},
+			{ID: "void", Input: InputConfig{Species: "Order", Where: WhereConfig{"status": {Eq: "cancelled"}}}, Rate: 1,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Invoice", Payload: map[string]any{"status": "void"}}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	order := func(id, status string, amount int) Molecule {
+		return Molecule{ID: MoleculeID(id), Species: "Order", Payload: map[string]any{"status": status, "amount": amount}}
+	}
+	env.Insert(order("o1", "new", 10))
+	env.Insert(order("o2", "paid", 20))
+	env.SetIndexField("Order", "status") // indexes the molecules already present
+	env.SetIndexField("Order", "amount")
+	env.Insert(order("o3", "cancelled", 10))
+	o4 := order("o4", "new", 30)
+	o4.TTL = 2
+	env.Insert(o4)
+	env.Insert(Molecule{ID: "i1", Species: "Invoice", Payload: map[string]any{"status": "new"}})
+
+	query := func(species SpeciesName, field string, value any) []MoleculeID {
+		t.Helper()
+		out := []MoleculeID{}
+		for _, m := range env.QueryMolecules(species, WhereConfig{field: {Eq: value}}) {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	if got := query("Order", "status", "new"); !slices.Equal(got, []MoleculeID{"o1", "o4"}) {
+		t.Errorf("Expected [o1 o4] new orders, got %v", got)
+	}
+	// numbers are served by the index too, whatever their Go type
+	if got := query("Order", "amount", 10.0); !slices.Equal(got, []MoleculeID{"o1", "o3"}) {
+		t.Errorf("Expected [o1 o3] orders of 10, got %v", got)
+	}
+	env.mu.RLock()
+	_, indexed := env.indexedCandidatesLocked("Order", WhereConfig{"amount": {Eq: 10}}, DefaultFloatTolerance)
+	env.mu.RUnlock()
+	if !indexed {
+		t.Error("Expected numeric equality to use the index")
+	}
+	assertIndexesConsistent(t, env)
+
+	env.Step() // t=1: o2 is shipped, o3 is consumed
+	if got := query("Order", "status", "paid"); len(got) != 0 {
+		t.Errorf("Expected no paid orders after the update, got %v", got)
+	}
+	if got := query("Order", "status", "shipped"); !slices.Equal(got, []MoleculeID{"o2"}) {
+		t.Errorf("Expected [o2] shipped, got %v", got)
+	}
+	if got := query("Order", "status", "cancelled"); len(got) != 0 {
+		t.Errorf("Expected the consumed order to leave the index, got %v", got)
+	}
+	assertIndexesConsistent(t, env)
+
+	env.Step() // t=2: o4 expires
+	if got := query("Order", "status", "new"); !slices.Equal(got, []MoleculeID{"o1"}) {
+		t.Errorf("Expected [o1] after o4 expired, got %v", got)
+	}
+	env.DeleteMolecule("o1")
+	if got := query("Order", "status", "new"); len(got) != 0 {
+		t.Errorf("Expected no new orders after the delete, got %v", got)
+	}
+	assertIndexesConsistent(t, env)
+
+	clone := env.Clone()
+	want := []IndexField{{Species: "Order", Field: "amount"}, {Species: "Order", Field: "status"}}
+	if got := clone.IndexFields(); !slices.Equal(got, want) {
+		t.Errorf("Expected the clone to keep the indexes %v, got %v", want, got)
+	}
+	clone.Insert(order("o5", "shipped", 50))
+	if got := query("Order", "status", "shipped"); !slices.Equal(got, []MoleculeID{"o2"}) {
+		t.Errorf("Expected the clone's index to be independent, got %v", got)
+	}
+	assertIndexesConsistent(t, clone)
+
+	env.Reset()
+	if got := env.IndexFields(); !slices.Equal(got, want) {
+		t.Errorf("Expected Reset to keep the indexes %v, got %v", want, got)
+	}
+	if got := query("Order", "status", "shipped"); len(got) != 0 {
+		t.Errorf("Expected Reset to empty the index, got %v", got)
+	}
+
+	env.Insert(order("o6", "paid", 60))
+	env.DropIndexField("Order", "status")
+	if got := env.IndexFields(); !slices.Equal(got, want[:1]) {
+		t.Errorf("Expected %v after the drop, got %v", want[:1], got)
+	}
+	if got := query("Order", "status", "paid"); !slices.Equal(got, []MoleculeID{"o6"}) {
+		t.Errorf("Expected queries to scan after the drop, got %v", got)
+	}
+}
+
+func benchmarkQueryMolecules(b *testing.B, indexed bool) {
+	env := NewEnvironment(NewSchema("bench"))
+	if indexed {
+		env.SetIndexField("Order", "status")
+	}
+	for i := 0; i < 10_000; i++ {
+		env.Insert(Molecule{
+			ID:      MoleculeID(fmt.Sprintf("o%d", i)),
+			Species: "Order",
+			Payload: map[string]any{"status": fmt.Sprintf("s%d", i%100)},
+		})
+	}
+	where := WhereConfig{"status": {Eq: "s42"}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if got := env.QueryMolecules("Order", where); len(got) != 100 {
+			b.Fatalf("Expected 100 molecules, got %d", len(got))
+		}
+	}
+}
+
+func BenchmarkQueryMolecules_Scan(b *testing.B) {
+	benchmarkQueryMolecules(b, false)
+}
+
+func BenchmarkQueryMolecules_Indexed(b *testing.B) {
+	benchmarkQueryMolecules(b, true)
+}
+
+// BenchmarkInsert_Indexed measures the maintenance cost of an index on inserts
+func BenchmarkInsert_Indexed(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		b.Run(fmt.Sprintf("indexed=%v", indexed), func(b *testing.B) {
+			env := NewEnvironment(NewSchema("bench"))
+			if indexed {
+				env.SetIndexField("Order", "status")
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				env.Insert(Molecule{
+					ID:      MoleculeID(fmt.Sprintf("o%d", i)),
+					Species: "Order",
+					Payload: map[string]any{"status": fmt.Sprintf("s%d", i%1000)},
+				})
+			}
+		})
+	}
+}
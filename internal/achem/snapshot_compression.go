@@ -0,0 +1,61 @@
+package achem
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// snapshotGzipExt is appended to snapshot file names when compression is enabled.
+const snapshotGzipExt = ".gz"
+
+// snapshotExtLocked returns the extension added to snapshot file names.
+// Must be called with e.mu held.
+func (e *Environment) snapshotExtLocked() string {
+	if e.snapshotCompression {
+		return snapshotGzipExt
+	}
+	return ""
+}
+
+// writeSnapshotFile writes data to path, compressing it with gzip if requested.
+func writeSnapshotFile(path string, data []byte, compress bool) error {
+	if !compress {
+		return os.WriteFile(path, data, 0644)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadSnapshotFile reads a snapshot file and returns its JSON content. Gzip-compressed
+// files are detected from their header and decompressed, whatever their name.
+func ReadSnapshotFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
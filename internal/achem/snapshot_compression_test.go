@@ -0,0 +1,108 @@
+package achem
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironment_SnapshotCompression_RoundTrip(t *testing.T) {
+	schema := NewSchema("test").WithSpecies(Species{Name: "A"})
+	tmpDir := t.TempDir()
+
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(tmpDir)
+	env.SetSnapshotCompression(true)
+	env.Insert(NewMolecule("A", map[string]any{"k": "v"}, 0))
+	env.mu.Lock()
+	env.time = 9
+	env.mu.Unlock()
+
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	path := filepath.Join(tmpDir, "test-env.snapshot.json.gz")
+	if got := env.SnapshotPath(); got != path {
+		t.Errorf("Expected snapshot path %s, got %s", path, got)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected temp file to be removed")
+	}
+
+	// the file is plain gzip wrapping the usual JSON encoding
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Snapshot is not gzip-compressed: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress snapshot: %v", err)
+	}
+	snapshot, err := DecodeSnapshotJSON(data)
+	if err != nil {
+		t.Fatalf("DecodeSnapshotJSON failed: %v", err)
+	}
+	if snapshot.Time != 9 || len(snapshot.Molecules) != 1 || snapshot.Molecules[0].Payload["k"] != "v" {
+		t.Errorf("Unexpected decompressed snapshot: %+v", snapshot)
+	}
+
+	// LoadSnapshot detects compression, whatever the environment's setting
+	restored := NewEnvironment(schema)
+	restored.SetEnvironmentID("test-env")
+	restored.SetSnapshotDir(tmpDir)
+	if err := restored.LoadSnapshot(); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.time != 9 || len(restored.AllMolecules()) != 1 {
+		t.Errorf("Expected time 9 and 1 molecule, got time %d and %d molecules", restored.time, len(restored.AllMolecules()))
+	}
+}
+
+func TestEnvironment_SnapshotCompression_WithRetention(t *testing.T) {
+	schema := NewSchema("test")
+	tmpDir := t.TempDir()
+
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(tmpDir)
+	env.SetSnapshotRetention(1)
+
+	// a plain snapshot followed by a compressed one: the plain one is pruned
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	env.SetSnapshotCompression(true)
+	env.mu.Lock()
+	env.time = 3
+	env.mu.Unlock()
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "test-env.history"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "3.snapshot.json.gz" {
+		t.Errorf("Expected only 3.snapshot.json.gz, got %v", entries)
+	}
+
+	restored := NewEnvironment(schema)
+	restored.SetEnvironmentID("test-env")
+	restored.SetSnapshotDir(tmpDir)
+	if err := restored.LoadSnapshot(); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.time != 3 {
+		t.Errorf("Expected time 3, got %d", restored.time)
+	}
+}
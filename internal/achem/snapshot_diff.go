@@ -0,0 +1,134 @@
+package achem
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// FieldChange is a field of a molecule that differs between two states. Field is the
+// name of the Molecule field, or "Payload.<key>" for a payload field; From or To is
+// nil when the payload field is unset on that side.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// MoleculeDiff lists the changed fields of a molecule present in both states
+type MoleculeDiff struct {
+	ID      MoleculeID    `json:"id"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// SnapshotDiff is the difference between two sets of molecules, each list sorted by
+// molecule ID
+type SnapshotDiff struct {
+	Added   []Molecule     `json:"added"`
+	Removed []Molecule     `json:"removed"`
+	Changed []MoleculeDiff `json:"changed"`
+}
+
+// DiffMolecules compares two sets of molecules by ID: molecules only in to are added,
+// molecules only in from are removed, and molecules in both with different fields are
+// changed. It doesn't depend on how the molecules were stored, so it works on snapshots,
+// bundles or query results alike.
+func DiffMolecules(from, to []Molecule) SnapshotDiff {
+	diff := SnapshotDiff{Added: []Molecule{}, Removed: []Molecule{}, Changed: []MoleculeDiff{}}
+
+	before := make(map[MoleculeID]Molecule, len(from))
+	for _, m := range from {
+		before[m.ID] = m
+	}
+	after := make(map[MoleculeID]Molecule, len(to))
+	for _, m := range to {
+		after[m.ID] = m
+		old, ok := before[m.ID]
+		if !ok {
+			diff.Added = append(diff.Added, m)
+			continue
+		}
+		if changes := diffMolecule(old, m); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, MoleculeDiff{ID: m.ID, Changes: changes})
+		}
+	}
+	for _, m := range from {
+		if _, ok := after[m.ID]; !ok {
+			diff.Removed = append(diff.Removed, m)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].ID < diff.Added[j].ID })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].ID < diff.Removed[j].ID })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].ID < diff.Changed[j].ID })
+	return diff
+}
+
+// diffMolecule returns the fields that differ between two states of a molecule, the
+// payload fields sorted by key
+func diffMolecule(a, b Molecule) []FieldChange {
+	var changes []FieldChange
+	field := func(name string, from, to any) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, FieldChange{Field: name, From: from, To: to})
+		}
+	}
+
+	field("Species", a.Species, b.Species)
+	for _, k := range payloadKeys(a.Payload, b.Payload) {
+		field("Payload."+k, a.Payload[k], b.Payload[k])
+	}
+	field("Energy", a.Energy, b.Energy)
+	field("Stability", a.Stability, b.Stability)
+	if len(a.Tags) > 0 || len(b.Tags) > 0 {
+		field("Tags", a.Tags, b.Tags)
+	}
+	field("CreatedAt", a.CreatedAt, b.CreatedAt)
+	field("LastTouchedAt", a.LastTouchedAt, b.LastTouchedAt)
+	field("TTL", a.TTL, b.TTL)
+	field("Position", a.Position, b.Position)
+	return changes
+}
+
+// payloadKeys returns the keys set in either payload, sorted
+func payloadKeys(a, b map[string]any) []string {
+	keys := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ReadSnapshotAt reads the timestamped snapshot the environment saved at time t in
+// retention mode (see SetSnapshotRetention). The returned error wraps fs.ErrNotExist
+// when there is no such snapshot, and it is an error if the file holds another
+// environment's snapshot.
+func (e *Environment) ReadSnapshotAt(t int64) (Snapshot, error) {
+	for _, f := range e.timestampedSnapshots() {
+		if f.time != t {
+			continue
+		}
+		data, err := ReadSnapshotFile(f.path)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("failed to read snapshot file: %w", err)
+		}
+		snapshot, err := DecodeSnapshotJSON(data)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		e.mu.RLock()
+		envID := e.envID
+		e.mu.RUnlock()
+		if snapshot.EnvironmentID != envID {
+			return Snapshot{}, fmt.Errorf("snapshot environment ID mismatch: expected %s, got %s", envID, snapshot.EnvironmentID)
+		}
+		return snapshot, nil
+	}
+	return Snapshot{}, fmt.Errorf("no snapshot at time %d: %w", t, fs.ErrNotExist)
+}
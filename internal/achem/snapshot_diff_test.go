@@ -0,0 +1,104 @@
+package achem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffMolecules(t *testing.T) {
+	from := []Molecule{
+		{ID: "b", Species: "Event", Payload: map[string]any{"n": 1.0, "gone": "x"}, Energy: 1},
+		{ID: "a", Species: "Event", Payload: map[string]any{"n": 1.0}, Energy: 1, Tags: []string{}},
+		{ID: "c", Species: "Event"},
+	}
+	to := []Molecule{
+		{ID: "a", Species: "Event", Payload: map[string]any{"n": 1.0}, Energy: 1}, // unchanged
+		{ID: "b", Species: "Alert", Payload: map[string]any{"n": 2.0, "new": true}, Energy: 0.5},
+		{ID: "e", Species: "Event"},
+		{ID: "d", Species: "Event"},
+	}
+
+	diff := DiffMolecules(from, to)
+	if len(diff.Added) != 2 || diff.Added[0].ID != "d" || diff.Added[1].ID != "e" {
+		t.Errorf("Expected d and e to be added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != "c" {
+		t.Errorf("Expected c to be removed, got %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].ID != "b" {
+		t.Fatalf("Expected only b to be changed, got %+v", diff.Changed)
+	}
+
+	want := []FieldChange{
+		{Field: "Species", From: SpeciesName("Event"), To: SpeciesName("Alert")},
+		{Field: "Payload.gone", From: "x", To: nil},
+		{Field: "Payload.n", From: 1.0, To: 2.0},
+		{Field: "Payload.new", From: nil, To: true},
+		{Field: "Energy", From: 1.0, To: 0.5},
+	}
+	got := diff.Changed[0].Changes
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d field changes, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if diff := DiffMolecules(nil, nil); diff.Added == nil || diff.Removed == nil || diff.Changed == nil {
+		t.Errorf("Expected empty (non-nil) lists, got %+v", diff)
+	}
+}
+
+func TestEnvironment_ReadSnapshotAt(t *testing.T) {
+	env := NewEnvironment(NewSchema("test").WithSpecies(Species{Name: "A"}))
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(t.TempDir())
+	env.SetSnapshotRetention(3)
+
+	env.Insert(Molecule{ID: "a1", Species: "A"})
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	env.StepN(5)
+	env.SetSnapshotCompression(true) // both formats are read
+	env.Insert(Molecule{ID: "a2", Species: "A"})
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	first, err := env.ReadSnapshotAt(0)
+	if err != nil || len(first.Molecules) != 1 {
+		t.Fatalf("Expected the snapshot at time 0 with 1 molecule, got %+v (%v)", first, err)
+	}
+	second, err := env.ReadSnapshotAt(5)
+	if err != nil || second.Time != 5 || len(second.Molecules) != 2 {
+		t.Fatalf("Expected the snapshot at time 5 with 2 molecules, got %+v (%v)", second, err)
+	}
+	if diff := DiffMolecules(first.Molecules, second.Molecules); len(diff.Added) != 1 || diff.Added[0].ID != "a2" {
+		t.Errorf("Expected a2 to be added, got %+v", diff)
+	}
+
+	if _, err := env.ReadSnapshotAt(3); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a not-exist error for a missing snapshot, got %v", err)
+	}
+
+	// a file holding another environment's snapshot is rejected
+	data, err := EncodeSnapshotJSON(Snapshot{EnvironmentID: "other", Time: 9})
+	if err != nil {
+		t.Fatalf("EncodeSnapshotJSON failed: %v", err)
+	}
+	env.mu.RLock()
+	path := filepath.Join(env.snapshotHistoryDirLocked(), "9.snapshot.json")
+	env.mu.RUnlock()
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := env.ReadSnapshotAt(9); err == nil {
+		t.Error("Expected an error for another environment's snapshot")
+	}
+}
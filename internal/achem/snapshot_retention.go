@@ -0,0 +1,151 @@
+package achem
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// snapshotFileSuffix is the suffix shared by single and timestamped snapshot files.
+const snapshotFileSuffix = ".snapshot.json"
+
+// snapshotHistorySuffix is the suffix of the directory holding an environment's
+// timestamped snapshots. File and directory names are decoded by stripping exactly one
+// fixed suffix, so environment IDs containing dots or digits can't be confused.
+const snapshotHistorySuffix = ".history"
+
+// timestampedSnapshot is a snapshot file written in retention mode.
+type timestampedSnapshot struct {
+	path string
+	time int64
+}
+
+// snapshotHistoryDirLocked returns the directory of the environment's timestamped
+// snapshots: "<SnapshotDir>/<envID>.history". Must be called with e.mu held.
+func (e *Environment) snapshotHistoryDirLocked() string {
+	return filepath.Join(e.snapshotDir, string(e.envID)+snapshotHistorySuffix)
+}
+
+// timestampedSnapshotPath returns the path of the snapshot taken at the given time.
+// Format: "<SnapshotDir>/<envID>.history/<time>.snapshot.json"
+func (e *Environment) timestampedSnapshotPath(t int64) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return filepath.Join(e.snapshotHistoryDirLocked(), strconv.FormatInt(t, 10)+snapshotFileSuffix+e.snapshotExtLocked())
+}
+
+// timestampedSnapshots lists the environment's timestamped snapshot files, newest first.
+// A missing or unreadable directory yields no files.
+func (e *Environment) timestampedSnapshots() []timestampedSnapshot {
+	e.mu.RLock()
+	dir := e.snapshotHistoryDirLocked()
+	e.mu.RUnlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []timestampedSnapshot
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), snapshotGzipExt)
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotFileSuffix) {
+			continue
+		}
+		t, err := strconv.ParseInt(strings.TrimSuffix(name, snapshotFileSuffix), 10, 64)
+		if err != nil {
+			continue // e.g. a temporary file left by an interrupted write
+		}
+		files = append(files, timestampedSnapshot{path: filepath.Join(dir, entry.Name()), time: t})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].time > files[j].time
+	})
+	return files
+}
+
+// LatestSnapshotPath returns the path of the most recent timestamped snapshot, or
+// SnapshotPath if there is none. If only the single file in the other format (plain
+// or compressed) exists, that one is returned. The file is not guaranteed to exist.
+func (e *Environment) LatestSnapshotPath() string {
+	if files := e.timestampedSnapshots(); len(files) > 0 {
+		return files[0].path
+	}
+	path := e.SnapshotPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		other := strings.TrimSuffix(path, snapshotGzipExt)
+		if other == path {
+			other = path + snapshotGzipExt
+		}
+		if _, err := os.Stat(other); err == nil {
+			return other
+		}
+	}
+	return path
+}
+
+// pruneSnapshots deletes timestamped snapshots beyond the keep most recent ones.
+// Failures are logged: a leftover file doesn't invalidate the snapshot just written.
+func (e *Environment) pruneSnapshots(keep int) {
+	files := e.timestampedSnapshots()
+	if len(files) <= keep {
+		return
+	}
+	for _, f := range files[keep:] {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			e.logger.Warnf("snapshot retention: failed to remove old snapshot: path=%s error=%v", f.path, err)
+		}
+	}
+}
+
+// clearSnapshotHistory deletes the environment's timestamped snapshots. Their times
+// would no longer be comparable with the ones written after a Reset.
+func (e *Environment) clearSnapshotHistory() {
+	e.mu.RLock()
+	dir, enabled := e.snapshotHistoryDirLocked(), e.snapshotDir != ""
+	e.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		e.logger.Warnf("snapshot retention: failed to clear snapshot history: path=%s error=%v", dir, err)
+	}
+}
+
+// SnapshotEnvironmentIDs returns the IDs of the environments that have snapshots in dir,
+// in single-file or retention mode, compressed or not, sorted.
+func SnapshotEnvironmentIDs(dir string) ([]EnvironmentID, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[EnvironmentID]struct{})
+	for _, entry := range entries {
+		var id string
+		if entry.IsDir() {
+			id = strings.TrimSuffix(entry.Name(), snapshotHistorySuffix)
+			if id == entry.Name() {
+				continue
+			}
+		} else {
+			name := strings.TrimSuffix(entry.Name(), snapshotGzipExt)
+			if !strings.HasSuffix(name, snapshotFileSuffix) {
+				continue
+			}
+			id = strings.TrimSuffix(name, snapshotFileSuffix)
+		}
+		if id != "" {
+			seen[EnvironmentID(id)] = struct{}{}
+		}
+	}
+
+	ids := slices.Collect(maps.Keys(seen))
+	slices.Sort(ids)
+	return ids, nil
+}
@@ -0,0 +1,189 @@
+package achem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironment_SnapshotRetention_KeepsMostRecent(t *testing.T) {
+	schema := NewSchema("test")
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("test-env")
+
+	tmpDir := t.TempDir()
+	env.SetSnapshotDir(tmpDir)
+	env.SetSnapshotRetention(2)
+
+	for _, tm := range []int64{5, 10, 100} {
+		env.mu.Lock()
+		env.time = tm
+		env.mu.Unlock()
+		if err := env.SaveSnapshot(); err != nil {
+			t.Fatalf("SaveSnapshot at time %d failed: %v", tm, err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(tmpDir, "test-env.history"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 2 || names[0] != "10.snapshot.json" || names[1] != "100.snapshot.json" {
+		t.Errorf("Expected the two most recent snapshots, got %v", names)
+	}
+
+	if got, want := env.LatestSnapshotPath(), filepath.Join(tmpDir, "test-env.history", "100.snapshot.json"); got != want {
+		t.Errorf("Expected latest snapshot path %s, got %s", want, got)
+	}
+}
+
+func TestEnvironment_SnapshotRetention_LoadsLatest(t *testing.T) {
+	schema := NewSchema("test").WithSpecies(Species{Name: "A"})
+	tmpDir := t.TempDir()
+
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(tmpDir)
+
+	// legacy file, older than the timestamped ones
+	env.Insert(NewMolecule("A", nil, 0))
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	env.SetSnapshotRetention(3)
+	for _, tm := range []int64{7, 42} {
+		env.Insert(NewMolecule("A", nil, tm))
+		env.mu.Lock()
+		env.time = tm
+		env.mu.Unlock()
+		if err := env.SaveSnapshot(); err != nil {
+			t.Fatalf("SaveSnapshot at time %d failed: %v", tm, err)
+		}
+	}
+
+	restored := NewEnvironment(schema)
+	restored.SetEnvironmentID("test-env")
+	restored.SetSnapshotDir(tmpDir)
+	if err := restored.LoadSnapshot(); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.time != 42 {
+		t.Errorf("Expected time 42 from the latest snapshot, got %d", restored.time)
+	}
+	if n := len(restored.AllMolecules()); n != 3 {
+		t.Errorf("Expected 3 molecules, got %d", n)
+	}
+}
+
+func TestEnvironment_SnapshotRetention_FallsBackToLegacyFile(t *testing.T) {
+	schema := NewSchema("test").WithSpecies(Species{Name: "A"})
+	tmpDir := t.TempDir()
+
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(tmpDir)
+	env.Insert(NewMolecule("A", nil, 0))
+	env.mu.Lock()
+	env.time = 12
+	env.mu.Unlock()
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	// another environment's timestamped snapshots must be ignored
+	other := NewEnvironment(schema)
+	other.SetEnvironmentID("test-env-2")
+	other.SetSnapshotDir(tmpDir)
+	other.SetSnapshotRetention(1)
+	if err := other.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	restored := NewEnvironment(schema)
+	restored.SetEnvironmentID("test-env")
+	restored.SetSnapshotDir(tmpDir)
+	restored.SetSnapshotRetention(1)
+	if err := restored.LoadSnapshot(); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.time != 12 {
+		t.Errorf("Expected time 12 from the legacy snapshot, got %d", restored.time)
+	}
+}
+
+func TestEnvironment_SnapshotRetention_IDsWithDots(t *testing.T) {
+	schema := NewSchema("test").WithSpecies(Species{Name: "A"})
+	tmpDir := t.TempDir()
+
+	// "a.5" looks like a snapshot of "a" at time 5 in a "<envID>.<time>" naming
+	save := func(id EnvironmentID, tm int64) {
+		env := NewEnvironment(schema)
+		env.SetEnvironmentID(id)
+		env.SetSnapshotDir(tmpDir)
+		env.SetSnapshotRetention(1)
+		env.mu.Lock()
+		env.time = tm
+		env.mu.Unlock()
+		if err := env.SaveSnapshot(); err != nil {
+			t.Fatalf("SaveSnapshot of %s failed: %v", id, err)
+		}
+	}
+	save("a", 3)
+	save("a.5", 100)
+	save("v1.2", 7)
+
+	env := NewEnvironment(schema)
+	env.SetEnvironmentID("a")
+	env.SetSnapshotDir(tmpDir)
+	if files := env.timestampedSnapshots(); len(files) != 1 || files[0].time != 3 {
+		t.Errorf("Expected only the snapshot of a, got %+v", files)
+	}
+	if err := env.LoadSnapshot(); err != nil || env.time != 3 {
+		t.Errorf("Expected the snapshot of a at time 3, got time %d (err=%v)", env.time, err)
+	}
+
+	ids, err := SnapshotEnvironmentIDs(tmpDir)
+	if err != nil {
+		t.Fatalf("SnapshotEnvironmentIDs failed: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[1] != "a.5" || ids[2] != "v1.2" {
+		t.Errorf("Expected a, a.5 and v1.2, got %v", ids)
+	}
+}
+
+func TestEnvironment_SnapshotRetention_ResetClearsHistory(t *testing.T) {
+	env := NewEnvironment(NewSchema("test"))
+	env.SetEnvironmentID("test-env")
+	env.SetSnapshotDir(t.TempDir())
+	env.SetSnapshotRetention(2)
+
+	env.mu.Lock()
+	env.time = 50
+	env.mu.Unlock()
+	if err := env.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	env.Reset()
+	if files := env.timestampedSnapshots(); len(files) != 0 {
+		t.Fatalf("Expected the history to be cleared, got %+v", files)
+	}
+
+	// the new run's snapshots are the latest ones and are kept by retention
+	for _, tm := range []int64{1, 2} {
+		env.mu.Lock()
+		env.time = tm
+		env.mu.Unlock()
+		if err := env.SaveSnapshot(); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+	}
+	if files := env.timestampedSnapshots(); len(files) != 2 || files[0].time != 2 {
+		t.Errorf("Expected the snapshots at 2 and 1, got %+v", files)
+	}
+}
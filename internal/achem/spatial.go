@@ -0,0 +1,45 @@
+package achem
+
+import "math"
+
+// Position locates a molecule in a 2D space, for reactions that only consider nearby
+// molecules (see PartnerConfig.WithinDistance). Molecules without a position live in
+// the global soup, where every molecule can react with every other.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// WithPosition places the molecule at (x, y).
+func WithPosition(x, y float64) MoleculeOption {
+	return func(m *Molecule) {
+		m.Position = &Position{X: x, Y: y}
+	}
+}
+
+// Distance returns the Euclidean distance between p and q.
+func (p Position) Distance(q Position) float64 {
+	return math.Hypot(p.X-q.X, p.Y-q.Y)
+}
+
+// withinDistance keeps the candidates at most radius away from m. A radius of 0 keeps
+// all of them. Molecules without a position are infinitely far from every other one,
+// unless includeUnpositioned is set, in which case they are always kept.
+func withinDistance(candidates []Molecule, m Molecule, radius float64, includeUnpositioned bool) []Molecule {
+	if radius <= 0 {
+		return candidates
+	}
+	out := candidates[:0:0]
+	for _, c := range candidates {
+		if m.Position == nil || c.Position == nil {
+			if includeUnpositioned {
+				out = append(out, c)
+			}
+			continue
+		}
+		if m.Position.Distance(*c.Position) <= radius {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package achem
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWithinDistance(t *testing.T) {
+	origin := NewMolecule("A", nil, 0, WithPosition(0, 0))
+	near := NewMolecule("B", nil, 0, WithPosition(3, 4))
+	far := NewMolecule("B", nil, 0, WithPosition(3, 4.1))
+	nowhere := NewMolecule("B", nil, 0)
+	candidates := []Molecule{near, far, nowhere}
+
+	ids := func(mols []Molecule) []MoleculeID {
+		out := []MoleculeID{}
+		for _, m := range mols {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	if got := withinDistance(candidates, origin, 0, false); len(got) != 3 {
+		t.Errorf("Expected no filtering without a radius, got %v", ids(got))
+	}
+	if got := ids(withinDistance(candidates, origin, 5, false)); !slices.Equal(got, []MoleculeID{near.ID}) {
+		t.Errorf("Expected only the molecule at distance 5, got %v", got)
+	}
+	if got := ids(withinDistance(candidates, origin, 5, true)); !slices.Equal(got, []MoleculeID{near.ID, nowhere.ID}) {
+		t.Errorf("Expected the unpositioned molecule to be included, got %v", got)
+	}
+	if got := withinDistance(candidates, nowhere, 5, false); len(got) != 0 {
+		t.Errorf("Expected an unpositioned input to be far from everything, got %v", ids(got))
+	}
+}
+
+func TestConfigReaction_PartnersWithinDistance(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "spatial",
+		Species: []SpeciesConfig{{Name: "Fuel"}, {Name: "Oxygen"}, {Name: "Ash"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "burn",
+				Input:   InputConfig{Species: "Fuel", Partners: []PartnerConfig{{Species: "Oxygen", WithinDistance: 2}}},
+				Rate:    1,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Ash"}}},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	for _, matchCache := range []bool{false, true} {
+		env := NewEnvironment(schema)
+		env.SetMatchCache(matchCache)
+		// only the fuel at (0, 0) has oxygen nearby
+		env.Insert(Molecule{ID: "burns", Species: "Fuel", Position: &Position{X: 0, Y: 0}})
+		env.Insert(Molecule{ID: "far", Species: "Fuel", Position: &Position{X: 10, Y: 0}})
+		env.Insert(Molecule{ID: "nowhere", Species: "Fuel"})
+		env.Insert(Molecule{ID: "o1", Species: "Oxygen", Position: &Position{X: 0, Y: 1}})
+		env.Insert(Molecule{ID: "o2", Species: "Oxygen"})
+		env.Step()
+
+		if _, ok := env.GetMolecule("burns"); ok {
+			t.Errorf("matchCache=%v: expected the fuel with oxygen nearby to burn", matchCache)
+		}
+		for _, id := range []MoleculeID{"far", "nowhere"} {
+			if _, ok := env.GetMolecule(id); !ok {
+				t.Errorf("matchCache=%v: expected %s not to burn", matchCache, id)
+			}
+		}
+		ash := env.QueryMolecules("Ash", nil)
+		if len(ash) != 1 || ash[0].Position == nil || *ash[0].Position != (Position{X: 0, Y: 0}) {
+			t.Errorf("matchCache=%v: expected one Ash at the position of the fuel, got %+v", matchCache, ash)
+		}
+	}
+}
+
+func TestConfigReaction_CatalystsWithinDistance(t *testing.T) {
+	reaction := &ConfigReaction{cfg: ReactionConfig{
+		ID:        "spark",
+		Input:     InputConfig{Species: "Fuel"},
+		Rate:      0.5,
+		Catalysts: []CatalystConfig{{Species: "Spark", RateBoost: 0.3, WithinDistance: 2}},
+	}}
+	fuel := NewMolecule("Fuel", nil, 0, WithPosition(0, 0))
+	near := NewMolecule("Spark", nil, 0, WithPosition(1, 1))
+	far := NewMolecule("Spark", nil, 0, WithPosition(5, 5))
+	nowhere := NewMolecule("Spark", nil, 0)
+
+	if rate := reaction.EffectiveRate(fuel, testEnvView{molecules: []Molecule{fuel, far, nowhere}}); rate != 0.5 {
+		t.Errorf("Expected rate 0.5 without a spark nearby, got %v", rate)
+	}
+	if rate := reaction.EffectiveRate(fuel, testEnvView{molecules: []Molecule{fuel, near, far}}); rate != 0.8 {
+		t.Errorf("Expected rate 0.8 with a spark nearby, got %v", rate)
+	}
+
+	reaction.cfg.Catalysts[0].IncludeUnpositioned = true
+	if rate := reaction.EffectiveRate(fuel, testEnvView{molecules: []Molecule{fuel, far, nowhere}}); rate != 0.8 {
+		t.Errorf("Expected the unpositioned spark to catalyze, got %v", rate)
+	}
+
+	// molecules that only differ by their position don't share cached rates
+	cache := newMatchCache([]Reaction{reaction})
+	view := testEnvView{molecules: []Molecule{near}}
+	for _, m := range []Molecule{fuel, NewMolecule("Fuel", nil, 0, WithPosition(9, 9))} {
+		sig, sigOK := moleculeSignature(m)
+		if got, want := cache.effectiveRate(0, reaction, m, view, sig, sigOK), reaction.EffectiveRate(m, view); got != want {
+			t.Errorf("Cached rate %v at %+v, expected %v", got, *m.Position, want)
+		}
+	}
+}
@@ -5,8 +5,32 @@ type SpeciesName string
 
 // Species represents a type of molecule in the artificial chemistry system.
 // Each species has a name, description, and optional metadata.
+// A positive MaxCount turns the species into a sink: at the end of every step the
+// excess molecules are evicted according to EvictOrder (EvictOldest by default).
+// DefaultEnergy and DefaultStability, when set, replace the generic defaults of
+// NewMolecule for molecules created by create effects that don't set their own values,
+// and for molecules inserted with WithSpeciesDefaults. Fields, when set,
+// constrains the payload of the molecules of the species (see Schema.ValidateMolecule).
 type Species struct {
-	Name        SpeciesName
-	Description string
-	Meta        map[string]any
+	Name             SpeciesName
+	Description      string
+	Meta             map[string]any
+	MaxCount         int
+	EvictOrder       string
+	EvictNotifiers   []string // notifiers triggered when molecules are evicted
+	ExpireNotifiers  []string // notifiers triggered when molecules expire (see Molecule.TTL)
+	DefaultEnergy    *float64
+	DefaultStability *float64
+	Fields           map[string]FieldSpec
+}
+
+// applyDefaults sets the species' default energy and stability, where declared, on m.
+// Callers only use it for values that were not given explicitly.
+func (sp Species) applyDefaults(m *Molecule) {
+	if sp.DefaultEnergy != nil {
+		m.Energy = *sp.DefaultEnergy
+	}
+	if sp.DefaultStability != nil {
+		m.Stability = *sp.DefaultStability
+	}
 }
@@ -1,6 +1,7 @@
 package achem
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -94,6 +95,73 @@ func TestSpecies(t *testing.T) {
 	}
 }
 
+func TestSpeciesDefaults(t *testing.T) {
+	energy, stability := 10.0, 0.25
+	cfg := SchemaConfig{
+		Name:    "sessions",
+		Species: []SpeciesConfig{{Name: "Session", DefaultEnergy: &energy, DefaultStability: &stability}, {Name: "Login"}, {Name: "Plain"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "open",
+				Input:   InputConfig{Species: "Login"},
+				Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Session"}}},
+			},
+			{
+				ID:      "open_weak",
+				Input:   InputConfig{Species: "Login"},
+				Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Session", Energy: &stability}}},
+			},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	// molecules inserted with WithSpeciesDefaults take the species defaults
+	inserted := NewMolecule("Session", nil, 0)
+	env.Insert(inserted, WithSpeciesDefaults())
+	if m, _ := env.GetMolecule(inserted.ID); m.Energy != 10 || m.Stability != 0.25 {
+		t.Errorf("Expected energy 10 and stability 0.25 on insert, got %v and %v", m.Energy, m.Stability)
+	}
+	ids, _ := env.InsertBatch([]Molecule{NewMolecule("Session", nil, 0), NewMolecule("Plain", nil, 0)}, WithSpeciesDefaults())
+	if m, _ := env.GetMolecule(ids[0]); m.Energy != 10 || m.Stability != 0.25 {
+		t.Errorf("Expected energy 10 and stability 0.25 on batch insert, got %v and %v", m.Energy, m.Stability)
+	}
+	if m, _ := env.GetMolecule(ids[1]); m.Energy != DefaultMoleculeEnergy || m.Stability != DefaultMoleculeStability {
+		t.Errorf("Expected generic defaults without species defaults, got %v and %v", m.Energy, m.Stability)
+	}
+
+	// without the option, values are kept as given, even when they equal the generic defaults
+	explicit := NewMolecule("Session", nil, 0)
+	env.Insert(explicit)
+	if m, _ := env.GetMolecule(explicit.ID); m.Energy != DefaultMoleculeEnergy || m.Stability != DefaultMoleculeStability {
+		t.Errorf("Expected the explicit energy and stability 1.0 to be kept, got %v and %v", m.Energy, m.Stability)
+	}
+	upserted, _, _ := env.Upsert(NewMolecule("Session", map[string]any{"user": "u1"}, 0), []string{"user"}, WithSpeciesDefaults())
+	if upserted.Energy != 10 || upserted.Stability != 0.25 {
+		t.Errorf("Expected energy 10 and stability 0.25 on upsert, got %v and %v", upserted.Energy, upserted.Stability)
+	}
+
+	// create effects apply the defaults unless they set their own values
+	env.Insert(NewMolecule("Login", nil, 0))
+	env.Step()
+	var energies []float64
+	for _, m := range env.AllMolecules() {
+		if m.Species == "Session" && m.ID != inserted.ID && m.ID != ids[0] && m.ID != explicit.ID && m.ID != upserted.ID {
+			energies = append(energies, m.Energy)
+			if m.Stability != 0.25 {
+				t.Errorf("Expected created sessions to have stability 0.25, got %v", m.Stability)
+			}
+		}
+	}
+	slices.Sort(energies)
+	if !slices.Equal(energies, []float64{0.25, 10}) {
+		t.Errorf("Expected created sessions with energies 0.25 and 10, got %v", energies)
+	}
+}
+
 func TestSpecies_Equality(t *testing.T) {
 	species1 := Species{
 		Name:        "TestSpecies",
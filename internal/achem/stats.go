@@ -0,0 +1,251 @@
+package achem
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// DefaultFieldStatsMaxSamples bounds how many values FieldStats keeps to compute
+// percentiles when no explicit limit is given.
+const DefaultFieldStatsMaxSamples = 100_000
+
+// FieldStats describes the distribution of a numeric payload field across a species.
+// Min, Max and Mean are computed over all numeric values; the percentiles are
+// computed over a uniform sample when Sampled is true.
+type FieldStats struct {
+	Species   SpeciesName `json:"species"`
+	Field     string      `json:"field"`
+	Molecules int         `json:"molecules"` // molecules of the species
+	Count     int         `json:"count"`     // numeric values included
+	Skipped   int         `json:"skipped"`   // molecules with a missing or non-numeric field
+	Sampled   bool        `json:"sampled"`
+	Min       float64     `json:"min"`
+	Max       float64     `json:"max"`
+	Mean      float64     `json:"mean"`
+	P50       float64     `json:"p50"`
+	P90       float64     `json:"p90"`
+	P95       float64     `json:"p95"`
+	P99       float64     `json:"p99"`
+}
+
+// FieldStats computes statistics of the numeric payload field across all molecules of
+// the given species. Non-numeric, missing and non-finite (NaN or ±Inf) values are
+// skipped and reported in Skipped, so that the result always encodes to JSON.
+// Percentiles are computed over a uniform sample of at most maxSamples values
+// (DefaultFieldStatsMaxSamples if maxSamples <= 0), drawn while scanning, so that
+// memory and sorting stay bounded on very large populations.
+func (e *Environment) FieldStats(species SpeciesName, field string, maxSamples int) FieldStats {
+	if maxSamples <= 0 {
+		maxSamples = DefaultFieldStatsMaxSamples
+	}
+
+	stats := FieldStats{Species: species, Field: field}
+	sample := make([]float64, 0)
+	// the sum of finite values can overflow: then fall back to the running mean
+	sum, runningMean := 0.0, 0.0
+
+	e.mu.RLock()
+	for _, m := range e.mols {
+		if m.Species != species {
+			continue
+		}
+		stats.Molecules++
+		v, ok := toFloat64(m.Payload[field])
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			stats.Skipped++
+			continue
+		}
+
+		stats.Count++
+		if stats.Count == 1 {
+			stats.Min, stats.Max = v, v
+		}
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+		sum += v
+		runningMean += v/float64(stats.Count) - runningMean/float64(stats.Count)
+
+		// reservoir sampling: every value ends up in the sample with the same probability
+		if len(sample) < maxSamples {
+			sample = append(sample, v)
+		} else if j := rand.Intn(stats.Count); j < maxSamples {
+			sample[j] = v
+		}
+	}
+	e.mu.RUnlock()
+
+	if stats.Count == 0 {
+		return stats
+	}
+	stats.Mean = sum / float64(stats.Count)
+	if math.IsInf(sum, 0) {
+		stats.Mean = runningMean
+	}
+	stats.Sampled = stats.Count > maxSamples
+
+	sort.Float64s(sample)
+	stats.P50 = percentile(sample, 50)
+	stats.P90 = percentile(sample, 90)
+	stats.P95 = percentile(sample, 95)
+	stats.P99 = percentile(sample, 99)
+
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted (nearest-rank method).
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// DefaultHistogramBuckets is the number of buckets used by Histogram when no explicit
+// count is given.
+const DefaultHistogramBuckets = 10
+
+// Histogram fields that read the molecule itself rather than its payload.
+const (
+	HistogramFieldEnergy    = "energy"
+	HistogramFieldStability = "stability"
+)
+
+// HistogramBucket counts the values in [Min, Max). The last bucket of a histogram
+// also includes its Max.
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// Histogram is the distribution of a numeric molecule field over equal-width buckets
+// spanning the observed range.
+type Histogram struct {
+	Species SpeciesName       `json:"species,omitempty"`
+	Field   string            `json:"field"`
+	Count   int               `json:"count"`   // numeric values included
+	Skipped int               `json:"skipped"` // molecules with a missing or non-numeric field
+	Min     float64           `json:"min"`
+	Max     float64           `json:"max"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// Histogram computes the distribution of a numeric field across the molecules of the
+// given species, or of all species if species is empty. The field is the molecule's
+// energy or stability (HistogramFieldEnergy, HistogramFieldStability), or otherwise a
+// payload key. Missing and non-numeric values are skipped and reported in Skipped.
+// buckets defaults to DefaultHistogramBuckets if <= 0.
+func (e *Environment) Histogram(species SpeciesName, field string, buckets int) Histogram {
+	if buckets <= 0 {
+		buckets = DefaultHistogramBuckets
+	}
+
+	hist := Histogram{Species: species, Field: field, Buckets: []HistogramBucket{}}
+	values := make([]float64, 0)
+
+	e.mu.RLock()
+	for _, m := range e.mols {
+		if species != "" && m.Species != species {
+			continue
+		}
+		v, ok := histogramValue(m, field)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			hist.Skipped++
+			continue
+		}
+		values = append(values, v)
+	}
+	e.mu.RUnlock()
+
+	hist.Count = len(values)
+	if hist.Count == 0 {
+		return hist
+	}
+
+	hist.Min, hist.Max = values[0], values[0]
+	for _, v := range values {
+		hist.Min = math.Min(hist.Min, v)
+		hist.Max = math.Max(hist.Max, v)
+	}
+
+	// divided before subtracting: the range of finite values can overflow
+	width := hist.Max/float64(buckets) - hist.Min/float64(buckets)
+	hist.Buckets = make([]HistogramBucket, buckets)
+	for i := range hist.Buckets {
+		hist.Buckets[i].Min = hist.Min + float64(i)*width
+		hist.Buckets[i].Max = hist.Min + float64(i+1)*width
+	}
+	hist.Buckets[buckets-1].Max = hist.Max
+
+	for _, v := range values {
+		i := 0
+		if width > 0 {
+			if pos := (v/2 - hist.Min/2) / (width / 2); pos < float64(buckets) {
+				i = min(int(pos), buckets-1)
+			} else {
+				i = buckets - 1
+			}
+		}
+		hist.Buckets[i].Count++
+	}
+	return hist
+}
+
+// histogramValue returns the numeric value of field for m, as read by Histogram.
+func histogramValue(m Molecule, field string) (float64, bool) {
+	switch field {
+	case HistogramFieldEnergy:
+		return m.Energy, true
+	case HistogramFieldStability:
+		return m.Stability, true
+	default:
+		return toFloat64(m.Payload[field])
+	}
+}
+
+// EnvStats is a cheap summary of an environment's progress and population.
+type EnvStats struct {
+	Time      int64               `json:"time"`
+	Molecules int                 `json:"molecules"`
+	Species   map[SpeciesName]int `json:"species"` // molecules per species
+}
+
+// Stats returns the current time, the total number of molecules and the number of
+// molecules of each species, read consistently under the environment's lock.
+func (e *Environment) Stats() EnvStats {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	stats := EnvStats{
+		Time:      e.time,
+		Molecules: len(e.mols),
+		Species:   make(map[SpeciesName]int),
+	}
+	for _, m := range e.mols {
+		stats.Species[m.Species]++
+	}
+	return stats
+}
+
+// SpeciesCounts returns the number of molecules of each species currently in the environment.
+func (e *Environment) SpeciesCounts() map[SpeciesName]int {
+	return e.Stats().Species
+}
+
+// ConservationReport sums the numeric field over the molecules of each species, reading
+// field as Histogram does: "energy", "stability" or a payload field. Molecules without a
+// numeric value count as 0. Comparing the totals before and after StepN checks that a
+// schema conserves a quantity, e.g. that its reactions neither leak nor duplicate energy.
+func (e *Environment) ConservationReport(field string) map[string]float64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	report := make(map[string]float64)
+	for _, m := range e.mols {
+		v, _ := histogramValue(m, field)
+		report[string(m.Species)] += v
+	}
+	return report
+}
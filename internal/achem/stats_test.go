@@ -0,0 +1,231 @@
+package achem
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEnvironment_FieldStats(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	for i := 1; i <= 100; i++ {
+		env.Insert(NewMolecule("Metric", map[string]any{"value": i}, 0))
+	}
+	env.Insert(NewMolecule("Metric", map[string]any{"value": "n/a"}, 0))
+	env.Insert(NewMolecule("Metric", map[string]any{}, 0))
+	env.Insert(NewMolecule("Other", map[string]any{"value": 1000}, 0))
+
+	stats := env.FieldStats("Metric", "value", 0)
+
+	if stats.Molecules != 102 || stats.Count != 100 || stats.Skipped != 2 {
+		t.Errorf("Expected molecules=102 count=100 skipped=2, got %+v", stats)
+	}
+	if stats.Sampled {
+		t.Error("Expected no sampling below the sample limit")
+	}
+	if stats.Min != 1 || stats.Max != 100 || stats.Mean != 50.5 {
+		t.Errorf("Expected min=1 max=100 mean=50.5, got %+v", stats)
+	}
+	if stats.P50 != 50 || stats.P90 != 90 || stats.P95 != 95 || stats.P99 != 99 {
+		t.Errorf("Expected p50=50 p90=90 p95=95 p99=99, got %+v", stats)
+	}
+}
+
+func TestEnvironment_FieldStats_Sampled(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	for i := 1; i <= 1000; i++ {
+		env.Insert(NewMolecule("Metric", map[string]any{"value": float64(i)}, 0))
+	}
+
+	stats := env.FieldStats("Metric", "value", 100)
+
+	if !stats.Sampled || stats.Count != 1000 {
+		t.Errorf("Expected sampled stats over 1000 values, got %+v", stats)
+	}
+	// min/max/mean are exact even when sampling
+	if stats.Min != 1 || stats.Max != 1000 || stats.Mean != 500.5 {
+		t.Errorf("Expected exact min/max/mean, got %+v", stats)
+	}
+	if stats.P50 < 1 || stats.P50 > 1000 || stats.P50 > stats.P99 {
+		t.Errorf("Expected ordered percentiles within range, got %+v", stats)
+	}
+}
+
+func TestEnvironment_FieldStats_Empty(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	stats := env.FieldStats("Metric", "value", 0)
+	if stats.Count != 0 || stats.Molecules != 0 || stats.P99 != 0 {
+		t.Errorf("Expected zero stats for empty species, got %+v", stats)
+	}
+}
+
+func TestEnvironment_FieldStats_NonFinite(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	for _, v := range []float64{math.MaxFloat64, math.MaxFloat64, math.Inf(1), math.Inf(-1), math.NaN()} {
+		env.Insert(NewMolecule("Metric", map[string]any{"value": v}, 0))
+	}
+
+	stats := env.FieldStats("Metric", "value", 0)
+	if stats.Count != 2 || stats.Skipped != 3 {
+		t.Errorf("Expected the non-finite values to be skipped, got %+v", stats)
+	}
+	// the sum overflows, the mean doesn't
+	if stats.Mean != math.MaxFloat64 {
+		t.Errorf("Expected mean %g, got %g", math.MaxFloat64, stats.Mean)
+	}
+	if _, err := json.Marshal(stats); err != nil {
+		t.Errorf("Expected the stats to encode, got %v", err)
+	}
+}
+
+func TestEnvironment_Histogram(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	for i := 0; i < 10; i++ {
+		m := NewMolecule("Metric", map[string]any{"value": i}, 0)
+		m.Energy = float64(i)
+		env.Insert(m)
+	}
+	env.Insert(NewMolecule("Metric", map[string]any{"value": "n/a"}, 0))
+	other := NewMolecule("Other", map[string]any{"value": 100}, 0)
+	other.Energy = 100
+	env.Insert(other)
+
+	hist := env.Histogram("Metric", "value", 5)
+	if hist.Count != 10 || hist.Skipped != 1 || hist.Min != 0 || hist.Max != 9 {
+		t.Errorf("Unexpected histogram summary: %+v", hist)
+	}
+	if len(hist.Buckets) != 5 {
+		t.Fatalf("Expected 5 buckets, got %d", len(hist.Buckets))
+	}
+	for i, b := range hist.Buckets {
+		if b.Count != 2 {
+			t.Errorf("Expected 2 values in bucket %d, got %+v", i, b)
+		}
+	}
+	if last := hist.Buckets[4]; last.Max != 9 {
+		t.Errorf("Expected the last bucket to end at the max, got %+v", last)
+	}
+
+	// energy across all species, with the default bucket count
+	hist = env.Histogram("", HistogramFieldEnergy, 0)
+	if hist.Count != 12 || hist.Skipped != 0 || hist.Max != 100 || len(hist.Buckets) != DefaultHistogramBuckets {
+		t.Errorf("Unexpected energy histogram: %+v", hist)
+	}
+	if hist.Buckets[0].Count != 11 || hist.Buckets[DefaultHistogramBuckets-1].Count != 1 {
+		t.Errorf("Expected 11 low-energy molecules and 1 outlier, got %+v", hist.Buckets)
+	}
+}
+
+func TestEnvironment_Histogram_SingleValue(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	for i := 0; i < 3; i++ {
+		env.Insert(NewMolecule("Metric", map[string]any{"value": 7}, 0))
+	}
+
+	hist := env.Histogram("Metric", "value", 4)
+	if hist.Count != 3 || hist.Buckets[0].Count != 3 {
+		t.Errorf("Expected all values in the first bucket, got %+v", hist)
+	}
+
+	if empty := env.Histogram("Missing", "value", 4); empty.Count != 0 || len(empty.Buckets) != 0 {
+		t.Errorf("Expected an empty histogram, got %+v", empty)
+	}
+}
+
+func TestEnvironment_Histogram_WideRange(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	for _, v := range []float64{-math.MaxFloat64, 0, math.MaxFloat64, math.Inf(1)} {
+		env.Insert(NewMolecule("Metric", map[string]any{"value": v}, 0))
+	}
+
+	// the range overflows a float64: the buckets must still be finite
+	hist := env.Histogram("Metric", "value", 2)
+	if hist.Count != 3 || hist.Skipped != 1 {
+		t.Errorf("Expected the infinite value to be skipped, got %+v", hist)
+	}
+	if hist.Buckets[0].Count != 1 || hist.Buckets[1].Count != 2 {
+		t.Errorf("Expected 1 and 2 values in the buckets, got %+v", hist.Buckets)
+	}
+	if _, err := json.Marshal(hist); err != nil {
+		t.Errorf("Expected the histogram to encode, got %v", err)
+	}
+}
+
+func TestEnvironment_SpeciesCounts(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	env.Insert(NewMolecule("A", map[string]any{}, 0))
+	env.Insert(NewMolecule("A", map[string]any{}, 0))
+	env.Insert(NewMolecule("B", map[string]any{}, 0))
+
+	counts := env.SpeciesCounts()
+	if counts["A"] != 2 || counts["B"] != 1 || len(counts) != 2 {
+		t.Errorf("Expected A=2 B=1, got %v", counts)
+	}
+}
+
+func TestEnvironment_ConservationReport(t *testing.T) {
+	three, two := 3.0, 2.0
+	reaction := func(energy *float64) *ConfigReaction {
+		return &ConfigReaction{cfg: ReactionConfig{
+			ID:      "trimerize",
+			Input:   InputConfig{Species: "A", Count: 3},
+			Rate:    1,
+			Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "B", Energy: energy}}},
+		}}
+	}
+	total := func(report map[string]float64) float64 {
+		sum := 0.0
+		for _, v := range report {
+			sum += v
+		}
+		return sum
+	}
+
+	// 3 A of energy 1 -> 1 B of energy 3 conserves energy
+	env := NewEnvironment(NewSchema("conserving").WithReactions(reaction(&three)))
+	for i := 0; i < 6; i++ {
+		env.Insert(Molecule{ID: MoleculeID(fmt.Sprintf("a%d", i)), Species: "A", Energy: 1, Payload: map[string]any{"mass": 2}})
+	}
+	env.Insert(Molecule{ID: "c", Species: "C", Payload: map[string]any{"mass": "heavy"}})
+
+	before := env.ConservationReport("energy")
+	if before["A"] != 6 || before["C"] != 0 || len(before) != 2 {
+		t.Errorf("Expected A=6 C=0, got %v", before)
+	}
+	if mass := env.ConservationReport("mass"); mass["A"] != 12 || mass["C"] != 0 {
+		t.Errorf("Expected A=12 and non-numeric values to count as 0, got %v", mass)
+	}
+	env.StepN(1)
+	after := env.ConservationReport("energy")
+	if after["B"] != 6 || total(after) != total(before) {
+		t.Errorf("Expected the energy of A to move to B, got %v then %v", before, after)
+	}
+
+	// 3 A of energy 1 -> 1 B of energy 2 leaks energy
+	env = NewEnvironment(NewSchema("leaking").WithReactions(reaction(&two)))
+	for i := 0; i < 3; i++ {
+		env.Insert(Molecule{ID: MoleculeID(fmt.Sprintf("a%d", i)), Species: "A", Energy: 1})
+	}
+	before = env.ConservationReport("energy")
+	env.StepN(1)
+	if after := env.ConservationReport("energy"); total(after) == total(before) {
+		t.Errorf("Expected the leak to show, got %v then %v", before, after)
+	}
+}
+
+func TestEnvironment_Stats(t *testing.T) {
+	env := NewEnvironment(NewSchema("stats"))
+	env.Insert(NewMolecule("A", map[string]any{}, 0))
+	env.Insert(NewMolecule("B", map[string]any{}, 0))
+	env.Step()
+	env.Step()
+
+	stats := env.Stats()
+	if stats.Time != 2 || stats.Molecules != 2 {
+		t.Errorf("Expected time=2 molecules=2, got %+v", stats)
+	}
+	if stats.Species["A"] != 1 || stats.Species["B"] != 1 {
+		t.Errorf("Expected A=1 B=1, got %v", stats.Species)
+	}
+}
@@ -0,0 +1,72 @@
+package achem
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// EnvironmentStepResult reports the tick of one environment in StepAll
+type EnvironmentStepResult struct {
+	EnvironmentID EnvironmentID `json:"env_id"`
+	Summary       *StepSummary  `json:"summary,omitempty"` // nil if the environment didn't step
+	Error         string        `json:"error,omitempty"`
+}
+
+// StepAll advances every managed environment by one tick, so that environments
+// modeling interacting subsystems move forward together. With parallel, the
+// environments step concurrently, each in its own goroutine; otherwise they step one
+// after another, in ID order. Auto-running environments are skipped, since their own
+// loop drives them. A panic while stepping an environment is recovered and reported in
+// its result, and doesn't prevent the others from stepping. Results are sorted by
+// environment ID.
+func (em *EnvironmentManager) StepAll(parallel bool) []EnvironmentStepResult {
+	em.mu.RLock()
+	ids := slices.Sorted(maps.Keys(em.environments))
+	envs := make([]*Environment, len(ids))
+	for i, id := range ids {
+		envs[i] = em.environments[id]
+	}
+	logger := em.logger
+	em.mu.RUnlock()
+
+	results := make([]EnvironmentStepResult, len(ids))
+	if !parallel {
+		for i, env := range envs {
+			results[i] = stepOne(ids[i], env, logger)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, env := range envs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = stepOne(ids[i], env, logger)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// stepOne runs a single tick of env for StepAll, recovering from panics
+func stepOne(id EnvironmentID, env *Environment, logger Logger) (res EnvironmentStepResult) {
+	res.EnvironmentID = id
+	if env.IsRunning() {
+		res.Error = "environment is auto-running"
+		return res
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			res.Summary = nil
+			res.Error = fmt.Sprintf("panic: %v", r)
+			logger.Errorf("step failed: env_id=%s error=%v", id, r)
+		}
+	}()
+	sum := env.StepN(1)
+	res.Summary = &sum
+	return res
+}
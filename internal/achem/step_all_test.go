@@ -0,0 +1,61 @@
+package achem
+
+import (
+	"strings"
+	"testing"
+)
+
+// panicReaction panics when it is applied, like a buggy custom reaction
+type panicReaction struct{}
+
+func (panicReaction) ID() string                                    { return "panic" }
+func (panicReaction) Name() string                                  { return "panic" }
+func (panicReaction) InputPattern(m Molecule) bool                  { return true }
+func (panicReaction) Rate() float64                                 { return 1 }
+func (panicReaction) EffectiveRate(m Molecule, env EnvView) float64 { return 1 }
+func (panicReaction) Apply(m Molecule, env EnvView, ctx ReactionContext) ReactionEffect {
+	panic("boom")
+}
+
+func TestEnvironmentManager_StepAll(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		em := NewEnvironmentManager()
+		cfg := SchemaConfig{
+			Name:    "alerts",
+			Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}},
+			Reactions: []ReactionConfig{
+				{ID: "alert", Input: InputConfig{Species: "Event"}, Rate: 1, Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}}},
+			},
+		}
+		schema, err := BuildSchemaFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+		}
+		for _, id := range []EnvironmentID{"c", "a"} {
+			if err := em.CreateEnvironment(id, schema); err != nil {
+				t.Fatalf("CreateEnvironment failed: %v", err)
+			}
+		}
+		if err := em.CreateEnvironment("b", NewSchema("broken").WithReactions(panicReaction{})); err != nil {
+			t.Fatalf("CreateEnvironment failed: %v", err)
+		}
+		a, _ := em.GetEnvironment("a")
+		a.Insert(NewMolecule("Event", nil, 0))
+		b, _ := em.GetEnvironment("b")
+		b.Insert(NewMolecule("Event", nil, 0))
+
+		results := em.StepAll(parallel)
+		if len(results) != 3 || results[0].EnvironmentID != "a" || results[1].EnvironmentID != "b" || results[2].EnvironmentID != "c" {
+			t.Fatalf("parallel=%v: expected results for [a b c], got %+v", parallel, results)
+		}
+		if r := results[0]; r.Error != "" || r.Summary == nil || r.Summary.Time != 1 || r.Summary.ReactionsFired["alert"] != 1 {
+			t.Errorf("parallel=%v: expected a to fire alert at time 1, got %+v", parallel, r)
+		}
+		if r := results[1]; r.Summary != nil || !strings.Contains(r.Error, "boom") {
+			t.Errorf("parallel=%v: expected the panic of b to be reported, got %+v", parallel, r)
+		}
+		if r := results[2]; r.Error != "" || r.Summary == nil || r.Summary.Time != 1 {
+			t.Errorf("parallel=%v: expected c to step to time 1, got %+v", parallel, r)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package achem
+
+import "testing"
+
+func TestEnvironment_StepN(t *testing.T) {
+	schema, err := BuildSchemaFromConfig(SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "escalate",
+				Input:   InputConfig{Species: "Event"},
+				Rate:    1.0,
+				Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	env := NewEnvironment(schema)
+	for i := 0; i < 3; i++ {
+		env.Insert(NewMolecule("Event", nil, 0))
+	}
+
+	sum := env.StepN(4)
+
+	if sum.Ticks != 4 || sum.Time != 4 || env.Time() != 4 {
+		t.Errorf("Expected 4 ticks ending at time 4, got %+v (env time %d)", sum, env.Time())
+	}
+	if sum.ReactionsFired["escalate"] != 3 {
+		t.Errorf("Expected escalate to fire 3 times, got %d", sum.ReactionsFired["escalate"])
+	}
+	if sum.Consumed["Event"] != 3 || sum.Created["Alert"] != 3 || sum.Created["Event"] != 0 {
+		t.Errorf("Unexpected per-species totals: created=%v consumed=%v", sum.Created, sum.Consumed)
+	}
+
+	// the environment counters see the same ticks
+	if c := env.Counters(false); c.ReactionsFired["escalate"] != 3 || c.MoleculesConsumed != 3 {
+		t.Errorf("Expected counters to match the summary, got %+v", c)
+	}
+
+	if sum := env.StepN(0); sum.Ticks != 0 || sum.Time != 4 || len(sum.ReactionsFired) != 0 {
+		t.Errorf("Expected StepN(0) to do nothing, got %+v", sum)
+	}
+}
+
+func TestEnvironment_StepN_DecayIsNotConsumed(t *testing.T) {
+	env := NewEnvironment(NewSchema("test").WithSpecies(Species{Name: "Event"}).WithDecayRate(1))
+	for i := 0; i < 3; i++ {
+		env.Insert(Molecule{Species: "Event", Stability: 0})
+	}
+
+	sum := env.StepN(1)
+	if len(env.AllMolecules()) != 0 {
+		t.Fatalf("Expected every molecule to decay, got %d left", len(env.AllMolecules()))
+	}
+	if sum.Consumed["Event"] != 0 {
+		t.Errorf("Expected decayed molecules not to count as consumed, got %v", sum.Consumed)
+	}
+}
+
+func TestEnvironment_StepN_MatchesSteps(t *testing.T) {
+	schema, err := BuildSchemaFromConfig(SchemaConfig{
+		Name:    "split",
+		Species: []SpeciesConfig{{Name: "Cell"}, {Name: "Spore"}},
+		Reactions: []ReactionConfig{
+			{ID: "divide", Input: InputConfig{Species: "Cell"}, Rate: 0.3, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Cell"}}}},
+			{ID: "sporulate", Input: InputConfig{Species: "Cell"}, Rate: 0.1, Effects: []EffectConfig{{Transform: &TransformEffectConfig{Species: "Spore"}}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+
+	// the ticks of StepN share their lock with the next snapshot: same run as Step
+	run := func(stepN bool) []Molecule {
+		env := NewEnvironmentWithSeed(schema, 11)
+		env.SetIDGenerator(SequentialIDs("m"))
+		env.Insert(Molecule{Species: "Cell"})
+		if stepN {
+			env.StepN(12)
+		} else {
+			for range 12 {
+				env.Step()
+			}
+		}
+		if env.Time() != 12 {
+			t.Errorf("Expected time 12, got %d", env.Time())
+		}
+		mols := env.AllMolecules()
+		sortForReplay(mols)
+		return mols
+	}
+
+	steps, stepN := run(false), run(true)
+	if len(steps) != len(stepN) {
+		t.Fatalf("Expected %d molecules, got %d", len(steps), len(stepN))
+	}
+	for i := range steps {
+		if steps[i].ID != stepN[i].ID || steps[i].Species != stepN[i].Species {
+			t.Errorf("Molecule %d: expected %s %s, got %s %s", i, steps[i].ID, steps[i].Species, stepN[i].ID, stepN[i].Species)
+		}
+	}
+}
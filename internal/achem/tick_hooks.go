@@ -0,0 +1,52 @@
+package achem
+
+import "slices"
+
+// tickHook is a function registered with OnTick
+type tickHook struct {
+	id string
+	fn func(time int64, summary StepSummary)
+}
+
+// OnTick registers a hook run after every tick of the environment, whatever runs it
+// (Step, StepN, Run or the manager's StepAll), with the time of the tick and a summary
+// of what it changed. Hooks run in registration order, outside the environment's lock,
+// once the tick is fully applied, so they may read the environment (but must not modify
+// the summary's maps, which are shared). They slow down the tick loop, so long work
+// should be handed off to another goroutine. Unlike reaction callbacks, hooks run on
+// every tick, even when no reaction fires; they are kept by Reset but not copied by
+// Clone. Returns the ID of the hook, for RemoveTickHook.
+func (e *Environment) OnTick(hook func(time int64, summary StepSummary)) string {
+	id := NewRandomID()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tickHooks = append(e.tickHooks, tickHook{id: id, fn: hook})
+	return id
+}
+
+// RemoveTickHook unregisters the hook with the given ID, returned by OnTick.
+// Returns false if there is no such hook.
+func (e *Environment) RemoveTickHook(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := slices.IndexFunc(e.tickHooks, func(h tickHook) bool { return h.id == id })
+	if i < 0 {
+		return false
+	}
+	e.tickHooks = slices.Delete(e.tickHooks, i, i+1)
+	return true
+}
+
+// tickHookFuncs returns the registered hooks, so that they can run without the lock
+func (e *Environment) tickHookFuncs() []func(int64, StepSummary) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if len(e.tickHooks) == 0 {
+		return nil
+	}
+	fns := make([]func(int64, StepSummary), len(e.tickHooks))
+	for i, h := range e.tickHooks {
+		fns[i] = h.fn
+	}
+	return fns
+}
@@ -0,0 +1,67 @@
+package achem
+
+import "testing"
+
+func TestEnvironment_OnTick(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{ID: "alert", Input: InputConfig{Species: "Event"}, Rate: 1, Effects: []EffectConfig{{Consume: true}, {Create: &CreateEffectConfig{Species: "Alert"}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	var order []string
+	var summaries []StepSummary
+	first := env.OnTick(func(time int64, sum StepSummary) {
+		order = append(order, "first")
+		summaries = append(summaries, sum)
+		if time != sum.Time {
+			t.Errorf("Expected the hook time %d to match the summary time %d", time, sum.Time)
+		}
+		if env.Time() != time {
+			t.Errorf("Expected the tick to be applied when the hook runs, got time %d", env.Time())
+		}
+	})
+	second := env.OnTick(func(int64, StepSummary) { order = append(order, "second") })
+
+	env.Insert(Molecule{Species: "Event"})
+	env.Insert(Molecule{Species: "Event"})
+	env.Step()
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("Expected both hooks in registration order, got %v", order)
+	}
+	if sum := summaries[0]; sum.Ticks != 1 || sum.Time != 1 || sum.ReactionsFired["alert"] != 2 || sum.Created["Alert"] != 2 || sum.Consumed["Event"] != 2 {
+		t.Errorf("Unexpected summary: %+v", sum)
+	}
+
+	// hooks run on every tick, even quiet ones, and StepN still returns the totals
+	if !env.RemoveTickHook(second) {
+		t.Fatal("Expected the second hook to be removed")
+	}
+	env.Insert(Molecule{Species: "Event"})
+	total := env.StepN(3)
+	if len(summaries) != 4 || summaries[3].Time != 4 || summaries[3].ReactionsFired["alert"] != 0 {
+		t.Errorf("Expected a summary per tick, got %+v", summaries)
+	}
+	if total.Ticks != 3 || total.Time != 4 || total.ReactionsFired["alert"] != 1 || total.Created["Alert"] != 1 {
+		t.Errorf("Unexpected StepN summary: %+v", total)
+	}
+	if len(order) != 5 {
+		t.Errorf("Expected the removed hook not to run, got %v", order)
+	}
+
+	if env.RemoveTickHook(second) {
+		t.Error("Expected removing a hook twice to fail")
+	}
+	env.RemoveTickHook(first)
+	env.Step()
+	if len(summaries) != 4 {
+		t.Errorf("Expected no hook to run, got %d summaries", len(summaries))
+	}
+}
@@ -0,0 +1,126 @@
+package achem
+
+import "fmt"
+
+// MoleculeTrace explains, reaction by reaction, whether a molecule can react in the
+// next tick. See Environment.TraceMolecule.
+type MoleculeTrace struct {
+	MoleculeID MoleculeID      `json:"molecule_id"`
+	Found      bool            `json:"found"` // false if the environment has no such molecule
+	Time       int64           `json:"time"`  // the tick the reactions were evaluated for
+	Reactions  []ReactionTrace `json:"reactions"`
+}
+
+// ReactionTrace is the evaluation of a reaction against the traced molecule. The rate,
+// partners and conditions are only evaluated when the input pattern matched.
+type ReactionTrace struct {
+	ReactionID   string `json:"reaction_id"`
+	Enabled      bool   `json:"enabled"`                 // false if disabled with DisableReaction
+	CoolingUntil int64  `json:"cooling_until,omitempty"` // last tick of the molecule's cooldown, if cooling
+	InputMatched bool   `json:"input_matched"`
+
+	EffectiveRate     float64          `json:"effective_rate"`            // probability of firing, catalysts and inhibitors included
+	InputsRequired    int              `json:"inputs_required,omitempty"` // input count, if above 1 (see InputConfig.Count)
+	InputsFound       int              `json:"inputs_found,omitempty"`    // input molecules available for the group, up to InputsRequired
+	PartnersSatisfied bool             `json:"partners_satisfied"`
+	Partners          []PartnerTrace   `json:"partners,omitempty"`
+	Conditions        []ConditionTrace `json:"conditions,omitempty"`
+}
+
+// PartnerTrace compares the partners required by a reaction with the ones found
+type PartnerTrace struct {
+	Species  string `json:"species"`
+	Required int    `json:"required"`
+	Found    int    `json:"found"`
+}
+
+// ConditionTrace is the result of an if-condition of the effects. Path locates it in
+// the reaction's effects, e.g. "effects[1].then[0]".
+type ConditionTrace struct {
+	Path      string            `json:"path"`
+	Condition IfConditionConfig `json:"condition"`
+	Result    bool              `json:"result"`
+}
+
+// TraceMolecule evaluates every reaction against the molecule id, in priority order,
+// as the next tick would: whether the input pattern matches, the effective rate, whether
+// the partners are available and how each if-condition of the effects evaluates. Nothing
+// is drawn, fired or changed. Partners and conditions are only reported for reactions
+// built from a ReactionConfig.
+func (e *Environment) TraceMolecule(id MoleculeID) MoleculeTrace {
+	e.mu.RLock()
+	m, found := e.mols[id]
+	trace := MoleculeTrace{MoleculeID: id, Found: found, Time: e.time + 1, Reactions: []ReactionTrace{}}
+	if !found || e.schema == nil {
+		e.mu.RUnlock()
+		return trace
+	}
+	st := e.snapshotLocked(trace.Time, nil)
+	reactions := sortReactionsByPriority(e.schema.Reactions())
+	rts := make([]ReactionTrace, len(reactions))
+	for i, r := range reactions {
+		_, disabled := e.disabledReactions[r.ID()]
+		rts[i] = ReactionTrace{ReactionID: r.ID(), Enabled: !disabled}
+		if until, ok := e.cooldowns[r.ID()][id]; ok && until >= trace.Time {
+			rts[i].CoolingUntil = until
+		}
+	}
+	e.mu.RUnlock()
+
+	for i, r := range reactions {
+		rt := &rts[i]
+		rt.InputMatched = r.InputPattern(m)
+		if !rt.InputMatched {
+			continue
+		}
+		rt.EffectiveRate = r.EffectiveRate(m, st.view)
+		rt.PartnersSatisfied = true
+
+		cr, ok := r.(*ConfigReaction)
+		if !ok {
+			continue
+		}
+		var exclude map[MoleculeID]struct{}
+		if cr.cfg.Input.Count > 1 {
+			group, _ := cr.matchInputGroup(m, st.view, ReactionContext{})
+			rt.InputsRequired, rt.InputsFound = cr.cfg.Input.Count, 1+len(group)
+			exclude = make(map[MoleculeID]struct{}, len(group))
+			for _, g := range group {
+				exclude[g.ID] = struct{}{}
+			}
+		}
+		for _, cfg := range cr.cfg.Input.Partners {
+			pt := PartnerTrace{Species: cfg.Species, Required: max(cfg.Count, 1)}
+			pt.Found = len(findPartners(cfg, m, st.view, cr.tolerance, exclude))
+			if pt.Found < pt.Required {
+				rt.PartnersSatisfied = false
+			}
+			rt.Partners = append(rt.Partners, pt)
+		}
+		rt.Conditions = cr.traceConditions(cr.cfg.Effects, "effects", m, st.view, nil)
+	}
+
+	trace.Reactions = rts
+	return trace
+}
+
+// traceConditions evaluates the if-conditions of effects and of their then, else and
+// choose branches, all of them whatever the outcome, so that the report covers every
+// branch
+func (r *ConfigReaction) traceConditions(effects []EffectConfig, path string, m Molecule, env EnvView, out []ConditionTrace) []ConditionTrace {
+	for i, eff := range effects {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if eff.If != nil {
+			out = append(out, ConditionTrace{Path: p, Condition: *eff.If, Result: evaluateIfCondition(eff.If, m, env, r.tolerance)})
+			out = r.traceConditions(eff.Then, p+".then", m, env, out)
+			out = r.traceConditions(eff.Else, p+".else", m, env, out)
+			continue
+		}
+		if eff.Choose != nil {
+			for j, branch := range eff.Choose.Branches {
+				out = r.traceConditions(branch.Effects, fmt.Sprintf("%s.choose.branches[%d].effects", p, j), m, env, out)
+			}
+		}
+	}
+	return out
+}
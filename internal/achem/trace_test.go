@@ -0,0 +1,95 @@
+package achem
+
+import "testing"
+
+func TestEnvironment_TraceMolecule(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "alerts",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Witness"}, {Name: "Booster"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:        "escalate",
+				Input:     InputConfig{Species: "Event", Partners: []PartnerConfig{{Species: "Witness", Count: 2}}},
+				Rate:      0.2,
+				Catalysts: []CatalystConfig{{Species: "Booster", RateBoost: 0.3}},
+				Effects: []EffectConfig{
+					{Consume: true},
+					{
+						If:   &IfConditionConfig{Field: "severity", Op: "gte", Value: 5.0},
+						Then: []EffectConfig{{Create: &CreateEffectConfig{Species: "Alert"}}},
+						Else: []EffectConfig{{If: &IfConditionConfig{Field: "severity", Op: "gte", Value: 1.0}}},
+					},
+				},
+			},
+			{ID: "ignore", Input: InputConfig{Species: "Witness"}, Rate: 1, Effects: []EffectConfig{{Consume: true}}},
+			{ID: "log", Input: InputConfig{Species: "Event"}, Rate: 1, CooldownTicks: 5, Priority: 1, Effects: []EffectConfig{{Update: &UpdateEffectConfig{PayloadIncrement: map[string]float64{"seen": 1}}}}},
+		},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+	env.Insert(Molecule{ID: "ev", Species: "Event", Payload: map[string]any{"severity": 3.0}})
+	env.Insert(Molecule{ID: "b", Species: "Booster"})
+	env.Insert(Molecule{ID: "w1", Species: "Witness"})
+	if err := env.DisableReaction("ignore"); err != nil {
+		t.Fatalf("DisableReaction failed: %v", err)
+	}
+	env.Step() // fires log, which starts its cooldown
+	env.Insert(Molecule{ID: "w2", Species: "Witness"})
+
+	trace := env.TraceMolecule("ev")
+	if !trace.Found || trace.Time != 2 || len(trace.Reactions) != 3 {
+		t.Fatalf("Unexpected trace: %+v", trace)
+	}
+
+	// reactions are listed in priority order
+	log, escalate, ignore := trace.Reactions[0], trace.Reactions[1], trace.Reactions[2]
+	if log.ReactionID != "log" || log.CoolingUntil != 6 || !log.InputMatched {
+		t.Errorf("Expected log to match and cool down until tick 6, got %+v", log)
+	}
+	if ignore.ReactionID != "ignore" || ignore.Enabled || ignore.InputMatched {
+		t.Errorf("Expected ignore to be disabled and not to match, got %+v", ignore)
+	}
+
+	if !escalate.Enabled || !escalate.InputMatched || escalate.EffectiveRate != 0.5 {
+		t.Errorf("Expected escalate to match with the boosted rate 0.5, got %+v", escalate)
+	}
+	if !escalate.PartnersSatisfied || len(escalate.Partners) != 1 || escalate.Partners[0] != (PartnerTrace{Species: "Witness", Required: 2, Found: 2}) {
+		t.Errorf("Expected 2 of 2 witnesses, got %+v", escalate.Partners)
+	}
+	if len(escalate.Conditions) != 2 ||
+		escalate.Conditions[0].Path != "effects[1]" || escalate.Conditions[0].Result ||
+		escalate.Conditions[1].Path != "effects[1].else[0]" || !escalate.Conditions[1].Result {
+		t.Errorf("Unexpected conditions: %+v", escalate.Conditions)
+	}
+
+	env.DeleteMolecule("w2")
+	if rt := env.TraceMolecule("ev").Reactions[1]; rt.PartnersSatisfied || rt.Partners[0].Found != 1 {
+		t.Errorf("Expected the partners to be missing, got %+v", rt)
+	}
+
+	if trace := env.TraceMolecule("ghost"); trace.Found || len(trace.Reactions) != 0 {
+		t.Errorf("Expected an empty trace for a missing molecule, got %+v", trace)
+	}
+	if env.Time() != 1 {
+		t.Errorf("Expected tracing not to advance time, got %d", env.Time())
+	}
+}
+
+func TestEnvironment_TraceMolecule_InputCount(t *testing.T) {
+	cfg := ReactionConfig{ID: "trimerize", Input: InputConfig{Species: "A", Count: 3}, Rate: 1, Effects: []EffectConfig{{Consume: true}}}
+	env := NewEnvironment(NewSchema("s").WithReactions(&ConfigReaction{cfg: cfg}))
+	env.Insert(Molecule{ID: "a1", Species: "A"})
+	env.Insert(Molecule{ID: "a2", Species: "A"})
+
+	if rt := env.TraceMolecule("a1").Reactions[0]; rt.InputsRequired != 3 || rt.InputsFound != 2 {
+		t.Errorf("Expected 2 of 3 inputs, got %+v", rt)
+	}
+	env.Insert(Molecule{ID: "a3", Species: "A"})
+	env.Insert(Molecule{ID: "a4", Species: "A"})
+	if rt := env.TraceMolecule("a1").Reactions[0]; rt.InputsRequired != 3 || rt.InputsFound != 3 {
+		t.Errorf("Expected 3 of 3 inputs, got %+v", rt)
+	}
+}
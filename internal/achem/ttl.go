@@ -0,0 +1,40 @@
+package achem
+
+import "time"
+
+// TTLExpireReactionID is the reserved reaction ID of notification events emitted when
+// molecules expire because their TTL elapsed. The expired molecules are reported in
+// ConsumedMolecules.
+const TTLExpireReactionID = "__ttl_expire__"
+
+// expired reports whether m's TTL has elapsed at time now.
+func (m Molecule) expired(now int64) bool {
+	return m.TTL > 0 && m.CreatedAt+m.TTL <= now
+}
+
+// expireLocked removes the molecules whose TTL has elapsed at e.time and returns them
+// grouped by species, in order of first appearance. Callers must hold e.mu.
+func (e *Environment) expireLocked() []evictionBatch {
+	var expired []Molecule
+	for _, m := range e.mols {
+		if m.expired(e.time) {
+			expired = append(expired, m)
+		}
+	}
+	return e.removeInBatchesLocked(expired)
+}
+
+// notifyExpired enqueues one expiry notification per species. Registered callbacks
+// always receive it; notifiers only if listed in the species' ExpireNotifiers.
+func notifyExpired(expired []evictionBatch, envID EnvironmentID, envTime int64, notifierMgr *NotificationManager) {
+	for _, batch := range expired {
+		notifierMgr.Enqueue(NotificationEvent{
+			EnvironmentID:     envID,
+			ReactionID:        TTLExpireReactionID,
+			ReactionName:      "expire " + string(batch.species.Name),
+			Timestamp:         time.Now().Unix(),
+			EnvTime:           envTime,
+			ConsumedMolecules: batch.molecules,
+		}, batch.species.ExpireNotifiers)
+	}
+}
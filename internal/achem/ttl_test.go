@@ -0,0 +1,121 @@
+package achem
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnvironment_Step_ExpiresTTL(t *testing.T) {
+	drain := -0.1
+	cfg := SchemaConfig{
+		Name:    "ttl",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{{
+			ID:    "raise",
+			Input: InputConfig{Species: "Event"},
+			Rate:  1.0,
+			Effects: []EffectConfig{
+				{Create: &CreateEffectConfig{Species: "Alert", TTL: 2}},
+				{Update: &UpdateEffectConfig{EnergyAdd: &drain}},
+			},
+		}},
+	}
+	schema, err := BuildSchemaFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("BuildSchemaFromConfig failed: %v", err)
+	}
+	env := NewEnvironment(schema)
+
+	var mu sync.Mutex
+	var events []NotificationEvent
+	env.RegisterCallback("test", func(event NotificationEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	// inserted at time 0, so it expires at the start of tick 1's apply phase
+	short := NewMolecule("Event", nil, 0, WithTTL(1))
+	env.Insert(short)
+	long := NewMolecule("Event", nil, 0)
+	env.Insert(long)
+
+	env.Step() // tick 1
+
+	if _, ok := env.GetMolecule(short.ID); ok {
+		t.Error("Expected the molecule with TTL 1 to expire at tick 1")
+	}
+	if m, ok := env.GetMolecule(long.ID); !ok || m.Energy >= 1.0 {
+		t.Errorf("Expected the molecule without TTL to survive and be updated, got %+v (found=%t)", m, ok)
+	}
+
+	diffs, _, _ := env.DiffsSince(0)
+	if len(diffs) != 1 || len(diffs[0].Consumed) != 1 || diffs[0].Consumed[0] != short.ID {
+		t.Errorf("Expected the expired molecule to be reported as consumed, got %+v", diffs)
+	}
+	for _, m := range diffs[0].Updated {
+		if m.ID == short.ID {
+			t.Error("Expected no update to be applied to the expired molecule")
+		}
+	}
+
+	countAlerts := func() int {
+		n := 0
+		for _, m := range env.AllMolecules() {
+			if m.Species == "Alert" {
+				n++
+				if m.TTL != 2 {
+					t.Errorf("Expected created alerts to inherit TTL 2, got %d", m.TTL)
+				}
+			}
+		}
+		return n
+	}
+	if n := countAlerts(); n != 2 {
+		t.Fatalf("Expected 2 alerts after tick 1, got %d", n)
+	}
+
+	env.Step() // tick 2: alerts created at tick 1 are still alive
+	if n := countAlerts(); n != 3 {
+		t.Errorf("Expected 3 alerts after tick 2, got %d", n)
+	}
+
+	env.Step() // tick 3: the tick 1 alerts expire
+	if n := countAlerts(); n != 2 {
+		t.Errorf("Expected 2 alerts after tick 3, got %d", n)
+	}
+
+	if c := env.Counters(false); c.MoleculesExpired != 3 {
+		t.Errorf("Expected 3 expired molecules, got %d", c.MoleculesExpired)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(events)
+		mu.Unlock()
+		if n >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var expiredEvents []NotificationEvent
+	for _, ev := range events {
+		if ev.ReactionID == TTLExpireReactionID {
+			expiredEvents = append(expiredEvents, ev)
+		}
+	}
+	if len(expiredEvents) != 2 {
+		t.Fatalf("Expected 2 expiry events, got %d", len(expiredEvents))
+	}
+	if ev := expiredEvents[0]; ev.EnvTime != 1 || len(ev.ConsumedMolecules) != 1 || ev.ConsumedMolecules[0].ID != short.ID {
+		t.Errorf("Expected first expiry event for the short-lived event at tick 1, got %+v", ev)
+	}
+	if ev := expiredEvents[1]; ev.EnvTime != 3 || ev.ReactionName != "expire Alert" || len(ev.ConsumedMolecules) != 2 {
+		t.Errorf("Expected second expiry event for 2 alerts at tick 3, got %+v", ev)
+	}
+}
@@ -3,6 +3,8 @@ package achem
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"sync/atomic"
 )
 
 func NewRandomID() string {
@@ -10,3 +12,15 @@ func NewRandomID() string {
 	_, _ = rand.Read(b)
 	return hex.EncodeToString(b)
 }
+
+// SequentialIDs returns an ID generator, for Environment.SetIDGenerator, producing
+// prefix followed by a counter starting at 1 ("m000001", "m000002", ...). The counter
+// is zero-padded to 6 digits, so that IDs sort in creation order up to a million
+// molecules. The generator is safe for concurrent use. The counter restarts with every
+// generator; the environment skips the IDs its molecules already hold.
+func SequentialIDs(prefix string) func() string {
+	var n atomic.Int64
+	return func() string {
+		return fmt.Sprintf("%s%06d", prefix, n.Add(1))
+	}
+}
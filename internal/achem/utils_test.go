@@ -26,3 +26,16 @@ func TestNewRandomID(t *testing.T) {
 		ids[id] = true
 	}
 }
+
+func TestSequentialIDs(t *testing.T) {
+	gen := SequentialIDs("m")
+	if id := gen(); id != "m000001" {
+		t.Errorf("Expected m000001, got %s", id)
+	}
+	if id := gen(); id != "m000002" {
+		t.Errorf("Expected m000002, got %s", id)
+	}
+	if id := SequentialIDs("x")(); id != "x000001" {
+		t.Errorf("Expected each generator to count on its own, got %s", id)
+	}
+}
@@ -2,6 +2,11 @@ package achem
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -37,6 +42,14 @@ func ValidateSchemaConfig(cfg SchemaConfig) error {
 		err.Add("schema name is required")
 	}
 
+	// Validate numeric tolerance
+	if cfg.FloatTolerance != nil && (*cfg.FloatTolerance < 0 || math.IsNaN(*cfg.FloatTolerance)) {
+		err.Add("float_tolerance must be a non-negative number")
+	}
+	if cfg.DecayRate < 0 || cfg.DecayRate > 1 || math.IsNaN(cfg.DecayRate) {
+		err.Add("decay_rate must be between 0 and 1")
+	}
+
 	// Build a map of species names for quick lookup
 	speciesMap := make(map[string]bool)
 
@@ -51,6 +64,24 @@ func ValidateSchemaConfig(cfg SchemaConfig) error {
 		} else {
 			speciesMap[sp.Name] = true
 		}
+		if sp.MaxCount < 0 {
+			err.Add("species '" + sp.Name + "': max_count must be non-negative")
+		}
+		switch sp.EvictOrder {
+		case "", EvictOldest, EvictLowestEnergy:
+		default:
+			err.Add("species '" + sp.Name + "': invalid evict_order '" + sp.EvictOrder + "' (expected '" + EvictOldest + "' or '" + EvictLowestEnergy + "')")
+		}
+		if sp.DefaultStability != nil && (*sp.DefaultStability < 0 || *sp.DefaultStability > 1) {
+			err.Add("species '" + sp.Name + "': default_stability must be between 0 and 1")
+		}
+		for _, name := range slices.Sorted(maps.Keys(sp.Fields)) {
+			if name == "" {
+				err.Add("species '" + sp.Name + "': field name is required")
+			} else if _, ok := fieldTypes[sp.Fields[name].Type]; !ok {
+				err.Add("species '" + sp.Name + "': field '" + name + "': invalid type '" + sp.Fields[name].Type + "' (expected one of " + strings.Join(slices.Sorted(maps.Keys(fieldTypes)), ", ") + ")")
+			}
+		}
 	}
 
 	// Build a map of reaction IDs for uniqueness check
@@ -75,10 +106,33 @@ func ValidateSchemaConfig(cfg SchemaConfig) error {
 		}
 
 		// Validate input
-		if rc.Input.Species == "" {
+		switch {
+		case rc.Input.Species == "" && len(rc.Input.AnySpecies) == 0:
 			err.Add(reactionPrefix + ": input species is required")
-		} else if !speciesMap[rc.Input.Species] {
-			err.Add(reactionPrefix + ": input species '" + rc.Input.Species + "' does not exist")
+		case rc.Input.Species != "" && len(rc.Input.AnySpecies) > 0:
+			err.Add(reactionPrefix + ": input species and any_species are mutually exclusive")
+		default:
+			seen := make(map[string]bool, len(rc.Input.AnySpecies))
+			for _, species := range rc.Input.inputSpecies() {
+				if !speciesMap[species] {
+					err.Add(reactionPrefix + ": input species '" + species + "' does not exist")
+				}
+				if seen[species] {
+					err.Add(reactionPrefix + ": input any_species has duplicate species '" + species + "'")
+				}
+				seen[species] = true
+			}
+		}
+		validateWhere(rc.Input.Where, reactionPrefix+" input", err)
+		if rc.Input.Count < 0 {
+			err.Add(reactionPrefix + ": input count must be at least 1")
+		}
+
+		if rc.MaxFiresPerTick < 0 {
+			err.Add(reactionPrefix + ": max_fires_per_tick must be non-negative")
+		}
+		if rc.CooldownTicks < 0 {
+			err.Add(reactionPrefix + ": cooldown_ticks must be non-negative")
 		}
 
 		// Validate partners
@@ -89,6 +143,11 @@ func ValidateSchemaConfig(cfg SchemaConfig) error {
 			} else if !speciesMap[partner.Species] {
 				err.Add(partnerPrefix + ": partner species '" + partner.Species + "' does not exist")
 			}
+			validateWhere(partner.Where, partnerPrefix, err)
+			validateWithinDistance(partner.WithinDistance, partner.IncludeUnpositioned, partnerPrefix, err)
+			if _, ok := partnerOrders[partner.Select]; partner.Select != "" && !ok {
+				err.Add(partnerPrefix + ": invalid select '" + partner.Select + "' (expected one of " + strings.Join(slices.Sorted(maps.Keys(partnerOrders)), ", ") + ")")
+			}
 		}
 
 		// Validate catalysts
@@ -99,10 +158,28 @@ func ValidateSchemaConfig(cfg SchemaConfig) error {
 			} else if !speciesMap[catalyst.Species] {
 				err.Add(catalystPrefix + ": catalyst species '" + catalyst.Species + "' does not exist")
 			}
+			validateWhere(catalyst.Where, catalystPrefix, err)
+			validateWithinDistance(catalyst.WithinDistance, catalyst.IncludeUnpositioned, catalystPrefix, err)
+			switch catalyst.Mode {
+			case "", CatalystModeAdd, CatalystModeMultiply:
+			default:
+				err.Add(catalystPrefix + ": invalid mode '" + catalyst.Mode + "' (expected '" + CatalystModeAdd + "' or '" + CatalystModeMultiply + "')")
+			}
+		}
+
+		// Validate inhibitors
+		for j, inhibitor := range rc.Inhibitors {
+			inhibitorPrefix := reactionPrefix + " inhibitor at index " + fmt.Sprintf("%d", j)
+			if inhibitor.Species == "" {
+				err.Add(inhibitorPrefix + ": inhibitor species is required")
+			} else if !speciesMap[inhibitor.Species] {
+				err.Add(inhibitorPrefix + ": inhibitor species '" + inhibitor.Species + "' does not exist")
+			}
+			validateWhere(inhibitor.Where, inhibitorPrefix, err)
 		}
 
 		// Validate effects recursively
-		validateEffects(rc.Effects, reactionPrefix, speciesMap, err)
+		validateEffects(rc.Effects, reactionPrefix, rc.Input.inputSpecies(), speciesMap, err)
 	}
 
 	if err.HasIssues() {
@@ -112,15 +189,64 @@ func ValidateSchemaConfig(cfg SchemaConfig) error {
 }
 
 // validateEffects recursively validates effects
-func validateEffects(effects []EffectConfig, prefix string, speciesMap map[string]bool, err *ValidationError) {
+func validateEffects(effects []EffectConfig, prefix string, inputSpecies []string, speciesMap map[string]bool, err *ValidationError) {
 	for i, eff := range effects {
 		effectPrefix := prefix + " effect at index " + fmt.Sprintf("%d", i)
 
 		// Validate create effect
 		if eff.Create != nil {
-			if eff.Create.Species != "" && !speciesMap[eff.Create.Species] {
+			// emitted molecules belong to the target environments' schemas, so their
+			// species can't be checked against this one
+			emitted := eff.Create.EmitTo != "" || len(eff.Create.EmitToMany) > 0
+			if !emitted && eff.Create.Species != "" && !speciesMap[eff.Create.Species] {
 				err.Add(effectPrefix + ": create effect species '" + eff.Create.Species + "' does not exist")
 			}
+			for j, target := range eff.Create.EmitToMany {
+				if target == "" {
+					err.Add(effectPrefix + ": create effect emit_to_many target at index " + fmt.Sprintf("%d", j) + " is empty")
+				}
+			}
+			if eff.Create.TTL < 0 {
+				err.Add(effectPrefix + ": create effect ttl must be non-negative")
+			}
+		}
+
+		// Validate promote effect
+		if eff.Promote != nil {
+			validatePromote(eff.Promote, effectPrefix, inputSpecies, speciesMap, err)
+		}
+
+		// Validate transform target
+		if eff.Transform != nil {
+			if eff.Transform.Species == "" {
+				err.Add(effectPrefix + ": transform effect species is required")
+			} else if !speciesMap[eff.Transform.Species] {
+				err.Add(effectPrefix + ": transform effect species '" + eff.Transform.Species + "' does not exist")
+			}
+		}
+
+		// Validate requeue bound
+		if eff.Requeue != nil && (eff.Requeue.MaxIterations < 1 || eff.Requeue.MaxIterations > MaxRequeueIterations) {
+			err.Add(effectPrefix + ": requeue effect max_iterations must be between 1 and " + fmt.Sprintf("%d", MaxRequeueIterations))
+		}
+
+		// Validate weighted branches
+		if eff.Choose != nil {
+			if len(eff.Choose.Branches) == 0 {
+				err.Add(effectPrefix + ": choose effect requires at least one branch")
+			}
+			// a choose effect applies its branch and nothing else: siblings would be ignored
+			if eff.Consume || eff.ConsumePartners || eff.Create != nil || eff.Update != nil || eff.Promote != nil ||
+				eff.Transform != nil || eff.Requeue != nil || eff.If != nil || len(eff.Then) > 0 || len(eff.Else) > 0 {
+				err.Add(effectPrefix + ": choose effect can't be combined with other effects in the same entry; put them in a separate effect or in the branches")
+			}
+			for j, branch := range eff.Choose.Branches {
+				branchPrefix := effectPrefix + " choose branch at index " + fmt.Sprintf("%d", j)
+				if !(branch.Weight > 0) || math.IsInf(branch.Weight, 0) {
+					err.Add(branchPrefix + ": weight must be a positive number")
+				}
+				validateEffects(branch.Effects, branchPrefix, inputSpecies, speciesMap, err)
+			}
 		}
 
 		// Validate conditional effects
@@ -130,10 +256,35 @@ func validateEffects(effects []EffectConfig, prefix string, speciesMap map[strin
 
 		// Recursively validate then/else effects
 		if len(eff.Then) > 0 {
-			validateEffects(eff.Then, effectPrefix+" then", speciesMap, err)
+			validateEffects(eff.Then, effectPrefix+" then", inputSpecies, speciesMap, err)
 		}
 		if len(eff.Else) > 0 {
-			validateEffects(eff.Else, effectPrefix+" else", speciesMap, err)
+			validateEffects(eff.Else, effectPrefix+" else", inputSpecies, speciesMap, err)
+		}
+	}
+}
+
+// validatePromote validates a PromoteEffectConfig
+func validatePromote(cfg *PromoteEffectConfig, prefix string, inputSpecies []string, speciesMap map[string]bool, err *ValidationError) {
+	if len(cfg.Ladder) < 2 {
+		err.Add(prefix + ": promote effect ladder must have at least 2 species")
+		return
+	}
+
+	seen := make(map[string]bool, len(cfg.Ladder))
+	for _, species := range cfg.Ladder {
+		if !speciesMap[species] {
+			err.Add(prefix + ": promote effect ladder species '" + species + "' does not exist")
+		}
+		if seen[species] {
+			err.Add(prefix + ": promote effect ladder has duplicate species '" + species + "'")
+		}
+		seen[species] = true
+	}
+
+	for _, species := range inputSpecies {
+		if species != "" && !seen[species] {
+			err.Add(prefix + ": promote effect ladder does not contain input species '" + species + "'")
 		}
 	}
 }
@@ -163,6 +314,48 @@ func validateIfCondition(cond *IfConditionConfig, prefix string, speciesMap map[
 	}
 }
 
+// validateWithinDistance reports negative radii, and include_unpositioned without a radius
+func validateWithinDistance(radius float64, includeUnpositioned bool, prefix string, err *ValidationError) {
+	if radius < 0 || math.IsNaN(radius) {
+		err.Add(prefix + ": within_distance must be non-negative")
+	} else if includeUnpositioned && radius == 0 {
+		err.Add(prefix + ": include_unpositioned requires within_distance")
+	}
+}
+
+// validateWhere reports unsupported operators in where conditions, and malformed
+// "$or"/"$and" groups, recursively
+func validateWhere(where WhereConfig, prefix string, err *ValidationError) {
+	fields := make([]string, 0, len(where))
+	for field := range where {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		cond := where[field]
+		if isWhereGroupKey(field) {
+			if len(cond.Clauses) == 0 {
+				err.Add(prefix + ": where '" + field + "' must be a non-empty list of where clauses")
+			}
+			for i, clause := range cond.Clauses {
+				validateWhere(clause, fmt.Sprintf("%s %s[%d]", prefix, field, i), err)
+			}
+			continue
+		}
+		if cond.Clauses != nil {
+			err.Add(prefix + ": where field '" + field + "' must be a condition object, lists are only allowed for '" + WhereKeyOr + "' and '" + WhereKeyAnd + "'")
+		}
+		for _, op := range cond.unknownOps {
+			err.Add(prefix + ": where field '" + field + "' has invalid operator '" + op + "', must be one of: eq, ne, in, nin, gt, gte, lt, lte, regex")
+		}
+		if cond.Regex != "" {
+			if _, reErr := regexp.Compile(cond.Regex); reErr != nil {
+				err.Add(prefix + ": where field '" + field + "' has invalid regex: " + reErr.Error())
+			}
+		}
+	}
+}
+
 // Valid operators for CountMoleculesConfig.Op
 var validOperators = map[string]bool{
 	"eq":  true,
@@ -185,6 +378,25 @@ func validateCountMolecules(cfg *CountMoleculesConfig, prefix string, speciesMap
 	} else if !speciesMap[cfg.Species] {
 		err.Add(prefix + ": count_molecules species '" + cfg.Species + "' does not exist")
 	}
+	validateWhere(cfg.Where, prefix+" count_molecules", err)
+
+	if cfg.WithinTicks < 0 {
+		err.Add(prefix + ": count_molecules within_ticks must be non-negative")
+	}
+
+	// Validate the aggregation: only count works without a field
+	switch cfg.Agg {
+	case "", AggCount:
+		if cfg.Field != "" {
+			err.Add(prefix + ": count_molecules field requires agg sum, avg, min or max")
+		}
+	case AggSum, AggAvg, AggMin, AggMax:
+		if cfg.Field == "" {
+			err.Add(prefix + ": count_molecules agg '" + cfg.Agg + "' requires a field")
+		}
+	default:
+		err.Add(prefix + ": count_molecules agg has invalid value '" + cfg.Agg + "', must be one of: count, sum, avg, min, max")
+	}
 
 	// Validate Op: must have exactly one entry
 	if len(cfg.Op) == 0 {
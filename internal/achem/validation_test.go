@@ -1,6 +1,7 @@
 package achem
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -170,6 +171,121 @@ func TestValidateSchemaConfig_CountMoleculesInvalidOp(t *testing.T) {
 	}
 }
 
+func TestValidateSchemaConfig_CountMoleculesAgg(t *testing.T) {
+	tests := []struct {
+		agg, field string
+		errMsg     string // empty if valid
+	}{
+		{"", "", ""},
+		{AggCount, "", ""},
+		{AggSum, "energy", ""},
+		{AggAvg, "price", ""},
+		{AggCount, "energy", "field requires agg sum, avg, min or max"},
+		{AggMax, "", "agg 'max' requires a field"},
+		{"median", "price", "agg has invalid value 'median'"},
+	}
+
+	for _, tt := range tests {
+		cfg := SchemaConfig{
+			Name:    "test_schema",
+			Species: []SpeciesConfig{{Name: "A"}},
+			Reactions: []ReactionConfig{{
+				ID:    "r1",
+				Input: InputConfig{Species: "A"},
+				Effects: []EffectConfig{{If: &IfConditionConfig{CountMolecules: &CountMoleculesConfig{
+					Species: "A",
+					Op:      map[string]any{"gt": 1},
+					Agg:     tt.agg,
+					Field:   tt.field,
+				}}}},
+			}},
+		}
+
+		err := ValidateSchemaConfig(cfg)
+		if tt.errMsg == "" && err != nil {
+			t.Errorf("agg %q field %q: expected no validation error, got: %v", tt.agg, tt.field, err)
+		}
+		if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+			t.Errorf("agg %q field %q: expected error containing %q, got: %v", tt.agg, tt.field, tt.errMsg, err)
+		}
+	}
+}
+
+func TestValidateSchemaConfig_CountMoleculesWithinTicks(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{{
+			ID:    "r1",
+			Input: InputConfig{Species: "A"},
+			Effects: []EffectConfig{{If: &IfConditionConfig{CountMolecules: &CountMoleculesConfig{
+				Species:     "A",
+				Op:          map[string]any{"gte": 3},
+				WithinTicks: -1,
+			}}}},
+		}},
+	}
+
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "within_ticks must be non-negative") {
+		t.Fatalf("Expected a within_ticks validation error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_Choose(t *testing.T) {
+	validate := func(choose *ChooseEffectConfig) error {
+		return ValidateSchemaConfig(SchemaConfig{
+			Name:    "test_schema",
+			Species: []SpeciesConfig{{Name: "A"}},
+			Reactions: []ReactionConfig{{
+				ID:      "r1",
+				Input:   InputConfig{Species: "A"},
+				Effects: []EffectConfig{{Choose: choose}},
+			}},
+		})
+	}
+
+	valid := &ChooseEffectConfig{Branches: []WeightedBranch{
+		{Weight: 0.5, Effects: []EffectConfig{{Consume: true}}},
+		{Weight: 2},
+	}}
+	if err := validate(valid); err != nil {
+		t.Errorf("Expected no validation error, got: %v", err)
+	}
+
+	tests := []struct {
+		choose *ChooseEffectConfig
+		errMsg string
+	}{
+		{&ChooseEffectConfig{}, "choose effect requires at least one branch"},
+		{&ChooseEffectConfig{Branches: []WeightedBranch{{Weight: 0}}}, "choose branch at index 0: weight must be a positive number"},
+		{&ChooseEffectConfig{Branches: []WeightedBranch{{Weight: 1}, {Weight: -1}}}, "choose branch at index 1: weight must be a positive number"},
+		{&ChooseEffectConfig{Branches: []WeightedBranch{{Weight: 1, Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "Missing"}}}}}}, "create effect species 'Missing' does not exist"},
+	}
+	for _, tt := range tests {
+		if err := validate(tt.choose); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("Expected error containing %q, got: %v", tt.errMsg, err)
+		}
+	}
+
+	// the siblings of a choose effect would be silently skipped
+	for _, sibling := range []EffectConfig{
+		{Consume: true},
+		{Update: &UpdateEffectConfig{PayloadSet: map[string]any{"x": 1}}},
+		{Then: []EffectConfig{{Consume: true}}},
+	} {
+		sibling.Choose = valid
+		err := ValidateSchemaConfig(SchemaConfig{
+			Name:      "test_schema",
+			Species:   []SpeciesConfig{{Name: "A"}},
+			Reactions: []ReactionConfig{{ID: "r1", Input: InputConfig{Species: "A"}, Effects: []EffectConfig{sibling}}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "choose effect can't be combined with other effects") {
+			t.Errorf("Expected %+v to be rejected, got: %v", sibling, err)
+		}
+	}
+}
+
 func TestValidateSchemaConfig_DuplicateReactionID(t *testing.T) {
 	cfg := SchemaConfig{
 		Name: "test_schema",
@@ -270,3 +386,427 @@ func TestValidateSchemaConfig_InvalidCreateEffectSpecies(t *testing.T) {
 		t.Fatalf("expected error message about create effect species not existing, got: %v", err)
 	}
 }
+
+func TestValidateSchemaConfig_EmitTargets(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "r1",
+				Input: InputConfig{Species: "A"},
+				Effects: []EffectConfig{
+					// species lives in the target environment's schema
+					{Create: &CreateEffectConfig{Species: "Remote", EmitTo: "other"}},
+				},
+			},
+		},
+	}
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Fatalf("expected emitted create effect to be valid, got: %v", err)
+	}
+
+	cfg.Reactions[0].Effects[0].Create.EmitToMany = []string{"ok", ""}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "emit_to_many target at index 1 is empty") {
+		t.Fatalf("expected error about empty emit target, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_Promote(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "Event"}, {Name: "Suspicion"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:    "r1",
+				Input: InputConfig{Species: "Suspicion"},
+				Effects: []EffectConfig{
+					{Promote: &PromoteEffectConfig{Ladder: []string{"Event", "Suspicion", "Alert"}}},
+				},
+			},
+		},
+	}
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Fatalf("expected valid promote effect, got: %v", err)
+	}
+
+	cfg.Reactions[0].Effects[0].Promote.Ladder = []string{"Event", "Incident"}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("expected validation error for invalid ladder")
+	}
+	if !strings.Contains(err.Error(), "promote effect ladder species 'Incident' does not exist") {
+		t.Errorf("expected error about unknown ladder species, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "promote effect ladder does not contain input species 'Suspicion'") {
+		t.Errorf("expected error about missing input species, got: %v", err)
+	}
+
+	cfg.Reactions[0].Effects[0].Promote.Ladder = []string{"Suspicion"}
+	err = ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "at least 2 species") {
+		t.Errorf("expected error about ladder length, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_InputAnySpecies(t *testing.T) {
+	species := []SpeciesConfig{{Name: "Error"}, {Name: "Warning"}, {Name: "Alert"}}
+	tests := []struct {
+		input   InputConfig
+		effects []EffectConfig
+		wantErr string
+	}{
+		{InputConfig{Species: "Error", AnySpecies: []string{"Warning"}}, nil, "mutually exclusive"},
+		{InputConfig{AnySpecies: []string{"Error", "Info"}}, nil, "input species 'Info' does not exist"},
+		{InputConfig{AnySpecies: []string{"Error", "Error"}}, nil, "duplicate species 'Error'"},
+		{InputConfig{AnySpecies: []string{}}, nil, "input species is required"},
+		{InputConfig{AnySpecies: []string{"Error", "Warning"}}, []EffectConfig{{Promote: &PromoteEffectConfig{Ladder: []string{"Warning", "Alert"}}}}, "ladder does not contain input species 'Error'"},
+	}
+	for _, tt := range tests {
+		cfg := SchemaConfig{Name: "test_schema", Species: species, Reactions: []ReactionConfig{{ID: "r1", Input: tt.input, Effects: tt.effects}}}
+		err := ValidateSchemaConfig(cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected error containing %q, got: %v", tt.input, tt.wantErr, err)
+		}
+	}
+
+	cfg := SchemaConfig{Name: "test_schema", Species: species, Reactions: []ReactionConfig{{ID: "r1", Input: InputConfig{AnySpecies: []string{"Error", "Warning"}}}}}
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Errorf("Expected a valid config, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_Transform(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "Suspicion"}, {Name: "Alert"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:      "r1",
+				Input:   InputConfig{Species: "Suspicion"},
+				Effects: []EffectConfig{{Transform: &TransformEffectConfig{Species: "Alert"}}},
+			},
+		},
+	}
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Fatalf("expected valid transform effect, got: %v", err)
+	}
+
+	cfg.Reactions[0].Effects[0].Transform.Species = "Incident"
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "transform effect species 'Incident' does not exist") {
+		t.Errorf("expected error about unknown transform species, got: %v", err)
+	}
+
+	cfg.Reactions[0].Effects[0].Transform.Species = ""
+	err = ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "transform effect species is required") {
+		t.Errorf("expected error about missing transform species, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_RequeueBound(t *testing.T) {
+	for _, n := range []int{0, MaxRequeueIterations + 1} {
+		cfg := SchemaConfig{
+			Name:    "test_schema",
+			Species: []SpeciesConfig{{Name: "A"}},
+			Reactions: []ReactionConfig{
+				{
+					ID:      "r1",
+					Input:   InputConfig{Species: "A"},
+					Effects: []EffectConfig{{Requeue: &RequeueEffectConfig{MaxIterations: n}}},
+				},
+			},
+		}
+		err := ValidateSchemaConfig(cfg)
+		if err == nil || !strings.Contains(err.Error(), "requeue effect max_iterations must be between 1 and") {
+			t.Errorf("max_iterations=%d: expected bound error, got: %v", n, err)
+		}
+	}
+}
+
+func TestValidateSchemaConfig_NegativeMaxFiresPerTick(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{
+			{ID: "r1", Input: InputConfig{Species: "A"}, MaxFiresPerTick: -1},
+		},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "max_fires_per_tick must be non-negative") {
+		t.Errorf("Expected max_fires_per_tick error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_NegativeCooldownTicks(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{
+			{ID: "r1", Input: InputConfig{Species: "A"}, CooldownTicks: -1},
+		},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "cooldown_ticks must be non-negative") {
+		t.Errorf("Expected cooldown_ticks error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_NegativeInputCount(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{
+			{ID: "r1", Input: InputConfig{Species: "A", Count: -1}},
+		},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "input count must be at least 1") {
+		t.Errorf("Expected input count error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_PartnerSelect(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{
+			{ID: "r1", Input: InputConfig{Species: "A", Partners: []PartnerConfig{{Species: "B", Select: "cheapest"}}}},
+		},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid select 'cheapest'") {
+		t.Errorf("Expected select error, got: %v", err)
+	}
+
+	cfg.Reactions[0].Input.Partners[0].Select = SelectOldest
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Errorf("Expected a valid schema, got %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_SpeciesFields(t *testing.T) {
+	cfg := SchemaConfig{
+		Name: "test_schema",
+		Species: []SpeciesConfig{{Name: "Order", Fields: map[string]FieldSpec{
+			"price": {Type: FieldTypeNumber, Required: true},
+			"note":  {Type: "text"},
+		}}},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "field 'note': invalid type 'text'") || strings.Contains(err.Error(), "price") {
+		t.Errorf("Expected only an invalid type error for note, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_SpeciesEviction(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:      "test_schema",
+		Species:   []SpeciesConfig{{Name: "A", MaxCount: -1}, {Name: "B", MaxCount: 10, EvictOrder: "newest"}},
+		Reactions: []ReactionConfig{},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if !strings.Contains(err.Error(), "max_count must be non-negative") {
+		t.Errorf("Expected max_count error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "invalid evict_order 'newest'") {
+		t.Errorf("Expected evict_order error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_SpeciesDefaultStability(t *testing.T) {
+	tooHigh, ok := 1.5, 0.5
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A", DefaultStability: &tooHigh}, {Name: "B", DefaultStability: &ok}},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "species 'A': default_stability must be between 0 and 1") {
+		t.Errorf("Expected default_stability error for A, got: %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "species 'B'") {
+		t.Errorf("Expected no error for B, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_CatalystMode(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "C"}},
+		Reactions: []ReactionConfig{{
+			ID:    "r1",
+			Input: InputConfig{Species: "A"},
+			Rate:  0.1,
+			Catalysts: []CatalystConfig{
+				{Species: "C", Mode: CatalystModeMultiply},
+				{Species: "C", Mode: "exponential"},
+			},
+		}},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if !strings.Contains(err.Error(), "catalyst at index 1: invalid mode 'exponential'") {
+		t.Errorf("Expected mode error for catalyst 1, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "catalyst at index 0") {
+		t.Errorf("Expected multiply mode to be accepted, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_InhibitorSpecies(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{{
+			ID:         "r1",
+			Input:      InputConfig{Species: "A"},
+			Rate:       0.1,
+			Inhibitors: []InhibitorConfig{{Species: ""}, {Species: "Missing"}},
+		}},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if !strings.Contains(err.Error(), "inhibitor at index 0: inhibitor species is required") {
+		t.Errorf("Expected missing species error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "inhibitor species 'Missing' does not exist") {
+		t.Errorf("Expected unknown species error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_NegativeCreateTTL(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{{
+			ID:      "r1",
+			Input:   InputConfig{Species: "A"},
+			Rate:    0.1,
+			Effects: []EffectConfig{{Create: &CreateEffectConfig{Species: "B", TTL: -1}}},
+		}},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "create effect ttl must be non-negative") {
+		t.Errorf("Expected ttl validation error, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_UnknownWhereOperator(t *testing.T) {
+	data := []byte(`{
+		"name": "test_schema",
+		"species": [{"name": "A"}],
+		"reactions": [{
+			"id": "r1",
+			"input": {
+				"species": "A",
+				"where": {"score": {"gte": 1}},
+				"partners": [{"species": "A", "where": {"level": {"above": 3}}}]
+			},
+			"rate": 0.1
+		}]
+	}`)
+	var cfg SchemaConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if !strings.Contains(err.Error(), "partner at index 0: where field 'level' has invalid operator 'above'") {
+		t.Errorf("Expected invalid operator error, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "'score'") {
+		t.Errorf("Expected gte to be accepted, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_WhereGroups(t *testing.T) {
+	var where WhereConfig
+	input := `{"$or": [{"type": {"eq": "a"}}, {"$and": [{"score": {"like": 1}}]}], "$and": [], "tags": [{"x": {"eq": 1}}]}`
+	if err := json.Unmarshal([]byte(input), &where); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	cfg := SchemaConfig{
+		Name:      "test_schema",
+		Species:   []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{{ID: "r1", Input: InputConfig{Species: "A", Where: where}}},
+	}
+
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{
+		"where '$and' must be a non-empty list of where clauses",
+		"input $or[1] $and[0]: where field 'score' has invalid operator 'like'",
+		"where field 'tags' must be a condition object",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got: %v", want, err)
+		}
+	}
+
+	cfg.Reactions[0].Input.Where = WhereConfig{"$or": {Clauses: []WhereConfig{{"type": {Eq: "a"}}, {"type": {Eq: "b"}}}}}
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Errorf("Expected valid groups, got: %v", err)
+	}
+}
+
+func TestValidateSchemaConfig_InvalidRegex(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "test_schema",
+		Species: []SpeciesConfig{{Name: "A"}},
+		Reactions: []ReactionConfig{{
+			ID:    "r1",
+			Input: InputConfig{Species: "A", Where: WhereConfig{"path": {Regex: `[a-`}}},
+		}},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "where field 'path' has invalid regex") {
+		t.Errorf("Expected invalid regex error, got: %v", err)
+	}
+	if _, err := BuildSchemaFromConfig(cfg); err == nil {
+		t.Error("Expected BuildSchemaFromConfig to reject the invalid regex")
+	}
+}
+
+func TestValidateSchemaConfig_WithinDistance(t *testing.T) {
+	cfg := SchemaConfig{
+		Name:    "spatial",
+		Species: []SpeciesConfig{{Name: "A"}, {Name: "B"}},
+		Reactions: []ReactionConfig{
+			{
+				ID:        "r",
+				Input:     InputConfig{Species: "A", Partners: []PartnerConfig{{Species: "B", WithinDistance: -1}}},
+				Rate:      1,
+				Catalysts: []CatalystConfig{{Species: "B", IncludeUnpositioned: true}},
+				Effects:   []EffectConfig{{Consume: true}},
+			},
+		},
+	}
+	err := ValidateSchemaConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"within_distance must be non-negative", "include_unpositioned requires within_distance"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got %v", want, err)
+		}
+	}
+
+	cfg.Reactions[0].Input.Partners[0].WithinDistance = 5
+	cfg.Reactions[0].Catalysts[0].WithinDistance = 5
+	if err := ValidateSchemaConfig(cfg); err != nil {
+		t.Errorf("Expected a valid schema, got %v", err)
+	}
+}
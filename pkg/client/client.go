@@ -1,13 +1,10 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 
 	"github.com/daniacca/achemdb/internal/achem"
 )
@@ -34,15 +31,50 @@ func NewSchema(name string) *SchemaBuilder {
 // Species adds a species definition to the schema.
 // A species represents a type of molecule in the system.
 // The meta parameter can be nil or contain additional metadata.
-func (sb *SchemaBuilder) Species(name, description string, meta map[string]any) *SchemaBuilder {
-	sb.species = append(sb.species, achem.SpeciesConfig{
+// Options such as DefaultEnergy customize the species further.
+func (sb *SchemaBuilder) Species(name, description string, meta map[string]any, opts ...SpeciesOption) *SchemaBuilder {
+	sp := achem.SpeciesConfig{
 		Name:        name,
 		Description: description,
 		Meta:        meta,
-	})
+	}
+	for _, opt := range opts {
+		opt(&sp)
+	}
+	sb.species = append(sb.species, sp)
 	return sb
 }
 
+// SpeciesOption customizes a species added with SchemaBuilder.Species.
+type SpeciesOption func(*achem.SpeciesConfig)
+
+// DefaultEnergy sets the energy of new molecules of the species that don't set their own.
+func DefaultEnergy(energy float64) SpeciesOption {
+	return func(sp *achem.SpeciesConfig) {
+		sp.DefaultEnergy = &energy
+	}
+}
+
+// DefaultStability sets the stability of new molecules of the species that don't set
+// their own. It must be between 0 and 1.
+func DefaultStability(stability float64) SpeciesOption {
+	return func(sp *achem.SpeciesConfig) {
+		sp.DefaultStability = &stability
+	}
+}
+
+// Field declares the type of a payload field of the species (one of the
+// achem.FieldType* constants), and whether molecules must set it.
+// Molecules whose payload doesn't conform are rejected.
+func Field(name, fieldType string, required bool) SpeciesOption {
+	return func(sp *achem.SpeciesConfig) {
+		if sp.Fields == nil {
+			sp.Fields = make(map[string]achem.FieldSpec)
+		}
+		sp.Fields[name] = achem.FieldSpec{Type: fieldType, Required: required}
+	}
+}
+
 // Reaction adds a reaction definition to the schema.
 // Reactions define how molecules transform when they interact.
 func (sb *SchemaBuilder) Reaction(rb *ReactionBuilder) *SchemaBuilder {
@@ -67,15 +99,19 @@ func (sb *SchemaBuilder) Build() achem.SchemaConfig {
 
 // ReactionBuilder provides a fluent API for building reaction configurations.
 // Reactions define how molecules of a specific species transform,
-// including input patterns, rates, catalysts, and effects.
+// including input patterns, rates, catalysts, inhibitors, and effects.
 type ReactionBuilder struct {
-	id        string
-	name      string
-	input     *InputBuilder
-	rate      float64
-	catalysts []*CatalystBuilder
-	effects   []*EffectBuilder
-	notify    *NotificationBuilder
+	id         string
+	name       string
+	input      *InputBuilder
+	rate       float64
+	catalysts  []*CatalystBuilder
+	inhibitors []*InhibitorBuilder
+	effects    []*EffectBuilder
+	notify     *NotificationBuilder
+	maxFires   int
+	cooldown   int
+	priority   int
 }
 
 // NewReaction creates a new reaction builder with the given ID.
@@ -110,6 +146,15 @@ func (rb *ReactionBuilder) Input(species string, whereEqs ...func(*InputBuilder)
 	return rb
 }
 
+// InputAny makes the reaction trigger on molecules of any of the given species,
+// instead of a single one (see Input).
+func (rb *ReactionBuilder) InputAny(species ...string) *ReactionBuilder {
+	ib := NewInput("")
+	ib.anySpecies = species
+	rb.input = ib
+	return rb
+}
+
 // Rate sets the base reaction rate, a value between 0.0 and 1.0.
 // This represents the probability that the reaction will fire when
 // a matching molecule is available. The effective rate can be modified
@@ -126,23 +171,66 @@ func (rb *ReactionBuilder) Catalyst(cb *CatalystBuilder) *ReactionBuilder {
 	return rb
 }
 
+// Inhibitor adds an inhibitor configuration to the reaction.
+// Inhibitors decrease the reaction rate when matching molecules are present.
+func (rb *ReactionBuilder) Inhibitor(ib *InhibitorBuilder) *ReactionBuilder {
+	rb.inhibitors = append(rb.inhibitors, ib)
+	return rb
+}
+
 // Effect adds one or more effects to the reaction.
 // Effects define what happens when the reaction fires, such as consuming
 // the input molecule, creating new molecules, or updating existing ones.
-// Accepts EffectBuilder, CreateEffectBuilder, UpdateEffectBuilder, or IfEffectBuilder.
+// Accepts EffectBuilder, CreateEffectBuilder, UpdateEffectBuilder, PromoteEffectBuilder,
+// TransformEffectBuilder, IfEffectBuilder, or ChooseEffectBuilder.
 func (rb *ReactionBuilder) Effect(ebs ...interface{}) *ReactionBuilder {
+	rb.effects = append(rb.effects, effectBuilders(ebs)...)
+	return rb
+}
+
+// effectBuilders converts the effect builders accepted by ReactionBuilder.Effect to
+// EffectBuilders, skipping values of any other type.
+func effectBuilders(ebs []interface{}) []*EffectBuilder {
+	var out []*EffectBuilder
 	for _, e := range ebs {
 		switch v := e.(type) {
 		case *EffectBuilder:
-			rb.effects = append(rb.effects, v)
+			out = append(out, v)
 		case *CreateEffectBuilder:
-			rb.effects = append(rb.effects, &EffectBuilder{create: v})
+			out = append(out, &EffectBuilder{create: v})
 		case *UpdateEffectBuilder:
-			rb.effects = append(rb.effects, &EffectBuilder{update: v})
+			out = append(out, &EffectBuilder{update: v})
+		case *PromoteEffectBuilder:
+			out = append(out, &EffectBuilder{promote: v})
+		case *TransformEffectBuilder:
+			out = append(out, &EffectBuilder{transform: v})
 		case *IfEffectBuilder:
-			rb.effects = append(rb.effects, &EffectBuilder{ifCond: v.ifCond})
+			out = append(out, &EffectBuilder{ifCond: v.ifCond})
+		case *ChooseEffectBuilder:
+			out = append(out, &EffectBuilder{choose: v})
 		}
 	}
+	return out
+}
+
+// MaxFiresPerTick limits how many times the reaction can fire in a single tick.
+// Zero (the default) means unlimited.
+func (rb *ReactionBuilder) MaxFiresPerTick(n int) *ReactionBuilder {
+	rb.maxFires = n
+	return rb
+}
+
+// Cooldown makes a molecule ineligible for the reaction for the given number of
+// ticks after it fired it. Zero (the default) means no cooldown.
+func (rb *ReactionBuilder) Cooldown(ticks int) *ReactionBuilder {
+	rb.cooldown = ticks
+	return rb
+}
+
+// Priority sets the reaction priority. Within a tick, reactions with a higher
+// priority process each molecule first; ties keep declaration order.
+func (rb *ReactionBuilder) Priority(p int) *ReactionBuilder {
+	rb.priority = p
 	return rb
 }
 
@@ -167,18 +255,28 @@ func (rb *ReactionBuilder) Build() achem.ReactionConfig {
 		catalysts = append(catalysts, cb.Build())
 	}
 
+	var inhibitors []achem.InhibitorConfig
+	for _, ib := range rb.inhibitors {
+		inhibitors = append(inhibitors, ib.Build())
+	}
+
 	effects := make([]achem.EffectConfig, 0, len(rb.effects))
 	for _, eb := range rb.effects {
 		effects = append(effects, eb.Build())
 	}
 
 	reactionCfg := achem.ReactionConfig{
-		ID:        rb.id,
-		Name:      rb.name,
-		Input:     input,
-		Rate:      rb.rate,
-		Catalysts: catalysts,
-		Effects:   effects,
+		ID:         rb.id,
+		Name:       rb.name,
+		Input:      input,
+		Rate:       rb.rate,
+		Catalysts:  catalysts,
+		Inhibitors: inhibitors,
+		Effects:    effects,
+
+		MaxFiresPerTick: rb.maxFires,
+		CooldownTicks:   rb.cooldown,
+		Priority:        rb.priority,
 	}
 
 	if rb.notify != nil {
@@ -192,9 +290,11 @@ func (rb *ReactionBuilder) Build() achem.ReactionConfig {
 // Inputs define which molecules can trigger a reaction, including
 // species filtering, where conditions, and partner requirements.
 type InputBuilder struct {
-	species  string
-	where    achem.WhereConfig
-	partners []*PartnerBuilder
+	species    string
+	anySpecies []string
+	where      achem.WhereConfig
+	partners   []*PartnerBuilder
+	count      int
 }
 
 // NewInput creates a new input builder for the specified species.
@@ -218,6 +318,68 @@ func WhereEq(field string, value any) func(*InputBuilder) {
 	}
 }
 
+// WhereOr is a helper function that returns a function adding a "$or" group to an
+// input builder: a molecule matches if it satisfies any of the given clauses. Each
+// argument is one clause; use WhereAnd to make a clause out of several conditions.
+//
+//	Input("Event", WhereOr(WhereEq("type", "login_failed"), WhereEq("type", "password_reset")))
+func WhereOr(clauses ...func(*InputBuilder)) func(*InputBuilder) {
+	return func(ib *InputBuilder) {
+		ib.where = addWhereGroup(ib.where, achem.WhereKeyOr, whereClauses(clauses))
+	}
+}
+
+// WhereAnd is a helper function that returns a function adding a "$and" group to an
+// input builder: a molecule matches if it satisfies all of the given clauses. It is
+// mostly useful inside WhereOr, since top-level conditions are already combined with AND.
+func WhereAnd(clauses ...func(*InputBuilder)) func(*InputBuilder) {
+	return func(ib *InputBuilder) {
+		ib.where = addWhereGroup(ib.where, achem.WhereKeyAnd, whereClauses(clauses))
+	}
+}
+
+// whereClauses builds one where clause per function, each on its own input builder.
+func whereClauses(fns []func(*InputBuilder)) []achem.WhereConfig {
+	clauses := make([]achem.WhereConfig, 0, len(fns))
+	for _, fn := range fns {
+		clause := NewInput("")
+		fn(clause)
+		clauses = append(clauses, clause.where)
+	}
+	return clauses
+}
+
+// addWhereGroup adds a "$or" or "$and" group to where. A second "$and" group is merged
+// into the first; a second "$or" group is ANDed with the first one through "$and".
+func addWhereGroup(where achem.WhereConfig, key string, clauses []achem.WhereConfig) achem.WhereConfig {
+	if where == nil {
+		where = make(achem.WhereConfig)
+	}
+	existing, ok := where[key]
+	switch {
+	case !ok:
+		where[key] = achem.EqCondition{Clauses: clauses}
+	case key == achem.WhereKeyAnd:
+		existing.Clauses = append(existing.Clauses, clauses...)
+		where[key] = existing
+	default:
+		where = addWhereGroup(where, achem.WhereKeyAnd, []achem.WhereConfig{{key: {Clauses: clauses}}})
+	}
+	return where
+}
+
+// WhereOr adds a "$or" group to the where clause (see the WhereOr function).
+func (ib *InputBuilder) WhereOr(clauses ...func(*InputBuilder)) *InputBuilder {
+	WhereOr(clauses...)(ib)
+	return ib
+}
+
+// WhereAnd adds a "$and" group to the where clause (see the WhereAnd function).
+func (ib *InputBuilder) WhereAnd(clauses ...func(*InputBuilder)) *InputBuilder {
+	WhereAnd(clauses...)(ib)
+	return ib
+}
+
 // WhereEq adds an equality condition to the where clause.
 // Only molecules with the specified field matching the value will match.
 func (ib *InputBuilder) WhereEq(field string, value any) *InputBuilder {
@@ -228,6 +390,82 @@ func (ib *InputBuilder) WhereEq(field string, value any) *InputBuilder {
 	return ib
 }
 
+// WhereNe adds an inequality condition to the where clause.
+// Only molecules with the specified field set to a different value will match.
+func (ib *InputBuilder) WhereNe(field string, value any) *InputBuilder {
+	if ib.where == nil {
+		ib.where = make(achem.WhereConfig)
+	}
+	ib.where[field] = achem.EqCondition{Ne: value}
+	return ib
+}
+
+// WhereIn adds a membership condition to the where clause.
+// Only molecules with the specified field equal to one of the values will match.
+func (ib *InputBuilder) WhereIn(field string, values ...any) *InputBuilder {
+	if ib.where == nil {
+		ib.where = make(achem.WhereConfig)
+	}
+	ib.where[field] = achem.EqCondition{In: append([]any{}, values...)}
+	return ib
+}
+
+// WhereNin adds an exclusion condition to the where clause.
+// Only molecules with the specified field equal to none of the values will match.
+func (ib *InputBuilder) WhereNin(field string, values ...any) *InputBuilder {
+	if ib.where == nil {
+		ib.where = make(achem.WhereConfig)
+	}
+	ib.where[field] = achem.EqCondition{Nin: append([]any{}, values...)}
+	return ib
+}
+
+// WhereRegex adds a pattern condition to the where clause: only molecules with the
+// specified field matching the regular expression (RE2 syntax) will match. Non-string
+// values are matched against their string form, and invalid patterns are reported
+// when the schema is validated.
+func (ib *InputBuilder) WhereRegex(field, pattern string) *InputBuilder {
+	ib.where = whereRange(ib.where, field, func(c *achem.EqCondition) { c.Regex = pattern })
+	return ib
+}
+
+// WhereGt adds a range condition to the where clause: only molecules with the
+// specified field greater than value will match. Range conditions on the same
+// field are combined, e.g. WhereGte("score", 1).WhereLt("score", 10).
+func (ib *InputBuilder) WhereGt(field string, value any) *InputBuilder {
+	ib.where = whereRange(ib.where, field, func(c *achem.EqCondition) { c.Gt = value })
+	return ib
+}
+
+// WhereGte adds a "greater than or equal" range condition to the where clause.
+func (ib *InputBuilder) WhereGte(field string, value any) *InputBuilder {
+	ib.where = whereRange(ib.where, field, func(c *achem.EqCondition) { c.Gte = value })
+	return ib
+}
+
+// WhereLt adds a "lower than" range condition to the where clause.
+func (ib *InputBuilder) WhereLt(field string, value any) *InputBuilder {
+	ib.where = whereRange(ib.where, field, func(c *achem.EqCondition) { c.Lt = value })
+	return ib
+}
+
+// WhereLte adds a "lower than or equal" range condition to the where clause.
+func (ib *InputBuilder) WhereLte(field string, value any) *InputBuilder {
+	ib.where = whereRange(ib.where, field, func(c *achem.EqCondition) { c.Lte = value })
+	return ib
+}
+
+// whereRange sets a range bound on the condition of field, keeping its other operators.
+func whereRange(where achem.WhereConfig, field string, set func(*achem.EqCondition)) achem.WhereConfig {
+	if where == nil {
+		where = make(achem.WhereConfig)
+	}
+	cond := where[field]
+	set(&cond)
+	where[field] = cond
+	return where
+}
+
 // Partner adds a partner molecule requirement to the input.
 // Partners are additional molecules that must be present for the reaction to fire.
 func (ib *InputBuilder) Partner(pb *PartnerBuilder) *InputBuilder {
@@ -235,6 +473,13 @@ func (ib *InputBuilder) Partner(pb *PartnerBuilder) *InputBuilder {
 	return ib
 }
 
+// Count sets how many input molecules the reaction takes at once, e.g. 3 for "3 A -> 1 B".
+// The default is 1 if not specified.
+func (ib *InputBuilder) Count(count int) *InputBuilder {
+	ib.count = count
+	return ib
+}
+
 // Build converts the builder to an InputConfig.
 func (ib *InputBuilder) Build() achem.InputConfig {
 	partners := make([]achem.PartnerConfig, 0, len(ib.partners))
@@ -243,18 +488,23 @@ func (ib *InputBuilder) Build() achem.InputConfig {
 	}
 
 	return achem.InputConfig{
-		Species:  ib.species,
-		Where:    ib.where,
-		Partners: partners,
+		Species:    ib.species,
+		AnySpecies: ib.anySpecies,
+		Where:      ib.where,
+		Partners:   partners,
+		Count:      ib.count,
 	}
 }
 
 // PartnerBuilder provides a fluent API for building partner molecule configurations.
 // Partners are additional molecules required for a reaction to fire.
 type PartnerBuilder struct {
-	species string
-	where   achem.WhereConfig
-	count   int
+	species             string
+	where               achem.WhereConfig
+	count               int
+	selectOrder         string
+	withinDistance      float64
+	includeUnpositioned bool
 }
 
 // NewPartner creates a new partner builder for the specified species.
@@ -275,6 +525,31 @@ func (pb *PartnerBuilder) WhereEq(field string, value any) *PartnerBuilder {
 	return pb
 }
 
+// WhereGt adds a "greater than" range condition to filter partner molecules.
+// Range conditions on the same field are combined.
+func (pb *PartnerBuilder) WhereGt(field string, value any) *PartnerBuilder {
+	pb.where = whereRange(pb.where, field, func(c *achem.EqCondition) { c.Gt = value })
+	return pb
+}
+
+// WhereGte adds a "greater than or equal" range condition to filter partner molecules.
+func (pb *PartnerBuilder) WhereGte(field string, value any) *PartnerBuilder {
+	pb.where = whereRange(pb.where, field, func(c *achem.EqCondition) { c.Gte = value })
+	return pb
+}
+
+// WhereLt adds a "lower than" range condition to filter partner molecules.
+func (pb *PartnerBuilder) WhereLt(field string, value any) *PartnerBuilder {
+	pb.where = whereRange(pb.where, field, func(c *achem.EqCondition) { c.Lt = value })
+	return pb
+}
+
+// WhereLte adds a "lower than or equal" range condition to filter partner molecules.
+func (pb *PartnerBuilder) WhereLte(field string, value any) *PartnerBuilder {
+	pb.where = whereRange(pb.where, field, func(c *achem.EqCondition) { c.Lte = value })
+	return pb
+}
+
 // Count sets the required number of partner molecules.
 // The default is 1 if not specified.
 func (pb *PartnerBuilder) Count(count int) *PartnerBuilder {
@@ -282,12 +557,36 @@ func (pb *PartnerBuilder) Count(count int) *PartnerBuilder {
 	return pb
 }
 
+// Select sets which partners are picked when more molecules match than Count, e.g.
+// achem.SelectOldest or achem.SelectLowestEnergy. By default the first ones found are
+// picked.
+func (pb *PartnerBuilder) Select(order string) *PartnerBuilder {
+	pb.selectOrder = order
+	return pb
+}
+
+// WithinDistance only considers partners at most radius away from the input
+// molecule. Molecules without a position are ignored, unless IncludeUnpositioned is set.
+func (pb *PartnerBuilder) WithinDistance(radius float64) *PartnerBuilder {
+	pb.withinDistance = radius
+	return pb
+}
+
+// IncludeUnpositioned lets molecules without a position pass the WithinDistance filter.
+func (pb *PartnerBuilder) IncludeUnpositioned() *PartnerBuilder {
+	pb.includeUnpositioned = true
+	return pb
+}
+
 // Build converts the builder to a PartnerConfig.
 func (pb *PartnerBuilder) Build() achem.PartnerConfig {
 	return achem.PartnerConfig{
-		Species: pb.species,
-		Where:   pb.where,
-		Count:   pb.count,
+		Species:             pb.species,
+		Where:               pb.where,
+		Count:               pb.count,
+		Select:              pb.selectOrder,
+		WithinDistance:      pb.withinDistance,
+		IncludeUnpositioned: pb.includeUnpositioned,
 	}
 }
 
@@ -295,10 +594,13 @@ func (pb *PartnerBuilder) Build() achem.PartnerConfig {
 // Catalysts increase the reaction rate when matching molecules are present
 // in the environment.
 type CatalystBuilder struct {
-	species   string
-	where     achem.WhereConfig
-	rateBoost float64
-	maxRate   *float64
+	species             string
+	where               achem.WhereConfig
+	rateBoost           float64
+	maxRate             *float64
+	mode                string
+	withinDistance      float64
+	includeUnpositioned bool
 }
 
 // NewCatalyst creates a new catalyst builder for the specified species.
@@ -333,13 +635,81 @@ func (cb *CatalystBuilder) MaxRate(max float64) *CatalystBuilder {
 	return cb
 }
 
+// Mode sets how the rate boost is applied: achem.CatalystModeAdd (the default)
+// adds it once when any catalyst matches, achem.CatalystModeMultiply multiplies
+// the rate by 1 + boost × the number of matching catalyst molecules.
+func (cb *CatalystBuilder) Mode(mode string) *CatalystBuilder {
+	cb.mode = mode
+	return cb
+}
+
+// WithinDistance only considers catalysts at most radius away from the input
+// molecule. Molecules without a position are ignored, unless IncludeUnpositioned is set.
+func (cb *CatalystBuilder) WithinDistance(radius float64) *CatalystBuilder {
+	cb.withinDistance = radius
+	return cb
+}
+
+// IncludeUnpositioned lets molecules without a position pass the WithinDistance filter.
+func (cb *CatalystBuilder) IncludeUnpositioned() *CatalystBuilder {
+	cb.includeUnpositioned = true
+	return cb
+}
+
 // Build converts the builder to a CatalystConfig.
 func (cb *CatalystBuilder) Build() achem.CatalystConfig {
 	return achem.CatalystConfig{
-		Species:   cb.species,
-		Where:     cb.where,
-		RateBoost: cb.rateBoost,
-		MaxRate:   cb.maxRate,
+		Species:             cb.species,
+		Where:               cb.where,
+		RateBoost:           cb.rateBoost,
+		MaxRate:             cb.maxRate,
+		Mode:                cb.mode,
+		WithinDistance:      cb.withinDistance,
+		IncludeUnpositioned: cb.includeUnpositioned,
+	}
+}
+
+// InhibitorBuilder provides a fluent API for building inhibitor configurations.
+// Inhibitors decrease the reaction rate when matching molecules are present
+// in the environment, e.g. to suppress alerts while a Suppression exists.
+type InhibitorBuilder struct {
+	species       string
+	where         achem.WhereConfig
+	rateReduction float64
+}
+
+// NewInhibitor creates a new inhibitor builder for the specified species.
+func NewInhibitor(species string) *InhibitorBuilder {
+	return &InhibitorBuilder{
+		species:       species,
+		where:         make(achem.WhereConfig),
+		rateReduction: 0.1, // Default reduction
+	}
+}
+
+// WhereEq adds an equality condition to filter inhibitor molecules.
+func (ib *InhibitorBuilder) WhereEq(field string, value any) *InhibitorBuilder {
+	if ib.where == nil {
+		ib.where = make(achem.WhereConfig)
+	}
+	ib.where[field] = achem.EqCondition{Eq: value}
+	return ib
+}
+
+// RateReduction sets the amount by which the reaction rate is decreased
+// when a matching inhibitor is present. The default is 0.1; the rate
+// never goes below 0.
+func (ib *InhibitorBuilder) RateReduction(reduction float64) *InhibitorBuilder {
+	ib.rateReduction = reduction
+	return ib
+}
+
+// Build converts the builder to an InhibitorConfig.
+func (ib *InhibitorBuilder) Build() achem.InhibitorConfig {
+	return achem.InhibitorConfig{
+		Species:       ib.species,
+		Where:         ib.where,
+		RateReduction: ib.rateReduction,
 	}
 }
 
@@ -347,10 +717,14 @@ func (cb *CatalystBuilder) Build() achem.CatalystConfig {
 // Effects define what happens when a reaction fires, such as consuming
 // molecules, creating new ones, or updating existing ones.
 type EffectBuilder struct {
-	consume bool
-	create  *CreateEffectBuilder
-	update  *UpdateEffectBuilder
-	ifCond  *IfConditionBuilder
+	consume   bool
+	create    *CreateEffectBuilder
+	update    *UpdateEffectBuilder
+	promote   *PromoteEffectBuilder
+	transform *TransformEffectBuilder
+	requeue   *int
+	ifCond    *IfConditionBuilder
+	choose    *ChooseEffectBuilder
 }
 
 // Consume creates an effect that consumes (removes) the input molecule
@@ -376,6 +750,30 @@ func Update() *UpdateEffectBuilder {
 	return &UpdateEffectBuilder{}
 }
 
+// Promote creates an effect builder that moves the input molecule to the next
+// species of the given ladder (lowest first) when the reaction fires.
+func Promote(ladder ...string) *PromoteEffectBuilder {
+	return &PromoteEffectBuilder{
+		ladder: ladder,
+	}
+}
+
+// Transform creates an effect builder that renames the species of the input
+// molecule in place, keeping its ID, payload and timestamps.
+func Transform(species string) *TransformEffectBuilder {
+	return &TransformEffectBuilder{
+		species: species,
+	}
+}
+
+// Requeue creates an effect that makes the input molecule react again within
+// the same tick, with its updates applied, at most maxIterations more times.
+func Requeue(maxIterations int) *EffectBuilder {
+	return &EffectBuilder{
+		requeue: &maxIterations,
+	}
+}
+
 // If creates a conditional effect builder that executes different effects
 // based on a condition. Use NewIfField or NewIfCount to create the condition.
 func If(icb *IfConditionBuilder) *IfEffectBuilder {
@@ -384,6 +782,44 @@ func If(icb *IfConditionBuilder) *IfEffectBuilder {
 	}
 }
 
+// Choose creates an effect builder that applies the effects of one of several
+// weighted branches, picked at random each time the reaction fires. Add the
+// branches with When.
+func Choose() *ChooseEffectBuilder {
+	return &ChooseEffectBuilder{}
+}
+
+// ChooseEffectBuilder provides a fluent API for building choose effects.
+type ChooseEffectBuilder struct {
+	branches []chooseBranch
+}
+
+type chooseBranch struct {
+	weight  float64
+	effects []*EffectBuilder
+}
+
+// When adds a branch picked with a probability proportional to weight (weights don't
+// need to sum to 1). Accepts the same effect builders as ReactionBuilder.Effect.
+// Example: Choose().When(70, Create("A")).When(30, Create("B")).
+func (ceb *ChooseEffectBuilder) When(weight float64, ebs ...interface{}) *ChooseEffectBuilder {
+	ceb.branches = append(ceb.branches, chooseBranch{weight: weight, effects: effectBuilders(ebs)})
+	return ceb
+}
+
+// Build converts the builder to a ChooseEffectConfig.
+func (ceb *ChooseEffectBuilder) Build() *achem.ChooseEffectConfig {
+	cfg := &achem.ChooseEffectConfig{Branches: make([]achem.WeightedBranch, 0, len(ceb.branches))}
+	for _, b := range ceb.branches {
+		branch := achem.WeightedBranch{Weight: b.weight, Effects: make([]achem.EffectConfig, 0, len(b.effects))}
+		for _, eb := range b.effects {
+			branch.Effects = append(branch.Effects, eb.Build())
+		}
+		cfg.Branches = append(cfg.Branches, branch)
+	}
+	return cfg
+}
+
 // IfEffectBuilder wraps an IfConditionBuilder to provide Then/Else methods
 // for conditional effect execution.
 type IfEffectBuilder struct {
@@ -391,34 +827,16 @@ type IfEffectBuilder struct {
 }
 
 // Then adds effects to execute if the condition is true.
-// Accepts EffectBuilder, CreateEffectBuilder, or UpdateEffectBuilder.
+// Accepts the same effect builders as ReactionBuilder.Effect.
 func (ieb *IfEffectBuilder) Then(ebs ...interface{}) *IfEffectBuilder {
-	for _, e := range ebs {
-		switch v := e.(type) {
-		case *EffectBuilder:
-			ieb.ifCond.then = append(ieb.ifCond.then, v)
-		case *CreateEffectBuilder:
-			ieb.ifCond.then = append(ieb.ifCond.then, &EffectBuilder{create: v})
-		case *UpdateEffectBuilder:
-			ieb.ifCond.then = append(ieb.ifCond.then, &EffectBuilder{update: v})
-		}
-	}
+	ieb.ifCond.then = append(ieb.ifCond.then, effectBuilders(ebs)...)
 	return ieb
 }
 
 // Else adds effects to execute if the condition is false.
-// Accepts EffectBuilder, CreateEffectBuilder, or UpdateEffectBuilder.
+// Accepts the same effect builders as ReactionBuilder.Effect.
 func (ieb *IfEffectBuilder) Else(ebs ...interface{}) *IfEffectBuilder {
-	for _, e := range ebs {
-		switch v := e.(type) {
-		case *EffectBuilder:
-			ieb.ifCond.else_ = append(ieb.ifCond.else_, v)
-		case *CreateEffectBuilder:
-			ieb.ifCond.else_ = append(ieb.ifCond.else_, &EffectBuilder{create: v})
-		case *UpdateEffectBuilder:
-			ieb.ifCond.else_ = append(ieb.ifCond.else_, &EffectBuilder{update: v})
-		}
-	}
+	ieb.ifCond.else_ = append(ieb.ifCond.else_, effectBuilders(ebs)...)
 	return ieb
 }
 
@@ -436,6 +854,22 @@ func (eb *EffectBuilder) Build() achem.EffectConfig {
 		effect.Update = eb.update.Build()
 	}
 
+	if eb.promote != nil {
+		effect.Promote = eb.promote.Build()
+	}
+
+	if eb.transform != nil {
+		effect.Transform = eb.transform.Build()
+	}
+
+	if eb.requeue != nil {
+		effect.Requeue = &achem.RequeueEffectConfig{MaxIterations: *eb.requeue}
+	}
+
+	if eb.choose != nil {
+		effect.Choose = eb.choose.Build()
+	}
+
 	if eb.ifCond != nil {
 		effect.If = eb.ifCond.Build()
 		effect.Then = make([]achem.EffectConfig, 0, len(eb.ifCond.then))
@@ -454,10 +888,13 @@ func (eb *EffectBuilder) Build() achem.EffectConfig {
 // CreateEffectBuilder provides a fluent API for building create effects.
 // Create effects generate new molecules when a reaction fires.
 type CreateEffectBuilder struct {
-	species   string
-	payload   map[string]any
-	energy    *float64
-	stability *float64
+	species    string
+	payload    map[string]any
+	energy     *float64
+	stability  *float64
+	ttl        int64
+	emitTo     string
+	emitToMany []string
 }
 
 // Payload adds a field to the payload of the created molecule.
@@ -485,20 +922,46 @@ func (ceb *CreateEffectBuilder) Stability(stability float64) *CreateEffectBuilde
 	return ceb
 }
 
+// TTL makes the created molecule expire the given number of ticks after
+// its creation. If not set, the molecule never expires.
+func (ceb *CreateEffectBuilder) TTL(ticks int64) *CreateEffectBuilder {
+	ceb.ttl = ticks
+	return ceb
+}
+
+// EmitTo routes the created molecule to another environment instead of
+// the one where the reaction fired.
+func (ceb *CreateEffectBuilder) EmitTo(envID string) *CreateEffectBuilder {
+	ceb.emitTo = envID
+	return ceb
+}
+
+// EmitToMany fans out the created molecule to several environments at once.
+// Each target environment receives its own copy; missing targets are skipped.
+func (ceb *CreateEffectBuilder) EmitToMany(envIDs ...string) *CreateEffectBuilder {
+	ceb.emitToMany = append(ceb.emitToMany, envIDs...)
+	return ceb
+}
+
 // Build converts the builder to a CreateEffectConfig.
 func (ceb *CreateEffectBuilder) Build() *achem.CreateEffectConfig {
 	return &achem.CreateEffectConfig{
-		Species:   ceb.species,
-		Payload:   ceb.payload,
-		Energy:    ceb.energy,
-		Stability: ceb.stability,
+		Species:    ceb.species,
+		Payload:    ceb.payload,
+		Energy:     ceb.energy,
+		Stability:  ceb.stability,
+		TTL:        ceb.ttl,
+		EmitTo:     ceb.emitTo,
+		EmitToMany: ceb.emitToMany,
 	}
 }
 
 // UpdateEffectBuilder provides a fluent API for building update effects.
 // Update effects modify existing molecules when a reaction fires.
 type UpdateEffectBuilder struct {
-	energyAdd *float64
+	energyAdd        *float64
+	payloadSet       map[string]any
+	payloadIncrement map[string]float64
 }
 
 // EnergyAdd sets the amount to add to the molecule's energy.
@@ -508,10 +971,79 @@ func (ueb *UpdateEffectBuilder) EnergyAdd(amount float64) *UpdateEffectBuilder {
 	return ueb
 }
 
+// PayloadSet sets a payload field of the molecule, overriding the existing value.
+// The value can be a literal or a reference using Ref() to the input molecule.
+func (ueb *UpdateEffectBuilder) PayloadSet(field string, value any) *UpdateEffectBuilder {
+	if ueb.payloadSet == nil {
+		ueb.payloadSet = make(map[string]any)
+	}
+	ueb.payloadSet[field] = value
+	return ueb
+}
+
+// PayloadIncrement adds amount to a numeric payload field of the molecule.
+// A missing field is created with the given amount.
+func (ueb *UpdateEffectBuilder) PayloadIncrement(field string, amount float64) *UpdateEffectBuilder {
+	if ueb.payloadIncrement == nil {
+		ueb.payloadIncrement = make(map[string]float64)
+	}
+	ueb.payloadIncrement[field] = amount
+	return ueb
+}
+
 // Build converts the builder to an UpdateEffectConfig.
 func (ueb *UpdateEffectBuilder) Build() *achem.UpdateEffectConfig {
 	return &achem.UpdateEffectConfig{
-		EnergyAdd: ueb.energyAdd,
+		EnergyAdd:        ueb.energyAdd,
+		PayloadSet:       ueb.payloadSet,
+		PayloadIncrement: ueb.payloadIncrement,
+	}
+}
+
+// PromoteEffectBuilder provides a fluent API for building promote effects.
+// Promote effects move a molecule one step up a species ladder, keeping its ID.
+type PromoteEffectBuilder struct {
+	ladder []string
+	carry  []string
+}
+
+// Carry restricts the payload carried forward to the given fields.
+// If not set, the whole payload is carried.
+func (peb *PromoteEffectBuilder) Carry(fields ...string) *PromoteEffectBuilder {
+	peb.carry = append(peb.carry, fields...)
+	return peb
+}
+
+// Build converts the builder to a PromoteEffectConfig.
+func (peb *PromoteEffectBuilder) Build() *achem.PromoteEffectConfig {
+	return &achem.PromoteEffectConfig{
+		Ladder: peb.ladder,
+		Carry:  peb.carry,
+	}
+}
+
+// TransformEffectBuilder provides a fluent API for building transform effects.
+// Transform effects change the species of a molecule while preserving its identity.
+type TransformEffectBuilder struct {
+	species string
+	payload map[string]any
+}
+
+// Payload sets a field on the transformed molecule, overriding the existing value.
+// The value can be a literal or a reference using Ref() to the input molecule.
+func (teb *TransformEffectBuilder) Payload(field string, value any) *TransformEffectBuilder {
+	if teb.payload == nil {
+		teb.payload = make(map[string]any)
+	}
+	teb.payload[field] = value
+	return teb
+}
+
+// Build converts the builder to a TransformEffectConfig.
+func (teb *TransformEffectBuilder) Build() *achem.TransformEffectConfig {
+	return &achem.TransformEffectConfig{
+		Species: teb.species,
+		Payload: teb.payload,
 	}
 }
 
@@ -582,6 +1114,9 @@ type CountMoleculesBuilder struct {
 	species string
 	where   achem.WhereConfig
 	op      map[string]any
+	agg     string
+	field   string
+	within  int64
 }
 
 // NewCountMolecules creates a new count molecules builder for the specified species.
@@ -613,12 +1148,32 @@ func (cmb *CountMoleculesBuilder) Op(operator string, value any) *CountMolecules
 	return cmb
 }
 
+// Agg compares an aggregate of field over the matching molecules instead of their
+// count. Supported aggregations: achem.AggSum, achem.AggAvg, achem.AggMin and
+// achem.AggMax (achem.AggCount, the default, takes no field).
+// Example: Agg(achem.AggSum, "energy").Op("gt", 10) means "sum of energy > 10".
+func (cmb *CountMoleculesBuilder) Agg(op string, field string) *CountMoleculesBuilder {
+	cmb.agg = op
+	cmb.field = field
+	return cmb
+}
+
+// WithinTicks only considers molecules created in the last ticks ticks, for rate-based
+// conditions. Example: WithinTicks(50).Op("gte", 3) means "at least 3 in the last 50 ticks".
+func (cmb *CountMoleculesBuilder) WithinTicks(ticks int64) *CountMoleculesBuilder {
+	cmb.within = ticks
+	return cmb
+}
+
 // Build converts the builder to a CountMoleculesConfig.
 func (cmb *CountMoleculesBuilder) Build() *achem.CountMoleculesConfig {
 	return &achem.CountMoleculesConfig{
-		Species: cmb.species,
-		Where:   cmb.where,
-		Op:      cmb.op,
+		Species:     cmb.species,
+		Where:       cmb.where,
+		Op:          cmb.op,
+		Agg:         cmb.agg,
+		Field:       cmb.field,
+		WithinTicks: cmb.within,
 	}
 }
 
@@ -645,33 +1200,8 @@ func ApplySchema(ctx context.Context, baseURL, envID string, schema *SchemaBuild
 		return fmt.Errorf("failed to marshal schema: %w", err)
 	}
 
-	// Build URL
-	u, err := url.JoinPath(baseURL, "env", envID, "schema")
-	if err != nil {
-		return fmt.Errorf("failed to build URL: %w", err)
-	}
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	_, err = sendRequest(ctx, http.MethodPost, baseURL, nil, jsonData, "env", envID, "schema")
+	return err
 }
 
 // NotificationBuilder provides a fluent API for building notification configurations.
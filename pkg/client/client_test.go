@@ -1,6 +1,7 @@
 package client
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/daniacca/achemdb/internal/achem"
@@ -26,6 +27,38 @@ func TestSchemaBuilder(t *testing.T) {
 	}
 }
 
+func TestSchemaBuilder_SpeciesDefaults(t *testing.T) {
+	cfg := NewSchema("test-schema").
+		Species("Session", "", nil, DefaultEnergy(10), DefaultStability(0.5)).
+		Species("Event", "", nil).
+		Build()
+
+	sp := cfg.Species[0]
+	if sp.DefaultEnergy == nil || *sp.DefaultEnergy != 10 || sp.DefaultStability == nil || *sp.DefaultStability != 0.5 {
+		t.Errorf("Expected defaults energy 10 and stability 0.5, got %+v", sp)
+	}
+	if cfg.Species[1].DefaultEnergy != nil || cfg.Species[1].DefaultStability != nil {
+		t.Errorf("Expected no defaults without options, got %+v", cfg.Species[1])
+	}
+}
+
+func TestSchemaBuilder_SpeciesFields(t *testing.T) {
+	cfg := NewSchema("test-schema").
+		Species("Order", "", nil, Field("price", achem.FieldTypeNumber, true), Field("note", achem.FieldTypeString, false)).
+		Build()
+
+	want := map[string]achem.FieldSpec{
+		"price": {Type: achem.FieldTypeNumber, Required: true},
+		"note":  {Type: achem.FieldTypeString},
+	}
+	if !reflect.DeepEqual(cfg.Species[0].Fields, want) {
+		t.Errorf("Expected fields %+v, got %+v", want, cfg.Species[0].Fields)
+	}
+	if _, err := achem.BuildSchemaFromConfig(cfg); err != nil {
+		t.Errorf("Expected a valid schema, got %v", err)
+	}
+}
+
 func TestReactionBuilder(t *testing.T) {
 	reaction := NewReaction("test_reaction").
 		Name("Test Reaction").
@@ -74,6 +107,84 @@ func TestReactionBuilder(t *testing.T) {
 	}
 }
 
+func TestReactionBuilder_MaxFiresPerTick(t *testing.T) {
+	cfg := NewReaction("r").Input("A").MaxFiresPerTick(5).Build()
+	if cfg.MaxFiresPerTick != 5 {
+		t.Errorf("Expected MaxFiresPerTick 5, got %d", cfg.MaxFiresPerTick)
+	}
+	if cfg := NewReaction("r").Input("A").Build(); cfg.MaxFiresPerTick != 0 {
+		t.Errorf("Expected unlimited by default, got %d", cfg.MaxFiresPerTick)
+	}
+}
+
+func TestReactionBuilder_Cooldown(t *testing.T) {
+	cfg := NewReaction("r").Input("A").Cooldown(3).Build()
+	if cfg.CooldownTicks != 3 {
+		t.Errorf("Expected CooldownTicks 3, got %d", cfg.CooldownTicks)
+	}
+	if cfg := NewReaction("r").Input("A").Build(); cfg.CooldownTicks != 0 {
+		t.Errorf("Expected no cooldown by default, got %d", cfg.CooldownTicks)
+	}
+}
+
+func TestReactionBuilder_InputAny(t *testing.T) {
+	cfg := NewReaction("r").InputAny("Error", "Warning").Build()
+	if cfg.Input.Species != "" || !reflect.DeepEqual(cfg.Input.AnySpecies, []string{"Error", "Warning"}) {
+		t.Errorf("Expected any_species [Error Warning], got %+v", cfg.Input)
+	}
+	if cfg := NewReaction("r").Input("A").Build(); cfg.Input.AnySpecies != nil {
+		t.Errorf("Expected no any_species with Input, got %v", cfg.Input.AnySpecies)
+	}
+}
+
+func TestReactionBuilder_Priority(t *testing.T) {
+	cfg := NewReaction("r").Input("A").Priority(7).Build()
+	if cfg.Priority != 7 {
+		t.Errorf("Expected Priority 7, got %d", cfg.Priority)
+	}
+}
+
+func TestWhereOrAnd(t *testing.T) {
+	cfg := NewReaction("r").
+		Input("Event",
+			WhereEq("source", "auth"),
+			WhereOr(
+				WhereEq("type", "login_failed"),
+				WhereAnd(WhereEq("type", "password_reset"), WhereEq("admin", true)),
+			),
+		).
+		Build()
+
+	where := cfg.Input.Where
+	if where["source"].Eq != "auth" {
+		t.Errorf("Expected the flat condition to be kept, got %+v", where)
+	}
+	or := where[achem.WhereKeyOr].Clauses
+	if len(or) != 2 || or[0]["type"].Eq != "login_failed" {
+		t.Fatalf("Unexpected $or clauses: %+v", or)
+	}
+	and := or[1][achem.WhereKeyAnd].Clauses
+	if len(and) != 2 || and[0]["type"].Eq != "password_reset" || and[1]["admin"].Eq != true {
+		t.Errorf("Unexpected nested $and clauses: %+v", and)
+	}
+
+	// a second $or is ANDed with the first one
+	ib := NewInput("Event").
+		WhereOr(WhereEq("a", 1), WhereEq("a", 2)).
+		WhereOr(WhereEq("b", 1), WhereEq("b", 2))
+	built := ib.Build().Where
+	if len(built[achem.WhereKeyOr].Clauses) != 2 || len(built[achem.WhereKeyAnd].Clauses) != 1 {
+		t.Errorf("Expected the second $or to be nested in $and, got %+v", built)
+	}
+}
+
+func TestInputBuilder_WhereRegex(t *testing.T) {
+	where := NewInput("Request").WhereRegex("path", `^/admin/`).WhereNe("method", "GET").Build().Where
+	if where["path"].Regex != `^/admin/` || where["method"].Ne != "GET" {
+		t.Errorf("Unexpected where: %+v", where)
+	}
+}
+
 func TestInputBuilder(t *testing.T) {
 	input := NewInput("TestSpecies").
 		WhereEq("field1", "value1").
@@ -94,6 +205,51 @@ func TestInputBuilder(t *testing.T) {
 	}
 }
 
+func TestInputBuilder_Count(t *testing.T) {
+	if cfg := NewInput("A").Count(3).Build(); cfg.Count != 3 {
+		t.Errorf("Expected count 3, got %d", cfg.Count)
+	}
+}
+
+func TestInputBuilder_SetConditions(t *testing.T) {
+	cfg := NewInput("Event").
+		WhereNe("status", "closed").
+		WhereIn("type", "login_failed", "password_reset").
+		WhereNin("ip", "127.0.0.1").
+		Build()
+
+	if cfg.Where["status"].Ne != "closed" {
+		t.Errorf("Expected status ne 'closed', got %+v", cfg.Where["status"])
+	}
+	if in := cfg.Where["type"].In; len(in) != 2 || in[0] != "login_failed" || in[1] != "password_reset" {
+		t.Errorf("Expected type in [login_failed password_reset], got %+v", cfg.Where["type"])
+	}
+	if nin := cfg.Where["ip"].Nin; len(nin) != 1 || nin[0] != "127.0.0.1" {
+		t.Errorf("Expected ip nin [127.0.0.1], got %+v", cfg.Where["ip"])
+	}
+}
+
+func TestBuilders_RangeConditions(t *testing.T) {
+	input := NewInput("Event").
+		WhereGte("score", 1).
+		WhereLt("score", 10).
+		WhereGt("retries", 2).
+		Build()
+
+	score := input.Where["score"]
+	if score.Gte != 1 || score.Lt != 10 || score.Gt != nil || score.Lte != nil {
+		t.Errorf("Expected score in [1, 10), got %+v", score)
+	}
+	if input.Where["retries"].Gt != 2 {
+		t.Errorf("Expected retries > 2, got %+v", input.Where["retries"])
+	}
+
+	partner := NewPartner("Event").WhereLte("level", 3).Build()
+	if partner.Where["level"].Lte != 3 {
+		t.Errorf("Expected partner level <= 3, got %+v", partner.Where["level"])
+	}
+}
+
 func TestCreateEffectBuilder(t *testing.T) {
 	create := Create("NewSpecies").
 		Payload("key1", "value1").
@@ -122,6 +278,25 @@ func TestCreateEffectBuilder(t *testing.T) {
 	if cfg.Stability == nil || *cfg.Stability != 1.5 {
 		t.Errorf("Expected stability 1.5, got %v", cfg.Stability)
 	}
+
+	if ttl := Create("Alert").TTL(30).Build().TTL; ttl != 30 {
+		t.Errorf("Expected ttl 30, got %d", ttl)
+	}
+}
+
+func TestCreateEffectBuilder_EmitTo(t *testing.T) {
+	cfg := Create("Alert").
+		EmitTo("ops").
+		EmitToMany("team-a", "team-b").
+		Build()
+
+	if cfg.EmitTo != "ops" {
+		t.Errorf("Expected emit_to 'ops', got '%s'", cfg.EmitTo)
+	}
+
+	if len(cfg.EmitToMany) != 2 || cfg.EmitToMany[0] != "team-a" || cfg.EmitToMany[1] != "team-b" {
+		t.Errorf("Expected emit_to_many [team-a team-b], got %v", cfg.EmitToMany)
+	}
 }
 
 func TestUpdateEffectBuilder(t *testing.T) {
@@ -134,6 +309,61 @@ func TestUpdateEffectBuilder(t *testing.T) {
 	}
 }
 
+func TestUpdateEffectBuilder_Payload(t *testing.T) {
+	cfg := Update().PayloadSet("status", "seen").PayloadSet("source", Ref("ip")).PayloadIncrement("hits", 1).Build()
+
+	if cfg.PayloadSet["status"] != "seen" || cfg.PayloadSet["source"] != "$m.ip" {
+		t.Errorf("Expected payload_set status and source, got %v", cfg.PayloadSet)
+	}
+	if cfg.PayloadIncrement["hits"] != 1 {
+		t.Errorf("Expected payload_increment hits=1, got %v", cfg.PayloadIncrement)
+	}
+}
+
+func TestPromoteEffectBuilder(t *testing.T) {
+	rc := NewReaction("escalate").
+		Input("Suspicion").
+		Effect(Promote("Event", "Suspicion", "Alert").Carry("ip")).
+		Build()
+
+	if len(rc.Effects) != 1 || rc.Effects[0].Promote == nil {
+		t.Fatalf("Expected 1 promote effect, got %+v", rc.Effects)
+	}
+	promote := rc.Effects[0].Promote
+	if len(promote.Ladder) != 3 || promote.Ladder[2] != "Alert" {
+		t.Errorf("Expected ladder [Event Suspicion Alert], got %v", promote.Ladder)
+	}
+	if len(promote.Carry) != 1 || promote.Carry[0] != "ip" {
+		t.Errorf("Expected carry [ip], got %v", promote.Carry)
+	}
+}
+
+func TestTransformEffectBuilder(t *testing.T) {
+	rc := NewReaction("confirm").
+		Input("Suspicion").
+		Effect(Transform("Alert").Payload("status", "open").Payload("source", Ref("ip"))).
+		Build()
+
+	if len(rc.Effects) != 1 || rc.Effects[0].Transform == nil {
+		t.Fatalf("Expected 1 transform effect, got %+v", rc.Effects)
+	}
+	transform := rc.Effects[0].Transform
+	if transform.Species != "Alert" {
+		t.Errorf("Expected species Alert, got %s", transform.Species)
+	}
+	if transform.Payload["status"] != "open" || transform.Payload["source"] != "$m.ip" {
+		t.Errorf("Expected payload merges, got %v", transform.Payload)
+	}
+}
+
+func TestRequeueEffectBuilder(t *testing.T) {
+	cfg := Requeue(3).Build()
+
+	if cfg.Requeue == nil || cfg.Requeue.MaxIterations != 3 {
+		t.Errorf("Expected requeue max_iterations 3, got %+v", cfg.Requeue)
+	}
+}
+
 func TestIfConditionBuilder(t *testing.T) {
 	ifEffect := If(NewIfField("energy", "gt", 3.0)).
 		Then(
@@ -172,6 +402,47 @@ func TestIfConditionBuilder(t *testing.T) {
 	}
 }
 
+func TestIfConditionBuilder_NestedEffects(t *testing.T) {
+	// Then and Else accept the same builders as Effect, nested ifs included
+	ifEffect := If(NewIfField("energy", "gt", 3.0)).
+		Then(If(NewIfField("energy", "gt", 10.0)).Then(Create("Critical")), Choose().When(1, Create("High"))).
+		Else(Consume(), Transform("Low"))
+
+	cfg := (&EffectBuilder{ifCond: ifEffect.ifCond}).Build()
+	if len(cfg.Then) != 2 || cfg.Then[0].If == nil || cfg.Then[0].Then[0].Create.Species != "Critical" || cfg.Then[1].Choose == nil {
+		t.Errorf("Expected a nested if and a choose in then, got %+v", cfg.Then)
+	}
+	if len(cfg.Else) != 2 || !cfg.Else[0].Consume || cfg.Else[1].Transform == nil {
+		t.Errorf("Expected consume and transform in else, got %+v", cfg.Else)
+	}
+}
+
+func TestChooseEffectBuilder(t *testing.T) {
+	reaction := NewReaction("split").
+		Input("Event").
+		Effect(
+			Consume(),
+			Choose().
+				When(70, Create("A")).
+				When(30, Create("B"), Update().EnergyAdd(1)),
+		).
+		Build()
+
+	if len(reaction.Effects) != 2 || reaction.Effects[1].Choose == nil {
+		t.Fatalf("Expected a choose effect, got %+v", reaction.Effects)
+	}
+	branches := reaction.Effects[1].Choose.Branches
+	if len(branches) != 2 || branches[0].Weight != 70 || branches[1].Weight != 30 {
+		t.Fatalf("Expected branches weighted 70 and 30, got %+v", branches)
+	}
+	if len(branches[0].Effects) != 1 || branches[0].Effects[0].Create.Species != "A" {
+		t.Errorf("Expected the first branch to create A, got %+v", branches[0].Effects)
+	}
+	if len(branches[1].Effects) != 2 || branches[1].Effects[1].Update == nil {
+		t.Errorf("Expected the second branch to create B and update, got %+v", branches[1].Effects)
+	}
+}
+
 func TestCountMoleculesBuilder(t *testing.T) {
 	count := NewCountMolecules("Suspicion").
 		WhereEq("ip", Ref("m.ip")).
@@ -192,6 +463,24 @@ func TestCountMoleculesBuilder(t *testing.T) {
 	}
 }
 
+func TestCountMoleculesBuilder_Agg(t *testing.T) {
+	cfg := NewCountMolecules("Order").
+		Agg(achem.AggSum, "energy").
+		WithinTicks(50).
+		Op("gt", 10).
+		Build()
+
+	if cfg.Agg != achem.AggSum || cfg.Field != "energy" {
+		t.Errorf("Expected agg sum over energy, got %q over %q", cfg.Agg, cfg.Field)
+	}
+	if cfg.WithinTicks != 50 {
+		t.Errorf("Expected within_ticks 50, got %d", cfg.WithinTicks)
+	}
+	if cfg.Op["gt"] != 10 {
+		t.Errorf("Expected op gt=10, got %v", cfg.Op)
+	}
+}
+
 func TestPartnerBuilder(t *testing.T) {
 	partner := NewPartner("PartnerSpecies").
 		WhereEq("ip", Ref("m.ip")).
@@ -206,6 +495,13 @@ func TestPartnerBuilder(t *testing.T) {
 	if cfg.Count != 2 {
 		t.Errorf("Expected count 2, got %d", cfg.Count)
 	}
+
+	if cfg.Select != "" {
+		t.Errorf("Expected no selection order by default, got '%s'", cfg.Select)
+	}
+	if cfg := NewPartner("Worker").Select(achem.SelectOldest).Build(); cfg.Select != "oldest" {
+		t.Errorf("Expected select 'oldest', got '%s'", cfg.Select)
+	}
 }
 
 func TestCatalystBuilder(t *testing.T) {
@@ -228,6 +524,46 @@ func TestCatalystBuilder(t *testing.T) {
 	if cfg.MaxRate == nil || *cfg.MaxRate != 0.9 {
 		t.Errorf("Expected max_rate 0.9, got %v", cfg.MaxRate)
 	}
+
+	if cfg.Mode != "" {
+		t.Errorf("Expected default mode to be empty, got '%s'", cfg.Mode)
+	}
+	if cfg := NewCatalyst("Enzyme").Mode(achem.CatalystModeMultiply).Build(); cfg.Mode != "multiply" {
+		t.Errorf("Expected mode 'multiply', got '%s'", cfg.Mode)
+	}
+}
+
+func TestWithinDistanceBuilders(t *testing.T) {
+	partner := NewPartner("Oxygen").WithinDistance(2).Build()
+	if partner.WithinDistance != 2 || partner.IncludeUnpositioned {
+		t.Errorf("Unexpected partner config: %+v", partner)
+	}
+	catalyst := NewCatalyst("Spark").WithinDistance(1.5).IncludeUnpositioned().Build()
+	if catalyst.WithinDistance != 1.5 || !catalyst.IncludeUnpositioned {
+		t.Errorf("Unexpected catalyst config: %+v", catalyst)
+	}
+}
+
+func TestInhibitorBuilder(t *testing.T) {
+	cfg := NewReaction("alert").
+		Input("Suspicion").
+		Inhibitor(NewInhibitor("Suppression").WhereEq("ip", Ref("ip")).RateReduction(1.0)).
+		Inhibitor(NewInhibitor("Maintenance")).
+		Build()
+
+	if len(cfg.Inhibitors) != 2 {
+		t.Fatalf("Expected 2 inhibitors, got %d", len(cfg.Inhibitors))
+	}
+	suppression := cfg.Inhibitors[0]
+	if suppression.Species != "Suppression" || suppression.RateReduction != 1.0 {
+		t.Errorf("Expected Suppression inhibitor with reduction 1.0, got %+v", suppression)
+	}
+	if cond, ok := suppression.Where["ip"]; !ok || cond.Eq != "$m.ip" {
+		t.Errorf("Expected where ip = $m.ip, got %+v", suppression.Where)
+	}
+	if cfg.Inhibitors[1].RateReduction != 0.1 {
+		t.Errorf("Expected default reduction 0.1, got %f", cfg.Inhibitors[1].RateReduction)
+	}
 }
 
 func TestRef(t *testing.T) {
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// StatusError is returned when the server answers with a non-200 status.
+// Use errors.As to inspect the status code, e.g. to tell a missing environment
+// (http.StatusNotFound) apart from other failures.
+type StatusError struct {
+	StatusCode int
+	Body       string // response body, usually the error message
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Tick advances the environment by a single step.
+func Tick(ctx context.Context, baseURL, envID string) error {
+	_, err := sendRequest(ctx, http.MethodPost, baseURL, nil, nil, "env", envID, "tick")
+	return err
+}
+
+// TickAll advances every environment on the server by a single step, concurrently if
+// parallel is true, and returns the result of each environment. An environment that
+// fails to step is reported in its result and doesn't make TickAll fail.
+func TickAll(ctx context.Context, baseURL string, parallel bool) ([]achem.EnvironmentStepResult, error) {
+	query := url.Values{"parallel": {strconv.FormatBool(parallel)}}
+	body, err := sendRequest(ctx, http.MethodPost, baseURL, query, nil, "envs", "tick")
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Environments []achem.EnvironmentStepResult `json:"environments"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.Environments, nil
+}
+
+// Start makes the environment run continuously, stepping once per interval.
+// The interval is sent in milliseconds and must be at least 1ms.
+func Start(ctx context.Context, baseURL, envID string, interval time.Duration) error {
+	ms := interval.Milliseconds()
+	if ms <= 0 {
+		return fmt.Errorf("interval must be at least 1ms, got %s", interval)
+	}
+	query := url.Values{"interval": {strconv.FormatInt(ms, 10)}}
+	_, err := sendRequest(ctx, http.MethodPost, baseURL, query, nil, "env", envID, "start")
+	return err
+}
+
+// Stop stops a continuously running environment.
+func Stop(ctx context.Context, baseURL, envID string) error {
+	_, err := sendRequest(ctx, http.MethodPost, baseURL, nil, nil, "env", envID, "stop")
+	return err
+}
+
+// ListEnvironments returns the IDs of all environments on the server.
+func ListEnvironments(ctx context.Context, baseURL string) ([]string, error) {
+	body, err := sendRequest(ctx, http.MethodGet, baseURL, nil, nil, "envs")
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Environments []string `json:"environments"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.Environments, nil
+}
+
+// DeleteEnvironment deletes an environment and all its molecules.
+func DeleteEnvironment(ctx context.Context, baseURL, envID string) error {
+	_, err := sendRequest(ctx, http.MethodDelete, baseURL, nil, nil, "env", envID)
+	return err
+}
+
+// sendRequest sends a request to baseURL joined with path and returns the response
+// body. A non-nil payload is sent as JSON. Non-200 responses return a *StatusError.
+func sendRequest(ctx context.Context, method, baseURL string, query url.Values, payload []byte, path ...string) ([]byte, error) {
+	u, err := url.JoinPath(baseURL, path...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL: %w", err)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEnvironmentLifecycle(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/envs":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"environments":["a","b"]}`))
+		case "/envs/tick":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"environments":[{"env_id":"a","summary":{"ticks":1,"time":4}},{"env_id":"b","error":"environment is auto-running"}]}`))
+		case "/env/missing/tick":
+			http.Error(w, "environment not found", http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if err := Tick(ctx, srv.URL, "prod"); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if err := Start(ctx, srv.URL, "prod", 250*time.Millisecond); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := Stop(ctx, srv.URL, "prod"); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	ids, err := ListEnvironments(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("ListEnvironments failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("Expected [a b], got %v", ids)
+	}
+	results, err := TickAll(ctx, srv.URL, true)
+	if err != nil {
+		t.Fatalf("TickAll failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Summary == nil || results[0].Summary.Time != 4 || results[1].Error == "" {
+		t.Errorf("Unexpected TickAll results: %+v", results)
+	}
+	if err := DeleteEnvironment(ctx, srv.URL, "prod"); err != nil {
+		t.Fatalf("DeleteEnvironment failed: %v", err)
+	}
+
+	want := []string{
+		"POST /env/prod/tick",
+		"POST /env/prod/start?interval=250",
+		"POST /env/prod/stop",
+		"GET /envs",
+		"POST /envs/tick?parallel=true",
+		"DELETE /env/prod",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("Expected requests %v, got %v", want, requests)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("Request %d: expected %q, got %q", i, want[i], requests[i])
+		}
+	}
+
+	err = Tick(ctx, srv.URL, "missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 StatusError, got %v", err)
+	}
+
+	if err := Start(ctx, srv.URL, "prod", time.Microsecond); err == nil {
+		t.Error("Expected an error for a sub-millisecond interval")
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/daniacca/achemdb/internal/achem"
+)
+
+// subscribeBuffer is the number of events buffered on the channel returned by Subscribe
+const subscribeBuffer = 64
+
+// Reconnection backoff for Subscribe: starts at subscribeMinBackoff, doubles on every
+// failed attempt up to subscribeMaxBackoff, and resets once a connection succeeds.
+const (
+	subscribeMinBackoff = 100 * time.Millisecond
+	subscribeMaxBackoff = 10 * time.Second
+)
+
+// Subscribe connects to the Server-Sent Events stream of an "sse" notifier
+// (GET /notifiers/{id}/events) and delivers the notification events on the returned
+// channel. If the stream breaks, it reconnects with exponential backoff; events sent
+// while disconnected are lost. The channel is closed when ctx is cancelled, or when
+// the server rejects the subscription, e.g. because the notifier was unregistered.
+// The error reports a failure of the initial connection.
+func Subscribe(ctx context.Context, baseURL, notifierID string) (<-chan achem.NotificationEvent, error) {
+	u, err := url.JoinPath(baseURL, "notifiers", notifierID, "events")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL: %w", err)
+	}
+
+	body, err := openEventStream(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan achem.NotificationEvent, subscribeBuffer)
+	go func() {
+		defer close(events)
+
+		backoff := subscribeMinBackoff
+		for {
+			readEventStream(ctx, body, events)
+			_ = body.Close()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, subscribeMaxBackoff)
+
+				body, err = openEventStream(ctx, u)
+				if err == nil {
+					backoff = subscribeMinBackoff
+					break
+				}
+				var statusErr *StatusError
+				if errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 {
+					// retrying won't help: the notifier is gone or the request is invalid
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// openEventStream sends the subscription request and returns the response body.
+// Non-200 responses return a *StatusError.
+func openEventStream(ctx context.Context, u string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp.Body, nil
+}
+
+// readEventStream decodes events from an SSE stream and sends them on events until
+// the stream ends or ctx is cancelled. Events that are not valid JSON are skipped.
+func readEventStream(ctx context.Context, body io.Reader, events chan<- achem.NotificationEvent) {
+	reader := bufio.NewReader(body)
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// a blank line terminates an event; data may span several lines
+		if line == "" {
+			if len(data) == 0 {
+				continue
+			}
+			var event achem.NotificationEvent
+			decodeErr := json.Unmarshal([]byte(strings.Join(data, "\n")), &event)
+			data = data[:0]
+			if decodeErr != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+}
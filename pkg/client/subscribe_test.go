@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	var connections atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/notifiers/dash/events" {
+			http.Error(w, "notifier not found", http.StatusNotFound)
+			return
+		}
+		switch connections.Add(1) {
+		case 1:
+			// two events, one of them split over two data lines, then the stream breaks
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"reaction_id\":\"r1\",\"env_time\":1}\n\n")
+			fmt.Fprint(w, "data: not json\n\n")
+			fmt.Fprint(w, "data: {\"reaction_id\":\"r2\",\n")
+			fmt.Fprint(w, "data: \"env_time\":2}\n\n")
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"reaction_id\":\"r3\",\"env_time\":3}\n\n")
+		default:
+			http.Error(w, "notifier closed", http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := Subscribe(ctx, srv.URL, "missing"); err == nil {
+		t.Fatal("Expected an error subscribing to an unknown notifier")
+	}
+	connections.Store(0)
+
+	events, err := Subscribe(ctx, srv.URL, "dash")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	var got []string
+	for event := range events {
+		got = append(got, fmt.Sprintf("%s@%d", event.ReactionID, event.EnvTime))
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected the channel to close once the notifier is gone, not on timeout")
+	}
+
+	want := []string{"r1@1", "r2@2", "r3@3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v across the reconnection, got %v", want, got)
+	}
+}
+
+func TestSubscribe_ContextCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := Subscribe(ctx, srv.URL, "dash")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no events")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the channel to close after cancellation")
+	}
+}